@@ -14,15 +14,23 @@ import (
 	"humphreys/api/internal/db"
 	"humphreys/api/internal/middleware"
 	"humphreys/api/internal/modules/aisettings"
+	"humphreys/api/internal/modules/apikeys"
 	"humphreys/api/internal/modules/auth"
 	authsecurity "humphreys/api/internal/modules/auth/security"
 	"humphreys/api/internal/modules/catalog"
+	"humphreys/api/internal/modules/costing"
 	"humphreys/api/internal/modules/emailtemplates"
+	"humphreys/api/internal/modules/inventory"
+	"humphreys/api/internal/modules/notifications"
+	"humphreys/api/internal/modules/partsmarkup"
+	"humphreys/api/internal/modules/purchasing"
 	"humphreys/api/internal/modules/roles"
+	"humphreys/api/internal/modules/teams"
 	"humphreys/api/internal/modules/uploads"
 	"humphreys/api/internal/modules/userpreferences"
 	"humphreys/api/internal/modules/users"
 	"humphreys/api/internal/modules/workorders"
+	"humphreys/api/internal/permissions"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -49,16 +57,40 @@ func main() {
 		log.Fatalf("migrations failed: %v", err)
 	}
 
-	ownerHash, err := authsecurity.HashPassword(cfg.OwnerPassword)
-	if err != nil {
-		log.Fatalf("owner password hash failed: %v", err)
+	passwordPolicy := authsecurity.PasswordPolicy{
+		MinLength:           cfg.PasswordMinLength,
+		MinCharacterClasses: cfg.PasswordMinClasses,
+	}
+	if cfg.PasswordBreachList != "" {
+		passwordPolicy.Breached, err = authsecurity.NewBreachedPasswordList(cfg.PasswordBreachList)
+		if err != nil {
+			log.Fatalf("password policy error: %v", err)
+		}
 	}
-	if err := bootstrap.EnsureOwner(ctx, pool, cfg.OwnerEmail, ownerHash, cfg.OwnerFullName); err != nil {
+
+	if err := bootstrap.SyncPermissions(ctx, pool, permissionRegistry()); err != nil {
+		log.Fatalf("permission sync failed: %v", err)
+	}
+
+	if err := bootstrap.EnsureOwner(ctx, pool, cfg.OwnerEmail, cfg.OwnerPassword, cfg.OwnerFullName, passwordPolicy); err != nil {
 		log.Fatalf("owner bootstrap failed: %v", err)
 	}
 
-	authHandler := auth.New(pool, cfg)
-	usersHandler := users.New(pool)
+	signingKeys, err := authsecurity.NewKeySet(authsecurity.KeySetConfig{
+		HMACSecret:     cfg.JWTSecret,
+		PrivateKeysPEM: cfg.JWTPrivateKeys,
+		ActiveKID:      cfg.JWTActiveKID,
+		AcceptHS256:    cfg.JWTAcceptHS256,
+	})
+	if err != nil {
+		log.Fatalf("jwt key config error: %v", err)
+	}
+	if signingKeys.SigningKeyID() == "" && cfg.AppEnv == "production" && cfg.JWTSecret == "change-me-jwt-secret" {
+		log.Printf("warning: access tokens are signed with the default JWT_SECRET; configure JWT_PRIVATE_KEYS")
+	}
+
+	authHandler := auth.New(pool, cfg, signingKeys, passwordPolicy)
+	usersHandler := users.New(pool, passwordPolicy)
 	rolesHandler := roles.New(pool)
 	catalogHandler := catalog.New(pool)
 	aiSettingsHandler := aisettings.New(pool)
@@ -66,9 +98,22 @@ func main() {
 	workOrdersHandler := workorders.New(pool)
 	uploadsHandler := uploads.New(cfg)
 	userPreferencesHandler := userpreferences.New(pool)
+	purchasingHandler := purchasing.New(pool)
+	notificationsHandler := notifications.New(pool)
+	inventoryHandler := inventory.New(pool)
+	partsMarkupHandler := partsmarkup.New(pool)
+	costingHandler := costing.New(pool)
+	apiKeysHandler := apikeys.New(pool)
+	teamsHandler := teams.New(pool)
 	workOrdersHandler.SetUploadsHandler(uploadsHandler)
+	usersHandler.SetAuthHandler(authHandler)
 
 	r := gin.New()
+	// Client IPs feed login throttling and audit logs, so X-Forwarded-For is
+	// only honoured from the listed proxies; by default it is ignored.
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(gin.Logger(), gin.Recovery())
 	r.Use(middleware.ErrorLogger())
 	r.Use(middleware.CORS(cfg.CORSOrigin))
@@ -78,8 +123,11 @@ func main() {
 
 	auth.RegisterPublicRoutes(r, authHandler)
 
-	authed := r.Group("/")
-	authed.Use(middleware.Auth(cfg.JWTSecret))
+	authedGroup := r.Group("/")
+	permissionVersions := middleware.NewPermissionVersionCache(auth.NewPermissionVersionStore(pool), cfg.PermissionCacheTTL)
+	authedGroup.Use(middleware.Auth(signingKeys, permissionVersions, apikeys.NewAuthenticator(pool)))
+	authedGroup.Use(middleware.AuditImpersonation(auth.NewImpersonationAuditor(pool)))
+	authed := middleware.NewRouter(authedGroup)
 	auth.RegisterProtectedRoutes(authed, authHandler)
 	users.RegisterRoutes(authed, usersHandler)
 	roles.RegisterRoutes(authed, rolesHandler)
@@ -89,6 +137,14 @@ func main() {
 	workorders.RegisterRoutes(authed, workOrdersHandler)
 	uploads.RegisterRoutes(authed, uploadsHandler)
 	userpreferences.RegisterRoutes(authed, userPreferencesHandler)
+	purchasing.RegisterRoutes(authed, purchasingHandler)
+	notifications.RegisterRoutes(authed, notificationsHandler)
+	inventory.RegisterRoutes(authed, inventoryHandler)
+	partsmarkup.RegisterRoutes(authed, partsMarkupHandler)
+	costing.RegisterRoutes(authed, costingHandler)
+	apikeys.RegisterRoutes(authed, apiKeysHandler)
+	teams.RegisterRoutes(authed, teamsHandler)
+	usersHandler.SetRoutePermissions(authed.RoutePermissions())
 
 	srv := &http.Server{Addr: cfg.ServerAddr, Handler: r}
 	go func() {
@@ -108,3 +164,20 @@ func main() {
 		log.Printf("graceful shutdown failed: %v", err)
 	}
 }
+
+// permissionRegistry collects the resources and actions every module owns.
+func permissionRegistry() *permissions.Registry {
+	reg := permissions.NewRegistry()
+	users.RegisterPermissions(reg)
+	roles.RegisterPermissions(reg)
+	catalog.RegisterPermissions(reg)
+	workorders.RegisterPermissions(reg)
+	uploads.RegisterPermissions(reg)
+	purchasing.RegisterPermissions(reg)
+	inventory.RegisterPermissions(reg)
+	partsmarkup.RegisterPermissions(reg)
+	costing.RegisterPermissions(reg)
+	apikeys.RegisterPermissions(reg)
+	teams.RegisterPermissions(reg)
+	return reg
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var permissionCodePattern = regexp.MustCompile(`^[a-z_]+:[a-z_:]+$`)
+
+// TestRouteConstantsAreRegistered reports permission constants declared in a
+// module's routes.go that no module registers, since SyncPermissions would
+// never create them and the route could only ever return 403.
+func TestRouteConstantsAreRegistered(t *testing.T) {
+	files, err := filepath.Glob("../../internal/modules/*/routes.go")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("failed to find routes files: %v", err)
+	}
+	reg := permissionRegistry()
+	fset := token.NewFileSet()
+	for _, path := range files {
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			spec, ok := node.(*ast.ValueSpec)
+			if !ok {
+				return true
+			}
+			for i, name := range spec.Names {
+				if i >= len(spec.Values) {
+					break
+				}
+				lit, ok := spec.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				code, err := strconv.Unquote(lit.Value)
+				if err != nil || !permissionCodePattern.MatchString(code) {
+					continue
+				}
+				if !reg.Has(code) {
+					t.Errorf("%s: %s = %q is not registered", fset.Position(name.Pos()), name.Name, code)
+				}
+			}
+			return true
+		})
+	}
+}
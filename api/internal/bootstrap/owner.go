@@ -3,14 +3,21 @@ package bootstrap
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 
+	authsecurity "humphreys/api/internal/modules/auth/security"
+
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func EnsureOwner(ctx context.Context, db *pgxpool.Pool, email, passwordHash, fullName string) error {
-	if email == "" || passwordHash == "" {
+// EnsureOwner creates the owner account on first boot. The configured
+// password must satisfy the policy and has to be changed at first sign-in;
+// once the owner exists the password setting is ignored. Owner role grants
+// are kept current by SyncPermissions.
+func EnsureOwner(ctx context.Context, db *pgxpool.Pool, email, password, fullName string, policy authsecurity.PasswordPolicy) error {
+	if email == "" || password == "" {
 		return errors.New("owner bootstrap values must not be empty")
 	}
 
@@ -25,20 +32,18 @@ func EnsureOwner(ctx context.Context, db *pgxpool.Pool, email, passwordHash, ful
 		return err
 	}
 
-	if _, err := tx.Exec(ctx, `
-		INSERT INTO role_permissions(role_id, permission_id)
-		SELECT $1, p.id
-		FROM permissions p
-		ON CONFLICT DO NOTHING
-	`, ownerRoleID); err != nil {
-		return err
-	}
-
 	var userID string
 	err = tx.QueryRow(ctx, `SELECT id FROM users WHERE email=$1`, strings.ToLower(email)).Scan(&userID)
 	if errors.Is(err, pgx.ErrNoRows) {
+		if err := policy.Validate(password, email); err != nil {
+			return fmt.Errorf("OWNER_PASSWORD: %w", err)
+		}
+		passwordHash, err := authsecurity.HashPassword(password)
+		if err != nil {
+			return err
+		}
 		err = tx.QueryRow(ctx,
-			`INSERT INTO users(email, password_hash, full_name, status) VALUES($1,$2,$3,'active') RETURNING id`,
+			`INSERT INTO users(email, password_hash, full_name, status, must_change_password) VALUES($1,$2,$3,'active',TRUE) RETURNING id`,
 			strings.ToLower(email), passwordHash, fullName,
 		).Scan(&userID)
 		if err != nil {
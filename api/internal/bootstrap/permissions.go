@@ -0,0 +1,67 @@
+package bootstrap
+
+import (
+	"context"
+
+	"humphreys/api/internal/permissions"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SyncPermissions upserts every registered resource and permission, then
+// grants the owner role anything it does not hold yet. Nothing is deleted:
+// retiring a permission still takes a migration.
+func SyncPermissions(ctx context.Context, db *pgxpool.Pool, reg *permissions.Registry) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, resource := range reg.Resources() {
+		var resourceID string
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO resources(name, description)
+			VALUES($1,$2)
+			ON CONFLICT (name) DO UPDATE SET description=EXCLUDED.description
+			RETURNING id
+		`, resource.Name, resource.Description).Scan(&resourceID); err != nil {
+			return err
+		}
+		for _, action := range resource.Actions {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO permissions(resource_id, action, code)
+				VALUES($1,$2,$3)
+				ON CONFLICT (code) DO NOTHING
+			`, resourceID, action, permissions.Code(resource.Name, action)); err != nil {
+				return err
+			}
+		}
+	}
+
+	var ownerRoleID string
+	if err := tx.QueryRow(ctx, `SELECT id FROM roles WHERE name='owner'`).Scan(&ownerRoleID); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO role_permissions(role_id, permission_id)
+		SELECT $1, p.id
+		FROM permissions p
+		ON CONFLICT DO NOTHING
+	`, ownerRoleID)
+	if err != nil {
+		return err
+	}
+	// Owners holding tokens minted before the new grants refresh into them.
+	if tag.RowsAffected() > 0 {
+		if _, err := tx.Exec(ctx, `
+			UPDATE users
+			SET permissions_version=permissions_version+1
+			WHERE id IN (SELECT user_id FROM user_roles WHERE role_id=$1)
+		`, ownerRoleID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
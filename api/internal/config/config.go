@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,11 +19,32 @@ type Config struct {
 	DBName             string
 	DBSSLMode          string
 	JWTSecret          string
+	JWTPrivateKeys     string
+	JWTActiveKID       string
+	JWTAcceptHS256     bool
 	AccessTokenTTL     time.Duration
 	RefreshTokenTTL    time.Duration
+	MFAChallengeTTL    time.Duration
+	MFAIssuer          string
+	InviteTokenTTL     time.Duration
+	PasswordResetTTL   time.Duration
+	WebBaseURL         string
+	PermissionCacheTTL time.Duration
+	ImpersonationTTL   time.Duration
+	OIDCIssuer         string
+	OIDCClientID       string
+	OIDCClientSecret   string
+	OIDCRedirectURL    string
+	OIDCScopes         string
+	OIDCAutoProvision  bool
+	OIDCDefaultRole    string
+	PasswordMinLength  int
+	PasswordMinClasses int
+	PasswordBreachList string
 	CookieSecure       bool
 	CookieDomain       string
 	CORSOrigin         string
+	TrustedProxies     []string
 	OwnerEmail         string
 	OwnerPassword      string
 	OwnerFullName      string
@@ -38,36 +60,62 @@ type Config struct {
 
 func Load() (Config, error) {
 	cfg := Config{
-		AppEnv:          env("APP_ENV", "development"),
-		ServerAddr:      resolveServerAddr(),
-		DatabaseURLRaw:  env("DATABASE_URL", ""),
-		DBHost:          env("DB_HOST", "localhost"),
-		DBPort:          envInt("DB_PORT", 5432),
-		DBUser:          env("DB_USER", "postgres"),
-		DBPassword:      env("DB_PASSWORD", ""),
-		DBName:          env("DB_NAME", "admin_panel"),
-		DBSSLMode:       env("DB_SSLMODE", "disable"),
-		JWTSecret:       env("JWT_SECRET", "change-me-jwt-secret"),
-		AccessTokenTTL:  time.Duration(envInt("ACCESS_TOKEN_TTL_MINUTES", 15)) * time.Minute,
-		RefreshTokenTTL: time.Duration(envInt("REFRESH_TOKEN_TTL_HOURS", 720)) * time.Hour,
-		CookieSecure:    envBool("COOKIE_SECURE", false),
-		CookieDomain:    env("COOKIE_DOMAIN", ""),
-		CORSOrigin:      env("CORS_ORIGIN", "http://localhost:5173"),
-		OwnerEmail:      env("OWNER_EMAIL", "owner@example.com"),
-		OwnerPassword:   env("OWNER_PASSWORD", "ChangeMe123!"),
-		OwnerFullName:   env("OWNER_FULL_NAME", "Owner"),
-		MigrationsDir:   env("MIGRATIONS_DIR", "./migrations"),
-		S3Endpoint:      env("S3_ENDPOINT", ""),
-		S3Region:        env("S3_REGION", "us-east-1"),
-		S3AccessKeyID:   env("S3_ACCESS_KEY_ID", ""),
-		S3SecretAccessKey: env("S3_SECRET_ACCESS_KEY", ""),
-		S3Bucket:          env("S3_BUCKET", ""),
-		S3UseSSL:          envBool("S3_USE_SSL", true),
-		S3PublicBaseURL:   env("S3_PUBLIC_BASE_URL", ""),
+		AppEnv:             env("APP_ENV", "development"),
+		ServerAddr:         resolveServerAddr(),
+		DatabaseURLRaw:     env("DATABASE_URL", ""),
+		DBHost:             env("DB_HOST", "localhost"),
+		DBPort:             envInt("DB_PORT", 5432),
+		DBUser:             env("DB_USER", "postgres"),
+		DBPassword:         env("DB_PASSWORD", ""),
+		DBName:             env("DB_NAME", "admin_panel"),
+		DBSSLMode:          env("DB_SSLMODE", "disable"),
+		JWTSecret:          env("JWT_SECRET", "change-me-jwt-secret"),
+		JWTPrivateKeys:     strings.ReplaceAll(env("JWT_PRIVATE_KEYS", ""), `\n`, "\n"),
+		JWTActiveKID:       env("JWT_ACTIVE_KID", ""),
+		JWTAcceptHS256:     envBool("JWT_ACCEPT_HS256", true),
+		AccessTokenTTL:     time.Duration(envInt("ACCESS_TOKEN_TTL_MINUTES", 15)) * time.Minute,
+		RefreshTokenTTL:    time.Duration(envInt("REFRESH_TOKEN_TTL_HOURS", 720)) * time.Hour,
+		MFAChallengeTTL:    time.Duration(envInt("MFA_CHALLENGE_TTL_MINUTES", 5)) * time.Minute,
+		MFAIssuer:          env("MFA_ISSUER", "Humphreys"),
+		InviteTokenTTL:     time.Duration(envInt("INVITE_TOKEN_TTL_HOURS", 72)) * time.Hour,
+		PasswordResetTTL:   time.Duration(envInt("PASSWORD_RESET_TTL_MINUTES", 60)) * time.Minute,
+		PermissionCacheTTL: time.Duration(envInt("PERMISSION_CACHE_TTL_SECONDS", 5)) * time.Second,
+		ImpersonationTTL:   time.Duration(envInt("IMPERSONATION_TTL_MINUTES", 15)) * time.Minute,
+		OIDCIssuer:         strings.TrimRight(env("OIDC_ISSUER", ""), "/"),
+		OIDCClientID:       env("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:   env("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:    env("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:         env("OIDC_SCOPES", "openid email profile"),
+		OIDCAutoProvision:  envBool("OIDC_AUTO_PROVISION", false),
+		OIDCDefaultRole:    env("OIDC_DEFAULT_ROLE", ""),
+		PasswordMinLength:  envInt("PASSWORD_MIN_LENGTH", 12),
+		PasswordMinClasses: envInt("PASSWORD_MIN_CHARACTER_CLASSES", 3),
+		PasswordBreachList: env("PASSWORD_BREACH_LIST_PATH", ""),
+		CookieSecure:       envBool("COOKIE_SECURE", false),
+		CookieDomain:       env("COOKIE_DOMAIN", ""),
+		CORSOrigin:         env("CORS_ORIGIN", "http://localhost:5173"),
+		TrustedProxies:     envList("TRUSTED_PROXIES"),
+		OwnerEmail:         env("OWNER_EMAIL", "owner@example.com"),
+		OwnerPassword:      env("OWNER_PASSWORD", "ChangeMe123!"),
+		OwnerFullName:      env("OWNER_FULL_NAME", "Owner"),
+		MigrationsDir:      env("MIGRATIONS_DIR", "./migrations"),
+		S3Endpoint:         env("S3_ENDPOINT", ""),
+		S3Region:           env("S3_REGION", "us-east-1"),
+		S3AccessKeyID:      env("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:  env("S3_SECRET_ACCESS_KEY", ""),
+		S3Bucket:           env("S3_BUCKET", ""),
+		S3UseSSL:           envBool("S3_USE_SSL", true),
+		S3PublicBaseURL:    env("S3_PUBLIC_BASE_URL", ""),
 	}
 
-	if cfg.JWTSecret == "" {
-		return Config{}, fmt.Errorf("JWT_SECRET must not be empty")
+	cfg.WebBaseURL = strings.TrimRight(env("WEB_BASE_URL", cfg.CORSOrigin), "/")
+
+	if cfg.OIDCIssuer != "" && (cfg.OIDCClientID == "" || cfg.OIDCRedirectURL == "") {
+		return Config{}, fmt.Errorf("OIDC_CLIENT_ID and OIDC_REDIRECT_URL must be set when OIDC_ISSUER is set")
+	}
+
+	if cfg.JWTSecret == "" && cfg.JWTPrivateKeys == "" {
+		return Config{}, fmt.Errorf("JWT_SECRET or JWT_PRIVATE_KEYS must be set")
 	}
 
 	return cfg, nil
@@ -109,6 +157,17 @@ func envInt(key string, fallback int) int {
 	return v
 }
 
+// envList splits a comma-separated variable, dropping blank entries.
+func envList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(env(key, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func envBool(key string, fallback bool) bool {
 	raw := env(key, "")
 	if raw == "" {
@@ -0,0 +1,68 @@
+package domain
+
+import "time"
+
+type WorkerCostShare struct {
+	WorkerID   int64    `json:"worker_id"`
+	WorkerName string   `json:"worker_name"`
+	Hours      float64  `json:"hours"`
+	HourlyRate *float64 `json:"hourly_rate"`
+	LabourCost float64  `json:"labour_cost"`
+}
+
+type WorkOrderCosting struct {
+	ReferenceID      int32             `json:"reference_id"`
+	JobTypeID        *int64            `json:"job_type_id"`
+	CompletedAt      *time.Time        `json:"completed_at"`
+	LabourRevenue    float64           `json:"labour_revenue"`
+	PartsRevenue     float64           `json:"parts_revenue"`
+	DeliveryRevenue  float64           `json:"delivery_revenue"`
+	SurchargeRevenue float64           `json:"surcharge_revenue"`
+	Revenue          float64           `json:"revenue"`
+	LabourHours      float64           `json:"labour_hours"`
+	UnratedHours     float64           `json:"unrated_hours"`
+	LabourCost       float64           `json:"labour_cost"`
+	PartsCost        float64           `json:"parts_cost"`
+	Margin           float64           `json:"margin"`
+	MarginPercent    *float64          `json:"margin_percent"`
+	Workers          []WorkerCostShare `json:"workers"`
+}
+
+type WorkerRate struct {
+	WorkerID   int64    `json:"worker_id"`
+	WorkerName string   `json:"worker_name"`
+	IsActive   bool     `json:"is_active"`
+	HourlyRate *float64 `json:"hourly_rate"`
+}
+
+type CommissionRule struct {
+	CommissionRuleID int64      `json:"commission_rule_id"`
+	RuleName         string     `json:"rule_name"`
+	WorkerID         *int64     `json:"worker_id"`
+	WorkerName       *string    `json:"worker_name"`
+	JobTypeID        *int64     `json:"job_type_id"`
+	JobTypeName      *string    `json:"job_type_name"`
+	Basis            string     `json:"basis"`
+	RatePercent      float64    `json:"rate_percent"`
+	IsActive         bool       `json:"is_active"`
+	CreatedAt        *time.Time `json:"created_at"`
+	UpdatedAt        *time.Time `json:"updated_at"`
+}
+
+type CommissionLine struct {
+	ReferenceID      int32   `json:"reference_id"`
+	CommissionRuleID *int64  `json:"commission_rule_id"`
+	Basis            *string `json:"basis"`
+	BasisAmount      float64 `json:"basis_amount"`
+	RatePercent      float64 `json:"rate_percent"`
+	Commission       float64 `json:"commission"`
+}
+
+type TechnicianCommission struct {
+	WorkerID   int64            `json:"worker_id"`
+	WorkerName string           `json:"worker_name"`
+	JobCount   int              `json:"job_count"`
+	Hours      float64          `json:"hours"`
+	Commission float64          `json:"commission"`
+	Lines      []CommissionLine `json:"lines"`
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+type InventoryItem struct {
+	PartsItemPresetID     int64      `json:"parts_item_preset_id"`
+	PresetName            string     `json:"preset_name"`
+	SKU                   *string    `json:"sku"`
+	TrackInventory        bool       `json:"track_inventory"`
+	OnHandQuantity        int32      `json:"on_hand_quantity"`
+	ReorderPoint          int32      `json:"reorder_point"`
+	ReorderQuantity       int32      `json:"reorder_quantity"`
+	UnitCost              *float64   `json:"unit_cost"`
+	LocationID            *int64     `json:"location_id"`
+	LocationShelf         *string    `json:"location_shelf"`
+	LocationFloor         *int32     `json:"location_floor"`
+	PreferredSupplierID   *int64     `json:"preferred_supplier_id"`
+	PreferredSupplierName *string    `json:"preferred_supplier_name"`
+	IsActive              bool       `json:"is_active"`
+	UpdatedAt             *time.Time `json:"updated_at"`
+}
+
+type LowStockItem struct {
+	InventoryItem
+	OnOrderQuantity   int32 `json:"on_order_quantity"`
+	SuggestedQuantity int32 `json:"suggested_quantity"`
+}
+
+type StockMovement struct {
+	StockMovementID        int64      `json:"stock_movement_id"`
+	PartsItemPresetID      int64      `json:"parts_item_preset_id"`
+	PartsPurchaseRequestID *int64     `json:"parts_purchase_request_id"`
+	ReferenceID            *int32     `json:"reference_id"`
+	MovementType           string     `json:"movement_type"`
+	QuantityDelta          int32      `json:"quantity_delta"`
+	UnitCost               *float64   `json:"unit_cost"`
+	Note                   *string    `json:"note"`
+	CreatedByUserID        *string    `json:"created_by_user_id"`
+	CreatedByName          *string    `json:"created_by_name"`
+	CreatedAt              *time.Time `json:"created_at"`
+}
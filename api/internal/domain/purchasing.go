@@ -0,0 +1,60 @@
+package domain
+
+import "time"
+
+type Supplier struct {
+	SupplierID    int64      `json:"supplier_id"`
+	SupplierName  string     `json:"supplier_name"`
+	ContactName   *string    `json:"contact_name"`
+	Email         *string    `json:"email"`
+	Phone         *string    `json:"phone"`
+	Website       *string    `json:"website"`
+	AccountNumber *string    `json:"account_number"`
+	Notes         *string    `json:"notes"`
+	IsActive      bool       `json:"is_active"`
+	CreatedAt     *time.Time `json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+}
+
+type PurchaseOrder struct {
+	PurchaseOrderID int64                  `json:"purchase_order_id"`
+	OrderNumber     string                 `json:"order_number"`
+	SupplierID      int64                  `json:"supplier_id"`
+	SupplierName    string                 `json:"supplier_name"`
+	Status          string                 `json:"status"`
+	ShippingCost    float64                `json:"shipping_cost"`
+	ItemsTotal      float64                `json:"items_total"`
+	TrackingNumber  *string                `json:"tracking_number"`
+	TrackingURL     *string                `json:"tracking_url"`
+	Notes           *string                `json:"notes"`
+	OrderedAt       *time.Time             `json:"ordered_at"`
+	ReceivedAt      *time.Time             `json:"received_at"`
+	CreatedByUserID string                 `json:"created_by_user_id"`
+	CreatedByName   *string                `json:"created_by_name"`
+	CreatedAt       *time.Time             `json:"created_at"`
+	UpdatedAt       *time.Time             `json:"updated_at"`
+	Items           []PartsPurchaseRequest `json:"items,omitempty"`
+}
+
+type Notification struct {
+	NotificationID   int64      `json:"notification_id"`
+	NotificationType string     `json:"notification_type"`
+	ReferenceID      *int32     `json:"reference_id"`
+	Title            string     `json:"title"`
+	Body             *string    `json:"body"`
+	ReadAt           *time.Time `json:"read_at"`
+	CreatedAt        *time.Time `json:"created_at"`
+}
+
+type PartsMarkupRule struct {
+	MarkupRuleID  int64      `json:"markup_rule_id"`
+	RuleName      string     `json:"rule_name"`
+	SupplierID    *int64     `json:"supplier_id"`
+	SupplierName  *string    `json:"supplier_name"`
+	MinUnitCost   *float64   `json:"min_unit_cost"`
+	MaxUnitCost   *float64   `json:"max_unit_cost"`
+	MarkupPercent float64    `json:"markup_percent"`
+	IsActive      bool       `json:"is_active"`
+	CreatedAt     *time.Time `json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+}
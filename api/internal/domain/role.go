@@ -5,5 +5,7 @@ type Role struct {
 	Name        string       `json:"name"`
 	Description string       `json:"description"`
 	IsSystem    bool         `json:"is_system"`
+	MFARequired bool         `json:"mfa_required"`
+	TemplateKey *string      `json:"template_key,omitempty"`
 	Permissions []Permission `json:"permissions,omitempty"`
 }
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+type Team struct {
+	TeamID      int64        `json:"team_id"`
+	TeamName    string       `json:"team_name"`
+	Description *string      `json:"description"`
+	Members     []TeamMember `json:"members"`
+	CreatedAt   *time.Time   `json:"created_at"`
+	UpdatedAt   *time.Time   `json:"updated_at"`
+}
+
+type TeamMember struct {
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name"`
+}
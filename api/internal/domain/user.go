@@ -10,4 +10,37 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Roles     []Role    `json:"roles"`
+	// WorkerID links the account to the catalog worker assigned on jobs.
+	WorkerID   *int64  `json:"worker_id"`
+	WorkerName *string `json:"worker_name"`
+}
+
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+type Session struct {
+	FamilyID   string    `json:"family_id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type LoginEvent struct {
+	ID          string    `json:"id"`
+	UserID      *string   `json:"user_id,omitempty"`
+	Email       string    `json:"email"`
+	Outcome     string    `json:"outcome"`
+	Reason      string    `json:"reason"`
+	IPAddress   string    `json:"ip_address"`
+	UserAgent   string    `json:"user_agent"`
+	ActorUserID *string   `json:"actor_user_id,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
 }
@@ -2,91 +2,110 @@ package domain
 
 import "time"
 
+// Work order response fields carry a fieldpolicy class; unclassified fields
+// are hidden from every caller.
 type WorkOrderListItem struct {
-	ReferenceID   int32      `json:"reference_id"`
-	CreatedAt     *time.Time `json:"created_at"`
-	UpdatedAt     *time.Time `json:"updated_at"`
-	Status        string     `json:"status"`
-	StatusGroup   *string    `json:"status_group"`
-	JobType       string     `json:"job_type"`
-	LocationID    *int64     `json:"location_id"`
-	LocationCode  *string    `json:"location_code"`
-	LocationShelf *string    `json:"location_shelf"`
-	LocationFloor *int32     `json:"location_floor"`
-	CustomerName  *string    `json:"customer_name"`
-	CustomerEmail *string    `json:"customer_email"`
-	ItemName      *string    `json:"item_name"`
-	BrandNames    []string   `json:"brand_names"`
-	ModelNumber   *string    `json:"model_number"`
-	SerialNumber  *string    `json:"serial_number"`
-	LabourTotal   *float64   `json:"labour_total"`
+	ReferenceID   int32      `json:"reference_id" field:"public"`
+	CreatedAt     *time.Time `json:"created_at" field:"public"`
+	UpdatedAt     *time.Time `json:"updated_at" field:"public"`
+	Status        string     `json:"status" field:"public"`
+	StatusGroup   *string    `json:"status_group" field:"public"`
+	JobType       string     `json:"job_type" field:"public"`
+	LocationID    *int64     `json:"location_id" field:"public"`
+	LocationCode  *string    `json:"location_code" field:"public"`
+	LocationShelf *string    `json:"location_shelf" field:"public"`
+	LocationFloor *int32     `json:"location_floor" field:"public"`
+	TeamID        *int64     `json:"team_id" field:"public"`
+	TeamName      *string    `json:"team_name" field:"public"`
+	Priority      string     `json:"priority" field:"public"`
+	DueAt         *time.Time `json:"due_at" field:"public"`
+	IsRush        bool       `json:"is_rush" field:"public"`
+	CustomerName  *string    `json:"customer_name" field:"public"`
+	CustomerEmail *string    `json:"customer_email" field:"customer_contact"`
+	ItemName      *string    `json:"item_name" field:"public"`
+	BrandNames    []string   `json:"brand_names" field:"public"`
+	ModelNumber   *string    `json:"model_number" field:"public"`
+	SerialNumber  *string    `json:"serial_number" field:"public"`
+	LabourTotal   *float64   `json:"labour_total" field:"financials"`
 }
 
 type WorkOrderCustomer struct {
-	CustomerID   *int64  `json:"customer_id"`
-	FirstName    *string `json:"first_name"`
-	LastName     *string `json:"last_name"`
-	Email        *string `json:"email"`
-	AddressLine1 *string `json:"address_line_1"`
-	AddressLine2 *string `json:"address_line_2"`
-	City         *string `json:"city"`
-	Province     *string `json:"province"`
-	PostalCode   *string `json:"postal_code"`
-	HomePhone    *string `json:"home_phone"`
-	WorkPhone    *string `json:"work_phone"`
-	Remark       *string `json:"remark"`
+	CustomerID   *int64  `json:"customer_id" field:"public"`
+	FirstName    *string `json:"first_name" field:"public"`
+	LastName     *string `json:"last_name" field:"public"`
+	Email        *string `json:"email" field:"customer_contact"`
+	AddressLine1 *string `json:"address_line_1" field:"customer_contact"`
+	AddressLine2 *string `json:"address_line_2" field:"customer_contact"`
+	City         *string `json:"city" field:"customer_contact"`
+	Province     *string `json:"province" field:"customer_contact"`
+	PostalCode   *string `json:"postal_code" field:"customer_contact"`
+	HomePhone    *string `json:"home_phone" field:"customer_contact"`
+	WorkPhone    *string `json:"work_phone" field:"customer_contact"`
+	Remark       *string `json:"remark" field:"customer_contact"`
 }
 
 type WorkOrderLineItem struct {
-	LineItemID    int64    `json:"line_item_id"`
-	ItemName      *string  `json:"item_name"`
-	UnitPrice     *float64 `json:"unit_price"`
-	QuantityText  *string  `json:"quantity_text"`
-	LineTotalText *string  `json:"line_total_text"`
+	LineItemID             int64    `json:"line_item_id" field:"line_items"`
+	ItemName               *string  `json:"item_name" field:"line_items"`
+	UnitPrice              *float64 `json:"unit_price" field:"line_items"`
+	QuantityText           *string  `json:"quantity_text" field:"line_items"`
+	LineTotalText          *string  `json:"line_total_text" field:"line_items"`
+	PartsPurchaseRequestID *int64   `json:"parts_purchase_request_id" field:"line_items"`
+	CostTotal              *float64 `json:"cost_total" field:"line_items"`
+	IsRushSurcharge        bool     `json:"is_rush_surcharge" field:"line_items"`
 }
 
 type WorkOrderDetail struct {
-	ReferenceID        int32               `json:"reference_id"`
-	OriginalJobID      *int32              `json:"original_job_id"`
-	WarrantyJobIDs     []int32             `json:"warranty_job_ids"`
-	CreatedAt          *time.Time          `json:"created_at"`
-	UpdatedAt          *time.Time          `json:"updated_at"`
-	StatusID           *int64              `json:"status_id"`
-	StatusKey          *string             `json:"status_key"`
-	StatusName         *string             `json:"status_name"`
-	StatusGroup        *string             `json:"status_group"`
-	StatusUpdatedAt    *time.Time          `json:"status_updated_at"`
-	JobTypeID          *int64              `json:"job_type_id"`
-	JobTypeKey         *string             `json:"job_type_key"`
-	JobTypeName        *string             `json:"job_type_name"`
-	LocationID         *int64              `json:"location_id"`
-	LocationCode       *string             `json:"location_code"`
-	LocationShelf      *string             `json:"location_shelf"`
-	LocationFloor      *int32              `json:"location_floor"`
-	Customer           WorkOrderCustomer   `json:"customer"`
-	ItemID             *int64              `json:"item_id"`
-	ItemName           *string             `json:"item_name"`
-	BrandIDs           []int64             `json:"brand_ids"`
-	BrandNames         []string            `json:"brand_names"`
-	ModelNumber        *string             `json:"model_number"`
-	SerialNumber       *string             `json:"serial_number"`
-	OtherRemarks       *string             `json:"other_remarks"`
-	RemoteControlQty   int32               `json:"remote_control_qty"`
-	CableQty           int32               `json:"cable_qty"`
-	CordQty            int32               `json:"cord_qty"`
-	DVDVHSQty          int32               `json:"dvd_vhs_qty"`
-	AlbumCDCassetteQty int32               `json:"album_cd_cassette_qty"`
-	ProblemDescription *string             `json:"problem_description"`
-	WorkerIDs          []int64             `json:"worker_ids"`
-	WorkerNames        []string            `json:"worker_names"`
-	WorkDone           *string             `json:"work_done"`
-	PaymentMethodIDs   []int64             `json:"payment_method_ids"`
-	PaymentMethodNames []string            `json:"payment_method_names"`
-	PartsTotal         *float64            `json:"parts_total"`
-	DeliveryTotal      *float64            `json:"delivery_total"`
-	LabourTotal        *float64            `json:"labour_total"`
-	Deposit            float64             `json:"deposit"`
-	LineItems          []WorkOrderLineItem `json:"line_items"`
+	ReferenceID        int32               `json:"reference_id" field:"public"`
+	OriginalJobID      *int32              `json:"original_job_id" field:"public"`
+	WarrantyJobIDs     []int32             `json:"warranty_job_ids" field:"public"`
+	CreatedAt          *time.Time          `json:"created_at" field:"public"`
+	UpdatedAt          *time.Time          `json:"updated_at" field:"public"`
+	StatusID           *int64              `json:"status_id" field:"public"`
+	StatusKey          *string             `json:"status_key" field:"public"`
+	StatusName         *string             `json:"status_name" field:"public"`
+	StatusGroup        *string             `json:"status_group" field:"public"`
+	StatusUpdatedAt    *time.Time          `json:"status_updated_at" field:"public"`
+	JobTypeID          *int64              `json:"job_type_id" field:"public"`
+	JobTypeKey         *string             `json:"job_type_key" field:"public"`
+	JobTypeName        *string             `json:"job_type_name" field:"public"`
+	LocationID         *int64              `json:"location_id" field:"public"`
+	LocationCode       *string             `json:"location_code" field:"public"`
+	LocationShelf      *string             `json:"location_shelf" field:"public"`
+	LocationFloor      *int32              `json:"location_floor" field:"public"`
+	TeamID             *int64              `json:"team_id" field:"public"`
+	TeamName           *string             `json:"team_name" field:"public"`
+	Priority           string              `json:"priority" field:"public"`
+	DueAt              *time.Time          `json:"due_at" field:"public"`
+	IsRush             bool                `json:"is_rush" field:"public"`
+	CompletedAt        *time.Time          `json:"completed_at" field:"public"`
+	Customer           WorkOrderCustomer   `json:"customer" field:"nested"`
+	ItemID             *int64              `json:"item_id" field:"public"`
+	ItemName           *string             `json:"item_name" field:"public"`
+	BrandIDs           []int64             `json:"brand_ids" field:"public"`
+	BrandNames         []string            `json:"brand_names" field:"public"`
+	ModelNumber        *string             `json:"model_number" field:"public"`
+	SerialNumber       *string             `json:"serial_number" field:"public"`
+	OtherRemarks       *string             `json:"other_remarks" field:"public"`
+	RemoteControlQty   int32               `json:"remote_control_qty" field:"public"`
+	CableQty           int32               `json:"cable_qty" field:"public"`
+	CordQty            int32               `json:"cord_qty" field:"public"`
+	DVDVHSQty          int32               `json:"dvd_vhs_qty" field:"public"`
+	AlbumCDCassetteQty int32               `json:"album_cd_cassette_qty" field:"public"`
+	ProblemDescription *string             `json:"problem_description" field:"public"`
+	WorkerIDs          []int64             `json:"worker_ids" field:"public"`
+	WorkerNames        []string            `json:"worker_names" field:"public"`
+	WorkDone           *string             `json:"work_done" field:"public"`
+	PaymentMethodIDs   []int64             `json:"payment_method_ids" field:"payment_methods"`
+	PaymentMethodNames []string            `json:"payment_method_names" field:"payment_methods"`
+	PartsTotal         *float64            `json:"parts_total" field:"financials"`
+	PartsCostTotal     *float64            `json:"parts_cost_total" field:"financials"`
+	PartsMargin        *float64            `json:"parts_margin" field:"financials"`
+	RushSurchargeTotal *float64            `json:"rush_surcharge_total" field:"financials"`
+	DeliveryTotal      *float64            `json:"delivery_total" field:"financials"`
+	LabourTotal        *float64            `json:"labour_total" field:"financials"`
+	Deposit            float64             `json:"deposit" field:"financials"`
+	LineItems          []WorkOrderLineItem `json:"line_items" field:"line_items"`
 }
 
 type RepairLog struct {
@@ -97,25 +116,93 @@ type RepairLog struct {
 	Details         string     `json:"details"`
 	CreatedByUserID string     `json:"created_by_user_id"`
 	CreatedByName   *string    `json:"created_by_name"`
-	CreatedAt       *time.Time `json:"created_at"`
-	UpdatedAt       *time.Time `json:"updated_at"`
+	// CreatedByWorkerID is the catalog worker linked to the author's account.
+	CreatedByWorkerID   *int64     `json:"created_by_worker_id"`
+	CreatedByWorkerName *string    `json:"created_by_worker_name"`
+	CreatedAt           *time.Time `json:"created_at"`
+	UpdatedAt           *time.Time `json:"updated_at"`
+	// SuggestedWorkerID is only set on create, when the author's worker is
+	// not yet among the work order's assigned workers.
+	SuggestedWorkerID *int64 `json:"suggested_worker_id,omitempty"`
+}
+
+// WorkOrderComment is an informal note in a work order's discussion thread,
+// unlike a RepairLog which records bench work.
+type WorkOrderComment struct {
+	CommentID       int64         `json:"comment_id"`
+	ReferenceID     int32         `json:"reference_id"`
+	Body            string        `json:"body"`
+	CreatedByUserID *string       `json:"created_by_user_id"`
+	CreatedByName   *string       `json:"created_by_name"`
+	Mentions        []CommentUser `json:"mentions"`
+	CreatedAt       *time.Time    `json:"created_at"`
+	UpdatedAt       *time.Time    `json:"updated_at"`
+	EditedAt        *time.Time    `json:"edited_at"`
+}
+
+type WorkOrderCommentRevision struct {
+	RevisionID     int64      `json:"revision_id"`
+	CommentID      int64      `json:"comment_id"`
+	Body           string     `json:"body"`
+	EditedByUserID *string    `json:"edited_by_user_id"`
+	EditedByName   *string    `json:"edited_by_name"`
+	CreatedAt      *time.Time `json:"created_at"`
+}
+
+// CommentUser is a user who can be, or was, @mentioned in a comment.
+type CommentUser struct {
+	UserID   string `json:"user_id"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name"`
 }
 
+// PartsPurchaseRequest has no ReferenceID when it restocks shelf inventory
+// instead of buying parts for a work order.
 type PartsPurchaseRequest struct {
 	PartsPurchaseRequestID int64      `json:"parts_purchase_request_id"`
-	ReferenceID            int32      `json:"reference_id"`
+	ReferenceID            *int32     `json:"reference_id"`
 	Source                 string     `json:"source"`
 	SourceURL              *string    `json:"source_url"`
 	Status                 string     `json:"status"`
 	TotalPrice             float64    `json:"total_price"`
 	ItemName               string     `json:"item_name"`
 	Quantity               int32      `json:"quantity"`
+	PartsItemPresetID      *int64     `json:"parts_item_preset_id"`
+	SupplierID             *int64     `json:"supplier_id"`
+	SupplierName           *string    `json:"supplier_name"`
+	PurchaseOrderID        *int64     `json:"purchase_order_id"`
+	ReceivedAt             *time.Time `json:"received_at"`
+	ApprovedByUserID       *string    `json:"approved_by_user_id"`
+	ApprovedAt             *time.Time `json:"approved_at"`
 	CreatedByUserID        string     `json:"created_by_user_id"`
 	CreatedByName          *string    `json:"created_by_name"`
 	CreatedAt              *time.Time `json:"created_at"`
 	UpdatedAt              *time.Time `json:"updated_at"`
 }
 
+type PartsPurchaseRequestApproval struct {
+	ApprovalID             int64      `json:"approval_id"`
+	PartsPurchaseRequestID int64      `json:"parts_purchase_request_id"`
+	ApproverUserID         *string    `json:"approver_user_id"`
+	ApproverName           *string    `json:"approver_name"`
+	Decision               string     `json:"decision"`
+	TotalPrice             float64    `json:"total_price"`
+	Comment                *string    `json:"comment"`
+	CreatedAt              *time.Time `json:"created_at"`
+}
+
+type PartsApprovalLimit struct {
+	UserID        string     `json:"user_id"`
+	FullName      *string    `json:"full_name"`
+	MaxTotalPrice float64    `json:"max_total_price"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+}
+
+type PartsApprovalSettings struct {
+	Threshold *float64             `json:"threshold"`
+	Limits    []PartsApprovalLimit `json:"limits"`
+}
+
 type DashboardWorkOrderItem struct {
 	ReferenceID     int32      `json:"reference_id"`
 	CustomerName    *string    `json:"customer_name"`
@@ -132,11 +219,22 @@ type DashboardOverdueItem struct {
 	StatusUpdatedAt *time.Time `json:"status_updated_at"`
 }
 
+// DashboardPromiseItem is an open work order whose due date has passed.
+type DashboardPromiseItem struct {
+	ReferenceID  int32      `json:"reference_id"`
+	CustomerName *string    `json:"customer_name"`
+	ItemName     *string    `json:"item_name"`
+	Priority     string     `json:"priority"`
+	DueAt        *time.Time `json:"due_at"`
+	LateDays     int32      `json:"late_days"`
+}
+
 type DashboardPartsReviewItem struct {
 	PartsPurchaseRequestID int64      `json:"parts_purchase_request_id"`
-	ReferenceID            int32      `json:"reference_id"`
+	ReferenceID            *int32     `json:"reference_id"`
 	ItemName               string     `json:"item_name"`
 	TotalPrice             float64    `json:"total_price"`
+	RequiresApproval       bool       `json:"requires_approval"`
 	CreatedAt              *time.Time `json:"created_at"`
 }
 
@@ -149,10 +247,72 @@ type DashboardActivityItem struct {
 }
 
 type DashboardData struct {
-	ReadyTotal       int64                      `json:"ready_total"`
-	OverdueTotal     int64                      `json:"overdue_total"`
-	ReadyItems       []DashboardWorkOrderItem   `json:"ready_items"`
-	OverdueItems     []DashboardOverdueItem     `json:"overdue_items"`
-	PartsReviewItems []DashboardPartsReviewItem `json:"parts_review_items"`
-	ActivityItems    []DashboardActivityItem    `json:"activity_items"`
+	ReadyTotal          int64                      `json:"ready_total"`
+	OverdueTotal        int64                      `json:"overdue_total"`
+	PromiseOverdueTotal int64                      `json:"promise_overdue_total"`
+	ReadyItems          []DashboardWorkOrderItem   `json:"ready_items"`
+	OverdueItems        []DashboardOverdueItem     `json:"overdue_items"`
+	PromiseOverdueItems []DashboardPromiseItem     `json:"promise_overdue_items"`
+	PartsReviewItems    []DashboardPartsReviewItem `json:"parts_review_items"`
+	ActivityItems       []DashboardActivityItem    `json:"activity_items"`
+}
+
+// WorkOrderQueueItem is one open job in a technician's queue, ordered by due
+// date, then priority, then age.
+type WorkOrderQueueItem struct {
+	ReferenceID    int32      `json:"reference_id" field:"public"`
+	Status         string     `json:"status" field:"public"`
+	StatusGroup    *string    `json:"status_group" field:"public"`
+	Priority       string     `json:"priority" field:"public"`
+	DueAt          *time.Time `json:"due_at" field:"public"`
+	IsRush         bool       `json:"is_rush" field:"public"`
+	PromiseOverdue bool       `json:"promise_overdue" field:"public"`
+	CreatedAt      *time.Time `json:"created_at" field:"public"`
+	AgeDays        int32      `json:"age_days" field:"public"`
+	CustomerName   *string    `json:"customer_name" field:"public"`
+	ItemName       *string    `json:"item_name" field:"public"`
+	TeamName       *string    `json:"team_name" field:"public"`
+}
+
+type WorkOrderDueDateChange struct {
+	DueDateChangeID int64      `json:"due_date_change_id"`
+	ReferenceID     int32      `json:"reference_id"`
+	PreviousDueAt   *time.Time `json:"previous_due_at"`
+	NewDueAt        *time.Time `json:"new_due_at"`
+	Reason          *string    `json:"reason"`
+	ChangedByUserID *string    `json:"changed_by_user_id"`
+	ChangedByName   *string    `json:"changed_by_name"`
+	CreatedAt       *time.Time `json:"created_at"`
+}
+
+type RushSettings struct {
+	// Surcharge is added as a line item to rush jobs; nil means none.
+	Surcharge *float64 `json:"surcharge"`
+}
+
+// PromiseReportItem is graded against OriginalDueAt, the first date the
+// customer was promised, however often DueAt has moved since.
+type PromiseReportItem struct {
+	ReferenceID    int32                    `json:"reference_id"`
+	ItemName       *string                  `json:"item_name"`
+	IsRush         bool                     `json:"is_rush"`
+	OriginalDueAt  time.Time                `json:"original_due_at"`
+	DueAt          *time.Time               `json:"due_at"`
+	CompletedAt    *time.Time               `json:"completed_at"`
+	DueDateChanges []WorkOrderDueDateChange `json:"due_date_changes"`
+	Outcome        string                   `json:"outcome"`
+}
+
+// PromiseReport counts every promise in the period. Promises whose date was
+// moved are listed under Moved, with the reasons given, instead of Items.
+type PromiseReport struct {
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Kept     int                 `json:"kept"`
+	Broken   int                 `json:"broken"`
+	Overdue  int                 `json:"overdue"`
+	Pending  int                 `json:"pending"`
+	KeptRate *float64            `json:"kept_rate"`
+	Items    []PromiseReportItem `json:"items"`
+	Moved    []PromiseReportItem `json:"moved"`
 }
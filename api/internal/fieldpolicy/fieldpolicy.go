@@ -0,0 +1,160 @@
+// Package fieldpolicy hides response fields the caller is not allowed to see.
+//
+// Fields are classified with a `field:"<class>"` struct tag and a Policy maps
+// each class to the permission that reveals it. A field with no tag, or with
+// a class the policy does not know, is always hidden, so adding a column to a
+// response type keeps it out of responses until someone classifies it.
+package fieldpolicy
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	tagName = "field"
+
+	// Public fields are visible to anyone who can read the resource.
+	Public = "public"
+	// Nested marks a struct (or pointer or slice of structs) whose own
+	// fields are classified one by one.
+	Nested = "nested"
+)
+
+// Policy maps field classes to the permission code that reveals them.
+type Policy struct {
+	classes map[string]string
+}
+
+func New(classes map[string]string) Policy {
+	copied := make(map[string]string, len(classes))
+	for class, permission := range classes {
+		copied[class] = permission
+	}
+	return Policy{classes: copied}
+}
+
+// Visibility is the set of classes one caller may see.
+type Visibility map[string]bool
+
+func (v Visibility) Allows(class string) bool {
+	return class == Public || v[class]
+}
+
+// Key identifies the visibility for cache keys; callers who see the same
+// classes share a key.
+func (v Visibility) Key() string {
+	classes := make([]string, 0, len(v))
+	for class, ok := range v {
+		if ok {
+			classes = append(classes, class)
+		}
+	}
+	sort.Strings(classes)
+	return strings.Join(classes, ",")
+}
+
+// Visible resolves which classes scope reveals.
+func (p Policy) Visible(scope []string) Visibility {
+	held := make(map[string]struct{}, len(scope))
+	for _, code := range scope {
+		held[code] = struct{}{}
+	}
+	visible := make(Visibility, len(p.classes))
+	for class, permission := range p.classes {
+		if _, ok := held[permission]; ok {
+			visible[class] = true
+		}
+	}
+	return visible
+}
+
+// Apply zeroes every field of target that visible does not allow. target must
+// be a pointer to a struct or to a slice of structs. Hidden slices become
+// empty rather than nil so they still encode as [].
+func (p Policy) Apply(target any, visible Visibility) {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return
+	}
+	p.apply(value.Elem(), visible)
+}
+
+func (p Policy) apply(value reflect.Value, visible Visibility) {
+	switch value.Kind() {
+	case reflect.Pointer:
+		if !value.IsNil() {
+			p.apply(value.Elem(), visible)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			p.apply(value.Index(i), visible)
+		}
+	case reflect.Struct:
+		structType := value.Type()
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			class := field.Tag.Get(tagName)
+			switch {
+			case class == Nested:
+				p.apply(value.Field(i), visible)
+			case p.known(class) && visible.Allows(class):
+			default:
+				hide(value.Field(i))
+			}
+		}
+	}
+}
+
+func (p Policy) known(class string) bool {
+	if class == Public {
+		return true
+	}
+	_, ok := p.classes[class]
+	return ok
+}
+
+func hide(value reflect.Value) {
+	if value.Kind() == reflect.Slice {
+		value.Set(reflect.MakeSlice(value.Type(), 0, 0))
+		return
+	}
+	value.Set(reflect.Zero(value.Type()))
+}
+
+// Unclassified lists the fields of sample's type, and of any nested types,
+// that carry no class this policy knows. Tests use it to catch new fields
+// that would otherwise be silently hidden.
+func (p Policy) Unclassified(sample any) []string {
+	var missing []string
+	p.unclassified(reflect.TypeOf(sample), "", &missing)
+	sort.Strings(missing)
+	return missing
+}
+
+func (p Policy) unclassified(t reflect.Type, prefix string, missing *[]string) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + field.Name
+		class := field.Tag.Get(tagName)
+		switch {
+		case class == Nested:
+			p.unclassified(field.Type, name+".", missing)
+		case !p.known(class):
+			*missing = append(*missing, name)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package fieldpolicy
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testContact struct {
+	Name  *string `field:"public"`
+	Email *string `field:"contact"`
+}
+
+type testRecord struct {
+	ID       int64         `field:"public"`
+	Contact  testContact   `field:"nested"`
+	Total    *float64      `field:"financials"`
+	Deposit  float64       `field:"financials"`
+	Lines    []string      `field:"financials"`
+	Related  []testContact `field:"nested"`
+	Internal string
+	Legacy   string `field:"retired"`
+}
+
+func TestPolicyApply(t *testing.T) {
+	policy := New(map[string]string{
+		"contact":    "customers_sensitive:read",
+		"financials": "financials:read",
+	})
+	name := "Ada"
+	email := "ada@example.com"
+	total := 42.0
+	record := func() testRecord {
+		return testRecord{
+			ID:       7,
+			Contact:  testContact{Name: &name, Email: &email},
+			Total:    &total,
+			Deposit:  10,
+			Lines:    []string{"part"},
+			Related:  []testContact{{Name: &name, Email: &email}},
+			Internal: "x",
+			Legacy:   "y",
+		}
+	}
+
+	got := record()
+	policy.Apply(&got, policy.Visible([]string{"financials:read"}))
+	if got.ID != 7 || got.Contact.Name == nil || got.Total == nil || got.Deposit != 10 || len(got.Lines) != 1 {
+		t.Fatalf("expected public and financial fields to survive, got %+v", got)
+	}
+	if got.Contact.Email != nil || got.Related[0].Email != nil || got.Related[0].Name == nil {
+		t.Fatalf("expected nested contact fields to be hidden, got %+v", got)
+	}
+	if got.Internal != "" || got.Legacy != "" {
+		t.Fatalf("expected unclassified fields to be hidden, got %+v", got)
+	}
+
+	items := []testRecord{record()}
+	policy.Apply(&items, policy.Visible(nil))
+	if items[0].Total != nil || items[0].Deposit != 0 || items[0].Lines == nil || len(items[0].Lines) != 0 {
+		t.Fatalf("expected financial fields to be zeroed with an empty slice, got %+v", items[0])
+	}
+	if items[0].ID != 7 || items[0].Contact.Name == nil {
+		t.Fatalf("expected public fields to survive, got %+v", items[0])
+	}
+}
+
+func TestPolicyUnclassified(t *testing.T) {
+	policy := New(map[string]string{"contact": "a", "financials": "b"})
+	got := policy.Unclassified(testRecord{})
+	want := []string{"Internal", "Legacy"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	authsecurity "humphreys/api/internal/modules/auth/security"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyAuthenticator resolves a raw API key to the claims it grants and
+// records each request made with it.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, raw string) (*authsecurity.Claims, error)
+	RecordAPIKeyRequest(ctx context.Context, req APIKeyRequest)
+}
+
+type APIKeyRequest struct {
+	APIKeyID  string
+	UserID    string
+	Method    string
+	Path      string
+	Route     string
+	Status    int
+	IPAddress string
+	UserAgent string
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, raw string) (*authsecurity.Claims, bool) {
+	if apiKeys == nil {
+		return nil, false
+	}
+	claims, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), raw)
+	if err != nil || claims == nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+func recordAPIKeyRequest(c *gin.Context, apiKeys APIKeyAuthenticator, claims *authsecurity.Claims) {
+	apiKeys.RecordAPIKeyRequest(context.WithoutCancel(c.Request.Context()), APIKeyRequest{
+		APIKeyID:  claims.APIKeyID,
+		UserID:    claims.UserID,
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Route:     c.FullPath(),
+		Status:    c.Writer.Status(),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+}
+
+// RejectAPIKeys keeps API keys off account-management routes (sessions, MFA,
+// key management itself) that only make sense for an interactive user.
+func RejectAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, ok := Claims(c); ok && claims.APIKeyID != "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "api keys cannot access this endpoint"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	if !strings.HasPrefix(strings.ToLower(header), "bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(header[len("Bearer "):]), true
+}
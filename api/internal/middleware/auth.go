@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"net/http"
-	"strings"
 
 	authsecurity "humphreys/api/internal/modules/auth/security"
 
@@ -11,19 +10,61 @@ import (
 
 const claimsContextKey = "auth_claims"
 
-func Auth(secret string) gin.HandlerFunc {
+// Auth validates the bearer token. When versions is set, tokens minted before
+// the user's latest role, permission or status change are rejected with
+// refresh_required so the client fetches a fresh access token. Bearer values
+// carrying the API key prefix are checked against apiKeys instead, and every
+// request made with a key is recorded once the handler has run. Impersonation
+// tokens also need the real actor to still be active and unchanged.
+func Auth(keys *authsecurity.KeySet, versions *PermissionVersionCache, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		header := c.GetHeader("Authorization")
-		if !strings.HasPrefix(strings.ToLower(header), "bearer ") {
+		raw, ok := bearerToken(c)
+		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
 			return
 		}
-		raw := strings.TrimSpace(header[len("Bearer "):])
-		claims, err := authsecurity.ParseAccessToken(secret, raw)
+		if authsecurity.IsAPIKey(raw) {
+			claims, ok := authenticateAPIKey(c, apiKeys, raw)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+				return
+			}
+			c.Set(claimsContextKey, claims)
+			c.Next()
+			recordAPIKeyRequest(c, apiKeys, claims)
+			return
+		}
+		claims, err := keys.ParseAccessToken(raw)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
+		if versions != nil {
+			entry, err := versions.lookup(c.Request.Context(), claims.UserID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify token"})
+				return
+			}
+			if !entry.active {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				return
+			}
+			if claims.PermissionsVersion != entry.version {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token permissions are stale", "refresh_required": true})
+				return
+			}
+			if claims.Actor != nil {
+				actor, err := versions.lookup(c.Request.Context(), claims.Actor.UserID)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "failed to verify token"})
+					return
+				}
+				if !actor.active || claims.Actor.PermissionsVersion != actor.version {
+					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "impersonation ended", "refresh_required": true})
+					return
+				}
+			}
+		}
 		c.Set(claimsContextKey, claims)
 		c.Next()
 	}
@@ -39,18 +80,45 @@ func Claims(c *gin.Context) (*authsecurity.Claims, bool) {
 }
 
 func RequirePermission(permission string) gin.HandlerFunc {
-	return func(c *gin.Context) {
+	return requireAnyPermission(permission)
+}
+
+// AssignedScopeSuffix narrows a permission to records assigned to the caller,
+// e.g. "work_orders:read:assigned".
+const AssignedScopeSuffix = ":assigned"
+
+// RequirePermissionOrAssigned also admits callers holding only the
+// ":assigned" form of permission. The handler must then restrict what it
+// returns to the caller's assigned records.
+func RequirePermissionOrAssigned(permission string) gin.HandlerFunc {
+	return requireAnyPermission(permission, permission+AssignedScopeSuffix)
+}
+
+// RequireAnyPermission admits callers holding at least one of permissions.
+func RequireAnyPermission(permissions ...string) gin.HandlerFunc {
+	return requireAnyPermission(permissions...)
+}
+
+// requireAnyPermission names the codes it wanted in its 403 body so a denied
+// caller can tell which grant is missing. Each check is remembered with its
+// codes so a Router can record what the routes it registers require.
+func requireAnyPermission(permissions ...string) gin.HandlerFunc {
+	check := func(c *gin.Context) {
 		claims, ok := Claims(c)
 		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
 			return
 		}
 		for _, code := range claims.Scope {
-			if code == permission {
-				c.Next()
-				return
+			for _, permission := range permissions {
+				if code == permission {
+					c.Next()
+					return
+				}
 			}
 		}
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden", "required_permissions": permissions})
 	}
+	rememberPermissionCheck(check, permissions)
+	return check
 }
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -31,3 +33,181 @@ func TestRequirePermission(t *testing.T) {
 		t.Fatalf("expected 200, got %d", w.Code)
 	}
 }
+
+func TestRequirePermissionOrAssigned(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		scope []string
+		want  int
+	}{
+		{scope: []string{"work_orders:read"}, want: http.StatusOK},
+		{scope: []string{"work_orders:read:assigned"}, want: http.StatusOK},
+		{scope: []string{"repair_logs:read:assigned"}, want: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			c.Set("auth_claims", &authsecurity.Claims{UserID: "u1", Scope: tc.scope})
+			c.Next()
+		})
+		r.GET("/test", RequirePermissionOrAssigned("work_orders:read"), func(c *gin.Context) { c.Status(http.StatusOK) })
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+		if w.Code != tc.want {
+			t.Fatalf("%v: expected %d, got %d", tc.scope, tc.want, w.Code)
+		}
+	}
+}
+
+type fakePermissionVersions struct {
+	version int64
+	active  bool
+}
+
+func (f fakePermissionVersions) PermissionVersion(context.Context, string) (int64, bool, error) {
+	return f.version, f.active, nil
+}
+
+func TestAuthRejectsStalePermissionsVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		name         string
+		tokenVersion int64
+		source       fakePermissionVersions
+		want         int
+	}{
+		{name: "current version", tokenVersion: 3, source: fakePermissionVersions{version: 3, active: true}, want: http.StatusOK},
+		{name: "stale version", tokenVersion: 2, source: fakePermissionVersions{version: 3, active: true}, want: http.StatusUnauthorized},
+		{name: "inactive user", tokenVersion: 3, source: fakePermissionVersions{version: 3, active: false}, want: http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		r := gin.New()
+		r.GET("/test", Auth(authsecurity.NewHMACKeySet("secret"), NewPermissionVersionCache(tc.source, time.Minute), nil), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		tok, _, _ := authsecurity.NewSessionAccessToken("secret", time.Minute, "u1", "f1", tc.tokenVersion, []string{"r1"}, []string{"users:read"})
+
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+tok)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.name, tc.want, w.Code)
+		}
+	}
+}
+
+type fakeAPIKeys struct {
+	raw      string
+	recorded []APIKeyRequest
+}
+
+func (f *fakeAPIKeys) AuthenticateAPIKey(_ context.Context, raw string) (*authsecurity.Claims, error) {
+	if raw != f.raw {
+		return nil, errors.New("unknown key")
+	}
+	return &authsecurity.Claims{UserID: "u1", APIKeyID: "k1", Scope: []string{"work_orders:read"}}, nil
+}
+
+func (f *fakeAPIKeys) RecordAPIKeyRequest(_ context.Context, req APIKeyRequest) {
+	f.recorded = append(f.recorded, req)
+}
+
+func TestAuthAcceptsScopedAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	raw, _, _ := authsecurity.NewAPIKey()
+	apiKeys := &fakeAPIKeys{raw: raw}
+	tampered := raw[:len(raw)-1] + "x"
+	if tampered == raw {
+		tampered = raw[:len(raw)-1] + "y"
+	}
+	r := gin.New()
+	authed := r.Group("/", Auth(authsecurity.NewHMACKeySet("secret"), nil, apiKeys))
+	authed.GET("/work-orders", RequirePermission("work_orders:read"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	authed.GET("/users", RequirePermission("users:read"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	authed.GET("/auth/sessions", RejectAPIKeys(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	cases := []struct {
+		path string
+		key  string
+		want int
+	}{
+		{path: "/work-orders", key: raw, want: http.StatusOK},
+		{path: "/users", key: raw, want: http.StatusForbidden},
+		{path: "/auth/sessions", key: raw, want: http.StatusForbidden},
+		{path: "/work-orders", key: tampered, want: http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d", tc.path, tc.want, w.Code)
+		}
+	}
+	if len(apiKeys.recorded) != 3 {
+		t.Fatalf("expected every authenticated key request to be recorded, got %d", len(apiKeys.recorded))
+	}
+	if got := apiKeys.recorded[1]; got.Route != "/users" || got.Status != http.StatusForbidden || got.APIKeyID != "k1" {
+		t.Fatalf("unexpected audit record: %#v", got)
+	}
+}
+
+type fakeUserVersions map[string]fakePermissionVersions
+
+func (f fakeUserVersions) PermissionVersion(_ context.Context, userID string) (int64, bool, error) {
+	entry := f[userID]
+	return entry.version, entry.active, nil
+}
+
+type fakeImpersonationAuditor struct {
+	recorded []ImpersonatedRequest
+}
+
+func (f *fakeImpersonationAuditor) RecordImpersonatedRequest(_ context.Context, req ImpersonatedRequest) {
+	f.recorded = append(f.recorded, req)
+}
+
+func TestImpersonationTokensAreAuditedAndTiedToActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := authsecurity.NewHMACKeySet("secret")
+	versions := fakeUserVersions{
+		"tech": {version: 1, active: true},
+		"boss": {version: 4, active: true},
+	}
+	auditor := &fakeImpersonationAuditor{}
+	r := gin.New()
+	authed := r.Group("/", Auth(keys, NewPermissionVersionCache(versions, time.Nanosecond), nil), AuditImpersonation(auditor))
+	authed.GET("/work-orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	authed.PATCH("/work-orders/1", func(c *gin.Context) { c.Status(http.StatusOK) })
+	authed.GET("/auth/sessions", RejectImpersonation(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tok, _, _ := keys.NewImpersonationAccessToken(time.Minute, "tech", 1, nil, []string{"work_orders:read"}, authsecurity.Actor{UserID: "boss", PermissionsVersion: 4})
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+tok)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := send(http.MethodGet, "/work-orders"); w.Code != http.StatusOK || w.Header().Get(ImpersonationHeader) != "boss" {
+		t.Fatalf("expected flagged read, got %d %q", w.Code, w.Header().Get(ImpersonationHeader))
+	}
+	if w := send(http.MethodPatch, "/work-orders/1"); w.Code != http.StatusOK {
+		t.Fatalf("expected mutation to succeed, got %d", w.Code)
+	}
+	if w := send(http.MethodGet, "/auth/sessions"); w.Code != http.StatusForbidden {
+		t.Fatalf("expected account routes to be blocked, got %d", w.Code)
+	}
+	if len(auditor.recorded) != 1 || auditor.recorded[0].ActorUserID != "boss" || auditor.recorded[0].UserID != "tech" {
+		t.Fatalf("expected only the mutation to be audited with both users, got %#v", auditor.recorded)
+	}
+
+	versions["boss"] = fakePermissionVersions{version: 5, active: true}
+	time.Sleep(time.Millisecond)
+	if w := send(http.MethodGet, "/work-orders"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected token to die with the actor's permissions change, got %d", w.Code)
+	}
+}
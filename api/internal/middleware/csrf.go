@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 
+	authsecurity "humphreys/api/internal/modules/auth/security"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -19,6 +21,10 @@ func CSRFMiddleware(exemptPaths map[string]struct{}) gin.HandlerFunc {
 			c.Next()
 			return
 		}
+		if raw, ok := bearerToken(c); ok && authsecurity.IsAPIKey(raw) {
+			c.Next()
+			return
+		}
 
 		cookie, err := c.Cookie("csrf_token")
 		if err != nil || cookie == "" {
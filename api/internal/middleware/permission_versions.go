@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// PermissionVersionSource reports a user's current permissions version and
+// whether the account may still use the API.
+type PermissionVersionSource interface {
+	PermissionVersion(ctx context.Context, userID string) (version int64, active bool, err error)
+}
+
+type permissionVersionEntry struct {
+	version int64
+	active  bool
+}
+
+// PermissionVersionCache keeps lookups off the database for most requests;
+// a change is picked up once the cached entry expires.
+type PermissionVersionCache struct {
+	source PermissionVersionSource
+	cache  *ttlcache.Cache[string, permissionVersionEntry]
+}
+
+func NewPermissionVersionCache(source PermissionVersionSource, ttl time.Duration) *PermissionVersionCache {
+	cache := ttlcache.New[string, permissionVersionEntry](
+		ttlcache.WithTTL[string, permissionVersionEntry](ttl),
+		ttlcache.WithDisableTouchOnHit[string, permissionVersionEntry](),
+	)
+	go cache.Start()
+	return &PermissionVersionCache{source: source, cache: cache}
+}
+
+func (c *PermissionVersionCache) lookup(ctx context.Context, userID string) (permissionVersionEntry, error) {
+	if item := c.cache.Get(userID); item != nil {
+		return item.Value(), nil
+	}
+	version, active, err := c.source.PermissionVersion(ctx, userID)
+	if err != nil {
+		return permissionVersionEntry{}, err
+	}
+	entry := permissionVersionEntry{version: version, active: active}
+	c.cache.Set(userID, entry, ttlcache.DefaultTTL)
+	return entry, nil
+}
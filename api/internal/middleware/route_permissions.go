@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoutePermission lists what one route requires. Each entry of Requirements
+// is satisfied by holding any one of its codes; all entries must be met.
+type RoutePermission struct {
+	Method       string     `json:"method"`
+	Path         string     `json:"path"`
+	Requirements [][]string `json:"requirements"`
+}
+
+func (r RoutePermission) String() string {
+	return r.Method + " " + r.Path
+}
+
+// SatisfiedBy reports whether scope meets every requirement of the route.
+func (r RoutePermission) SatisfiedBy(scope map[string]struct{}) bool {
+	for _, anyOf := range r.Requirements {
+		if !holdsAny(scope, anyOf) {
+			return false
+		}
+	}
+	return true
+}
+
+// Uses reports whether code is one of the codes that can meet a requirement.
+func (r RoutePermission) Uses(code string) bool {
+	for _, anyOf := range r.Requirements {
+		for _, candidate := range anyOf {
+			if candidate == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func holdsAny(scope map[string]struct{}, codes []string) bool {
+	for _, code := range codes {
+		if _, ok := scope[code]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionChecks holds the codes of every check requireAnyPermission has
+// built, keyed by the check's closure. Each call allocates its own closure,
+// so the key tells two checks apart even though they share their code.
+var permissionChecks sync.Map
+
+func rememberPermissionCheck(check gin.HandlerFunc, permissions []string) {
+	permissionChecks.Store(handlerKey(check), append([]string(nil), permissions...))
+}
+
+func permissionCheckCodes(h gin.HandlerFunc) ([]string, bool) {
+	codes, ok := permissionChecks.Load(handlerKey(h))
+	if !ok {
+		return nil, false
+	}
+	return codes.([]string), true
+}
+
+// handlerKey is the closure a func value points at.
+func handlerKey(h gin.HandlerFunc) unsafe.Pointer {
+	return *(*unsafe.Pointer)(unsafe.Pointer(&h))
+}
+
+// Router registers routes on a gin group and records, as each route is
+// added, the codes its permission checks require. Routes without a
+// permission check are left out of the index.
+type Router struct {
+	group        *gin.RouterGroup
+	requirements [][]string
+	index        *[]RoutePermission
+}
+
+func NewRouter(group *gin.RouterGroup) *Router {
+	return &Router{group: group, index: &[]RoutePermission{}}
+}
+
+// Group adds a sub-group; permission checks among handlers apply to every
+// route registered under it.
+func (r *Router) Group(relativePath string, handlers ...gin.HandlerFunc) *Router {
+	return &Router{
+		group:        r.group.Group(relativePath, handlers...),
+		requirements: appendRequirements(r.requirements, handlers),
+		index:        r.index,
+	}
+}
+
+func (r *Router) Handle(method, relativePath string, handlers ...gin.HandlerFunc) {
+	r.group.Handle(method, relativePath, handlers...)
+	requirements := appendRequirements(r.requirements, handlers)
+	if len(requirements) == 0 {
+		return
+	}
+	*r.index = append(*r.index, RoutePermission{
+		Method:       method,
+		Path:         joinPaths(r.group.BasePath(), relativePath),
+		Requirements: requirements,
+	})
+}
+
+func (r *Router) GET(relativePath string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodGet, relativePath, handlers...)
+}
+
+func (r *Router) POST(relativePath string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPost, relativePath, handlers...)
+}
+
+func (r *Router) PUT(relativePath string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPut, relativePath, handlers...)
+}
+
+func (r *Router) PATCH(relativePath string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPatch, relativePath, handlers...)
+}
+
+func (r *Router) DELETE(relativePath string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodDelete, relativePath, handlers...)
+}
+
+// RoutePermissions lists the recorded routes ordered by path, then method.
+func (r *Router) RoutePermissions() []RoutePermission {
+	out := append([]RoutePermission{}, *r.index...)
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return out[i].Method < out[j].Method
+	})
+	return out
+}
+
+func appendRequirements(requirements [][]string, handlers []gin.HandlerFunc) [][]string {
+	out := append([][]string(nil), requirements...)
+	for _, h := range handlers {
+		if codes, ok := permissionCheckCodes(h); ok {
+			out = append(out, codes)
+		}
+	}
+	return out
+}
+
+// joinPaths builds a route's full path the way gin does.
+func joinPaths(basePath, relativePath string) string {
+	if relativePath == "" {
+		return basePath
+	}
+	joined := path.Join(basePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(joined, "/") {
+		return joined + "/"
+	}
+	return joined
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	authsecurity "humphreys/api/internal/modules/auth/security"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouterRecordsRoutePermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	engine := gin.New()
+	authed := NewRouter(engine.Group("/"))
+	authed.GET("/public", handler)
+	authed.GET("/orders", RequirePermissionOrAssigned("work_orders:read"), handler)
+	group := authed.Group("/roles", RejectAPIKeys())
+	group.POST("/:id/clone", RequirePermission("roles:create"), RequirePermission("roles:assign"), handler)
+	admin := authed.Group("/admin", RequirePermission("settings:read"))
+	admin.PATCH("", RequirePermission("settings:update"), handler)
+
+	index := authed.RoutePermissions()
+	want := []RoutePermission{
+		{Method: http.MethodPatch, Path: "/admin", Requirements: [][]string{{"settings:read"}, {"settings:update"}}},
+		{Method: http.MethodGet, Path: "/orders", Requirements: [][]string{{"work_orders:read", "work_orders:read:assigned"}}},
+		{Method: http.MethodPost, Path: "/roles/:id/clone", Requirements: [][]string{{"roles:create"}, {"roles:assign"}}},
+	}
+	if !reflect.DeepEqual(index, want) {
+		t.Fatalf("expected %+v, got %+v", want, index)
+	}
+	scope := map[string]struct{}{"roles:create": {}}
+	if index[2].SatisfiedBy(scope) || !index[2].Uses("roles:assign") {
+		t.Fatalf("expected clone to need both create and assign")
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected routes registered through the router to serve, got %d", w.Code)
+	}
+}
+
+func TestForbiddenNamesRequiredPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("auth_claims", &authsecurity.Claims{UserID: "u1", Scope: []string{"users:read"}})
+		c.Next()
+	})
+	r.GET("/test", RequirePermissionOrAssigned("work_orders:read"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", w.Code)
+	}
+	var body struct {
+		RequiredPermissions []string `json:"required_permissions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if !reflect.DeepEqual(body.RequiredPermissions, []string{"work_orders:read", "work_orders:read:assigned"}) {
+		t.Fatalf("unexpected required_permissions: %v", body.RequiredPermissions)
+	}
+}
@@ -1,14 +1,10 @@
 package aisettings
 
-import (
-	"humphreys/api/internal/middleware"
-
-	"github.com/gin-gonic/gin"
-)
+import "humphreys/api/internal/middleware"
 
 const permWorkOrdersUpdate = "work_orders:update"
 
-func RegisterRoutes(authed *gin.RouterGroup, h *Handler) {
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
 	group := authed.Group("/ai-settings")
 	group.GET("", middleware.RequirePermission(permWorkOrdersUpdate), h.Get)
 	group.PATCH("", middleware.RequirePermission(permWorkOrdersUpdate), h.Update)
@@ -0,0 +1,117 @@
+package apikeys
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"humphreys/api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func New(db *pgxpool.Pool) *Handler {
+	return &Handler{
+		service: NewService(NewRepository(db)),
+	}
+}
+
+func NewWithService(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scope     []string   `json:"scope"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.service.ListAPIKeys(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list api keys"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": keys})
+}
+
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	key, err := h.service.CreateAPIKey(c.Request.Context(), CreateInput{
+		Name:            req.Name,
+		PermissionCodes: req.Scope,
+		ExpiresAt:       req.ExpiresAt,
+	}, claims.UserID, claims.Scope)
+	switch {
+	case errors.Is(err, ErrScopeNotHeld):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrNameRequired), errors.Is(err, ErrScopeRequired), errors.Is(err, ErrExpiryInPast), errors.Is(err, ErrUnknownPermission):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create api key"})
+		return
+	}
+	c.JSON(http.StatusCreated, key)
+}
+
+func (h *Handler) GetAPIKey(c *gin.Context) {
+	key, err := h.service.GetAPIKey(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load api key"})
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+	err := h.service.RevokeAPIKey(c.Request.Context(), c.Param("id"), claims.UserID)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke api key"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ListAPIKeyRequests(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	requests, err := h.service.ListAPIKeyRequests(c.Request.Context(), c.Param("id"), limit)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list api key requests"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": requests})
+}
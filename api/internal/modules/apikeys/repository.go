@@ -0,0 +1,315 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"humphreys/api/internal/domain"
+	"humphreys/api/internal/middleware"
+	authsecurity "humphreys/api/internal/modules/auth/security"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	auditTargetAPIKey  = "api_key"
+	auditActionRequest = "api_key.request"
+	auditActionCreated = "api_key.created"
+	auditActionRevoked = "api_key.revoked"
+)
+
+type CreateAPIKeyInput struct {
+	Name            string
+	Prefix          string
+	KeyHash         string
+	PermissionCodes []string
+	CreatedByUserID string
+	ExpiresAt       *time.Time
+}
+
+type Repository interface {
+	ListAPIKeys(ctx context.Context) ([]domain.APIKey, error)
+	GetAPIKey(ctx context.Context, id string) (domain.APIKey, error)
+	CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id, actorUserID string) error
+	ListAPIKeyRequests(ctx context.Context, id string, limit int) ([]domain.APIKeyRequest, error)
+	ExistingPermissionCodes(ctx context.Context, codes []string) ([]string, error)
+}
+
+type storeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &storeRepository{db: db}
+}
+
+const apiKeyColumns = `
+	k.id::text, k.name, k.prefix,
+	COALESCE((
+		SELECT array_agg(p.code ORDER BY p.code)
+		FROM api_key_permissions kp
+		JOIN permissions p ON p.id = kp.permission_id
+		WHERE kp.api_key_id = k.id
+	), '{}'::text[]),
+	k.created_by_user_id::text, k.created_at, k.expires_at, k.last_used_at, k.last_used_ip, k.revoked_at`
+
+func scanAPIKey(row pgx.Row) (domain.APIKey, error) {
+	var key domain.APIKey
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Scope, &key.CreatedByUserID, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt, &key.LastUsedIP, &key.RevokedAt)
+	return key, err
+}
+
+func (r *storeRepository) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys k ORDER BY k.revoked_at IS NOT NULL, k.created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, key)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) GetAPIKey(ctx context.Context, id string) (domain.APIKey, error) {
+	return scanAPIKey(r.db.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys k WHERE k.id::text=$1`, id))
+}
+
+func (r *storeRepository) CreateAPIKey(ctx context.Context, input CreateAPIKeyInput) (domain.APIKey, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO api_keys(name, prefix, key_hash, created_by_user_id, expires_at)
+		VALUES($1,$2,$3,$4,$5)
+		RETURNING id::text
+	`, input.Name, input.Prefix, input.KeyHash, input.CreatedByUserID, input.ExpiresAt).Scan(&id)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO api_key_permissions(api_key_id, permission_id)
+		SELECT $1, id FROM permissions WHERE code = ANY($2)
+	`, id, input.PermissionCodes); err != nil {
+		return domain.APIKey{}, err
+	}
+	if err := insertAudit(ctx, tx, input.CreatedByUserID, auditActionCreated, id, map[string]any{
+		"name":   input.Name,
+		"prefix": input.Prefix,
+		"scope":  input.PermissionCodes,
+	}); err != nil {
+		return domain.APIKey{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.APIKey{}, err
+	}
+	return r.GetAPIKey(ctx, id)
+}
+
+func (r *storeRepository) RevokeAPIKey(ctx context.Context, id, actorUserID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE api_keys
+		SET revoked_at=now(), revoked_by_user_id=$2
+		WHERE id::text=$1 AND revoked_at IS NULL
+	`, id, actorUserID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if err := insertAudit(ctx, tx, actorUserID, auditActionRevoked, id, map[string]any{}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *storeRepository) ListAPIKeyRequests(ctx context.Context, id string, limit int) ([]domain.APIKeyRequest, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id::text,
+		       COALESCE(metadata_json->>'method', ''),
+		       COALESCE(metadata_json->>'path', ''),
+		       COALESCE(metadata_json->>'route', ''),
+		       COALESCE((metadata_json->>'status')::int, 0),
+		       COALESCE(metadata_json->>'ip_address', ''),
+		       COALESCE(metadata_json->>'user_agent', ''),
+		       created_at
+		FROM audit_logs
+		WHERE target_type=$1 AND target_id::text=$2 AND action=$3
+		ORDER BY created_at DESC
+		LIMIT $4
+	`, auditTargetAPIKey, id, auditActionRequest, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.APIKeyRequest, 0)
+	for rows.Next() {
+		var req domain.APIKeyRequest
+		if err := rows.Scan(&req.ID, &req.Method, &req.Path, &req.Route, &req.Status, &req.IPAddress, &req.UserAgent, &req.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, req)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) ExistingPermissionCodes(ctx context.Context, codes []string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT code FROM permissions WHERE code = ANY($1) ORDER BY code`, codes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]string, 0, len(codes))
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		out = append(out, code)
+	}
+	return out, rows.Err()
+}
+
+func insertAudit(ctx context.Context, tx pgx.Tx, actorUserID, action, apiKeyID string, metadata map[string]any) error {
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO audit_logs(actor_user_id, action, target_type, target_id, metadata_json)
+		VALUES($1,$2,$3,$4,$5)
+	`, actorUserID, action, auditTargetAPIKey, apiKeyID, encoded)
+	return err
+}
+
+type authenticator struct {
+	db *pgxpool.Pool
+}
+
+// NewAuthenticator backs middleware.Auth's API key path.
+func NewAuthenticator(db *pgxpool.Pool) middleware.APIKeyAuthenticator {
+	return &authenticator{db: db}
+}
+
+var errInvalidAPIKey = errors.New("invalid api key")
+
+// AuthenticateAPIKey grants the key's permissions that its creator still
+// holds, so demoting or disabling the creator narrows or disables the key.
+func (a *authenticator) AuthenticateAPIKey(ctx context.Context, raw string) (*authsecurity.Claims, error) {
+	prefix, ok := authsecurity.APIKeyLookupPrefix(raw)
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+
+	var (
+		id      string
+		hash    string
+		userID  string
+		usable  bool
+		roleIDs []string
+	)
+	err := a.db.QueryRow(ctx, `
+		SELECT k.id::text, k.key_hash, k.created_by_user_id::text,
+		       k.revoked_at IS NULL
+		         AND (k.expires_at IS NULL OR k.expires_at > now())
+		         AND u.status = 'active'
+		         AND u.deleted_at IS NULL,
+		       COALESCE((SELECT array_agg(ur.role_id::text) FROM user_roles ur WHERE ur.user_id = u.id), '{}'::text[])
+		FROM api_keys k
+		JOIN users u ON u.id = k.created_by_user_id
+		WHERE k.prefix=$1
+	`, prefix).Scan(&id, &hash, &userID, &usable, &roleIDs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(authsecurity.HashToken(raw))) != 1 || !usable {
+		return nil, errInvalidAPIKey
+	}
+
+	rows, err := a.db.Query(ctx, `
+		SELECT p.code
+		FROM api_key_permissions kp
+		JOIN permissions p ON p.id = kp.permission_id
+		WHERE kp.api_key_id::text=$1
+		  AND EXISTS (
+		    SELECT 1
+		    FROM user_roles ur
+		    JOIN role_permissions rp ON rp.role_id = ur.role_id
+		    WHERE ur.user_id::text=$2 AND rp.permission_id = kp.permission_id
+		  )
+		ORDER BY p.code
+	`, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scope := make([]string, 0)
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		scope = append(scope, code)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &authsecurity.Claims{UserID: userID, RoleIDs: roleIDs, Scope: scope, APIKeyID: id}, nil
+}
+
+// RecordAPIKeyRequest writes the per-call audit entry and bumps last-used.
+// Failures are logged rather than surfaced; the response has already gone out.
+func (a *authenticator) RecordAPIKeyRequest(ctx context.Context, req middleware.APIKeyRequest) {
+	metadata, err := json.Marshal(map[string]any{
+		"method":     req.Method,
+		"path":       req.Path,
+		"route":      req.Route,
+		"status":     req.Status,
+		"ip_address": req.IPAddress,
+		"user_agent": req.UserAgent,
+	})
+	if err != nil {
+		log.Printf("api key audit encode failed key=%s: %v", req.APIKeyID, err)
+		return
+	}
+	if _, err := a.db.Exec(ctx, `
+		INSERT INTO audit_logs(actor_user_id, action, target_type, target_id, metadata_json)
+		VALUES($1,$2,$3,$4,$5)
+	`, req.UserID, auditActionRequest, auditTargetAPIKey, req.APIKeyID, metadata); err != nil {
+		log.Printf("api key audit insert failed key=%s: %v", req.APIKeyID, err)
+	}
+	if _, err := a.db.Exec(ctx, `
+		UPDATE api_keys SET last_used_at=now(), last_used_ip=$2 WHERE id::text=$1
+	`, req.APIKeyID, req.IPAddress); err != nil {
+		log.Printf("api key last-used update failed key=%s: %v", req.APIKeyID, err)
+	}
+}
@@ -0,0 +1,26 @@
+package apikeys
+
+import (
+	"humphreys/api/internal/middleware"
+	"humphreys/api/internal/permissions"
+)
+
+const (
+	permRead   = "api_keys:read"
+	permCreate = "api_keys:create"
+	permDelete = "api_keys:delete"
+)
+
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("api_keys", "Scoped API keys for machine integrations", "create", "read", "delete")
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
+	keys := authed.Group("/api-keys", middleware.RejectAPIKeys(), middleware.RejectImpersonation())
+	keys.GET("", middleware.RequirePermission(permRead), h.ListAPIKeys)
+	keys.POST("", middleware.RequirePermission(permCreate), h.CreateAPIKey)
+	keys.GET("/:id", middleware.RequirePermission(permRead), h.GetAPIKey)
+	keys.DELETE("/:id", middleware.RequirePermission(permDelete), h.RevokeAPIKey)
+	keys.GET("/:id/requests", middleware.RequirePermission(permRead), h.ListAPIKeyRequests)
+}
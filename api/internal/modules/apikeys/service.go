@@ -0,0 +1,155 @@
+package apikeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"humphreys/api/internal/domain"
+	authsecurity "humphreys/api/internal/modules/auth/security"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrAPIKeyNotFound    = errors.New("api key not found")
+	ErrNameRequired      = errors.New("name is required")
+	ErrScopeRequired     = errors.New("at least one permission is required")
+	ErrExpiryInPast      = errors.New("expires_at must be in the future")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrScopeNotHeld      = errors.New("api keys can only be granted permissions you hold")
+)
+
+type CreateInput struct {
+	Name            string
+	PermissionCodes []string
+	ExpiresAt       *time.Time
+}
+
+// CreatedAPIKey carries the raw key, which is only ever returned once.
+type CreatedAPIKey struct {
+	domain.APIKey
+	Key string `json:"key"`
+}
+
+type Service struct {
+	repo Repository
+	now  func() time.Time
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo, now: time.Now}
+}
+
+func (s *Service) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	return s.repo.ListAPIKeys(ctx)
+}
+
+func (s *Service) GetAPIKey(ctx context.Context, id string) (domain.APIKey, error) {
+	key, err := s.repo.GetAPIKey(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.APIKey{}, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// CreateAPIKey issues a key whose scope must be a subset of the caller's own
+// permissions, so nobody can mint a key more powerful than themselves.
+func (s *Service) CreateAPIKey(ctx context.Context, input CreateInput, actorUserID string, actorScope []string) (CreatedAPIKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return CreatedAPIKey{}, ErrNameRequired
+	}
+	codes := normalizeCodes(input.PermissionCodes)
+	if len(codes) == 0 {
+		return CreatedAPIKey{}, ErrScopeRequired
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(s.now()) {
+		return CreatedAPIKey{}, ErrExpiryInPast
+	}
+
+	held := make(map[string]struct{}, len(actorScope))
+	for _, code := range actorScope {
+		held[code] = struct{}{}
+	}
+	for _, code := range codes {
+		if _, ok := held[code]; !ok {
+			return CreatedAPIKey{}, fmt.Errorf("%w: %s", ErrScopeNotHeld, code)
+		}
+	}
+	existing, err := s.repo.ExistingPermissionCodes(ctx, codes)
+	if err != nil {
+		return CreatedAPIKey{}, err
+	}
+	if len(existing) != len(codes) {
+		return CreatedAPIKey{}, fmt.Errorf("%w: %s", ErrUnknownPermission, strings.Join(missingCodes(codes, existing), ", "))
+	}
+
+	raw, prefix, err := authsecurity.NewAPIKey()
+	if err != nil {
+		return CreatedAPIKey{}, err
+	}
+	key, err := s.repo.CreateAPIKey(ctx, CreateAPIKeyInput{
+		Name:            name,
+		Prefix:          prefix,
+		KeyHash:         authsecurity.HashToken(raw),
+		PermissionCodes: codes,
+		CreatedByUserID: actorUserID,
+		ExpiresAt:       input.ExpiresAt,
+	})
+	if err != nil {
+		return CreatedAPIKey{}, err
+	}
+	return CreatedAPIKey{APIKey: key, Key: raw}, nil
+}
+
+func (s *Service) RevokeAPIKey(ctx context.Context, id, actorUserID string) error {
+	err := s.repo.RevokeAPIKey(ctx, id, actorUserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+func (s *Service) ListAPIKeyRequests(ctx context.Context, id string, limit int) ([]domain.APIKeyRequest, error) {
+	if _, err := s.GetAPIKey(ctx, id); err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.ListAPIKeyRequests(ctx, id, limit)
+}
+
+func normalizeCodes(codes []string) []string {
+	seen := make(map[string]struct{}, len(codes))
+	out := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.TrimSpace(code)
+		if code == "" {
+			continue
+		}
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		out = append(out, code)
+	}
+	return out
+}
+
+func missingCodes(requested, existing []string) []string {
+	found := make(map[string]struct{}, len(existing))
+	for _, code := range existing {
+		found[code] = struct{}{}
+	}
+	missing := make([]string, 0)
+	for _, code := range requested {
+		if _, ok := found[code]; !ok {
+			missing = append(missing, code)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"humphreys/api/internal/mailer"
+)
+
+const (
+	accountTokenInvite         = "invite"
+	accountTokenPasswordReset  = "password_reset"
+	accountTokenPasswordChange = "password_change"
+)
+
+func accountLink(baseURL, path, token string) string {
+	return strings.TrimRight(baseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+func buildInvitationMessage(email, fullName, inviterName, link string, ttl time.Duration) mailer.Message {
+	invitedBy := "The Humphreys Electronics team"
+	if strings.TrimSpace(inviterName) != "" {
+		invitedBy = strings.TrimSpace(inviterName)
+	}
+	return mailer.Message{
+		To:      email,
+		Subject: "You're invited to Humphreys Electronics",
+		Body: strings.Join([]string{
+			fmt.Sprintf("Hi %s,", greetingName(fullName)),
+			"",
+			fmt.Sprintf("%s has invited you to the Humphreys Electronics workshop system.", invitedBy),
+			"",
+			fmt.Sprintf("[Accept your invitation](%s) to choose a password and sign in.", link),
+			"",
+			fmt.Sprintf("This link can be used once and expires in %s.", describeTTL(ttl)),
+			"",
+			"Thank you,",
+			"Humphreys Electronics",
+		}, "\n"),
+	}
+}
+
+func buildPasswordResetMessage(email, fullName, link string, ttl time.Duration) mailer.Message {
+	return mailer.Message{
+		To:      email,
+		Subject: "Reset your Humphreys Electronics password",
+		Body: strings.Join([]string{
+			fmt.Sprintf("Hi %s,", greetingName(fullName)),
+			"",
+			"We received a request to reset your password.",
+			"",
+			fmt.Sprintf("[Choose a new password](%s)", link),
+			"",
+			fmt.Sprintf("This link can be used once and expires in %s. Resetting your password signs you out on every device.", describeTTL(ttl)),
+			"",
+			"If you did not ask for this, you can ignore this email.",
+			"",
+			"Humphreys Electronics",
+		}, "\n"),
+	}
+}
+
+func greetingName(fullName string) string {
+	if name := strings.TrimSpace(fullName); name != "" {
+		return name
+	}
+	return "there"
+}
+
+func describeTTL(ttl time.Duration) string {
+	switch {
+	case ttl >= 48*time.Hour:
+		return fmt.Sprintf("%d days", int(ttl.Hours()/24))
+	case ttl >= 2*time.Hour:
+		return fmt.Sprintf("%d hours", int(ttl.Hours()))
+	case ttl >= time.Hour:
+		return "1 hour"
+	default:
+		return fmt.Sprintf("%d minutes", max(int(ttl.Minutes()), 1))
+	}
+}
@@ -1,12 +1,20 @@
 package auth
 
 import (
+	"context"
+	"crypto/subtle"
 	"errors"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"humphreys/api/internal/config"
+	"humphreys/api/internal/domain"
 	"humphreys/api/internal/middleware"
+	"humphreys/api/internal/modules/auth/security"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,8 +22,9 @@ import (
 )
 
 type Handler struct {
-	service *Service
-	cfg     cookieConfig
+	service    *Service
+	cfg        cookieConfig
+	webBaseURL string
 }
 
 type cookieConfig struct {
@@ -24,8 +33,8 @@ type cookieConfig struct {
 	secure            bool
 }
 
-func New(db *pgxpool.Pool, cfg config.Config) *Handler {
-	return NewWithService(NewService(NewRepository(db), cfg), cfg)
+func New(db *pgxpool.Pool, cfg config.Config, keys *security.KeySet, passwords security.PasswordPolicy) *Handler {
+	return NewWithService(NewService(NewRepository(db), cfg, keys, passwords), cfg)
 }
 
 func NewWithService(service *Service, cfg config.Config) *Handler {
@@ -36,6 +45,7 @@ func NewWithService(service *Service, cfg config.Config) *Handler {
 			domain:            cfg.CookieDomain,
 			secure:            cfg.CookieSecure,
 		},
+		webBaseURL: cfg.WebBaseURL,
 	}
 }
 
@@ -50,7 +60,13 @@ func (h *Handler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
-	session, err := h.service.Login(c.Request.Context(), req.Email, req.Password, c.ClientIP(), c.GetHeader("User-Agent"))
+	result, err := h.service.Login(c.Request.Context(), req.Email, req.Password, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, ErrTooManyLoginAttempts) {
+		retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many login attempts", "retry_after": retryAfter})
+		return
+	}
 	if errors.Is(err, ErrInvalidCredentials) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
@@ -63,18 +79,190 @@ func (h *Handler) Login(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
 		return
 	}
-	h.setRefreshCookie(c, session.RefreshToken)
-	csrfToken := h.setCSRFCookie(c)
+	h.writeLoginResult(c, result)
+}
+
+func (h *Handler) writeLoginResult(c *gin.Context, result LoginResult) {
+	if result.PasswordChange != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"password_change_required": true,
+			"password_change_token":    result.PasswordChange.Token,
+			"expires_in":               result.PasswordChange.ExpiresIn,
+		})
+		return
+	}
+	if result.Challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":            true,
+			"mfa_enrollment_required": result.Challenge.EnrollmentRequired,
+			"mfa_token":               result.Challenge.Token,
+			"expires_in":              result.Challenge.ExpiresIn,
+		})
+		return
+	}
+	h.writeSession(c, *result.Session)
+}
+
+type changeRequiredPasswordRequest struct {
+	PasswordChangeToken string `json:"password_change_token" binding:"required"`
+	NewPassword         string `json:"new_password" binding:"required"`
+}
+
+func (h *Handler) ChangeRequiredPassword(c *gin.Context) {
+	var req changeRequiredPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	result, err := h.service.ChangeRequiredPassword(c.Request.Context(), req.PasswordChangeToken, req.NewPassword, c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, ErrUserNotActive) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "user not active"})
+		return
+	}
+	if err != nil {
+		writeAccountTokenError(c, err, "failed to change password")
+		return
+	}
+	h.writeLoginResult(c, result)
+}
+
+const oidcStateCookie = "oidc_state"
+
+// BeginOIDCLogin redirects the browser to the identity provider. The state is
+// also pinned in a cookie so a callback URL cannot be replayed in another
+// browser to sign it into someone else's account.
+func (h *Handler) BeginOIDCLogin(c *gin.Context) {
+	start, err := h.service.BeginOIDCLogin(c.Request.Context(), c.Query("return_to"), c.ClientIP(), c.GetHeader("User-Agent"))
+	if errors.Is(err, ErrOIDCNotConfigured) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrOIDCLoginFailed) {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start sign-in"})
+		return
+	}
+	// Lax so the cookie comes back on the provider's top-level redirect.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, start.State, start.ExpiresIn, "/", h.cfg.domain, h.cfg.secure, true)
+	c.Redirect(http.StatusFound, start.RedirectURL)
+}
+
+// OIDCCallback finishes the provider round trip and sends the browser back to
+// the web app, which picks the session up through /auth/refresh.
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	state := c.Query("state")
+	cookieState, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/", h.cfg.domain, h.cfg.secure, true)
+
+	if c.Query("error") != "" {
+		h.redirectToLogin(c, url.Values{"sso_error": {"denied"}})
+		return
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		h.redirectToLogin(c, url.Values{"sso_error": {"invalid_state"}})
+		return
+	}
+
+	result, err := h.service.CompleteOIDCLogin(c.Request.Context(), state, c.Query("code"), c.ClientIP(), c.GetHeader("User-Agent"))
+	switch {
+	case errors.Is(err, ErrInvalidOIDCState):
+		h.redirectToLogin(c, url.Values{"sso_error": {"invalid_state"}})
+		return
+	case errors.Is(err, ErrOIDCUserNotFound):
+		h.redirectToLogin(c, url.Values{"sso_error": {"unknown_user"}})
+		return
+	case errors.Is(err, ErrOIDCEmailNotVerified):
+		h.redirectToLogin(c, url.Values{"sso_error": {"unverified_email"}})
+		return
+	case errors.Is(err, ErrOIDCIdentityConflict):
+		h.redirectToLogin(c, url.Values{"sso_error": {"identity_conflict"}})
+		return
+	case errors.Is(err, ErrUserNotActive):
+		h.redirectToLogin(c, url.Values{"sso_error": {"inactive"}})
+		return
+	case err != nil:
+		h.redirectToLogin(c, url.Values{"sso_error": {"failed"}})
+		return
+	}
+	if result.Challenge != nil {
+		// The fragment keeps the MFA token out of server and proxy logs.
+		fragment := url.Values{
+			"mfa_token":               {result.Challenge.Token},
+			"mfa_enrollment_required": {strconv.FormatBool(result.Challenge.EnrollmentRequired)},
+			"return_to":               {result.ReturnTo},
+		}
+		c.Redirect(http.StatusFound, h.webBaseURL+"/login#"+fragment.Encode())
+		return
+	}
+	h.setRefreshCookie(c, result.Session.RefreshToken)
+	h.setCSRFCookie(c)
+	c.Redirect(http.StatusFound, h.webBaseURL+result.ReturnTo)
+}
+
+func (h *Handler) redirectToLogin(c *gin.Context, query url.Values) {
+	c.Redirect(http.StatusFound, h.webBaseURL+"/login?"+query.Encode())
+}
+
+type mfaTokenRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type verifyMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
 
+func (h *Handler) BeginLoginMFAEnrollment(c *gin.Context) {
+	var req mfaTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	enrollment, err := h.service.BeginMFAChallengeEnrollment(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		writeMFAError(c, err, "failed to start mfa enrollment")
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"access_token": session.AccessToken,
-		"csrf_token":   csrfToken,
-		"expires_in":   session.ExpiresIn,
-		"scope":        session.Scope,
-		"user":         session.User,
+		"secret":           enrollment.Secret,
+		"provisioning_uri": enrollment.ProvisioningURI,
 	})
 }
 
+func (h *Handler) VerifyLoginMFA(c *gin.Context) {
+	var req verifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" && strings.TrimSpace(req.RecoveryCode) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code is required"})
+		return
+	}
+	session, err := h.service.VerifyMFAChallenge(
+		c.Request.Context(),
+		req.MFAToken,
+		MFAVerifyInput{Code: req.Code, RecoveryCode: req.RecoveryCode},
+		c.ClientIP(),
+		c.GetHeader("User-Agent"),
+	)
+	if err != nil {
+		writeMFAError(c, err, "failed to verify mfa")
+		return
+	}
+	h.writeSession(c, session)
+}
+
 func (h *Handler) Refresh(c *gin.Context) {
 	cookieToken, err := c.Cookie("refresh_token")
 	if err != nil {
@@ -103,16 +291,7 @@ func (h *Handler) Refresh(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh"})
 		return
 	}
-	h.setRefreshCookie(c, session.RefreshToken)
-	csrfToken := h.setCSRFCookie(c)
-
-	c.JSON(http.StatusOK, gin.H{
-		"access_token": session.AccessToken,
-		"csrf_token":   csrfToken,
-		"expires_in":   session.ExpiresIn,
-		"scope":        session.Scope,
-		"user":         session.User,
-	})
+	h.writeSession(c, session)
 }
 
 func (h *Handler) Logout(c *gin.Context) {
@@ -125,6 +304,11 @@ func (h *Handler) Logout(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.service.JWKS())
+}
+
 func (h *Handler) Me(c *gin.Context) {
 	claims, ok := middleware.Claims(c)
 	if !ok {
@@ -140,7 +324,267 @@ func (h *Handler) Me(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
 		return
 	}
-	c.JSON(http.StatusOK, me)
+	if claims.Actor == nil {
+		c.JSON(http.StatusOK, me)
+		return
+	}
+	// Impersonating: the UI shows who is really signed in.
+	actor, err := h.service.Me(c.Request.Context(), claims.Actor.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+		return
+	}
+	c.JSON(http.StatusOK, impersonatedMeResponse{
+		User: me,
+		ImpersonatedBy: impersonator{
+			ID:       actor.ID,
+			Email:    actor.Email,
+			FullName: actor.FullName,
+		},
+		ImpersonationExpiresAt: claims.ExpiresAt.Time,
+	})
+}
+
+type impersonator struct {
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name"`
+}
+
+type impersonatedMeResponse struct {
+	domain.User
+	ImpersonatedBy         impersonator `json:"impersonated_by"`
+	ImpersonationExpiresAt time.Time    `json:"impersonation_expires_at"`
+}
+
+func (h *Handler) ListSessions(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	sessions, err := h.service.ListSessions(c.Request.Context(), claims.UserID, claims.SessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": sessions})
+}
+
+func (h *Handler) RevokeSession(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	familyID := c.Param("family_id")
+	err := h.service.RevokeSession(c.Request.Context(), claims.UserID, familyID)
+	if errors.Is(err, ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	if familyID == claims.SessionID {
+		h.clearCookies(c)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) MFAStatus(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	status, err := h.service.MFAStatus(c.Request.Context(), claims.UserID)
+	if err != nil {
+		writeMFAError(c, err, "failed to load mfa status")
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *Handler) BeginMFAEnrollment(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	enrollment, err := h.service.BeginMFAEnrollment(c.Request.Context(), claims.UserID)
+	if err != nil {
+		writeMFAError(c, err, "failed to start mfa enrollment")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"secret":           enrollment.Secret,
+		"provisioning_uri": enrollment.ProvisioningURI,
+	})
+}
+
+func (h *Handler) ConfirmMFAEnrollment(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	codes, err := h.service.ConfirmMFAEnrollment(c.Request.Context(), claims.UserID, req.Code)
+	if err != nil {
+		writeMFAError(c, err, "failed to confirm mfa enrollment")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	codes, err := h.service.RegenerateRecoveryCodes(c.Request.Context(), claims.UserID, req.Code)
+	if err != nil {
+		writeMFAError(c, err, "failed to regenerate recovery codes")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (h *Handler) DisableMFA(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if err := h.service.DisableMFA(c.Request.Context(), claims.UserID, req.Code); err != nil {
+		writeMFAError(c, err, "failed to disable mfa")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+type accountTokenPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if err := h.service.RequestPasswordReset(c.Request.Context(), req.Email, c.ClientIP()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to request password reset"})
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req accountTokenPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	err := h.service.ResetPassword(c.Request.Context(), req.Token, req.Password)
+	if err != nil {
+		writeAccountTokenError(c, err, "failed to reset password")
+		return
+	}
+	h.clearCookies(c)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	var req accountTokenPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	err := h.service.AcceptInvitation(c.Request.Context(), req.Token, req.Password)
+	if err != nil {
+		writeAccountTokenError(c, err, "failed to accept invitation")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SendInvitation lets the users module email an invite link for a user it
+// has just created in the invited state.
+func (h *Handler) SendInvitation(ctx context.Context, userID, invitedByUserID string) error {
+	return h.service.SendInvitation(ctx, userID, invitedByUserID)
+}
+
+func writeAccountTokenError(c *gin.Context, err error, fallback string) {
+	var policyErr *security.PasswordPolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "password_errors": policyErr.Problems})
+	case errors.Is(err, ErrPasswordReused):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidAccountToken):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func writeMFAError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInvalidMFAToken),
+		errors.Is(err, ErrMFAChallengeExpired),
+		errors.Is(err, ErrMFATooManyAttempts),
+		errors.Is(err, ErrInvalidMFACode),
+		errors.Is(err, ErrAuthenticatedUserAbsent):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUserNotActive), errors.Is(err, ErrMFARequiredByRole):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrMFAAlreadyEnabled),
+		errors.Is(err, ErrMFANotEnabled),
+		errors.Is(err, ErrMFAEnrollmentNotStarted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func (h *Handler) writeSession(c *gin.Context, session SessionResult) {
+	h.setRefreshCookie(c, session.RefreshToken)
+	csrfToken := h.setCSRFCookie(c)
+
+	body := gin.H{
+		"access_token": session.AccessToken,
+		"csrf_token":   csrfToken,
+		"expires_in":   session.ExpiresIn,
+		"scope":        session.Scope,
+		"user":         session.User,
+	}
+	if len(session.RecoveryCodes) > 0 {
+		body["recovery_codes"] = session.RecoveryCodes
+	}
+	c.JSON(http.StatusOK, body)
 }
 
 func (h *Handler) setRefreshCookie(c *gin.Context, token string) {
@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"humphreys/api/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	errOIDCDiscovery     = errors.New("oidc discovery failed")
+	errOIDCTokenExchange = errors.New("oidc token exchange failed")
+	errOIDCInvalidToken  = errors.New("oidc id token invalid")
+)
+
+// oidcKeyRefreshInterval limits how often an unknown kid triggers a JWKS
+// refetch, so garbage tokens cannot hammer the issuer.
+const oidcKeyRefreshInterval = time.Minute
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcIdentity is who the issuer says signed in. Email is only set when the
+// issuer asserted email_verified, since an unverified address can be typed in
+// by whoever controls the identity.
+type oidcIdentity struct {
+	Issuer  string
+	Subject string
+	Email   string
+	Name    string
+}
+
+type oidcIDTokenClaims struct {
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified"`
+	Name          string `json:"name"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// oidcProvider runs the authorization-code flow with PKCE against a single
+// issuer. Discovery and signing keys are fetched lazily and cached.
+type oidcProvider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	client       *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]any
+	keysFetchedAt time.Time
+}
+
+// newOIDCProvider returns nil when SSO is not configured.
+func newOIDCProvider(cfg config.Config, client *http.Client) *oidcProvider {
+	if cfg.OIDCIssuer == "" || cfg.OIDCClientID == "" {
+		return nil
+	}
+	return &oidcProvider{
+		issuer:       strings.TrimRight(cfg.OIDCIssuer, "/"),
+		clientID:     cfg.OIDCClientID,
+		clientSecret: cfg.OIDCClientSecret,
+		redirectURL:  cfg.OIDCRedirectURL,
+		scopes:       strings.Fields(cfg.OIDCScopes),
+		client:       client,
+	}
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *oidcProvider) authCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	discovery, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.clientID)
+	params.Set("redirect_uri", p.redirectURL)
+	params.Set("scope", strings.Join(p.scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", pkceChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return discovery.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// exchange redeems the authorization code and returns the identity asserted
+// by the verified ID token.
+func (p *oidcProvider) exchange(ctx context.Context, code, codeVerifier, nonce string) (oidcIdentity, error) {
+	discovery, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return oidcIdentity{}, err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("client_id", p.clientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.clientSecret != "" {
+		form.Set("client_secret", p.clientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oidcIdentity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return oidcIdentity{}, fmt.Errorf("%w: %v", errOIDCTokenExchange, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return oidcIdentity{}, fmt.Errorf("%w: %v", errOIDCTokenExchange, err)
+	}
+	var payload struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return oidcIdentity{}, fmt.Errorf("%w: status %d", errOIDCTokenExchange, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || payload.IDToken == "" {
+		return oidcIdentity{}, fmt.Errorf("%w: status %d %s %s", errOIDCTokenExchange, resp.StatusCode, payload.Error, payload.ErrorDescription)
+	}
+	return p.verifyIDToken(ctx, payload.IDToken, nonce)
+}
+
+func (p *oidcProvider) verifyIDToken(ctx context.Context, raw, nonce string) (oidcIdentity, error) {
+	claims := &oidcIDTokenClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return oidcIdentity{}, fmt.Errorf("%w: %v", errOIDCInvalidToken, err)
+	}
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return oidcIdentity{}, fmt.Errorf("%w: nonce mismatch", errOIDCInvalidToken)
+	}
+	if claims.Subject == "" {
+		return oidcIdentity{}, fmt.Errorf("%w: no subject claim", errOIDCInvalidToken)
+	}
+
+	identity := oidcIdentity{Issuer: p.issuer, Subject: claims.Subject, Name: strings.TrimSpace(claims.Name)}
+	if claims.EmailVerified != nil && *claims.EmailVerified {
+		identity.Email = strings.ToLower(strings.TrimSpace(claims.Email))
+	}
+	return identity, nil
+}
+
+func (p *oidcProvider) loadDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	var discovery oidcDiscovery
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("%w: %v", errOIDCDiscovery, err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("%w: issuer mismatch %q", errOIDCDiscovery, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete provider metadata", errOIDCDiscovery)
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+func (p *oidcProvider) signingKey(ctx context.Context, kid string) (any, error) {
+	discovery, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if !p.keysFetchedAt.IsZero() && time.Since(p.keysFetchedAt) < oidcKeyRefreshInterval {
+		return nil, errors.New("unknown signing key")
+	}
+
+	var set struct {
+		Keys []oidcJWK `json:"keys"`
+	}
+	if err := p.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// lookupKey falls back to the only published key when the token has no kid.
+func (p *oidcProvider) lookupKey(kid string) (any, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, endpoint string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+type oidcJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k oidcJWK) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"humphreys/api/internal/config"
+	"humphreys/api/internal/domain"
+	"humphreys/api/internal/modules/auth/security"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	mockClientID     = "humphreys-api"
+	mockClientSecret = "client-secret"
+	mockRedirectURL  = "http://localhost:8080/auth/oidc/callback"
+)
+
+// mockIssuer is a minimal OpenID provider: discovery, JWKS and a token
+// endpoint that enforces PKCE against the challenge seen at /authorize.
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu         sync.Mutex
+	challenges map[string]string
+	nonces     map[string]string
+	claims     jwt.MapClaims
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	m := &mockIssuer{t: t, key: key, challenges: map[string]string{}, nonces: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "mock-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// authorize stands in for the user signing in: it records the PKCE challenge
+// and nonce from the authorization URL and returns the code to redirect with.
+func (m *mockIssuer) authorize(authURL string) (code, state string) {
+	m.t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatalf("parse auth url: %v", err)
+	}
+	q := parsed.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != mockClientID || q.Get("redirect_uri") != mockRedirectURL {
+		m.t.Fatalf("unexpected authorization request: %s", authURL)
+	}
+	code = "code-" + q.Get("state")[:8]
+	m.mu.Lock()
+	m.challenges[code] = q.Get("code_challenge")
+	m.nonces[code] = q.Get("nonce")
+	m.mu.Unlock()
+	return code, q.Get("state")
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	code := r.PostForm.Get("code")
+	m.mu.Lock()
+	challenge, ok := m.challenges[code]
+	nonce := m.nonces[code]
+	delete(m.challenges, code)
+	m.mu.Unlock()
+	if !ok || r.PostForm.Get("client_secret") != mockClientSecret || r.PostForm.Get("redirect_uri") != mockRedirectURL ||
+		pkceChallenge(r.PostForm.Get("code_verifier")) != challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            mockClientID,
+		"sub":            "subject-1",
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "Staff@Example.com",
+		"email_verified": true,
+		"name":           "Staff Member",
+	}
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+	tkn := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tkn.Header["kid"] = "mock-1"
+	signed, err := tkn.SignedString(m.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id_token": signed, "token_type": "Bearer", "access_token": "unused"})
+}
+
+func (m *mockIssuer) config() config.Config {
+	return config.Config{
+		OIDCIssuer:       m.server.URL,
+		OIDCClientID:     mockClientID,
+		OIDCClientSecret: mockClientSecret,
+		OIDCRedirectURL:  mockRedirectURL,
+		OIDCScopes:       "openid email profile",
+		AccessTokenTTL:   time.Minute,
+		RefreshTokenTTL:  time.Hour,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestOIDCProviderCodeFlowWithPKCE(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := newOIDCProvider(issuer.config(), issuer.server.Client())
+	ctx := context.Background()
+
+	authURL, err := provider.authCodeURL(ctx, "state-0123456789", "nonce-1", "verifier-0123456789-0123456789-0123456789")
+	if err != nil {
+		t.Fatalf("auth url: %v", err)
+	}
+	code, _ := issuer.authorize(authURL)
+	identity, err := provider.exchange(ctx, code, "verifier-0123456789-0123456789-0123456789", "nonce-1")
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+	if identity.Email != "staff@example.com" || identity.Subject != "subject-1" || identity.Issuer != issuer.server.URL || identity.Name != "Staff Member" {
+		t.Fatalf("unexpected identity: %#v", identity)
+	}
+
+	code, _ = issuer.authorize(authURL)
+	if _, err := provider.exchange(ctx, code, "some-other-verifier-0123456789-0123456789", "nonce-1"); err == nil {
+		t.Fatal("expected a mismatched code verifier to be rejected")
+	}
+}
+
+func TestOIDCProviderRejectsBadIDTokens(t *testing.T) {
+	cases := []struct {
+		name   string
+		claims jwt.MapClaims
+		nonce  string
+	}{
+		{name: "wrong nonce", nonce: "other-nonce"},
+		{name: "wrong audience", nonce: "nonce-1", claims: jwt.MapClaims{"aud": "someone-else"}},
+		{name: "wrong issuer", nonce: "nonce-1", claims: jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{name: "expired", nonce: "nonce-1", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{name: "no subject", nonce: "nonce-1", claims: jwt.MapClaims{"sub": ""}},
+	}
+	for _, tc := range cases {
+		issuer := newMockIssuer(t)
+		issuer.claims = tc.claims
+		provider := newOIDCProvider(issuer.config(), issuer.server.Client())
+		authURL, err := provider.authCodeURL(context.Background(), "state-0123456789", "nonce-1", "verifier-0123456789-0123456789-0123456789")
+		if err != nil {
+			t.Fatalf("%s: auth url: %v", tc.name, err)
+		}
+		code, _ := issuer.authorize(authURL)
+		if _, err := provider.exchange(context.Background(), code, "verifier-0123456789-0123456789-0123456789", tc.nonce); err == nil {
+			t.Fatalf("%s: expected id token to be rejected", tc.name)
+		}
+	}
+}
+
+func TestOIDCProviderOnlyTrustsVerifiedEmail(t *testing.T) {
+	cases := map[string]jwt.MapClaims{
+		"unverified":             {"email_verified": false},
+		"verification not given": {"email_verified": nil},
+		"username only":          {"email": "", "email_verified": nil, "preferred_username": "owner@example.com"},
+	}
+	for name, claims := range cases {
+		issuer := newMockIssuer(t)
+		issuer.claims = claims
+		provider := newOIDCProvider(issuer.config(), issuer.server.Client())
+		authURL, err := provider.authCodeURL(context.Background(), "state-0123456789", "nonce-1", "verifier-0123456789-0123456789-0123456789")
+		if err != nil {
+			t.Fatalf("%s: auth url: %v", name, err)
+		}
+		code, _ := issuer.authorize(authURL)
+		identity, err := provider.exchange(context.Background(), code, "verifier-0123456789-0123456789-0123456789", "nonce-1")
+		if err != nil {
+			t.Fatalf("%s: exchange: %v", name, err)
+		}
+		if identity.Email != "" || identity.Subject != "subject-1" {
+			t.Fatalf("%s: expected an identity without email, got %#v", name, identity)
+		}
+	}
+}
+
+// oidcTestRepo implements the repository calls an SSO login makes; anything
+// else panics through the nil embedded interface.
+type oidcTestRepo struct {
+	Repository
+	states      map[string]OIDCLoginState
+	users       map[string]DBUser
+	identities  map[string]string
+	provisioned []string
+	events      []LoginEventInput
+}
+
+func (r *oidcTestRepo) SaveOIDCLoginState(_ context.Context, state OIDCLoginState) error {
+	r.states[state.StateHash] = state
+	return nil
+}
+
+func (r *oidcTestRepo) ConsumeOIDCLoginState(_ context.Context, stateHash string) (OIDCLoginState, error) {
+	state, ok := r.states[stateHash]
+	if !ok {
+		return OIDCLoginState{}, pgx.ErrNoRows
+	}
+	delete(r.states, stateHash)
+	return state, nil
+}
+
+func (r *oidcTestRepo) GetUserByEmail(_ context.Context, email string) (DBUser, error) {
+	user, ok := r.users[email]
+	if !ok {
+		return DBUser{}, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (r *oidcTestRepo) ProvisionOIDCUser(_ context.Context, email, fullName, _, roleName string) (string, error) {
+	r.provisioned = append(r.provisioned, email+"|"+roleName)
+	r.users[email] = DBUser{ID: "new-user", Email: email, FullName: fullName, Status: "active"}
+	return "new-user", nil
+}
+
+func (r *oidcTestRepo) GetUserByOIDCIdentity(_ context.Context, issuer, subject string) (DBUser, error) {
+	userID, ok := r.identities[issuer+"|"+subject]
+	if !ok {
+		return DBUser{}, pgx.ErrNoRows
+	}
+	for _, user := range r.users {
+		if user.ID == userID {
+			return user, nil
+		}
+	}
+	return DBUser{}, pgx.ErrNoRows
+}
+
+func (r *oidcTestRepo) LinkOIDCIdentity(_ context.Context, userID, issuer, subject string) error {
+	for key, linked := range r.identities {
+		if key == issuer+"|"+subject || (linked == userID && strings.HasPrefix(key, issuer+"|")) {
+			return ErrOIDCIdentityConflict
+		}
+	}
+	r.identities[issuer+"|"+subject] = userID
+	return nil
+}
+
+func (r *oidcTestRepo) GetMFAState(context.Context, string) (MFAState, error) {
+	return MFAState{}, nil
+}
+
+func (r *oidcTestRepo) GetPermissionsVersion(context.Context, string) (int64, error) {
+	return 1, nil
+}
+
+func (r *oidcTestRepo) ListPermissionsByUserID(context.Context, string) ([]domain.Permission, []string, error) {
+	return []domain.Permission{{Code: "work_orders:read"}}, []string{"role-1"}, nil
+}
+
+func (r *oidcTestRepo) SaveRefreshToken(context.Context, string, string, string, time.Time, string, string) (string, error) {
+	return "refresh-1", nil
+}
+
+func (r *oidcTestRepo) MarkLastLogin(context.Context, string) error {
+	return nil
+}
+
+func (r *oidcTestRepo) GetUserByID(_ context.Context, id string) (domain.User, error) {
+	for _, user := range r.users {
+		if user.ID == id {
+			return domain.User{ID: user.ID, Email: user.Email, FullName: user.FullName, Status: user.Status}, nil
+		}
+	}
+	return domain.User{}, pgx.ErrNoRows
+}
+
+func (r *oidcTestRepo) RecordLoginEvent(_ context.Context, event LoginEventInput) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestCompleteOIDCLoginMatchesOrProvisionsByEmail(t *testing.T) {
+	cases := []struct {
+		name          string
+		autoProvision bool
+		existing      bool
+		claims        jwt.MapClaims
+		linkedTo      string
+		wantErr       error
+		wantProvision bool
+	}{
+		{name: "existing user", existing: true},
+		{name: "unknown user without provisioning", wantErr: ErrOIDCUserNotFound},
+		{name: "unknown user with provisioning", autoProvision: true, wantProvision: true},
+		{name: "unverified email", existing: true, autoProvision: true, claims: jwt.MapClaims{"email_verified": nil}, wantErr: ErrOIDCEmailNotVerified},
+		{name: "linked subject after email change", existing: true, claims: jwt.MapClaims{"email": "renamed@example.com", "email_verified": nil}, linkedTo: "subject-1"},
+		{name: "account linked to another subject", existing: true, linkedTo: "subject-2", wantErr: ErrOIDCIdentityConflict},
+	}
+	for _, tc := range cases {
+		issuer := newMockIssuer(t)
+		issuer.claims = tc.claims
+		cfg := issuer.config()
+		cfg.OIDCAutoProvision = tc.autoProvision
+		cfg.OIDCDefaultRole = "technician"
+		repo := &oidcTestRepo{states: map[string]OIDCLoginState{}, users: map[string]DBUser{}, identities: map[string]string{}}
+		if tc.existing {
+			repo.users["staff@example.com"] = DBUser{ID: "user-1", Email: "staff@example.com", Status: "active"}
+		}
+		if tc.linkedTo != "" {
+			repo.identities[issuer.server.URL+"|"+tc.linkedTo] = "user-1"
+		}
+		svc := NewService(repo, cfg, security.NewHMACKeySet("secret"), security.PasswordPolicy{})
+		ctx := context.Background()
+
+		start, err := svc.BeginOIDCLogin(ctx, "//evil.example.com", "127.0.0.1", "test")
+		if err != nil {
+			t.Fatalf("%s: begin: %v", tc.name, err)
+		}
+		code, state := issuer.authorize(start.RedirectURL)
+		if state != start.State {
+			t.Fatalf("%s: state not forwarded to the issuer", tc.name)
+		}
+		result, err := svc.CompleteOIDCLogin(ctx, state, code, "127.0.0.1", "test")
+		if result.ReturnTo != "/" {
+			t.Fatalf("%s: expected unsafe return path to be replaced, got %q", tc.name, result.ReturnTo)
+		}
+		if tc.wantErr != nil {
+			if err != tc.wantErr {
+				t.Fatalf("%s: expected %v, got %v", tc.name, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: complete: %v", tc.name, err)
+		}
+		if result.Session == nil || result.Session.RefreshToken == "" || result.Session.User.Email != "staff@example.com" {
+			t.Fatalf("%s: expected a session, got %#v", tc.name, result.LoginResult)
+		}
+		if got := len(repo.provisioned) > 0; got != tc.wantProvision {
+			t.Fatalf("%s: provisioned=%v", tc.name, repo.provisioned)
+		}
+		if _, ok := repo.identities[issuer.server.URL+"|subject-1"]; !ok {
+			t.Fatalf("%s: expected the identity to be linked, got %v", tc.name, repo.identities)
+		}
+		if last := repo.events[len(repo.events)-1]; last.Outcome != loginOutcomeSuccess || last.Reason != loginReasonSSO {
+			t.Fatalf("%s: unexpected login event %#v", tc.name, last)
+		}
+		if _, err := svc.CompleteOIDCLogin(ctx, state, code, "127.0.0.1", "test"); err != ErrInvalidOIDCState {
+			t.Fatalf("%s: expected replayed state to be rejected, got %v", tc.name, err)
+		}
+	}
+}
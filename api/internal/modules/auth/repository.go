@@ -2,11 +2,16 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
 	"humphreys/api/internal/domain"
+	"humphreys/api/internal/middleware"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -14,12 +19,39 @@ type Repository interface {
 	GetUserByEmail(ctx context.Context, email string) (DBUser, error)
 	GetUserByID(ctx context.Context, id string) (domain.User, error)
 	ListPermissionsByUserID(ctx context.Context, userID string) ([]domain.Permission, []string, error)
+	GetPermissionsVersion(ctx context.Context, userID string) (int64, error)
 	SaveRefreshToken(ctx context.Context, userID, tokenHash, familyID string, expiresAt time.Time, createdByIP, userAgent string) (string, error)
 	GetRefreshToken(ctx context.Context, tokenHash string) (RefreshTokenRecord, error)
 	RevokeTokenAndSetReplacement(ctx context.Context, tokenID, replacementID string) error
 	RevokeFamily(ctx context.Context, familyID string) error
 	RevokeByHash(ctx context.Context, hash string) error
 	MarkLastLogin(ctx context.Context, userID string) error
+	GetMFAState(ctx context.Context, userID string) (MFAState, error)
+	SavePendingMFASecret(ctx context.Context, userID, secret string) error
+	EnableMFA(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error
+	MarkTOTPStepUsed(ctx context.Context, userID string, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID string, recoveryCodeHashes []string) error
+	ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	DeleteMFA(ctx context.Context, userID string) error
+	SaveMFAChallenge(ctx context.Context, userID, tokenHash string, expiresAt time.Time, createdByIP, userAgent string) error
+	GetMFAChallenge(ctx context.Context, tokenHash string) (MFAChallengeRecord, error)
+	IncrementMFAChallengeAttempts(ctx context.Context, challengeID string) (int, error)
+	ConsumeMFAChallenge(ctx context.Context, challengeID string) (bool, error)
+	SaveAccountToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time, createdByUserID *string, createdByIP string) error
+	GetAccountToken(ctx context.Context, tokenHash string) (AccountTokenRecord, error)
+	AcceptInvitation(ctx context.Context, tokenID, userID, passwordHash string) error
+	ResetPassword(ctx context.Context, tokenID, userID, passwordHash string) error
+	ListSessions(ctx context.Context, userID string) ([]domain.Session, error)
+	RevokeUserSession(ctx context.Context, userID, familyID string) (bool, error)
+	RecordLoginEvent(ctx context.Context, event LoginEventInput) error
+	GetLoginAttemptStats(ctx context.Context, email, ipAddress string, since time.Time) (loginAttemptStats, loginAttemptStats, error)
+	SaveOIDCLoginState(ctx context.Context, state OIDCLoginState) error
+	ConsumeOIDCLoginState(ctx context.Context, stateHash string) (OIDCLoginState, error)
+	ProvisionOIDCUser(ctx context.Context, email, fullName, passwordHash, roleName string) (string, error)
+	GetUserByOIDCIdentity(ctx context.Context, issuer, subject string) (DBUser, error)
+	LinkOIDCIdentity(ctx context.Context, userID, issuer, subject string) error
+	ActivateInvitedUser(ctx context.Context, userID string) error
+	RecordImpersonation(ctx context.Context, actorUserID, userID, action string, metadata map[string]any) error
 }
 
 type storeRepository struct {
@@ -32,8 +64,10 @@ type DBUser struct {
 	PasswordHash string
 	FullName     string
 	Status       string
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// MustChangePassword is set for bootstrapped and admin-set passwords.
+	MustChangePassword bool
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
 }
 
 type RefreshTokenRecord struct {
@@ -45,6 +79,53 @@ type RefreshTokenRecord struct {
 	ReplacedByTokenID *string
 }
 
+type MFAState struct {
+	Secret                 *string
+	EnabledAt              *time.Time
+	Required               bool
+	RecoveryCodesRemaining int
+}
+
+func (s MFAState) Enabled() bool {
+	return s.Secret != nil && s.EnabledAt != nil
+}
+
+type MFAChallengeRecord struct {
+	ID         string
+	UserID     string
+	ExpiresAt  time.Time
+	Attempts   int
+	ConsumedAt *time.Time
+}
+
+type AccountTokenRecord struct {
+	ID        string
+	UserID    string
+	Purpose   string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+type LoginEventInput struct {
+	UserID    *string
+	Email     string
+	Outcome   string
+	Reason    string
+	IPAddress string
+	UserAgent string
+}
+
+type OIDCLoginState struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ReturnTo     string
+	ExpiresAt    time.Time
+	CreatedByIP  string
+	UserAgent    string
+}
+
 func NewRepository(db *pgxpool.Pool) Repository {
 	return &storeRepository{db: db}
 }
@@ -52,20 +133,21 @@ func NewRepository(db *pgxpool.Pool) Repository {
 func (r *storeRepository) GetUserByEmail(ctx context.Context, email string) (DBUser, error) {
 	var user DBUser
 	err := r.db.QueryRow(ctx, `
-		SELECT id, email, password_hash, full_name, status, created_at, updated_at
+		SELECT id, email, password_hash, full_name, status, must_change_password, created_at, updated_at
 		FROM users
 		WHERE email=$1 AND deleted_at IS NULL
-	`, strings.ToLower(email)).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.FullName, &user.Status, &user.CreatedAt, &user.UpdatedAt)
+	`, strings.ToLower(email)).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.FullName, &user.Status, &user.MustChangePassword, &user.CreatedAt, &user.UpdatedAt)
 	return user, err
 }
 
 func (r *storeRepository) GetUserByID(ctx context.Context, id string) (domain.User, error) {
 	var user domain.User
 	err := r.db.QueryRow(ctx, `
-		SELECT id, email, full_name, status, created_at, updated_at
+		SELECT id, email, full_name, status, created_at, updated_at, worker_id,
+			(SELECT w.worker_name FROM public.workers w WHERE w.worker_id = users.worker_id)
 		FROM users
 		WHERE id=$1 AND deleted_at IS NULL
-	`, id).Scan(&user.ID, &user.Email, &user.FullName, &user.Status, &user.CreatedAt, &user.UpdatedAt)
+	`, id).Scan(&user.ID, &user.Email, &user.FullName, &user.Status, &user.CreatedAt, &user.UpdatedAt, &user.WorkerID, &user.WorkerName)
 	if err != nil {
 		return user, err
 	}
@@ -114,6 +196,12 @@ func (r *storeRepository) ListPermissionsByUserID(ctx context.Context, userID st
 	return perms, roleIDs, nil
 }
 
+func (r *storeRepository) GetPermissionsVersion(ctx context.Context, userID string) (int64, error) {
+	var version int64
+	err := r.db.QueryRow(ctx, `SELECT permissions_version FROM users WHERE id=$1`, userID).Scan(&version)
+	return version, err
+}
+
 func (r *storeRepository) SaveRefreshToken(ctx context.Context, userID, tokenHash, familyID string, expiresAt time.Time, createdByIP, userAgent string) (string, error) {
 	var id string
 	err := r.db.QueryRow(ctx, `
@@ -162,9 +250,596 @@ func (r *storeRepository) MarkLastLogin(ctx context.Context, userID string) erro
 	return err
 }
 
+func (r *storeRepository) GetMFAState(ctx context.Context, userID string) (MFAState, error) {
+	var state MFAState
+	err := r.db.QueryRow(ctx, `
+		SELECT
+			m.totp_secret,
+			m.enabled_at,
+			EXISTS(
+				SELECT 1
+				FROM user_roles ur
+				JOIN roles ro ON ro.id = ur.role_id
+				WHERE ur.user_id = u.id AND ro.mfa_required
+			),
+			(
+				SELECT COUNT(*)
+				FROM user_mfa_recovery_codes rc
+				WHERE rc.user_id = u.id AND rc.used_at IS NULL
+			)
+		FROM users u
+		LEFT JOIN user_mfa m ON m.user_id = u.id
+		WHERE u.id = $1 AND u.deleted_at IS NULL
+	`, userID).Scan(&state.Secret, &state.EnabledAt, &state.Required, &state.RecoveryCodesRemaining)
+	return state, err
+}
+
+func (r *storeRepository) SavePendingMFASecret(ctx context.Context, userID, secret string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO user_mfa(user_id, totp_secret)
+		VALUES($1,$2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET totp_secret=EXCLUDED.totp_secret, last_used_step=NULL, updated_at=now()
+		WHERE user_mfa.enabled_at IS NULL
+	`, userID, secret)
+	return err
+}
+
+func (r *storeRepository) EnableMFA(ctx context.Context, userID string, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE user_mfa
+		SET enabled_at=now(), last_used_step=$2, updated_at=now()
+		WHERE user_id=$1 AND enabled_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if err := replaceRecoveryCodesTx(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *storeRepository) MarkTOTPStepUsed(ctx context.Context, userID string, step int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE user_mfa
+		SET last_used_step=$2, updated_at=now()
+		WHERE user_id=$1 AND (last_used_step IS NULL OR last_used_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *storeRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodesTx(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *storeRepository) ConsumeRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE user_mfa_recovery_codes
+		SET used_at=now()
+		WHERE user_id=$1 AND code_hash=$2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *storeRepository) DeleteMFA(ctx context.Context, userID string) error {
+	return DeleteUserMFA(ctx, r.db, userID)
+}
+
+func (r *storeRepository) SaveMFAChallenge(ctx context.Context, userID, tokenHash string, expiresAt time.Time, createdByIP, userAgent string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO mfa_challenges(user_id, token_hash, expires_at, created_by_ip, user_agent)
+		VALUES($1,$2,$3,$4,$5)
+	`, userID, tokenHash, expiresAt, createdByIP, userAgent)
+	return err
+}
+
+func (r *storeRepository) GetMFAChallenge(ctx context.Context, tokenHash string) (MFAChallengeRecord, error) {
+	var rec MFAChallengeRecord
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, expires_at, attempts, consumed_at
+		FROM mfa_challenges
+		WHERE token_hash=$1
+	`, tokenHash).Scan(&rec.ID, &rec.UserID, &rec.ExpiresAt, &rec.Attempts, &rec.ConsumedAt)
+	return rec, err
+}
+
+func (r *storeRepository) IncrementMFAChallengeAttempts(ctx context.Context, challengeID string) (int, error) {
+	var attempts int
+	err := r.db.QueryRow(ctx, `
+		UPDATE mfa_challenges
+		SET attempts=attempts+1
+		WHERE id=$1
+		RETURNING attempts
+	`, challengeID).Scan(&attempts)
+	return attempts, err
+}
+
+func (r *storeRepository) ConsumeMFAChallenge(ctx context.Context, challengeID string) (bool, error) {
+	tag, err := r.db.Exec(ctx, `UPDATE mfa_challenges SET consumed_at=now() WHERE id=$1 AND consumed_at IS NULL`, challengeID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SaveAccountToken supersedes any outstanding token with the same purpose so
+// only the most recent invitation or reset link works.
+func (r *storeRepository) SaveAccountToken(ctx context.Context, userID, purpose, tokenHash string, expiresAt time.Time, createdByUserID *string, createdByIP string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := revokeAccountTokensTx(ctx, tx, userID, purpose); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO account_tokens(user_id, purpose, token_hash, expires_at, created_by_user_id, created_by_ip)
+		VALUES($1,$2,$3,$4,$5,$6)
+	`, userID, purpose, tokenHash, expiresAt, createdByUserID, createdByIP); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *storeRepository) GetAccountToken(ctx context.Context, tokenHash string) (AccountTokenRecord, error) {
+	var rec AccountTokenRecord
+	err := r.db.QueryRow(ctx, `
+		SELECT id, user_id, purpose, expires_at, used_at, revoked_at
+		FROM account_tokens
+		WHERE token_hash=$1
+	`, tokenHash).Scan(&rec.ID, &rec.UserID, &rec.Purpose, &rec.ExpiresAt, &rec.UsedAt, &rec.RevokedAt)
+	return rec, err
+}
+
+func (r *storeRepository) AcceptInvitation(ctx context.Context, tokenID, userID, passwordHash string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := useAccountTokenTx(ctx, tx, tokenID); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE users
+		SET password_hash=$2, status='active', must_change_password=FALSE, updated_at=now()
+		WHERE id=$1 AND status='invited' AND deleted_at IS NULL
+	`, userID, passwordHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *storeRepository) ResetPassword(ctx context.Context, tokenID, userID, passwordHash string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := useAccountTokenTx(ctx, tx, tokenID); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE users
+		SET password_hash=$2, must_change_password=FALSE, updated_at=now()
+		WHERE id=$1 AND deleted_at IS NULL
+	`, userID, passwordHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	if err := revokeAccountTokensTx(ctx, tx, userID, accountTokenPasswordReset); err != nil {
+		return err
+	}
+	if err := revokeAccountTokensTx(ctx, tx, userID, accountTokenPasswordChange); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at=now() WHERE user_id=$1 AND revoked_at IS NULL`, userID); err != nil {
+		return err
+	}
+	if err := BumpPermissionsVersionTx(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func useAccountTokenTx(ctx context.Context, tx pgx.Tx, tokenID string) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE account_tokens
+		SET used_at=now()
+		WHERE id=$1 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > now()
+	`, tokenID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func revokeAccountTokensTx(ctx context.Context, tx pgx.Tx, userID, purpose string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE account_tokens
+		SET revoked_at=now()
+		WHERE user_id=$1 AND purpose=$2 AND used_at IS NULL AND revoked_at IS NULL
+	`, userID, purpose)
+	return err
+}
+
+func (r *storeRepository) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	return ListUserSessions(ctx, r.db, userID)
+}
+
+func (r *storeRepository) RevokeUserSession(ctx context.Context, userID, familyID string) (bool, error) {
+	return RevokeUserSession(ctx, r.db, userID, familyID)
+}
+
+// ListUserSessions groups a user's refresh tokens by family. Every rotation
+// inserts a new row, so the newest row carries the latest IP and user agent.
+func ListUserSessions(ctx context.Context, db *pgxpool.Pool, userID string) ([]domain.Session, error) {
+	rows, err := db.Query(ctx, `
+		SELECT
+			family_id::text,
+			COALESCE((array_agg(user_agent ORDER BY created_at DESC))[1], ''),
+			COALESCE((array_agg(created_by_ip ORDER BY created_at DESC))[1], ''),
+			MIN(created_at),
+			MAX(created_at),
+			MAX(expires_at)
+		FROM refresh_tokens
+		WHERE user_id::text = $1
+		GROUP BY family_id
+		HAVING bool_or(revoked_at IS NULL AND expires_at > now())
+		ORDER BY MAX(created_at) DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]domain.Session, 0)
+	for rows.Next() {
+		var session domain.Session
+		if err := rows.Scan(&session.FamilyID, &session.UserAgent, &session.IPAddress, &session.SignedInAt, &session.LastUsedAt, &session.ExpiresAt); err != nil {
+			return nil, err
+		}
+		session.Device = describeDevice(session.UserAgent)
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func RevokeUserSession(ctx context.Context, db *pgxpool.Pool, userID, familyID string) (bool, error) {
+	tag, err := db.Exec(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at=now()
+		WHERE user_id::text=$1 AND family_id::text=$2 AND revoked_at IS NULL
+	`, userID, familyID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *storeRepository) RecordLoginEvent(ctx context.Context, event LoginEventInput) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO login_events(user_id, email, outcome, reason, ip_address, user_agent)
+		VALUES($1,$2,$3,$4,$5,$6)
+	`, event.UserID, event.Email, event.Outcome, event.Reason, event.IPAddress, event.UserAgent)
+	return err
+}
+
+// GetLoginAttemptStats counts recent failures for the email (reset by a
+// successful login or an admin unlock) and for the client IP.
+func (r *storeRepository) GetLoginAttemptStats(ctx context.Context, email, ipAddress string, since time.Time) (loginAttemptStats, loginAttemptStats, error) {
+	var account, ip loginAttemptStats
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*), MAX(created_at)
+		FROM login_events
+		WHERE email=$1
+		  AND outcome='failure'
+		  AND created_at > $2
+		  AND created_at > COALESCE((
+		    SELECT MAX(created_at)
+		    FROM login_events
+		    WHERE email=$1 AND outcome IN ('success', 'unlocked')
+		  ), '-infinity'::timestamptz)
+	`, email, since).Scan(&account.Failures, &account.LastFailureAt)
+	if err != nil {
+		return account, ip, err
+	}
+	err = r.db.QueryRow(ctx, `
+		SELECT COUNT(*), MAX(created_at)
+		FROM login_events
+		WHERE ip_address=$1 AND outcome='failure' AND created_at > $2
+	`, ipAddress, since).Scan(&ip.Failures, &ip.LastFailureAt)
+	return account, ip, err
+}
+
+func (r *storeRepository) SaveOIDCLoginState(ctx context.Context, state OIDCLoginState) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO oidc_login_states(state_hash, nonce, code_verifier, return_to, expires_at, created_by_ip, user_agent)
+		VALUES($1,$2,$3,$4,$5,$6,$7)
+	`, state.StateHash, state.Nonce, state.CodeVerifier, state.ReturnTo, state.ExpiresAt, state.CreatedByIP, state.UserAgent)
+	return err
+}
+
+// ConsumeOIDCLoginState marks the state used and returns it, so a callback
+// URL only works once and only before it expires.
+func (r *storeRepository) ConsumeOIDCLoginState(ctx context.Context, stateHash string) (OIDCLoginState, error) {
+	state := OIDCLoginState{StateHash: stateHash}
+	err := r.db.QueryRow(ctx, `
+		UPDATE oidc_login_states
+		SET consumed_at=now()
+		WHERE state_hash=$1 AND consumed_at IS NULL AND expires_at > now()
+		RETURNING nonce, code_verifier, return_to, expires_at
+	`, stateHash).Scan(&state.Nonce, &state.CodeVerifier, &state.ReturnTo, &state.ExpiresAt)
+	return state, err
+}
+
+// ProvisionOIDCUser creates an active SSO user with an unusable password and,
+// when roleName is set, the named role. The owner role is never granted here.
+func (r *storeRepository) ProvisionOIDCUser(ctx context.Context, email, fullName, passwordHash, roleName string) (string, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO users(email,password_hash,full_name,status) VALUES($1,$2,$3,'active') RETURNING id`,
+		strings.ToLower(email), passwordHash, fullName,
+	).Scan(&id); err != nil {
+		return "", err
+	}
+	if roleName != "" {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO user_roles(user_id, role_id)
+			SELECT $1, id FROM roles WHERE name=$2 AND name <> 'owner'
+		`, id, roleName)
+		if err != nil {
+			return "", err
+		}
+		if tag.RowsAffected() == 0 {
+			return "", fmt.Errorf("oidc default role %q not found", roleName)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (r *storeRepository) GetUserByOIDCIdentity(ctx context.Context, issuer, subject string) (DBUser, error) {
+	var user DBUser
+	err := r.db.QueryRow(ctx, `
+		SELECT u.id, u.email, u.password_hash, u.full_name, u.status, u.must_change_password, u.created_at, u.updated_at
+		FROM user_oidc_identities i
+		JOIN users u ON u.id = i.user_id
+		WHERE i.issuer=$1 AND i.subject=$2 AND u.deleted_at IS NULL
+	`, issuer, subject).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.FullName, &user.Status, &user.MustChangePassword, &user.CreatedAt, &user.UpdatedAt)
+	return user, err
+}
+
+// LinkOIDCIdentity binds the user to an issuer subject. It returns
+// ErrOIDCIdentityConflict when the user is already bound to a different
+// subject at the issuer, or the subject to a different user.
+func (r *storeRepository) LinkOIDCIdentity(ctx context.Context, userID, issuer, subject string) error {
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO user_oidc_identities(issuer, subject, user_id)
+		VALUES($1,$2,$3)
+		ON CONFLICT DO NOTHING
+	`, issuer, subject, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOIDCIdentityConflict
+	}
+	return nil
+}
+
+// ActivateInvitedUser lets an invited user finish onboarding through SSO; the
+// emailed invitation link stops working.
+func (r *storeRepository) ActivateInvitedUser(ctx context.Context, userID string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE users
+		SET status='active', updated_at=now()
+		WHERE id=$1 AND status='invited' AND deleted_at IS NULL
+	`, userID); err != nil {
+		return err
+	}
+	if err := revokeAccountTokensTx(ctx, tx, userID, accountTokenInvite); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func ListLoginEvents(ctx context.Context, db *pgxpool.Pool, userID string, limit int) ([]domain.LoginEvent, error) {
+	rows, err := db.Query(ctx, `
+		SELECT id::text, user_id::text, email, outcome, reason, ip_address, user_agent, actor_user_id::text, created_at
+		FROM login_events
+		WHERE user_id::text=$1
+		   OR email=(SELECT email FROM users WHERE id::text=$1)
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]domain.LoginEvent, 0)
+	for rows.Next() {
+		var event domain.LoginEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Email, &event.Outcome, &event.Reason, &event.IPAddress, &event.UserAgent, &event.ActorUserID, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (r *storeRepository) RecordImpersonation(ctx context.Context, actorUserID, userID, action string, metadata map[string]any) error {
+	return insertImpersonationAudit(ctx, r.db, actorUserID, userID, action, metadata)
+}
+
+// insertImpersonationAudit files the entry under the impersonated user with
+// the real admin as actor, so either side's history shows it.
+func insertImpersonationAudit(ctx context.Context, db *pgxpool.Pool, actorUserID, userID, action string, metadata map[string]any) error {
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		INSERT INTO audit_logs(actor_user_id, action, target_type, target_id, metadata_json)
+		VALUES($1,$2,'user',$3,$4)
+	`, actorUserID, action, userID, encoded)
+	return err
+}
+
+// UnlockLogin clears the per-account lockout by recording an unlock event,
+// which resets the failure count for the user's email.
+func UnlockLogin(ctx context.Context, db *pgxpool.Pool, userID, actorUserID string) error {
+	tag, err := db.Exec(ctx, `
+		INSERT INTO login_events(user_id, email, outcome, actor_user_id)
+		SELECT id, email, 'unlocked', $2
+		FROM users
+		WHERE id::text=$1 AND deleted_at IS NULL
+	`, userID, actorUserID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeUserSessions signs a user out everywhere: refresh tokens stop
+// rotating and the version bump makes outstanding access tokens stale.
+func RevokeUserSessions(ctx context.Context, db *pgxpool.Pool, userID string) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at=now() WHERE user_id::text=$1 AND revoked_at IS NULL`, userID); err != nil {
+		return err
+	}
+	if err := BumpPermissionsVersionTx(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func BumpPermissionsVersionTx(ctx context.Context, tx pgx.Tx, userID string) error {
+	_, err := tx.Exec(ctx, `UPDATE users SET permissions_version=permissions_version+1 WHERE id::text=$1`, userID)
+	return err
+}
+
+type permissionVersionStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPermissionVersionStore backs middleware.Auth's stale-token check.
+func NewPermissionVersionStore(db *pgxpool.Pool) middleware.PermissionVersionSource {
+	return &permissionVersionStore{db: db}
+}
+
+func (s *permissionVersionStore) PermissionVersion(ctx context.Context, userID string) (int64, bool, error) {
+	var version int64
+	var active bool
+	err := s.db.QueryRow(ctx, `
+		SELECT permissions_version, status = 'active'
+		FROM users
+		WHERE id::text=$1 AND deleted_at IS NULL
+	`, userID).Scan(&version, &active)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	return version, active, err
+}
+
+// DeleteUserMFA removes a user's authenticator, recovery codes and any open
+// login challenges. The users module calls it for the admin reset path.
+func DeleteUserMFA(ctx context.Context, db *pgxpool.Pool, userID string) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_mfa_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE mfa_challenges SET consumed_at=now() WHERE user_id=$1 AND consumed_at IS NULL`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodesTx(ctx context.Context, tx pgx.Tx, userID string, recoveryCodeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM user_mfa_recovery_codes WHERE user_id=$1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, `INSERT INTO user_mfa_recovery_codes(user_id, code_hash) VALUES($1,$2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *storeRepository) listRolesByUserID(ctx context.Context, userID string) ([]domain.Role, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT r.id, r.name, r.description, r.is_system
+		SELECT r.id, r.name, r.description, r.is_system, r.mfa_required
 		FROM roles r
 		JOIN user_roles ur ON ur.role_id = r.id
 		WHERE ur.user_id = $1
@@ -178,7 +853,7 @@ func (r *storeRepository) listRolesByUserID(ctx context.Context, userID string)
 	roles := make([]domain.Role, 0)
 	for rows.Next() {
 		var role domain.Role
-		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem); err != nil {
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem, &role.MFARequired); err != nil {
 			return nil, err
 		}
 		roles = append(roles, role)
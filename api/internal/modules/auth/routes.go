@@ -1,19 +1,53 @@
 package auth
 
-import "github.com/gin-gonic/gin"
+import (
+	"humphreys/api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
 
 func CSRFExemptPaths() map[string]struct{} {
 	return map[string]struct{}{
-		"/auth/login": {},
+		"/auth/login":              {},
+		"/auth/login/mfa":          {},
+		"/auth/login/mfa/enroll":   {},
+		"/auth/password/forgot":    {},
+		"/auth/password/reset":     {},
+		"/auth/password/change":    {},
+		"/auth/invitations/accept": {},
 	}
 }
 
 func RegisterPublicRoutes(r gin.IRoutes, h *Handler) {
+	r.GET("/.well-known/jwks.json", h.JWKS)
 	r.POST("/auth/login", h.Login)
+	r.POST("/auth/login/mfa", h.VerifyLoginMFA)
+	r.POST("/auth/login/mfa/enroll", h.BeginLoginMFAEnrollment)
 	r.POST("/auth/refresh", h.Refresh)
+	r.GET("/auth/oidc/login", h.BeginOIDCLogin)
+	r.GET("/auth/oidc/callback", h.OIDCCallback)
+	r.POST("/auth/password/forgot", h.ForgotPassword)
+	r.POST("/auth/password/reset", h.ResetPassword)
+	r.POST("/auth/password/change", h.ChangeRequiredPassword)
+	r.POST("/auth/invitations/accept", h.AcceptInvitation)
 }
 
-func RegisterProtectedRoutes(authed *gin.RouterGroup, h *Handler) {
-	authed.POST("/auth/logout", h.Logout)
-	authed.GET("/auth/me", h.Me)
+func RegisterProtectedRoutes(authed *middleware.Router, h *Handler) {
+	// Account routes act on the signed-in user and are off-limits to API keys.
+	account := authed.Group("/auth", middleware.RejectAPIKeys())
+	// An impersonating client still loads /auth/me, which names the admin
+	// behind the token.
+	account.GET("/me", h.Me)
+
+	// The rest would let an impersonation token sign out, read or change the
+	// user's own sessions and MFA.
+	personal := account.Group("", middleware.RejectImpersonation())
+	personal.POST("/logout", h.Logout)
+	personal.GET("/sessions", h.ListSessions)
+	personal.DELETE("/sessions/:family_id", h.RevokeSession)
+	personal.GET("/mfa", h.MFAStatus)
+	personal.POST("/mfa/enroll", h.BeginMFAEnrollment)
+	personal.POST("/mfa/confirm", h.ConfirmMFAEnrollment)
+	personal.POST("/mfa/recovery-codes", h.RegenerateRecoveryCodes)
+	personal.DELETE("/mfa", h.DisableMFA)
 }
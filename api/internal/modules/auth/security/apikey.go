@@ -0,0 +1,56 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+)
+
+// APIKeyPrefix marks a bearer credential as an API key rather than a JWT.
+const APIKeyPrefix = "hk_"
+
+const (
+	apiKeyIDLength     = 8
+	apiKeySecretLength = 32
+)
+
+var apiKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewAPIKey returns a key formatted as hk_<id>_<secret> along with its
+// public prefix (hk_<id>), which is stored in clear for lookup and display.
+func NewAPIKey() (raw string, prefix string, err error) {
+	id, err := randomAPIKeyPart(apiKeyIDLength)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := randomAPIKeyPart(apiKeySecretLength)
+	if err != nil {
+		return "", "", err
+	}
+	prefix = APIKeyPrefix + id
+	return prefix + "_" + secret, prefix, nil
+}
+
+func IsAPIKey(raw string) bool {
+	return strings.HasPrefix(raw, APIKeyPrefix)
+}
+
+// APIKeyLookupPrefix extracts the public prefix from a raw key.
+func APIKeyLookupPrefix(raw string) (string, bool) {
+	if !IsAPIKey(raw) {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(raw[len(APIKeyPrefix):], "_")
+	if !ok || len(id) != apiKeyIDLength || len(secret) != apiKeySecretLength {
+		return "", false
+	}
+	return APIKeyPrefix + id, true
+}
+
+func randomAPIKeyPart(length int) (string, error) {
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return strings.ToLower(apiKeyEncoding.EncodeToString(buf))[:length], nil
+}
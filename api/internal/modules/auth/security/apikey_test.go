@@ -0,0 +1,19 @@
+package security
+
+import "testing"
+
+func TestNewAPIKeyLookupPrefix(t *testing.T) {
+	raw, prefix, err := NewAPIKey()
+	if err != nil {
+		t.Fatalf("new api key: %v", err)
+	}
+	got, ok := APIKeyLookupPrefix(raw)
+	if !ok || got != prefix {
+		t.Fatalf("expected prefix %q, got %q ok=%t", prefix, got, ok)
+	}
+	for _, bad := range []string{"", "eyJhbGciOi.x.y", prefix, prefix + "_short", "hk_abc_" + raw[len(prefix)+1:]} {
+		if _, ok := APIKeyLookupPrefix(bad); ok {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type KeySetConfig struct {
+	// HMACSecret signs tokens when no private keys are configured and, while
+	// AcceptHS256 is set, still verifies tokens issued before the switch.
+	HMACSecret string
+	// PrivateKeysPEM holds one or more PKCS#8 (or PKCS#1 RSA) private keys.
+	// The first key signs unless ActiveKID names another one.
+	PrivateKeysPEM string
+	ActiveKID      string
+	AcceptHS256    bool
+}
+
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// KeySet signs access tokens with the active key and verifies tokens from any
+// configured key, so a new key can be rolled out without logging users out.
+type KeySet struct {
+	active      *signingKey
+	keys        map[string]*signingKey
+	order       []string
+	hmacSecret  []byte
+	acceptHMAC  bool
+	validMethod []string
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewKeySet(cfg KeySetConfig) (*KeySet, error) {
+	ks := &KeySet{
+		keys:       map[string]*signingKey{},
+		hmacSecret: []byte(cfg.HMACSecret),
+	}
+
+	rest := []byte(strings.TrimSpace(cfg.PrivateKeysPEM))
+	for len(rest) > 0 {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("jwt private keys: invalid PEM data")
+		}
+		key, err := parseSigningKey(block)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := ks.keys[key.kid]; exists {
+			continue
+		}
+		ks.keys[key.kid] = key
+		ks.order = append(ks.order, key.kid)
+	}
+
+	if len(ks.order) > 0 {
+		activeKID := strings.TrimSpace(cfg.ActiveKID)
+		if activeKID == "" {
+			activeKID = ks.order[0]
+		}
+		active, ok := ks.keys[activeKID]
+		if !ok {
+			return nil, fmt.Errorf("jwt active kid %q does not match any configured key", activeKID)
+		}
+		ks.active = active
+		ks.acceptHMAC = cfg.AcceptHS256 && len(ks.hmacSecret) > 0
+	} else {
+		if len(ks.hmacSecret) == 0 {
+			return nil, errors.New("either JWT_SECRET or JWT_PRIVATE_KEYS must be set")
+		}
+		ks.acceptHMAC = true
+	}
+
+	for _, key := range ks.keys {
+		ks.validMethod = appendUnique(ks.validMethod, key.method.Alg())
+	}
+	if ks.acceptHMAC {
+		ks.validMethod = appendUnique(ks.validMethod, jwt.SigningMethodHS256.Alg())
+	}
+	return ks, nil
+}
+
+// NewHMACKeySet is the legacy single-secret configuration.
+func NewHMACKeySet(secret string) *KeySet {
+	return &KeySet{
+		keys:        map[string]*signingKey{},
+		hmacSecret:  []byte(secret),
+		acceptHMAC:  true,
+		validMethod: []string{jwt.SigningMethodHS256.Alg()},
+	}
+}
+
+// SigningKeyID returns the kid stamped on new tokens, or "" for HS256.
+func (k *KeySet) SigningKeyID() string {
+	if k.active == nil {
+		return ""
+	}
+	return k.active.kid
+}
+
+func (k *KeySet) NewSessionAccessToken(ttl time.Duration, userID, sessionID string, permissionsVersion int64, roleIDs []string, scope []string) (string, time.Time, error) {
+	claims, expires := newClaims(ttl, userID, sessionID, permissionsVersion, roleIDs, scope)
+	signed, err := k.Sign(claims)
+	return signed, expires, err
+}
+
+// NewImpersonationAccessToken issues a token for userID on behalf of actor.
+// It carries no session id, so it cannot be refreshed.
+func (k *KeySet) NewImpersonationAccessToken(ttl time.Duration, userID string, permissionsVersion int64, roleIDs []string, scope []string, actor Actor) (string, time.Time, error) {
+	claims, expires := newClaims(ttl, userID, "", permissionsVersion, roleIDs, scope)
+	claims.Actor = &actor
+	signed, err := k.Sign(claims)
+	return signed, expires, err
+}
+
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if k.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.hmacSecret)
+	}
+	tkn := jwt.NewWithClaims(k.active.method, claims)
+	tkn.Header["kid"] = k.active.kid
+	return tkn.SignedString(k.active.private)
+}
+
+func (k *KeySet) ParseAccessToken(raw string) (*Claims, error) {
+	claims := &Claims{}
+	if err := k.ParseWithClaims(raw, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (k *KeySet) ParseWithClaims(raw string, claims jwt.Claims) error {
+	tkn, err := jwt.ParseWithClaims(raw, claims, k.verificationKey, jwt.WithValidMethods(k.validMethod))
+	if err != nil {
+		return err
+	}
+	if !tkn.Valid {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+func (k *KeySet) verificationKey(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		if !k.acceptHMAC {
+			return nil, errors.New("invalid signing method")
+		}
+		return k.hmacSecret, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok || key.method.Alg() != token.Method.Alg() {
+		return nil, errors.New("unknown signing key")
+	}
+	return key.public, nil
+}
+
+// JWKS lists the public half of every configured key, active key first.
+func (k *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: make([]JWK, 0, len(k.order))}
+	if k.active != nil {
+		out.Keys = append(out.Keys, k.active.jwk())
+	}
+	for _, kid := range k.order {
+		if k.active != nil && kid == k.active.kid {
+			continue
+		}
+		out.Keys = append(out.Keys, k.keys[kid].jwk())
+	}
+	return out
+}
+
+func parseSigningKey(block *pem.Block) (*signingKey, error) {
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("jwt private keys: unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt private keys: %w", err)
+	}
+
+	key := &signingKey{}
+	switch priv := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.method = jwt.SigningMethodEdDSA
+		key.private = priv
+		key.public = priv.Public()
+	case *rsa.PrivateKey:
+		if priv.N.BitLen() < 2048 {
+			return nil, errors.New("jwt private keys: RSA keys must be at least 2048 bits")
+		}
+		key.method = jwt.SigningMethodRS256
+		key.private = priv
+		key.public = priv.Public()
+	default:
+		return nil, errors.New("jwt private keys: only Ed25519 and RSA keys are supported")
+	}
+	key.kid = key.thumbprint()
+	return key, nil
+}
+
+// thumbprint derives the kid from the public key (RFC 7638), so the same key
+// always gets the same id and nothing extra needs configuring.
+func (k *signingKey) thumbprint() string {
+	jwk := k.jwk()
+	var canonical string
+	switch jwk.KeyType {
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Curve, jwk.X)
+	default:
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (k *signingKey) jwk() JWK {
+	switch pub := k.public.(type) {
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			KeyID:     k.kid,
+			Use:       "sig",
+			Algorithm: k.method.Alg(),
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(pub),
+		}
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			KeyID:     k.kid,
+			Use:       "sig",
+			Algorithm: k.method.Alg(),
+			N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}
+	default:
+		return JWK{KeyID: k.kid}
+	}
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,114 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func ed25519PEM(t *testing.T) string {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("marshal ed25519: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func rsaPEM(t *testing.T) string {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(priv)}))
+}
+
+func TestKeySetRotationKeepsOldTokensValid(t *testing.T) {
+	oldKey := ed25519PEM(t)
+	newKey := rsaPEM(t)
+
+	before, err := NewKeySet(KeySetConfig{PrivateKeysPEM: oldKey})
+	if err != nil {
+		t.Fatalf("old key set: %v", err)
+	}
+	oldToken, _, err := before.NewSessionAccessToken(time.Minute, "user-1", "family-1", 1, nil, []string{"users:read"})
+	if err != nil {
+		t.Fatalf("sign with old key: %v", err)
+	}
+
+	after, err := NewKeySet(KeySetConfig{PrivateKeysPEM: newKey + oldKey})
+	if err != nil {
+		t.Fatalf("rotated key set: %v", err)
+	}
+	if after.SigningKeyID() == before.SigningKeyID() {
+		t.Fatal("expected the new key to become active")
+	}
+	claims, err := after.ParseAccessToken(oldToken)
+	if err != nil {
+		t.Fatalf("expected old token to verify after rotation: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.SessionID != "family-1" {
+		t.Fatalf("unexpected claims: %#v", claims)
+	}
+
+	newToken, _, err := after.NewSessionAccessToken(time.Minute, "user-2", "", 1, nil, nil)
+	if err != nil {
+		t.Fatalf("sign with new key: %v", err)
+	}
+	if _, err := before.ParseAccessToken(newToken); err == nil {
+		t.Fatal("expected a key set without the new key to reject its tokens")
+	}
+
+	jwks := after.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != after.SigningKeyID() || jwks.Keys[0].Algorithm != "RS256" {
+		t.Fatalf("unexpected jwks: %#v", jwks)
+	}
+	if jwks.Keys[1].Algorithm != "EdDSA" || jwks.Keys[1].Curve != "Ed25519" {
+		t.Fatalf("unexpected retired key entry: %#v", jwks.Keys[1])
+	}
+}
+
+func TestKeySetHS256MigrationPath(t *testing.T) {
+	legacyToken, _, err := NewAccessToken("secret", time.Minute, "user-1", nil, nil)
+	if err != nil {
+		t.Fatalf("legacy token: %v", err)
+	}
+	key := ed25519PEM(t)
+
+	cases := []struct {
+		name        string
+		acceptHS256 bool
+		wantValid   bool
+	}{
+		{name: "accepted during migration", acceptHS256: true, wantValid: true},
+		{name: "rejected once migration ends", acceptHS256: false, wantValid: false},
+	}
+	for _, tc := range cases {
+		ks, err := NewKeySet(KeySetConfig{HMACSecret: "secret", PrivateKeysPEM: key, AcceptHS256: tc.acceptHS256})
+		if err != nil {
+			t.Fatalf("%s: key set: %v", tc.name, err)
+		}
+		_, err = ks.ParseAccessToken(legacyToken)
+		if (err == nil) != tc.wantValid {
+			t.Fatalf("%s: expected valid=%t, got err=%v", tc.name, tc.wantValid, err)
+		}
+	}
+}
+
+func TestKeySetRequiresSomeKey(t *testing.T) {
+	if _, err := NewKeySet(KeySetConfig{}); err == nil {
+		t.Fatal("expected an error without a secret or private key")
+	}
+	if _, err := NewKeySet(KeySetConfig{PrivateKeysPEM: ed25519PEM(t), ActiveKID: "missing"}); err == nil {
+		t.Fatal("expected an error for an unknown active kid")
+	}
+}
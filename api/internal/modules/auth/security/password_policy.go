@@ -0,0 +1,192 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// knownDefaultPasswords are values that ship in config defaults or docs and
+// must never be accepted, whatever else the policy allows.
+var knownDefaultPasswords = []string{"changeme123!"}
+
+type PasswordPolicy struct {
+	MinLength int
+	// MinCharacterClasses counts lowercase, uppercase, digits and symbols.
+	MinCharacterClasses int
+	Breached            *BreachedPasswordList
+}
+
+// PasswordPolicyError lists every rule a password failed so the caller can
+// show them all at once.
+type PasswordPolicyError struct {
+	Problems []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password " + strings.Join(e.Problems, "; ")
+}
+
+func (p PasswordPolicy) Validate(password, email string) error {
+	var problems []string
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MinCharacterClasses > 0 && characterClasses(password) < p.MinCharacterClasses {
+		problems = append(problems, fmt.Sprintf("must mix at least %d of lowercase, uppercase, digits and symbols", p.MinCharacterClasses))
+	}
+	if matchesEmail(password, email) {
+		problems = append(problems, "must not match the email address")
+	}
+	for _, known := range knownDefaultPasswords {
+		if strings.EqualFold(password, known) {
+			problems = append(problems, "must not be a default password")
+			break
+		}
+	}
+	if len(problems) == 0 && p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			problems = append(problems, "has appeared in a known data breach")
+		}
+	}
+	if len(problems) > 0 {
+		return &PasswordPolicyError{Problems: problems}
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+func matchesEmail(password, email string) bool {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return false
+	}
+	if strings.EqualFold(password, email) {
+		return true
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return local != "" && strings.EqualFold(password, local)
+}
+
+// BreachedPasswordList checks passwords against a local copy of a
+// breached-password corpus keyed by SHA-1, as published by Have I Been Pwned.
+// The path is either a directory of range files named by the first five hex
+// digits of the hash (each line "SUFFIX:COUNT", the k-anonymity range format)
+// or a single file of full "HASH[:COUNT]" lines, which is loaded into memory.
+type BreachedPasswordList struct {
+	dir    string
+	hashes map[string]struct{}
+}
+
+func NewBreachedPasswordList(path string) (*BreachedPasswordList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("breached password list: %w", err)
+	}
+	if info.IsDir() {
+		return &BreachedPasswordList{dir: path}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("breached password list: %w", err)
+	}
+	defer file.Close()
+
+	list := &BreachedPasswordList{hashes: map[string]struct{}{}}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash, ok := parseBreachedLine(scanner.Text(), 40)
+		if ok {
+			list.hashes[hash] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("breached password list: %w", err)
+	}
+	return list, nil
+}
+
+func (l *BreachedPasswordList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	if l.hashes != nil {
+		_, ok := l.hashes[hash]
+		return ok, nil
+	}
+
+	prefix, suffix := hash[:5], hash[5:]
+	file, err := openRangeFile(l.dir, prefix)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("breached password list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, ok := parseBreachedLine(scanner.Text(), 35)
+		if ok && candidate == suffix {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+func openRangeFile(dir, prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return os.Open(filepath.Join(dir, prefix))
+	}
+	return file, err
+}
+
+// parseBreachedLine skips padding entries (count 0) that range responses add
+// to hide the real number of matches.
+func parseBreachedLine(line string, hashLen int) (string, bool) {
+	hash, count, hasCount := strings.Cut(strings.TrimSpace(line), ":")
+	if len(hash) != hashLen {
+		return "", false
+	}
+	if hasCount {
+		if n, err := strconv.Atoi(strings.TrimSpace(count)); err == nil && n == 0 {
+			return "", false
+		}
+	}
+	return strings.ToUpper(hash), true
+}
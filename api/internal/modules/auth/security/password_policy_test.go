@@ -0,0 +1,72 @@
+package security
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 12, MinCharacterClasses: 3}
+	cases := []struct {
+		password string
+		wantOK   bool
+	}{
+		{password: "Tractor-Engine-42", wantOK: true},
+		{password: "Short1!", wantOK: false},
+		{password: "alllowercaseletters", wantOK: false},
+		{password: "ChangeMe123!", wantOK: false},
+		{password: "Mechanic.Owner@example.com", wantOK: false},
+	}
+	for _, tc := range cases {
+		err := policy.Validate(tc.password, "mechanic.owner@example.com")
+		if (err == nil) != tc.wantOK {
+			t.Fatalf("%q: expected ok=%t, got %v", tc.password, tc.wantOK, err)
+		}
+		var policyErr *PasswordPolicyError
+		if err != nil && !errors.As(err, &policyErr) {
+			t.Fatalf("%q: expected a policy error, got %T", tc.password, err)
+		}
+	}
+}
+
+func TestBreachedPasswordListRangeDirectory(t *testing.T) {
+	sum := sha1.Sum([]byte("Tractor-Engine-42"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	dir := t.TempDir()
+	rangeFile := "0000000000000000000000000000000000A:0\n" + hash[5:] + ":12\n"
+	if err := os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(rangeFile), 0o600); err != nil {
+		t.Fatalf("write range file: %v", err)
+	}
+	list, err := NewBreachedPasswordList(dir)
+	if err != nil {
+		t.Fatalf("load list: %v", err)
+	}
+
+	policy := PasswordPolicy{MinLength: 12, MinCharacterClasses: 3, Breached: list}
+	if err := policy.Validate("Tractor-Engine-42", ""); err == nil {
+		t.Fatal("expected breached password to be rejected")
+	}
+	if err := policy.Validate("Harvester-Gearbox-77", ""); err != nil {
+		t.Fatalf("expected unlisted password to pass, got %v", err)
+	}
+}
+
+func TestBreachedPasswordListSingleFile(t *testing.T) {
+	sum := sha1.Sum([]byte("Tractor-Engine-42"))
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.ToUpper(hex.EncodeToString(sum[:]))+":3\n"), 0o600); err != nil {
+		t.Fatalf("write list: %v", err)
+	}
+	list, err := NewBreachedPasswordList(path)
+	if err != nil {
+		t.Fatalf("load list: %v", err)
+	}
+	if found, err := list.Contains("Tractor-Engine-42"); err != nil || !found {
+		t.Fatalf("expected listed hash to be found, found=%t err=%v", found, err)
+	}
+}
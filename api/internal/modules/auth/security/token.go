@@ -5,7 +5,6 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
-	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -13,47 +12,52 @@ import (
 )
 
 type Claims struct {
-	UserID  string   `json:"sub"`
-	RoleIDs []string `json:"role_ids"`
-	Scope   []string `json:"scope"`
-	JTI     string   `json:"jti"`
+	UserID             string   `json:"sub"`
+	RoleIDs            []string `json:"role_ids"`
+	Scope              []string `json:"scope"`
+	JTI                string   `json:"jti"`
+	SessionID          string   `json:"sid,omitempty"`
+	PermissionsVersion int64    `json:"pv"`
+	APIKeyID           string   `json:"-"`
+	// Actor is set on impersonation tokens and names the admin really making
+	// the request (RFC 8693 "act").
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+type Actor struct {
+	UserID             string `json:"sub"`
+	PermissionsVersion int64  `json:"pv"`
+}
+
 func NewAccessToken(secret string, ttl time.Duration, userID string, roleIDs []string, scope []string) (string, time.Time, error) {
+	return NewSessionAccessToken(secret, ttl, userID, "", 0, roleIDs, scope)
+}
+
+func NewSessionAccessToken(secret string, ttl time.Duration, userID, sessionID string, permissionsVersion int64, roleIDs []string, scope []string) (string, time.Time, error) {
+	return NewHMACKeySet(secret).NewSessionAccessToken(ttl, userID, sessionID, permissionsVersion, roleIDs, scope)
+}
+
+func ParseAccessToken(secret, raw string) (*Claims, error) {
+	return NewHMACKeySet(secret).ParseAccessToken(raw)
+}
+
+func newClaims(ttl time.Duration, userID, sessionID string, permissionsVersion int64, roleIDs []string, scope []string) (Claims, time.Time) {
 	now := time.Now().UTC()
 	expires := now.Add(ttl)
-	claims := Claims{
-		UserID:  userID,
-		RoleIDs: roleIDs,
-		Scope:   scope,
-		JTI:     uuid.NewString(),
+	return Claims{
+		UserID:             userID,
+		RoleIDs:            roleIDs,
+		Scope:              scope,
+		JTI:                uuid.NewString(),
+		SessionID:          sessionID,
+		PermissionsVersion: permissionsVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   userID,
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(expires),
 		},
-	}
-	tkn := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := tkn.SignedString([]byte(secret))
-	return signed, expires, err
-}
-
-func ParseAccessToken(secret, raw string) (*Claims, error) {
-	tkn, err := jwt.ParseWithClaims(raw, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(secret), nil
-	})
-	if err != nil {
-		return nil, err
-	}
-	claims, ok := tkn.Claims.(*Claims)
-	if !ok || !tkn.Valid {
-		return nil, errors.New("invalid token")
-	}
-	return claims, nil
+	}, expires
 }
 
 func NewRefreshToken() (string, error) {
@@ -0,0 +1,77 @@
+package security
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCodeMatchesRFC6238Vectors(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tc := range cases {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", tc.unix, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%d: expected %s, got %s", tc.unix, tc.want, got)
+		}
+	}
+}
+
+func TestVerifyTOTPAllowsOneStepSkew(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatalf("new secret: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	previous, _ := TOTPCode(secret, TOTPStep(now)-1)
+	if step, ok := VerifyTOTP(secret, previous, now); !ok || step != TOTPStep(now)-1 {
+		t.Fatalf("expected previous step code to verify, got step=%d ok=%t", step, ok)
+	}
+	stale, _ := TOTPCode(secret, TOTPStep(now)-2)
+	if _, ok := VerifyTOTP(secret, stale, now); ok {
+		t.Fatal("expected code two steps old to be rejected")
+	}
+	if _, ok := VerifyTOTP(secret, "12345", now); ok {
+		t.Fatal("expected short code to be rejected")
+	}
+}
+
+func TestRecoveryCodesFormat(t *testing.T) {
+	codes, err := NewRecoveryCodes(3)
+	if err != nil {
+		t.Fatalf("new recovery codes: %v", err)
+	}
+	if len(codes) != 3 {
+		t.Fatalf("expected 3 codes, got %d", len(codes))
+	}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Fatalf("unexpected recovery code format: %s", code)
+		}
+		if NormalizeRecoveryCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))) != code {
+			t.Fatalf("expected normalized code to round-trip: %s", code)
+		}
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Humphreys", "owner@example.com", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/Humphreys:owner@example.com?") {
+		t.Fatalf("unexpected uri prefix: %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=Humphreys") {
+		t.Fatalf("expected secret and issuer params: %s", uri)
+	}
+}
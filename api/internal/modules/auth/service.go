@@ -3,10 +3,15 @@ package auth
 import (
 	"context"
 	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"humphreys/api/internal/config"
 	"humphreys/api/internal/domain"
+	"humphreys/api/internal/mailer"
 	"humphreys/api/internal/modules/auth/security"
 
 	"github.com/google/uuid"
@@ -21,12 +26,46 @@ var (
 	ErrRefreshTokenReuse       = errors.New("refresh token reuse detected")
 	ErrRefreshTokenExpired     = errors.New("refresh token expired")
 	ErrAuthenticatedUserAbsent = errors.New("user not found")
+	ErrInvalidMFAToken         = errors.New("invalid mfa token")
+	ErrMFAChallengeExpired     = errors.New("mfa challenge expired")
+	ErrMFATooManyAttempts      = errors.New("too many mfa attempts")
+	ErrInvalidMFACode          = errors.New("invalid mfa code")
+	ErrMFAAlreadyEnabled       = errors.New("mfa already enabled")
+	ErrMFANotEnabled           = errors.New("mfa not enabled")
+	ErrMFAEnrollmentNotStarted = errors.New("mfa enrollment not started")
+	ErrMFARequiredByRole       = errors.New("mfa is required for this account")
+	ErrInvalidAccountToken     = errors.New("invalid or expired token")
+	ErrPasswordReused          = errors.New("new password must differ from the current one")
+	ErrUserNotInvited          = errors.New("user is not awaiting an invitation")
+	ErrSessionNotFound         = errors.New("session not found")
+	ErrTooManyLoginAttempts    = errors.New("too many login attempts")
+	ErrOIDCNotConfigured       = errors.New("single sign-on is not configured")
+	ErrInvalidOIDCState        = errors.New("invalid or expired sign-in request")
+	ErrOIDCLoginFailed         = errors.New("single sign-on failed")
+	ErrOIDCUserNotFound        = errors.New("no account for this email")
+	ErrOIDCEmailNotVerified    = errors.New("single sign-on did not return a verified email")
+	ErrOIDCIdentityConflict    = errors.New("this account is linked to a different single sign-on identity")
+)
+
+type accountMailer interface {
+	Send(ctx context.Context, msg mailer.Message) error
+}
+
+const (
+	mfaMaxChallengeAttempts = 5
+	mfaRecoveryCodeCount    = 10
+	passwordChangeTTL       = 15 * time.Minute
 )
 
 type Service struct {
 	repo Repository
 	cfg  config.Config
 	now  func() time.Time
+	mail accountMailer
+	keys *security.KeySet
+	oidc *oidcProvider
+
+	passwords security.PasswordPolicy
 }
 
 type SessionResult struct {
@@ -35,45 +74,536 @@ type SessionResult struct {
 	Scope        []string
 	User         domain.User
 	RefreshToken string
+	// RecoveryCodes is only set when the login completed a role-enforced enrolment.
+	RecoveryCodes []string
+}
+
+type LoginResult struct {
+	Session        *SessionResult
+	Challenge      *MFAChallenge
+	PasswordChange *PasswordChangeChallenge
+	// RetryAfter is set alongside ErrTooManyLoginAttempts.
+	RetryAfter time.Duration
+}
+
+type MFAChallenge struct {
+	Token              string
+	ExpiresIn          int
+	EnrollmentRequired bool
 }
 
-func NewService(repo Repository, cfg config.Config) *Service {
+// PasswordChangeChallenge stands in for a session when the password was set
+// by someone else and the user has to pick their own before signing in.
+type PasswordChangeChallenge struct {
+	Token     string
+	ExpiresIn int
+}
+
+type MFAEnrollment struct {
+	Secret          string
+	ProvisioningURI string
+}
+
+type OIDCLoginStart struct {
+	RedirectURL string
+	State       string
+	ExpiresIn   int
+}
+
+// OIDCLoginResult carries the path the browser asked to return to, which is
+// needed whether or not the login succeeded.
+type OIDCLoginResult struct {
+	LoginResult
+	ReturnTo string
+}
+
+type MFAVerifyInput struct {
+	Code         string
+	RecoveryCode string
+}
+
+func NewService(repo Repository, cfg config.Config, keys *security.KeySet, passwords security.PasswordPolicy) *Service {
 	return &Service{
 		repo: repo,
 		cfg:  cfg,
 		now:  time.Now,
+		mail: mailer.NewGraphClientFromEnv(&http.Client{Timeout: 25 * time.Second}),
+		keys: keys,
+		oidc: newOIDCProvider(cfg, &http.Client{Timeout: 15 * time.Second}),
+
+		passwords: passwords,
 	}
 }
 
-func (s *Service) Login(ctx context.Context, email, password, clientIP, userAgent string) (SessionResult, error) {
+func (s *Service) Login(ctx context.Context, email, password, clientIP, userAgent string) (LoginResult, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	event := LoginEventInput{Email: email, IPAddress: clientIP, UserAgent: userAgent}
+
+	// Throttling is checked before the password so a locked account cannot be
+	// probed, and it is keyed on the email so unknown addresses behave the same.
+	accountStats, ipStats, err := s.repo.GetLoginAttemptStats(ctx, email, clientIP, s.now().Add(-loginWindow))
+	if err != nil {
+		return LoginResult{}, err
+	}
+	now := s.now()
+	if wait := ipThrottle.retryAfter(ipStats, now); wait > 0 {
+		return LoginResult{RetryAfter: wait}, s.recordThrottled(ctx, event, loginReasonIPLimit)
+	}
+	if wait := accountThrottle.retryAfter(accountStats, now); wait > 0 {
+		return LoginResult{RetryAfter: wait}, s.recordThrottled(ctx, event, loginReasonAccountLimit)
+	}
+
 	user, err := s.repo.GetUserByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return SessionResult{}, ErrInvalidCredentials
+			// Burn the same argon2 work as a real check so response timing
+			// does not reveal whether the email is registered.
+			security.VerifyPassword(dummyPasswordHash(), password)
+			return LoginResult{}, s.recordLoginFailure(ctx, event, loginReasonUnknownEmail, ErrInvalidCredentials)
+		}
+		return LoginResult{}, err
+	}
+	event.UserID = &user.ID
+	// The password is checked first so only its owner learns the account is
+	// not active.
+	if !security.VerifyPassword(user.PasswordHash, password) {
+		return LoginResult{}, s.recordLoginFailure(ctx, event, loginReasonBadPassword, ErrInvalidCredentials)
+	}
+	if user.Status != "active" {
+		return LoginResult{}, s.recordLoginFailure(ctx, event, loginReasonInactive, ErrUserNotActive)
+	}
+	if user.MustChangePassword {
+		token, err := s.issueAccountToken(ctx, user.ID, accountTokenPasswordChange, passwordChangeTTL, nil, clientIP)
+		if err != nil {
+			return LoginResult{}, err
+		}
+		return LoginResult{PasswordChange: &PasswordChangeChallenge{
+			Token:     token,
+			ExpiresIn: int(passwordChangeTTL.Seconds()),
+		}}, nil
+	}
+	return s.finishLogin(ctx, event, user.ID)
+}
+
+// ChangeRequiredPassword swaps a bootstrapped or admin-set password for one
+// the user chose, then carries on with the login (MFA included).
+func (s *Service) ChangeRequiredPassword(ctx context.Context, token, password, clientIP, userAgent string) (LoginResult, error) {
+	rec, err := s.loadAccountToken(ctx, token, accountTokenPasswordChange)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	me, err := s.repo.GetUserByID(ctx, rec.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return LoginResult{}, ErrInvalidAccountToken
+		}
+		return LoginResult{}, err
+	}
+	user, err := s.repo.GetUserByEmail(ctx, me.Email)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if user.Status != "active" {
+		return LoginResult{}, ErrUserNotActive
+	}
+	if err := s.passwords.Validate(password, user.Email); err != nil {
+		return LoginResult{}, err
+	}
+	if security.VerifyPassword(user.PasswordHash, password) {
+		return LoginResult{}, ErrPasswordReused
+	}
+	hash, err := security.HashPassword(password)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if err := s.repo.ResetPassword(ctx, rec.ID, rec.UserID, hash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return LoginResult{}, ErrInvalidAccountToken
+		}
+		return LoginResult{}, err
+	}
+	event := LoginEventInput{UserID: &user.ID, Email: user.Email, Reason: loginReasonPasswordChange, IPAddress: clientIP, UserAgent: userAgent}
+	return s.finishLogin(ctx, event, user.ID)
+}
+
+const oidcLoginTTL = 10 * time.Minute
+
+func (s *Service) OIDCEnabled() bool {
+	return s.oidc != nil
+}
+
+// BeginOIDCLogin stores the PKCE verifier and nonce for a new sign-in and
+// returns the issuer URL to send the browser to.
+func (s *Service) BeginOIDCLogin(ctx context.Context, returnTo, clientIP, userAgent string) (OIDCLoginStart, error) {
+	if s.oidc == nil {
+		return OIDCLoginStart{}, ErrOIDCNotConfigured
+	}
+	state, err := security.NewRefreshToken()
+	if err != nil {
+		return OIDCLoginStart{}, err
+	}
+	nonce, err := security.NewRefreshToken()
+	if err != nil {
+		return OIDCLoginStart{}, err
+	}
+	verifier, err := security.NewRefreshToken()
+	if err != nil {
+		return OIDCLoginStart{}, err
+	}
+	redirectURL, err := s.oidc.authCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("[oidc] begin login failed: %v", err)
+		return OIDCLoginStart{}, ErrOIDCLoginFailed
+	}
+	if err := s.repo.SaveOIDCLoginState(ctx, OIDCLoginState{
+		StateHash:    security.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ReturnTo:     safeReturnPath(returnTo),
+		ExpiresAt:    s.now().Add(oidcLoginTTL),
+		CreatedByIP:  clientIP,
+		UserAgent:    userAgent,
+	}); err != nil {
+		return OIDCLoginStart{}, err
+	}
+	return OIDCLoginStart{RedirectURL: redirectURL, State: state, ExpiresIn: int(oidcLoginTTL.Seconds())}, nil
+}
+
+// CompleteOIDCLogin redeems the authorization code, finds the user bound to
+// the identity (see resolveOIDCUser) and then continues exactly like a
+// password login, including any MFA challenge.
+func (s *Service) CompleteOIDCLogin(ctx context.Context, state, code, clientIP, userAgent string) (OIDCLoginResult, error) {
+	result := OIDCLoginResult{ReturnTo: "/"}
+	if s.oidc == nil {
+		return result, ErrOIDCNotConfigured
+	}
+	if state == "" || code == "" {
+		return result, ErrInvalidOIDCState
+	}
+	login, err := s.repo.ConsumeOIDCLoginState(ctx, security.HashToken(state))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return result, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return result, err
+	}
+	result.ReturnTo = login.ReturnTo
+
+	identity, err := s.oidc.exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		log.Printf("[oidc] code exchange failed: %v", err)
+		return result, ErrOIDCLoginFailed
+	}
+
+	event := LoginEventInput{Email: identity.Email, IPAddress: clientIP, UserAgent: userAgent}
+	user, err := s.resolveOIDCUser(ctx, identity, event)
+	if err != nil {
+		return result, err
+	}
+	event.UserID = &user.ID
+	event.Email = user.Email
+	if user.Status == "invited" {
+		if err := s.repo.ActivateInvitedUser(ctx, user.ID); err != nil {
+			return result, err
+		}
+		user.Status = "active"
+	}
+	if user.Status != "active" {
+		return result, s.recordLoginFailure(ctx, event, loginReasonInactive, ErrUserNotActive)
+	}
+
+	event.Reason = loginReasonSSO
+	result.LoginResult, err = s.finishLogin(ctx, event, user.ID)
+	return result, err
+}
+
+// resolveOIDCUser returns the user bound to the issuer subject. An identity
+// seen for the first time is matched by verified email, or provisioned when
+// enabled, and bound so that later sign-ins no longer depend on the email.
+func (s *Service) resolveOIDCUser(ctx context.Context, identity oidcIdentity, event LoginEventInput) (DBUser, error) {
+	user, err := s.repo.GetUserByOIDCIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return DBUser{}, err
+	}
+	if identity.Email == "" {
+		return DBUser{}, s.recordLoginFailure(ctx, event, loginReasonSSOUnverifiedEmail, ErrOIDCEmailNotVerified)
+	}
+
+	user, err = s.repo.GetUserByEmail(ctx, identity.Email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if !s.cfg.OIDCAutoProvision {
+			return DBUser{}, s.recordLoginFailure(ctx, event, loginReasonSSOUnknownEmail, ErrOIDCUserNotFound)
+		}
+		user, err = s.provisionOIDCUser(ctx, identity)
+	}
+	if err != nil {
+		return DBUser{}, err
+	}
+	if err := s.repo.LinkOIDCIdentity(ctx, user.ID, identity.Issuer, identity.Subject); err != nil {
+		if errors.Is(err, ErrOIDCIdentityConflict) {
+			event.UserID = &user.ID
+			return DBUser{}, s.recordLoginFailure(ctx, event, loginReasonSSOIdentityConflict, err)
+		}
+		return DBUser{}, err
+	}
+	return user, nil
+}
+
+func (s *Service) provisionOIDCUser(ctx context.Context, identity oidcIdentity) (DBUser, error) {
+	// SSO users never sign in with a password; a random one keeps the column
+	// populated until they choose to set one through a reset.
+	passwordHash, err := security.HashPassword(uuid.NewString())
+	if err != nil {
+		return DBUser{}, err
+	}
+	fullName := identity.Name
+	if fullName == "" {
+		fullName = identity.Email
+	}
+	if _, err := s.repo.ProvisionOIDCUser(ctx, identity.Email, fullName, passwordHash, s.cfg.OIDCDefaultRole); err != nil {
+		return DBUser{}, err
+	}
+	return s.repo.GetUserByEmail(ctx, identity.Email)
+}
+
+// finishLogin runs once the first factor has passed: it either starts an MFA
+// challenge or issues the session and records the successful login.
+func (s *Service) finishLogin(ctx context.Context, event LoginEventInput, userID string) (LoginResult, error) {
+	mfa, err := s.repo.GetMFAState(ctx, userID)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if mfa.Enabled() || mfa.Required {
+		challenge, err := s.startMFAChallenge(ctx, userID, event.IPAddress, event.UserAgent)
+		if err != nil {
+			return LoginResult{}, err
+		}
+		challenge.EnrollmentRequired = !mfa.Enabled()
+		return LoginResult{Challenge: &challenge}, nil
+	}
+
+	session, err := s.completeLogin(ctx, userID, event.IPAddress, event.UserAgent)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	event.Outcome = loginOutcomeSuccess
+	if err := s.repo.RecordLoginEvent(ctx, event); err != nil {
+		return LoginResult{}, err
+	}
+	return LoginResult{Session: &session}, nil
+}
+
+// safeReturnPath keeps post-login redirects on the web app.
+func safeReturnPath(path string) string {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
+
+// BeginMFAChallengeEnrollment hands out a TOTP secret to a user whose role
+// requires MFA but who has not enrolled yet, so they can finish logging in.
+func (s *Service) BeginMFAChallengeEnrollment(ctx context.Context, mfaToken string) (MFAEnrollment, error) {
+	challenge, err := s.loadMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	return s.BeginMFAEnrollment(ctx, challenge.UserID)
+}
+
+func (s *Service) VerifyMFAChallenge(ctx context.Context, mfaToken string, input MFAVerifyInput, clientIP, userAgent string) (SessionResult, error) {
+	challenge, err := s.loadMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return SessionResult{}, err
+	}
+	attempts, err := s.repo.IncrementMFAChallengeAttempts(ctx, challenge.ID)
+	if err != nil {
+		return SessionResult{}, err
+	}
+	if attempts > mfaMaxChallengeAttempts {
+		return SessionResult{}, ErrMFATooManyAttempts
+	}
+
+	user, err := s.repo.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SessionResult{}, ErrInvalidMFAToken
 		}
 		return SessionResult{}, err
 	}
 	if user.Status != "active" {
 		return SessionResult{}, ErrUserNotActive
 	}
-	if !security.VerifyPassword(user.PasswordHash, password) {
-		return SessionResult{}, ErrInvalidCredentials
+
+	mfa, err := s.repo.GetMFAState(ctx, challenge.UserID)
+	if err != nil {
+		return SessionResult{}, err
+	}
+	event := LoginEventInput{UserID: &user.ID, Email: user.Email, IPAddress: clientIP, UserAgent: userAgent}
+	var recoveryCodes []string
+	if mfa.Enabled() {
+		err = s.verifySecondFactor(ctx, challenge.UserID, mfa, input)
+	} else {
+		recoveryCodes, err = s.ConfirmMFAEnrollment(ctx, challenge.UserID, input.Code)
+	}
+	if errors.Is(err, ErrInvalidMFACode) {
+		return SessionResult{}, s.recordLoginFailure(ctx, event, loginReasonMFA, err)
+	}
+	if err != nil {
+		return SessionResult{}, err
 	}
 
-	session, err := s.issueSession(ctx, user.ID, clientIP, userAgent)
+	consumed, err := s.repo.ConsumeMFAChallenge(ctx, challenge.ID)
 	if err != nil {
 		return SessionResult{}, err
 	}
+	if !consumed {
+		return SessionResult{}, ErrInvalidMFAToken
+	}
 
-	_ = s.repo.MarkLastLogin(ctx, user.ID)
-	me, err := s.repo.GetUserByID(ctx, user.ID)
+	session, err := s.completeLogin(ctx, challenge.UserID, clientIP, userAgent)
 	if err != nil {
 		return SessionResult{}, err
 	}
-	session.User = me
+	event.Outcome = loginOutcomeSuccess
+	if err := s.repo.RecordLoginEvent(ctx, event); err != nil {
+		return SessionResult{}, err
+	}
+	session.RecoveryCodes = recoveryCodes
 	return session, nil
 }
 
+func (s *Service) MFAStatus(ctx context.Context, userID string) (domain.MFAStatus, error) {
+	mfa, err := s.repo.GetMFAState(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.MFAStatus{}, ErrAuthenticatedUserAbsent
+	}
+	if err != nil {
+		return domain.MFAStatus{}, err
+	}
+	status := domain.MFAStatus{
+		Enabled:  mfa.Enabled(),
+		Required: mfa.Required,
+	}
+	if status.Enabled {
+		status.EnabledAt = mfa.EnabledAt
+		status.RecoveryCodesRemaining = mfa.RecoveryCodesRemaining
+	}
+	return status, nil
+}
+
+func (s *Service) BeginMFAEnrollment(ctx context.Context, userID string) (MFAEnrollment, error) {
+	mfa, err := s.repo.GetMFAState(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return MFAEnrollment{}, ErrAuthenticatedUserAbsent
+	}
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	if mfa.Enabled() {
+		return MFAEnrollment{}, ErrMFAAlreadyEnabled
+	}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+
+	secret, err := security.NewTOTPSecret()
+	if err != nil {
+		return MFAEnrollment{}, err
+	}
+	if err := s.repo.SavePendingMFASecret(ctx, userID, secret); err != nil {
+		return MFAEnrollment{}, err
+	}
+	return MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: security.TOTPProvisioningURI(s.cfg.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFAEnrollment checks the first code from the authenticator app,
+// switches MFA on and returns the plaintext recovery codes exactly once.
+func (s *Service) ConfirmMFAEnrollment(ctx context.Context, userID, code string) ([]string, error) {
+	mfa, err := s.repo.GetMFAState(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAuthenticatedUserAbsent
+	}
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if mfa.Secret == nil {
+		return nil, ErrMFAEnrollmentNotStarted
+	}
+	step, ok := security.VerifyTOTP(*mfa.Secret, code, s.now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.EnableMFA(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	mfa, err := s.repo.GetMFAState(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAuthenticatedUserAbsent
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.Enabled() {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.verifySecondFactor(ctx, userID, mfa, MFAVerifyInput{Code: code}); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *Service) DisableMFA(ctx context.Context, userID, code string) error {
+	mfa, err := s.repo.GetMFAState(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrAuthenticatedUserAbsent
+	}
+	if err != nil {
+		return err
+	}
+	if mfa.Required {
+		return ErrMFARequiredByRole
+	}
+	if !mfa.Enabled() {
+		return ErrMFANotEnabled
+	}
+	if err := s.verifySecondFactor(ctx, userID, mfa, MFAVerifyInput{Code: code}); err != nil {
+		return err
+	}
+	return s.repo.DeleteMFA(ctx, userID)
+}
+
 func (s *Service) Refresh(ctx context.Context, cookieToken, clientIP, userAgent string) (SessionResult, error) {
 	if cookieToken == "" {
 		return SessionResult{}, ErrMissingRefreshToken
@@ -115,6 +645,10 @@ func (s *Service) Refresh(ctx context.Context, cookieToken, clientIP, userAgent
 		return SessionResult{}, err
 	}
 
+	permissionsVersion, err := s.repo.GetPermissionsVersion(ctx, rec.UserID)
+	if err != nil {
+		return SessionResult{}, err
+	}
 	perms, roleIDs, err := s.repo.ListPermissionsByUserID(ctx, rec.UserID)
 	if err != nil {
 		return SessionResult{}, err
@@ -123,7 +657,7 @@ func (s *Service) Refresh(ctx context.Context, cookieToken, clientIP, userAgent
 	for _, p := range perms {
 		scope = append(scope, p.Code)
 	}
-	accessToken, accessExpAt, err := security.NewAccessToken(s.cfg.JWTSecret, s.cfg.AccessTokenTTL, rec.UserID, roleIDs, scope)
+	accessToken, accessExpAt, err := s.keys.NewSessionAccessToken(s.cfg.AccessTokenTTL, rec.UserID, rec.FamilyID, permissionsVersion, roleIDs, scope)
 	if err != nil {
 		return SessionResult{}, err
 	}
@@ -148,6 +682,32 @@ func (s *Service) Logout(ctx context.Context, refreshToken string) error {
 	return s.repo.RevokeByHash(ctx, security.HashToken(refreshToken))
 }
 
+func (s *Service) ListSessions(ctx context.Context, userID, currentSessionID string) ([]domain.Session, error) {
+	sessions, err := s.repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = currentSessionID != "" && sessions[i].FamilyID == currentSessionID
+	}
+	return sessions, nil
+}
+
+func (s *Service) RevokeSession(ctx context.Context, userID, familyID string) error {
+	revoked, err := s.repo.RevokeUserSession(ctx, userID, familyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *Service) JWKS() security.JWKS {
+	return s.keys.JWKS()
+}
+
 func (s *Service) Me(ctx context.Context, userID string) (domain.User, error) {
 	me, err := s.repo.GetUserByID(ctx, userID)
 	if errors.Is(err, pgx.ErrNoRows) {
@@ -156,7 +716,297 @@ func (s *Service) Me(ctx context.Context, userID string) (domain.User, error) {
 	return me, err
 }
 
+// SendInvitation emails a single-use link that lets an invited user pick
+// their own password. Resending supersedes the previous link.
+func (s *Service) SendInvitation(ctx context.Context, userID, invitedByUserID string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAuthenticatedUserAbsent
+		}
+		return err
+	}
+	if user.Status != "invited" {
+		return ErrUserNotInvited
+	}
+	inviterName := ""
+	var invitedBy *string
+	if invitedByUserID != "" {
+		invitedBy = &invitedByUserID
+		if inviter, err := s.repo.GetUserByID(ctx, invitedByUserID); err == nil {
+			inviterName = inviter.FullName
+		}
+	}
+
+	token, err := s.issueAccountToken(ctx, user.ID, accountTokenInvite, s.cfg.InviteTokenTTL, invitedBy, "")
+	if err != nil {
+		return err
+	}
+	link := accountLink(s.cfg.WebBaseURL, "/accept-invite", token)
+	return s.mail.Send(ctx, buildInvitationMessage(user.Email, user.FullName, inviterName, link, s.cfg.InviteTokenTTL))
+}
+
+// RequestPasswordReset never reports whether the address belongs to an
+// account; the email is sent in the background so response timing does not
+// leak it either.
+func (s *Service) RequestPasswordReset(ctx context.Context, email, clientIP string) error {
+	user, err := s.repo.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Status != "active" {
+		return nil
+	}
+
+	token, err := s.issueAccountToken(ctx, user.ID, accountTokenPasswordReset, s.cfg.PasswordResetTTL, nil, clientIP)
+	if err != nil {
+		return err
+	}
+	msg := buildPasswordResetMessage(user.Email, user.FullName, accountLink(s.cfg.WebBaseURL, "/reset-password", token), s.cfg.PasswordResetTTL)
+	go func() {
+		sendCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.mail.Send(sendCtx, msg); err != nil {
+			log.Printf("[auth] password reset email failed user_id=%s: %v", user.ID, err)
+		}
+	}()
+	return nil
+}
+
+func (s *Service) AcceptInvitation(ctx context.Context, token, password string) error {
+	rec, err := s.loadAccountToken(ctx, token, accountTokenInvite)
+	if err != nil {
+		return err
+	}
+	if err := s.validateNewPassword(ctx, rec.UserID, password); err != nil {
+		return err
+	}
+	hash, err := security.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.AcceptInvitation(ctx, rec.ID, rec.UserID, hash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidAccountToken
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) ResetPassword(ctx context.Context, token, password string) error {
+	rec, err := s.loadAccountToken(ctx, token, accountTokenPasswordReset)
+	if err != nil {
+		return err
+	}
+	if err := s.validateNewPassword(ctx, rec.UserID, password); err != nil {
+		return err
+	}
+	hash, err := security.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.ResetPassword(ctx, rec.ID, rec.UserID, hash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidAccountToken
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Service) validateNewPassword(ctx context.Context, userID, password string) error {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidAccountToken
+		}
+		return err
+	}
+	return s.passwords.Validate(password, user.Email)
+}
+
+func (s *Service) issueAccountToken(ctx context.Context, userID, purpose string, ttl time.Duration, createdByUserID *string, clientIP string) (string, error) {
+	token, err := security.NewRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.SaveAccountToken(
+		ctx,
+		userID,
+		purpose,
+		security.HashToken(token),
+		s.now().Add(ttl),
+		createdByUserID,
+		clientIP,
+	); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *Service) loadAccountToken(ctx context.Context, token, purpose string) (AccountTokenRecord, error) {
+	if token == "" {
+		return AccountTokenRecord{}, ErrInvalidAccountToken
+	}
+	rec, err := s.repo.GetAccountToken(ctx, security.HashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AccountTokenRecord{}, ErrInvalidAccountToken
+		}
+		return AccountTokenRecord{}, err
+	}
+	if rec.Purpose != purpose || rec.UsedAt != nil || rec.RevokedAt != nil || rec.ExpiresAt.Before(s.now()) {
+		return AccountTokenRecord{}, ErrInvalidAccountToken
+	}
+	return rec, nil
+}
+
+// recordLoginFailure logs the failed attempt and hands back the error the
+// caller should return.
+func (s *Service) recordLoginFailure(ctx context.Context, event LoginEventInput, reason string, result error) error {
+	event.Outcome = loginOutcomeFailure
+	event.Reason = reason
+	if err := s.repo.RecordLoginEvent(ctx, event); err != nil {
+		return err
+	}
+	return result
+}
+
+func (s *Service) recordThrottled(ctx context.Context, event LoginEventInput, reason string) error {
+	event.Outcome = loginOutcomeThrottled
+	event.Reason = reason
+	if err := s.repo.RecordLoginEvent(ctx, event); err != nil {
+		return err
+	}
+	return ErrTooManyLoginAttempts
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = security.HashPassword(uuid.NewString())
+	})
+	return dummyHash
+}
+
+func (s *Service) completeLogin(ctx context.Context, userID, clientIP, userAgent string) (SessionResult, error) {
+	session, err := s.issueSession(ctx, userID, clientIP, userAgent)
+	if err != nil {
+		return SessionResult{}, err
+	}
+
+	_ = s.repo.MarkLastLogin(ctx, userID)
+	me, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return SessionResult{}, err
+	}
+	session.User = me
+	return session, nil
+}
+
+func (s *Service) startMFAChallenge(ctx context.Context, userID, clientIP, userAgent string) (MFAChallenge, error) {
+	token, err := security.NewRefreshToken()
+	if err != nil {
+		return MFAChallenge{}, err
+	}
+	if err := s.repo.SaveMFAChallenge(
+		ctx,
+		userID,
+		security.HashToken(token),
+		s.now().Add(s.cfg.MFAChallengeTTL),
+		clientIP,
+		userAgent,
+	); err != nil {
+		return MFAChallenge{}, err
+	}
+	return MFAChallenge{
+		Token:     token,
+		ExpiresIn: int(s.cfg.MFAChallengeTTL.Seconds()),
+	}, nil
+}
+
+func (s *Service) loadMFAChallenge(ctx context.Context, mfaToken string) (MFAChallengeRecord, error) {
+	if mfaToken == "" {
+		return MFAChallengeRecord{}, ErrInvalidMFAToken
+	}
+	rec, err := s.repo.GetMFAChallenge(ctx, security.HashToken(mfaToken))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return MFAChallengeRecord{}, ErrInvalidMFAToken
+		}
+		return MFAChallengeRecord{}, err
+	}
+	if rec.ConsumedAt != nil {
+		return MFAChallengeRecord{}, ErrInvalidMFAToken
+	}
+	if rec.ExpiresAt.Before(s.now()) {
+		return MFAChallengeRecord{}, ErrMFAChallengeExpired
+	}
+	if rec.Attempts >= mfaMaxChallengeAttempts {
+		return MFAChallengeRecord{}, ErrMFATooManyAttempts
+	}
+	return rec, nil
+}
+
+func (s *Service) verifySecondFactor(ctx context.Context, userID string, mfa MFAState, input MFAVerifyInput) error {
+	if input.RecoveryCode != "" {
+		ok, err := s.repo.ConsumeRecoveryCode(ctx, userID, hashRecoveryCode(userID, input.RecoveryCode))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	step, ok := security.VerifyTOTP(*mfa.Secret, input.Code, s.now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	fresh, err := s.repo.MarkTOTPStepUsed(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func newRecoveryCodes(userID string) ([]string, []string, error) {
+	codes, err := security.NewRecoveryCodes(mfaRecoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, hashRecoveryCode(userID, code))
+	}
+	return codes, hashes, nil
+}
+
+// Recovery codes are hashed with the user id so equal codes never collide
+// across accounts on the unique index.
+func hashRecoveryCode(userID, code string) string {
+	return security.HashToken(userID + ":" + security.NormalizeRecoveryCode(code))
+}
+
 func (s *Service) issueSession(ctx context.Context, userID, clientIP, userAgent string) (SessionResult, error) {
+	// Read the version before the permissions so a concurrent change leaves
+	// the new token stale rather than silently missing the update.
+	permissionsVersion, err := s.repo.GetPermissionsVersion(ctx, userID)
+	if err != nil {
+		return SessionResult{}, err
+	}
 	perms, roleIDs, err := s.repo.ListPermissionsByUserID(ctx, userID)
 	if err != nil {
 		return SessionResult{}, err
@@ -165,7 +1015,8 @@ func (s *Service) issueSession(ctx context.Context, userID, clientIP, userAgent
 	for _, p := range perms {
 		scope = append(scope, p.Code)
 	}
-	accessToken, accessExpAt, err := security.NewAccessToken(s.cfg.JWTSecret, s.cfg.AccessTokenTTL, userID, roleIDs, scope)
+	familyID := uuid.NewString()
+	accessToken, accessExpAt, err := s.keys.NewSessionAccessToken(s.cfg.AccessTokenTTL, userID, familyID, permissionsVersion, roleIDs, scope)
 	if err != nil {
 		return SessionResult{}, err
 	}
@@ -174,7 +1025,6 @@ func (s *Service) issueSession(ctx context.Context, userID, clientIP, userAgent
 	if err != nil {
 		return SessionResult{}, err
 	}
-	familyID := uuid.NewString()
 	if _, err := s.repo.SaveRefreshToken(
 		ctx,
 		userID,
@@ -0,0 +1,61 @@
+package auth
+
+import "time"
+
+const (
+	loginOutcomeSuccess   = "success"
+	loginOutcomeFailure   = "failure"
+	loginOutcomeThrottled = "throttled"
+	loginOutcomeUnlocked  = "unlocked"
+
+	loginReasonUnknownEmail        = "unknown_email"
+	loginReasonBadPassword         = "bad_password"
+	loginReasonInactive            = "inactive"
+	loginReasonMFA                 = "mfa"
+	loginReasonAccountLimit        = "account_limit"
+	loginReasonIPLimit             = "ip_limit"
+	loginReasonSSO                 = "sso"
+	loginReasonSSOUnknownEmail     = "sso_unknown_email"
+	loginReasonSSOUnverifiedEmail  = "sso_unverified_email"
+	loginReasonSSOIdentityConflict = "sso_identity_conflict"
+	loginReasonPasswordChange      = "password_change"
+)
+
+// loginWindow bounds how far back failed attempts are counted.
+const loginWindow = 15 * time.Minute
+
+type throttlePolicy struct {
+	freeAttempts int
+	baseDelay    time.Duration
+	lockAfter    int
+	lockFor      time.Duration
+}
+
+// Accounts are keyed by the submitted email, whether or not it exists, so a
+// locked response says nothing about which addresses are registered.
+var (
+	accountThrottle = throttlePolicy{freeAttempts: 3, baseDelay: 5 * time.Second, lockAfter: 5, lockFor: 15 * time.Minute}
+	ipThrottle      = throttlePolicy{freeAttempts: 10, baseDelay: 2 * time.Second, lockAfter: 30, lockFor: 15 * time.Minute}
+)
+
+type loginAttemptStats struct {
+	Failures      int
+	LastFailureAt *time.Time
+}
+
+// retryAfter returns how long a caller must wait before the next attempt:
+// nothing for the first few failures, then a doubling delay, then a lockout.
+func (p throttlePolicy) retryAfter(stats loginAttemptStats, now time.Time) time.Duration {
+	if stats.LastFailureAt == nil || stats.Failures < p.freeAttempts {
+		return 0
+	}
+	wait := p.lockFor
+	if stats.Failures < p.lockAfter {
+		wait = p.baseDelay << (stats.Failures - p.freeAttempts)
+	}
+	remaining := stats.LastFailureAt.Add(wait).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
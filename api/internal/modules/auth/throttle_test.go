@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleRetryAfter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	justNow := now.Add(-time.Second)
+	longAgo := now.Add(-time.Hour)
+	cases := []struct {
+		name  string
+		stats loginAttemptStats
+		want  time.Duration
+	}{
+		{name: "no failures", stats: loginAttemptStats{}, want: 0},
+		{name: "within free attempts", stats: loginAttemptStats{Failures: 2, LastFailureAt: &justNow}, want: 0},
+		{name: "first delay", stats: loginAttemptStats{Failures: 3, LastFailureAt: &justNow}, want: 4 * time.Second},
+		{name: "delay doubles", stats: loginAttemptStats{Failures: 4, LastFailureAt: &justNow}, want: 9 * time.Second},
+		{name: "locked", stats: loginAttemptStats{Failures: 5, LastFailureAt: &justNow}, want: 15*time.Minute - time.Second},
+		{name: "lock expired", stats: loginAttemptStats{Failures: 8, LastFailureAt: &longAgo}, want: 0},
+	}
+	for _, tc := range cases {
+		if got := accountThrottle.retryAfter(tc.stats, now); got != tc.want {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
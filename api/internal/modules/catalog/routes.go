@@ -2,8 +2,7 @@ package catalog
 
 import (
 	"humphreys/api/internal/middleware"
-
-	"github.com/gin-gonic/gin"
+	"humphreys/api/internal/permissions"
 )
 
 const (
@@ -13,7 +12,13 @@ const (
 	permWorkOrdersUpdate = "work_orders:update"
 )
 
-func RegisterRoutes(authed *gin.RouterGroup, h *Handler) {
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("permissions", "Permission catalog", permissions.StandardActions()...)
+	reg.Register("resources", "Resource catalog", permissions.StandardActions()...)
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
 	authed.GET("/resources", middleware.RequirePermission(permResourcesRead), h.ListResources)
 	authed.GET("/permissions", middleware.RequirePermission(permPermissionsRead), h.ListPermissions)
 	authed.GET("/catalog/dropdown-management", middleware.RequirePermission(permWorkOrdersRead), h.ListDropdownManagement)
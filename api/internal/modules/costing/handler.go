@@ -0,0 +1,224 @@
+package costing
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func New(db *pgxpool.Pool) *Handler {
+	return &Handler{
+		service: NewService(NewRepository(db)),
+	}
+}
+
+func NewWithService(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+type setWorkerRateRequest struct {
+	HourlyRate *float64 `json:"hourly_rate"`
+}
+
+type commissionRuleRequest struct {
+	RuleName    string  `json:"rule_name" binding:"required"`
+	WorkerID    *int64  `json:"worker_id"`
+	JobTypeID   *int64  `json:"job_type_id"`
+	Basis       string  `json:"basis" binding:"required"`
+	RatePercent float64 `json:"rate_percent"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+func (req commissionRuleRequest) input() CommissionRuleInput {
+	return CommissionRuleInput{
+		RuleName:    req.RuleName,
+		WorkerID:    req.WorkerID,
+		JobTypeID:   req.JobTypeID,
+		Basis:       req.Basis,
+		RatePercent: req.RatePercent,
+		IsActive:    req.IsActive,
+	}
+}
+
+func (h *Handler) GetWorkOrderCosting(c *gin.Context) {
+	referenceID, err := strconv.Atoi(c.Param("reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
+		return
+	}
+	item, err := h.service.GetWorkOrderCosting(c.Request.Context(), referenceID)
+	if err != nil {
+		writeCostingError(c, err, "failed to load work order costing")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) ListWorkOrderCosting(c *gin.Context) {
+	from, to, ok := parsePeriod(c)
+	if !ok {
+		return
+	}
+	items, err := h.service.ListCompletedWorkOrderCosting(c.Request.Context(), from, to)
+	if err != nil {
+		writeCostingError(c, err, "failed to list work order costing")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) ListCommissions(c *gin.Context) {
+	from, to, ok := parsePeriod(c)
+	if !ok {
+		return
+	}
+	var workerID *int64
+	if raw := strings.TrimSpace(c.Query("worker_id")); raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid worker_id"})
+			return
+		}
+		workerID = &parsed
+	}
+	items, err := h.service.ListCommissions(c.Request.Context(), from, to, workerID)
+	if err != nil {
+		writeCostingError(c, err, "failed to calculate commissions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) ListWorkerRates(c *gin.Context) {
+	items, err := h.service.ListWorkerRates(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list worker rates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) SetWorkerRate(c *gin.Context) {
+	workerID, ok := parseIDParam(c, "worker_id")
+	if !ok {
+		return
+	}
+	var req setWorkerRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.SetWorkerHourlyRate(c.Request.Context(), workerID, req.HourlyRate)
+	if errors.Is(err, ErrWorkerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeCostingError(c, err, "failed to update worker rate")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) ListCommissionRules(c *gin.Context) {
+	items, err := h.service.ListCommissionRules(c.Request.Context(), c.Query("include_inactive") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list commission rules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) CreateCommissionRule(c *gin.Context) {
+	var req commissionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.CreateCommissionRule(c.Request.Context(), req.input())
+	if err != nil {
+		writeCostingError(c, err, "failed to create commission rule")
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *Handler) UpdateCommissionRule(c *gin.Context) {
+	commissionRuleID, ok := parseIDParam(c, "commission_rule_id")
+	if !ok {
+		return
+	}
+	var req commissionRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.UpdateCommissionRule(c.Request.Context(), commissionRuleID, req.input())
+	if err != nil {
+		writeCostingError(c, err, "failed to update commission rule")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) DeleteCommissionRule(c *gin.Context) {
+	commissionRuleID, ok := parseIDParam(c, "commission_rule_id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteCommissionRule(c.Request.Context(), commissionRuleID); err != nil {
+		writeCostingError(c, err, "failed to delete commission rule")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseIDParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+func parsePeriod(c *gin.Context) (time.Time, time.Time, bool) {
+	from, err := time.Parse("2006-01-02", strings.TrimSpace(c.Query("from")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date (YYYY-MM-DD)"})
+		return time.Time{}, time.Time{}, false
+	}
+	to, err := time.Parse("2006-01-02", strings.TrimSpace(c.Query("to")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date (YYYY-MM-DD)"})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+func writeCostingError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrWorkOrderNotFound),
+		errors.Is(err, ErrCommissionRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrWorkerNotFound),
+		errors.Is(err, ErrJobTypeNotFound),
+		errors.Is(err, ErrInvalidHourlyRate),
+		errors.Is(err, ErrInvalidRuleName),
+		errors.Is(err, ErrInvalidCommissionBasis),
+		errors.Is(err, ErrInvalidCommissionRate),
+		errors.Is(err, ErrInvalidPeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
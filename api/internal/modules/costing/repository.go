@@ -0,0 +1,343 @@
+package costing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"humphreys/api/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository interface {
+	GetJobCostInput(ctx context.Context, referenceID int) (JobCostInput, error)
+	ListCompletedJobCostInputs(ctx context.Context, from, to time.Time) ([]JobCostInput, error)
+	ListWorkerRates(ctx context.Context) ([]domain.WorkerRate, error)
+	SetWorkerHourlyRate(ctx context.Context, workerID int64, hourlyRate *float64) (domain.WorkerRate, error)
+	ListCommissionRules(ctx context.Context, includeInactive bool) ([]domain.CommissionRule, error)
+	CreateCommissionRule(ctx context.Context, input CommissionRuleInput) (domain.CommissionRule, error)
+	UpdateCommissionRule(ctx context.Context, commissionRuleID int64, input CommissionRuleInput) (domain.CommissionRule, error)
+	DeleteCommissionRule(ctx context.Context, commissionRuleID int64) error
+}
+
+type storeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &storeRepository{db: db}
+}
+
+const jobCostSelect = `
+	SELECT
+		wo.reference_id,
+		wo.job_type_id::bigint,
+		CASE WHEN COALESCE(st.status_group, 'to_do') = 'completed' THEN wo.status_updated_at END,
+		COALESCE(wo.labour_total, 0)::double precision,
+		COALESCE(wo.parts_total, 0)::double precision,
+		COALESCE(wo.delivery_total, 0)::double precision,
+		wo.rush_surcharge_total::double precision,
+		COALESCE((
+			SELECT SUM(rl.hours_used)
+			FROM public.repair_logs rl
+			WHERE rl.reference_id = wo.reference_id
+		), 0)::double precision,
+		COALESCE((
+			SELECT SUM(ppr.total_price)
+			FROM public.parts_purchase_requests ppr
+			WHERE ppr.reference_id = wo.reference_id
+				AND ppr.status IN ('ordered', 'received', 'used')
+		), 0)::double precision,
+		COALESCE(wo.worker_ids::bigint[], ARRAY[]::bigint[])
+	FROM public.work_orders wo
+	LEFT JOIN public.work_order_statuses st ON st.status_id = wo.status_id
+`
+
+func (r *storeRepository) GetJobCostInput(ctx context.Context, referenceID int) (JobCostInput, error) {
+	rows, err := r.db.Query(ctx, jobCostSelect+` WHERE wo.reference_id = $1`, referenceID)
+	if err != nil {
+		return JobCostInput{}, err
+	}
+	inputs, err := r.collectJobCostInputs(ctx, rows)
+	if err != nil {
+		return JobCostInput{}, err
+	}
+	if len(inputs) == 0 {
+		return JobCostInput{}, ErrWorkOrderNotFound
+	}
+	return inputs[0], nil
+}
+
+func (r *storeRepository) ListCompletedJobCostInputs(ctx context.Context, from, to time.Time) ([]JobCostInput, error) {
+	rows, err := r.db.Query(ctx, jobCostSelect+`
+		WHERE COALESCE(st.status_group, 'to_do') = 'completed'
+			AND wo.status_updated_at >= $1::date
+			AND wo.status_updated_at < ($2::date + 1)
+		ORDER BY wo.status_updated_at ASC, wo.reference_id ASC
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return r.collectJobCostInputs(ctx, rows)
+}
+
+func (r *storeRepository) collectJobCostInputs(ctx context.Context, rows pgx.Rows) ([]JobCostInput, error) {
+	defer rows.Close()
+
+	out := make([]JobCostInput, 0)
+	workerIDs := make([][]int64, 0)
+	allWorkerIDs := make([]int64, 0)
+	for rows.Next() {
+		var item JobCostInput
+		var ids []int64
+		if err := rows.Scan(
+			&item.ReferenceID,
+			&item.JobTypeID,
+			&item.CompletedAt,
+			&item.LabourTotal,
+			&item.PartsTotal,
+			&item.DeliveryTotal,
+			&item.SurchargeTotal,
+			&item.LabourHours,
+			&item.PartsCost,
+			&ids,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+		workerIDs = append(workerIDs, ids)
+		allWorkerIDs = append(allWorkerIDs, ids...)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	workers, err := r.loadWorkerRates(ctx, allWorkerIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		seen := make(map[int64]struct{}, len(workerIDs[i]))
+		out[i].Workers = make([]domain.WorkerRate, 0, len(workerIDs[i]))
+		for _, id := range workerIDs[i] {
+			worker, ok := workers[id]
+			if !ok {
+				continue
+			}
+			if _, dup := seen[id]; dup {
+				continue
+			}
+			seen[id] = struct{}{}
+			out[i].Workers = append(out[i].Workers, worker)
+		}
+	}
+	return out, nil
+}
+
+func (r *storeRepository) loadWorkerRates(ctx context.Context, workerIDs []int64) (map[int64]domain.WorkerRate, error) {
+	out := make(map[int64]domain.WorkerRate)
+	if len(workerIDs) == 0 {
+		return out, nil
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT worker_id::bigint, worker_name, is_active, hourly_rate::double precision
+		FROM public.workers
+		WHERE worker_id = ANY($1)
+	`, workerIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item domain.WorkerRate
+		if err := rows.Scan(&item.WorkerID, &item.WorkerName, &item.IsActive, &item.HourlyRate); err != nil {
+			return nil, err
+		}
+		out[item.WorkerID] = item
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) ListWorkerRates(ctx context.Context) ([]domain.WorkerRate, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT worker_id::bigint, worker_name, is_active, hourly_rate::double precision
+		FROM public.workers
+		ORDER BY is_active DESC, worker_name ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.WorkerRate, 0)
+	for rows.Next() {
+		var item domain.WorkerRate
+		if err := rows.Scan(&item.WorkerID, &item.WorkerName, &item.IsActive, &item.HourlyRate); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) SetWorkerHourlyRate(ctx context.Context, workerID int64, hourlyRate *float64) (domain.WorkerRate, error) {
+	var item domain.WorkerRate
+	err := r.db.QueryRow(ctx, `
+		UPDATE public.workers
+		SET hourly_rate = $2
+		WHERE worker_id = $1
+		RETURNING worker_id::bigint, worker_name, is_active, hourly_rate::double precision
+	`, workerID, hourlyRate).Scan(&item.WorkerID, &item.WorkerName, &item.IsActive, &item.HourlyRate)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.WorkerRate{}, ErrWorkerNotFound
+	}
+	return item, err
+}
+
+const commissionRuleSelect = `
+	SELECT
+		cr.commission_rule_id,
+		cr.rule_name,
+		cr.worker_id,
+		w.worker_name,
+		cr.job_type_id,
+		jt.display_name,
+		cr.basis,
+		cr.rate_percent::double precision,
+		cr.is_active,
+		cr.created_at,
+		cr.updated_at
+	FROM public.commission_rules cr
+	LEFT JOIN public.workers w ON w.worker_id = cr.worker_id
+	LEFT JOIN public.job_types jt ON jt.job_type_id = cr.job_type_id
+`
+
+func scanCommissionRule(row pgx.Row, item *domain.CommissionRule) error {
+	return row.Scan(
+		&item.CommissionRuleID,
+		&item.RuleName,
+		&item.WorkerID,
+		&item.WorkerName,
+		&item.JobTypeID,
+		&item.JobTypeName,
+		&item.Basis,
+		&item.RatePercent,
+		&item.IsActive,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+}
+
+func (r *storeRepository) ListCommissionRules(ctx context.Context, includeInactive bool) ([]domain.CommissionRule, error) {
+	sql := commissionRuleSelect
+	if !includeInactive {
+		sql += ` WHERE cr.is_active = true`
+	}
+	sql += ` ORDER BY w.worker_name ASC NULLS FIRST, jt.display_name ASC NULLS FIRST, cr.commission_rule_id ASC`
+
+	rows, err := r.db.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.CommissionRule, 0)
+	for rows.Next() {
+		var item domain.CommissionRule
+		if err := scanCommissionRule(rows, &item); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) getCommissionRule(ctx context.Context, commissionRuleID int64) (domain.CommissionRule, error) {
+	var item domain.CommissionRule
+	err := scanCommissionRule(r.db.QueryRow(ctx, commissionRuleSelect+` WHERE cr.commission_rule_id = $1`, commissionRuleID), &item)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.CommissionRule{}, ErrCommissionRuleNotFound
+	}
+	return item, err
+}
+
+func (r *storeRepository) CreateCommissionRule(ctx context.Context, input CommissionRuleInput) (domain.CommissionRule, error) {
+	if err := r.ensureRuleTargetsExist(ctx, input); err != nil {
+		return domain.CommissionRule{}, err
+	}
+	isActive := true
+	if input.IsActive != nil {
+		isActive = *input.IsActive
+	}
+
+	var commissionRuleID int64
+	if err := r.db.QueryRow(ctx, `
+		INSERT INTO public.commission_rules(rule_name, worker_id, job_type_id, basis, rate_percent, is_active)
+		VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING commission_rule_id
+	`, input.RuleName, input.WorkerID, input.JobTypeID, input.Basis, input.RatePercent, isActive).Scan(&commissionRuleID); err != nil {
+		return domain.CommissionRule{}, err
+	}
+	return r.getCommissionRule(ctx, commissionRuleID)
+}
+
+func (r *storeRepository) UpdateCommissionRule(ctx context.Context, commissionRuleID int64, input CommissionRuleInput) (domain.CommissionRule, error) {
+	if err := r.ensureRuleTargetsExist(ctx, input); err != nil {
+		return domain.CommissionRule{}, err
+	}
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE public.commission_rules
+		SET
+			rule_name = $2,
+			worker_id = $3,
+			job_type_id = $4,
+			basis = $5,
+			rate_percent = $6,
+			is_active = COALESCE($7, is_active),
+			updated_at = now()
+		WHERE commission_rule_id = $1
+	`, commissionRuleID, input.RuleName, input.WorkerID, input.JobTypeID, input.Basis, input.RatePercent, input.IsActive)
+	if err != nil {
+		return domain.CommissionRule{}, err
+	}
+	if cmd.RowsAffected() == 0 {
+		return domain.CommissionRule{}, ErrCommissionRuleNotFound
+	}
+	return r.getCommissionRule(ctx, commissionRuleID)
+}
+
+func (r *storeRepository) DeleteCommissionRule(ctx context.Context, commissionRuleID int64) error {
+	cmd, err := r.db.Exec(ctx, `DELETE FROM public.commission_rules WHERE commission_rule_id = $1`, commissionRuleID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrCommissionRuleNotFound
+	}
+	return nil
+}
+
+func (r *storeRepository) ensureRuleTargetsExist(ctx context.Context, input CommissionRuleInput) error {
+	if input.WorkerID != nil {
+		var exists bool
+		if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.workers WHERE worker_id = $1)`, *input.WorkerID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrWorkerNotFound
+		}
+	}
+	if input.JobTypeID != nil {
+		var exists bool
+		if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.job_types WHERE job_type_id = $1)`, *input.JobTypeID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrJobTypeNotFound
+		}
+	}
+	return nil
+}
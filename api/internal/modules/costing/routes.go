@@ -0,0 +1,31 @@
+package costing
+
+import (
+	"humphreys/api/internal/middleware"
+	"humphreys/api/internal/permissions"
+)
+
+const (
+	permRead   = "costing:read"
+	permCreate = "costing:create"
+	permUpdate = "costing:update"
+	permDelete = "costing:delete"
+)
+
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("costing", "Job profitability, technician rates, and commission rules", permissions.StandardActions()...)
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
+	group := authed.Group("/costing")
+	group.GET("/work-orders", middleware.RequirePermission(permRead), h.ListWorkOrderCosting)
+	group.GET("/work-orders/:reference_id", middleware.RequirePermission(permRead), h.GetWorkOrderCosting)
+	group.GET("/commissions", middleware.RequirePermission(permRead), h.ListCommissions)
+	group.GET("/worker-rates", middleware.RequirePermission(permRead), h.ListWorkerRates)
+	group.PATCH("/worker-rates/:worker_id", middleware.RequirePermission(permUpdate), h.SetWorkerRate)
+	group.GET("/commission-rules", middleware.RequirePermission(permRead), h.ListCommissionRules)
+	group.POST("/commission-rules", middleware.RequirePermission(permCreate), h.CreateCommissionRule)
+	group.PATCH("/commission-rules/:commission_rule_id", middleware.RequirePermission(permUpdate), h.UpdateCommissionRule)
+	group.DELETE("/commission-rules/:commission_rule_id", middleware.RequirePermission(permDelete), h.DeleteCommissionRule)
+}
@@ -0,0 +1,290 @@
+package costing
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"humphreys/api/internal/domain"
+)
+
+var ErrWorkOrderNotFound = errors.New("work order not found")
+var ErrWorkerNotFound = errors.New("worker not found")
+var ErrJobTypeNotFound = errors.New("job type not found")
+var ErrInvalidHourlyRate = errors.New("hourly rate must be zero or greater")
+var ErrCommissionRuleNotFound = errors.New("commission rule not found")
+var ErrInvalidRuleName = errors.New("rule name is required")
+var ErrInvalidCommissionBasis = errors.New("commission basis must be revenue, labour, or margin")
+var ErrInvalidCommissionRate = errors.New("commission rate must be between 0 and 100")
+var ErrInvalidPeriod = errors.New("period must have from on or before to")
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// JobCostInput is the raw money and time recorded against one work order.
+// Parts cost counts requests from ordered onwards, since that money is spent
+// whether or not the part has been fitted yet.
+type JobCostInput struct {
+	ReferenceID   int32
+	JobTypeID     *int64
+	CompletedAt   *time.Time
+	LabourTotal   float64
+	PartsTotal    float64
+	DeliveryTotal float64
+	// SurchargeTotal is the rush surcharge, which is revenue but not parts.
+	SurchargeTotal float64
+	LabourHours    float64
+	PartsCost      float64
+	Workers        []domain.WorkerRate
+}
+
+type CommissionRuleInput struct {
+	RuleName    string
+	WorkerID    *int64
+	JobTypeID   *int64
+	Basis       string
+	RatePercent float64
+	IsActive    *bool
+}
+
+func (s *Service) GetWorkOrderCosting(ctx context.Context, referenceID int) (domain.WorkOrderCosting, error) {
+	input, err := s.repo.GetJobCostInput(ctx, referenceID)
+	if err != nil {
+		return domain.WorkOrderCosting{}, err
+	}
+	return computeWorkOrderCosting(input), nil
+}
+
+func (s *Service) ListCompletedWorkOrderCosting(ctx context.Context, from, to time.Time) ([]domain.WorkOrderCosting, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidPeriod
+	}
+	inputs, err := s.repo.ListCompletedJobCostInputs(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.WorkOrderCosting, 0, len(inputs))
+	for _, input := range inputs {
+		out = append(out, computeWorkOrderCosting(input))
+	}
+	return out, nil
+}
+
+func (s *Service) ListCommissions(ctx context.Context, from, to time.Time, workerID *int64) ([]domain.TechnicianCommission, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidPeriod
+	}
+	inputs, err := s.repo.ListCompletedJobCostInputs(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.repo.ListCommissionRules(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	return computeCommissions(inputs, rules, workerID), nil
+}
+
+func (s *Service) ListWorkerRates(ctx context.Context) ([]domain.WorkerRate, error) {
+	return s.repo.ListWorkerRates(ctx)
+}
+
+func (s *Service) SetWorkerHourlyRate(ctx context.Context, workerID int64, hourlyRate *float64) (domain.WorkerRate, error) {
+	if hourlyRate != nil && *hourlyRate < 0 {
+		return domain.WorkerRate{}, ErrInvalidHourlyRate
+	}
+	return s.repo.SetWorkerHourlyRate(ctx, workerID, hourlyRate)
+}
+
+func (s *Service) ListCommissionRules(ctx context.Context, includeInactive bool) ([]domain.CommissionRule, error) {
+	return s.repo.ListCommissionRules(ctx, includeInactive)
+}
+
+func (s *Service) CreateCommissionRule(ctx context.Context, input CommissionRuleInput) (domain.CommissionRule, error) {
+	normalized, err := normalizeCommissionRuleInput(input)
+	if err != nil {
+		return domain.CommissionRule{}, err
+	}
+	return s.repo.CreateCommissionRule(ctx, normalized)
+}
+
+func (s *Service) UpdateCommissionRule(ctx context.Context, commissionRuleID int64, input CommissionRuleInput) (domain.CommissionRule, error) {
+	normalized, err := normalizeCommissionRuleInput(input)
+	if err != nil {
+		return domain.CommissionRule{}, err
+	}
+	return s.repo.UpdateCommissionRule(ctx, commissionRuleID, normalized)
+}
+
+func (s *Service) DeleteCommissionRule(ctx context.Context, commissionRuleID int64) error {
+	return s.repo.DeleteCommissionRule(ctx, commissionRuleID)
+}
+
+func normalizeCommissionRuleInput(input CommissionRuleInput) (CommissionRuleInput, error) {
+	input.RuleName = strings.TrimSpace(input.RuleName)
+	if input.RuleName == "" {
+		return CommissionRuleInput{}, ErrInvalidRuleName
+	}
+	input.Basis = strings.TrimSpace(strings.ToLower(input.Basis))
+	if input.Basis != "revenue" && input.Basis != "labour" && input.Basis != "margin" {
+		return CommissionRuleInput{}, ErrInvalidCommissionBasis
+	}
+	if input.RatePercent < 0 || input.RatePercent > 100 {
+		return CommissionRuleInput{}, ErrInvalidCommissionRate
+	}
+	return input, nil
+}
+
+// computeWorkOrderCosting splits logged hours evenly across the technicians on
+// the job, since repair logs are not recorded per technician. Hours for a
+// technician without an hourly rate are reported as unrated and cost nothing.
+func computeWorkOrderCosting(input JobCostInput) domain.WorkOrderCosting {
+	out := domain.WorkOrderCosting{
+		ReferenceID:      input.ReferenceID,
+		JobTypeID:        input.JobTypeID,
+		CompletedAt:      input.CompletedAt,
+		LabourRevenue:    input.LabourTotal,
+		PartsRevenue:     input.PartsTotal,
+		DeliveryRevenue:  input.DeliveryTotal,
+		SurchargeRevenue: input.SurchargeTotal,
+		Revenue:          roundCents(input.LabourTotal + input.PartsTotal + input.DeliveryTotal + input.SurchargeTotal),
+		LabourHours:      input.LabourHours,
+		PartsCost:        input.PartsCost,
+		Workers:          make([]domain.WorkerCostShare, 0, len(input.Workers)),
+	}
+
+	if len(input.Workers) == 0 {
+		out.UnratedHours = input.LabourHours
+	} else {
+		share := input.LabourHours / float64(len(input.Workers))
+		for _, worker := range input.Workers {
+			line := domain.WorkerCostShare{
+				WorkerID:   worker.WorkerID,
+				WorkerName: worker.WorkerName,
+				Hours:      share,
+				HourlyRate: worker.HourlyRate,
+			}
+			if worker.HourlyRate == nil {
+				out.UnratedHours += share
+			} else {
+				line.LabourCost = roundCents(share * *worker.HourlyRate)
+				out.LabourCost += line.LabourCost
+			}
+			out.Workers = append(out.Workers, line)
+		}
+	}
+
+	out.LabourCost = roundCents(out.LabourCost)
+	out.Margin = roundCents(out.Revenue - out.LabourCost - out.PartsCost)
+	if out.Revenue > 0 {
+		percent := math.Round(out.Margin/out.Revenue*10000) / 100
+		out.MarginPercent = &percent
+	}
+	return out
+}
+
+// computeCommissions pays each technician on their even share of every job.
+// The most specific active rule wins: technician and job type, then
+// technician, then job type, then a general rule.
+func computeCommissions(inputs []JobCostInput, rules []domain.CommissionRule, workerID *int64) []domain.TechnicianCommission {
+	byWorker := make(map[int64]*domain.TechnicianCommission)
+	for _, input := range inputs {
+		if len(input.Workers) == 0 {
+			continue
+		}
+		costing := computeWorkOrderCosting(input)
+		shareCount := float64(len(input.Workers))
+		for _, worker := range costing.Workers {
+			if workerID != nil && worker.WorkerID != *workerID {
+				continue
+			}
+			entry, ok := byWorker[worker.WorkerID]
+			if !ok {
+				entry = &domain.TechnicianCommission{
+					WorkerID:   worker.WorkerID,
+					WorkerName: worker.WorkerName,
+					Lines:      make([]domain.CommissionLine, 0),
+				}
+				byWorker[worker.WorkerID] = entry
+			}
+
+			line := domain.CommissionLine{ReferenceID: input.ReferenceID}
+			if rule, ok := matchCommissionRule(rules, worker.WorkerID, input.JobTypeID); ok {
+				ruleID := rule.CommissionRuleID
+				basis := rule.Basis
+				line.CommissionRuleID = &ruleID
+				line.Basis = &basis
+				line.RatePercent = rule.RatePercent
+				switch rule.Basis {
+				case "revenue":
+					line.BasisAmount = roundCents(costing.Revenue / shareCount)
+				case "labour":
+					line.BasisAmount = roundCents(costing.LabourRevenue / shareCount)
+				case "margin":
+					line.BasisAmount = roundCents(costing.Margin / shareCount)
+				}
+				// Loss-making jobs do not claw commission back.
+				if line.BasisAmount > 0 {
+					line.Commission = roundCents(line.BasisAmount * rule.RatePercent / 100)
+				}
+			}
+
+			entry.JobCount++
+			entry.Hours += worker.Hours
+			entry.Commission = roundCents(entry.Commission + line.Commission)
+			entry.Lines = append(entry.Lines, line)
+		}
+	}
+
+	out := make([]domain.TechnicianCommission, 0, len(byWorker))
+	for _, entry := range byWorker {
+		out = append(out, *entry)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].WorkerName != out[j].WorkerName {
+			return out[i].WorkerName < out[j].WorkerName
+		}
+		return out[i].WorkerID < out[j].WorkerID
+	})
+	return out
+}
+
+func matchCommissionRule(rules []domain.CommissionRule, workerID int64, jobTypeID *int64) (domain.CommissionRule, bool) {
+	var best domain.CommissionRule
+	bestScore := -1
+	for _, rule := range rules {
+		if !rule.IsActive {
+			continue
+		}
+		score := 0
+		if rule.WorkerID != nil {
+			if *rule.WorkerID != workerID {
+				continue
+			}
+			score += 2
+		}
+		if rule.JobTypeID != nil {
+			if jobTypeID == nil || *rule.JobTypeID != *jobTypeID {
+				continue
+			}
+			score++
+		}
+		if score > bestScore || (score == bestScore && rule.CommissionRuleID < best.CommissionRuleID) {
+			best = rule
+			bestScore = score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
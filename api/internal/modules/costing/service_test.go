@@ -0,0 +1,86 @@
+package costing
+
+import (
+	"testing"
+
+	"humphreys/api/internal/domain"
+)
+
+func TestComputeWorkOrderCostingSplitsHoursAcrossWorkers(t *testing.T) {
+	rate := 30.0
+	costing := computeWorkOrderCosting(JobCostInput{
+		ReferenceID:   42,
+		LabourTotal:   200,
+		PartsTotal:    80,
+		DeliveryTotal: 20,
+		LabourHours:   4,
+		PartsCost:     50,
+		Workers: []domain.WorkerRate{
+			{WorkerID: 1, WorkerName: "Alex", HourlyRate: &rate},
+			{WorkerID: 2, WorkerName: "Sam"},
+		},
+	})
+
+	if costing.Revenue != 300 {
+		t.Fatalf("expected revenue 300, got %.2f", costing.Revenue)
+	}
+	if costing.LabourCost != 60 {
+		t.Fatalf("expected labour cost 60, got %.2f", costing.LabourCost)
+	}
+	if costing.UnratedHours != 2 {
+		t.Fatalf("expected 2 unrated hours, got %.2f", costing.UnratedHours)
+	}
+	if costing.Margin != 190 {
+		t.Fatalf("expected margin 190, got %.2f", costing.Margin)
+	}
+	if costing.MarginPercent == nil || *costing.MarginPercent != 63.33 {
+		t.Fatalf("expected margin percent 63.33, got %v", costing.MarginPercent)
+	}
+}
+
+func TestComputeWorkOrderCostingKeepsRushSurchargeOutOfParts(t *testing.T) {
+	costing := computeWorkOrderCosting(JobCostInput{
+		ReferenceID:    43,
+		LabourTotal:    100,
+		PartsTotal:     80,
+		SurchargeTotal: 25,
+		PartsCost:      50,
+	})
+
+	if costing.PartsRevenue != 80 {
+		t.Fatalf("expected parts revenue 80 without the surcharge, got %.2f", costing.PartsRevenue)
+	}
+	if costing.SurchargeRevenue != 25 {
+		t.Fatalf("expected surcharge revenue 25, got %.2f", costing.SurchargeRevenue)
+	}
+	if costing.Revenue != 205 {
+		t.Fatalf("expected revenue 205, got %.2f", costing.Revenue)
+	}
+	if costing.Margin != 155 {
+		t.Fatalf("expected margin 155, got %.2f", costing.Margin)
+	}
+}
+
+func TestMatchCommissionRulePrefersMostSpecific(t *testing.T) {
+	workerID := int64(7)
+	jobTypeID := int64(3)
+	rules := []domain.CommissionRule{
+		{CommissionRuleID: 1, Basis: "revenue", RatePercent: 5, IsActive: true},
+		{CommissionRuleID: 2, JobTypeID: &jobTypeID, Basis: "revenue", RatePercent: 6, IsActive: true},
+		{CommissionRuleID: 3, WorkerID: &workerID, Basis: "margin", RatePercent: 10, IsActive: true},
+		{CommissionRuleID: 4, WorkerID: &workerID, JobTypeID: &jobTypeID, Basis: "labour", RatePercent: 12, IsActive: false},
+	}
+
+	rule, ok := matchCommissionRule(rules, workerID, &jobTypeID)
+	if !ok || rule.CommissionRuleID != 3 {
+		t.Fatalf("expected worker rule 3, got %d (ok=%v)", rule.CommissionRuleID, ok)
+	}
+	rule, ok = matchCommissionRule(rules, 99, &jobTypeID)
+	if !ok || rule.CommissionRuleID != 2 {
+		t.Fatalf("expected job type rule 2, got %d (ok=%v)", rule.CommissionRuleID, ok)
+	}
+	rule, ok = matchCommissionRule(rules, 99, nil)
+	if !ok || rule.CommissionRuleID != 1 {
+		t.Fatalf("expected general rule 1, got %d (ok=%v)", rule.CommissionRuleID, ok)
+	}
+}
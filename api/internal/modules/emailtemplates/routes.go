@@ -2,8 +2,6 @@ package emailtemplates
 
 import (
 	"humphreys/api/internal/middleware"
-
-	"github.com/gin-gonic/gin"
 )
 
 const (
@@ -11,7 +9,7 @@ const (
 	permWorkOrdersUpdate = "work_orders:update"
 )
 
-func RegisterRoutes(authed *gin.RouterGroup, h *Handler) {
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
 	group := authed.Group("/email-templates")
 	group.GET("", middleware.RequirePermission(permWorkOrdersRead), h.List)
 	group.POST("/test", middleware.RequirePermission(permWorkOrdersUpdate), h.SendTest)
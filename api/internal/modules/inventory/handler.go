@@ -0,0 +1,196 @@
+package inventory
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"humphreys/api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func New(db *pgxpool.Pool) *Handler {
+	return &Handler{
+		service: NewService(NewRepository(db)),
+	}
+}
+
+func NewWithService(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+type updateInventoryItemRequest struct {
+	SKU                 *string  `json:"sku"`
+	TrackInventory      bool     `json:"track_inventory"`
+	ReorderPoint        int32    `json:"reorder_point" binding:"gte=0"`
+	ReorderQuantity     int32    `json:"reorder_quantity" binding:"gte=0"`
+	UnitCost            *float64 `json:"unit_cost"`
+	LocationID          *int64   `json:"location_id"`
+	PreferredSupplierID *int64   `json:"preferred_supplier_id"`
+}
+
+type adjustStockRequest struct {
+	QuantityDelta int32  `json:"quantity_delta" binding:"required"`
+	Note          string `json:"note" binding:"required"`
+}
+
+type draftLowStockRequestsRequest struct {
+	ReferenceID        *int    `json:"reference_id"`
+	PartsItemPresetIDs []int64 `json:"parts_item_preset_ids"`
+}
+
+func (h *Handler) ListInventory(c *gin.Context) {
+	items, err := h.service.ListInventory(c.Request.Context(), c.Query("q"), c.Query("tracked") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list inventory"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) GetInventoryItem(c *gin.Context) {
+	partsItemPresetID, ok := parsePresetID(c)
+	if !ok {
+		return
+	}
+	item, err := h.service.GetInventoryItem(c.Request.Context(), partsItemPresetID)
+	if err != nil {
+		writeInventoryError(c, err, "failed to load inventory item")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) UpdateInventoryItem(c *gin.Context) {
+	partsItemPresetID, ok := parsePresetID(c)
+	if !ok {
+		return
+	}
+	var req updateInventoryItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.UpdateInventoryItem(c.Request.Context(), partsItemPresetID, UpdateInventoryItemInput{
+		SKU:                 req.SKU,
+		TrackInventory:      req.TrackInventory,
+		ReorderPoint:        req.ReorderPoint,
+		ReorderQuantity:     req.ReorderQuantity,
+		UnitCost:            req.UnitCost,
+		LocationID:          req.LocationID,
+		PreferredSupplierID: req.PreferredSupplierID,
+	})
+	if err != nil {
+		writeInventoryError(c, err, "failed to update inventory item")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) AdjustStock(c *gin.Context) {
+	partsItemPresetID, ok := parsePresetID(c)
+	if !ok {
+		return
+	}
+	claims, ok := middleware.Claims(c)
+	if !ok || claims.UserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+	var req adjustStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.AdjustStock(c.Request.Context(), partsItemPresetID, AdjustStockInput{
+		QuantityDelta:   req.QuantityDelta,
+		Note:            req.Note,
+		CreatedByUserID: claims.UserID,
+	})
+	if err != nil {
+		writeInventoryError(c, err, "failed to adjust stock")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) ListMovements(c *gin.Context) {
+	partsItemPresetID, ok := parsePresetID(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	items, err := h.service.ListMovements(c.Request.Context(), partsItemPresetID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list stock movements"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) LowStockReport(c *gin.Context) {
+	items, err := h.service.LowStockReport(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load low stock report"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) DraftLowStockRequests(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok || claims.UserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+	var req draftLowStockRequestsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	items, err := h.service.DraftLowStockRequests(c.Request.Context(), DraftLowStockRequestsInput{
+		ReferenceID:        req.ReferenceID,
+		PartsItemPresetIDs: req.PartsItemPresetIDs,
+		CreatedByUserID:    claims.UserID,
+	})
+	if err != nil {
+		writeInventoryError(c, err, "failed to draft parts requests")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"items": items})
+}
+
+func parsePresetID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("parts_item_preset_id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parts_item_preset_id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeInventoryError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrInventoryItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidReorderPoint),
+		errors.Is(err, ErrInvalidReorderQuantity),
+		errors.Is(err, ErrInvalidUnitCost),
+		errors.Is(err, ErrInvalidAdjustment),
+		errors.Is(err, ErrAdjustmentNoteRequired),
+		errors.Is(err, ErrLocationNotFound),
+		errors.Is(err, ErrSupplierNotFound),
+		errors.Is(err, ErrWorkOrderNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrSKUTaken), errors.Is(err, ErrNothingToReorder):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
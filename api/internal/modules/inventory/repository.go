@@ -0,0 +1,452 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+
+	"humphreys/api/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository interface {
+	ListInventory(ctx context.Context, query string, trackedOnly bool) ([]domain.InventoryItem, error)
+	GetInventoryItem(ctx context.Context, partsItemPresetID int64) (domain.InventoryItem, error)
+	UpdateInventoryItem(ctx context.Context, partsItemPresetID int64, input UpdateInventoryItemInput) (domain.InventoryItem, error)
+	AdjustStock(ctx context.Context, partsItemPresetID int64, input AdjustStockInput) (domain.InventoryItem, error)
+	ListMovements(ctx context.Context, partsItemPresetID int64, limit int) ([]domain.StockMovement, error)
+	ListLowStock(ctx context.Context) ([]domain.LowStockItem, error)
+	CreateDraftRequests(ctx context.Context, referenceID *int, createdByUserID string, items []domain.LowStockItem) ([]domain.PartsPurchaseRequest, error)
+}
+
+type storeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &storeRepository{db: db}
+}
+
+// RecordPartsRequestMovementTx books the stock effect of a parts request reaching
+// received (+quantity) or used (-quantity). Requests without a tracked preset are
+// ignored, and each request moves stock at most once per movement type.
+func RecordPartsRequestMovementTx(ctx context.Context, tx pgx.Tx, partsPurchaseRequestID int64, movementType, userID string) error {
+	if movementType != "received" && movementType != "used" {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		WITH inserted AS (
+			INSERT INTO public.stock_movements(
+				parts_item_preset_id,
+				parts_purchase_request_id,
+				movement_type,
+				quantity_delta,
+				unit_cost,
+				created_by_user_id
+			)
+			SELECT
+				ppr.parts_item_preset_id,
+				ppr.parts_purchase_request_id,
+				$2,
+				CASE WHEN $2 = 'used' THEN -ppr.quantity ELSE ppr.quantity END,
+				ROUND(ppr.total_price / ppr.quantity, 2),
+				NULLIF($3, '')::uuid
+			FROM public.parts_purchase_requests ppr
+			JOIN public.parts_item_presets pip ON pip.parts_item_preset_id = ppr.parts_item_preset_id
+			WHERE ppr.parts_purchase_request_id = $1
+				AND pip.track_inventory = true
+			ON CONFLICT (parts_purchase_request_id, movement_type) DO NOTHING
+			RETURNING parts_item_preset_id, quantity_delta
+		)
+		UPDATE public.parts_item_presets pip
+		SET
+			on_hand_quantity = pip.on_hand_quantity + inserted.quantity_delta,
+			updated_at = now()
+		FROM inserted
+		WHERE pip.parts_item_preset_id = inserted.parts_item_preset_id
+	`, partsPurchaseRequestID, movementType, userID)
+	return err
+}
+
+const inventoryItemSelect = `
+	SELECT
+		pip.parts_item_preset_id,
+		pip.preset_name,
+		pip.sku,
+		pip.track_inventory,
+		pip.on_hand_quantity,
+		pip.reorder_point,
+		pip.reorder_quantity,
+		pip.unit_cost::double precision,
+		pip.location_id,
+		l.shelf,
+		l.floor,
+		pip.preferred_supplier_id,
+		s.supplier_name,
+		pip.is_active,
+		pip.updated_at
+	FROM public.parts_item_presets pip
+	LEFT JOIN public.locations l ON l.location_id = pip.location_id
+	LEFT JOIN public.suppliers s ON s.supplier_id = pip.preferred_supplier_id
+`
+
+func inventoryItemScanTargets(item *domain.InventoryItem) []any {
+	return []any{
+		&item.PartsItemPresetID,
+		&item.PresetName,
+		&item.SKU,
+		&item.TrackInventory,
+		&item.OnHandQuantity,
+		&item.ReorderPoint,
+		&item.ReorderQuantity,
+		&item.UnitCost,
+		&item.LocationID,
+		&item.LocationShelf,
+		&item.LocationFloor,
+		&item.PreferredSupplierID,
+		&item.PreferredSupplierName,
+		&item.IsActive,
+		&item.UpdatedAt,
+	}
+}
+
+func (r *storeRepository) ListInventory(ctx context.Context, query string, trackedOnly bool) ([]domain.InventoryItem, error) {
+	rows, err := r.db.Query(ctx, inventoryItemSelect+`
+		WHERE pip.is_active = true
+			AND (NOT $1::boolean OR pip.track_inventory = true)
+			AND ($2 = '' OR pip.preset_name ILIKE '%' || $2 || '%' OR pip.sku ILIKE '%' || $2 || '%')
+		ORDER BY pip.preset_name ASC
+	`, trackedOnly, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.InventoryItem, 0)
+	for rows.Next() {
+		var item domain.InventoryItem
+		if err := rows.Scan(inventoryItemScanTargets(&item)...); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) GetInventoryItem(ctx context.Context, partsItemPresetID int64) (domain.InventoryItem, error) {
+	var item domain.InventoryItem
+	err := r.db.QueryRow(ctx, inventoryItemSelect+` WHERE pip.parts_item_preset_id = $1`, partsItemPresetID).Scan(inventoryItemScanTargets(&item)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.InventoryItem{}, ErrInventoryItemNotFound
+	}
+	return item, err
+}
+
+func (r *storeRepository) UpdateInventoryItem(ctx context.Context, partsItemPresetID int64, input UpdateInventoryItemInput) (domain.InventoryItem, error) {
+	if input.SKU != nil {
+		var taken bool
+		if err := r.db.QueryRow(ctx, `
+			SELECT EXISTS(
+				SELECT 1
+				FROM public.parts_item_presets
+				WHERE LOWER(BTRIM(sku)) = LOWER($1)
+					AND parts_item_preset_id <> $2
+			)
+		`, *input.SKU, partsItemPresetID).Scan(&taken); err != nil {
+			return domain.InventoryItem{}, err
+		}
+		if taken {
+			return domain.InventoryItem{}, ErrSKUTaken
+		}
+	}
+	if input.LocationID != nil {
+		var exists bool
+		if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.locations WHERE location_id = $1 AND is_active = true)`, *input.LocationID).Scan(&exists); err != nil {
+			return domain.InventoryItem{}, err
+		}
+		if !exists {
+			return domain.InventoryItem{}, ErrLocationNotFound
+		}
+	}
+	if input.PreferredSupplierID != nil {
+		var exists bool
+		if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.suppliers WHERE supplier_id = $1 AND is_active = true)`, *input.PreferredSupplierID).Scan(&exists); err != nil {
+			return domain.InventoryItem{}, err
+		}
+		if !exists {
+			return domain.InventoryItem{}, ErrSupplierNotFound
+		}
+	}
+
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE public.parts_item_presets
+		SET
+			sku = $2,
+			track_inventory = $3,
+			reorder_point = $4,
+			reorder_quantity = $5,
+			unit_cost = $6,
+			location_id = $7,
+			preferred_supplier_id = $8,
+			updated_at = now()
+		WHERE parts_item_preset_id = $1
+	`,
+		partsItemPresetID,
+		input.SKU,
+		input.TrackInventory,
+		input.ReorderPoint,
+		input.ReorderQuantity,
+		input.UnitCost,
+		input.LocationID,
+		input.PreferredSupplierID,
+	)
+	if err != nil {
+		return domain.InventoryItem{}, err
+	}
+	if cmd.RowsAffected() == 0 {
+		return domain.InventoryItem{}, ErrInventoryItemNotFound
+	}
+	return r.GetInventoryItem(ctx, partsItemPresetID)
+}
+
+func (r *storeRepository) AdjustStock(ctx context.Context, partsItemPresetID int64, input AdjustStockInput) (domain.InventoryItem, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.InventoryItem{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	cmd, err := tx.Exec(ctx, `
+		UPDATE public.parts_item_presets
+		SET
+			on_hand_quantity = on_hand_quantity + $2,
+			updated_at = now()
+		WHERE parts_item_preset_id = $1
+	`, partsItemPresetID, input.QuantityDelta)
+	if err != nil {
+		return domain.InventoryItem{}, err
+	}
+	if cmd.RowsAffected() == 0 {
+		return domain.InventoryItem{}, ErrInventoryItemNotFound
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO public.stock_movements(
+			parts_item_preset_id,
+			movement_type,
+			quantity_delta,
+			unit_cost,
+			note,
+			created_by_user_id
+		)
+		SELECT
+			parts_item_preset_id,
+			'adjustment',
+			$2,
+			unit_cost,
+			$3,
+			NULLIF($4, '')::uuid
+		FROM public.parts_item_presets
+		WHERE parts_item_preset_id = $1
+	`, partsItemPresetID, input.QuantityDelta, input.Note, input.CreatedByUserID); err != nil {
+		return domain.InventoryItem{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.InventoryItem{}, err
+	}
+	return r.GetInventoryItem(ctx, partsItemPresetID)
+}
+
+func (r *storeRepository) ListMovements(ctx context.Context, partsItemPresetID int64, limit int) ([]domain.StockMovement, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			sm.stock_movement_id,
+			sm.parts_item_preset_id,
+			sm.parts_purchase_request_id,
+			ppr.reference_id,
+			sm.movement_type,
+			sm.quantity_delta,
+			sm.unit_cost::double precision,
+			sm.note,
+			sm.created_by_user_id::text,
+			u.full_name,
+			sm.created_at
+		FROM public.stock_movements sm
+		LEFT JOIN public.parts_purchase_requests ppr ON ppr.parts_purchase_request_id = sm.parts_purchase_request_id
+		LEFT JOIN public.users u ON u.id = sm.created_by_user_id
+		WHERE sm.parts_item_preset_id = $1
+		ORDER BY sm.created_at DESC, sm.stock_movement_id DESC
+		LIMIT $2
+	`, partsItemPresetID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.StockMovement, 0)
+	for rows.Next() {
+		var item domain.StockMovement
+		if err := rows.Scan(
+			&item.StockMovementID,
+			&item.PartsItemPresetID,
+			&item.PartsPurchaseRequestID,
+			&item.ReferenceID,
+			&item.MovementType,
+			&item.QuantityDelta,
+			&item.UnitCost,
+			&item.Note,
+			&item.CreatedByUserID,
+			&item.CreatedByName,
+			&item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) ListLowStock(ctx context.Context) ([]domain.LowStockItem, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			pip.parts_item_preset_id,
+			pip.preset_name,
+			pip.sku,
+			pip.track_inventory,
+			pip.on_hand_quantity,
+			pip.reorder_point,
+			pip.reorder_quantity,
+			pip.unit_cost::double precision,
+			pip.location_id,
+			l.shelf,
+			l.floor,
+			pip.preferred_supplier_id,
+			s.supplier_name,
+			pip.is_active,
+			pip.updated_at,
+			COALESCE(on_order.quantity, 0)::integer
+		FROM public.parts_item_presets pip
+		LEFT JOIN public.locations l ON l.location_id = pip.location_id
+		LEFT JOIN public.suppliers s ON s.supplier_id = pip.preferred_supplier_id
+		LEFT JOIN LATERAL (
+			SELECT SUM(ppr.quantity) AS quantity
+			FROM public.parts_purchase_requests ppr
+			WHERE ppr.parts_item_preset_id = pip.parts_item_preset_id
+				AND ppr.status IN ('draft', 'waiting_approval', 'ordered')
+		) on_order ON TRUE
+		WHERE pip.is_active = true
+			AND pip.track_inventory = true
+			AND pip.on_hand_quantity <= pip.reorder_point
+		ORDER BY (pip.on_hand_quantity - pip.reorder_point) ASC, pip.preset_name ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.LowStockItem, 0)
+	for rows.Next() {
+		var item domain.LowStockItem
+		targets := append(inventoryItemScanTargets(&item.InventoryItem), &item.OnOrderQuantity)
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) CreateDraftRequests(ctx context.Context, referenceID *int, createdByUserID string, items []domain.LowStockItem) ([]domain.PartsPurchaseRequest, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if referenceID != nil {
+		var workOrderExists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.work_orders WHERE reference_id = $1)`, *referenceID).Scan(&workOrderExists); err != nil {
+			return nil, err
+		}
+		if !workOrderExists {
+			return nil, ErrWorkOrderNotFound
+		}
+	}
+
+	out := make([]domain.PartsPurchaseRequest, 0, len(items))
+	for _, item := range items {
+		totalPrice := 0.0
+		if item.UnitCost != nil {
+			totalPrice = *item.UnitCost * float64(item.SuggestedQuantity)
+		}
+		var created domain.PartsPurchaseRequest
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO public.parts_purchase_requests(
+				reference_id,
+				source,
+				status,
+				total_price,
+				item_name,
+				quantity,
+				parts_item_preset_id,
+				supplier_id,
+				created_by_user_id
+			)
+			VALUES($1, 'supplier', 'draft', ROUND($2::numeric, 2), $3, $4, $5, $6, $7::uuid)
+			RETURNING
+				parts_purchase_request_id,
+				reference_id,
+				source,
+				source_url,
+				status,
+				total_price::double precision,
+				item_name,
+				quantity,
+				parts_item_preset_id,
+				supplier_id,
+				purchase_order_id,
+				received_at,
+				created_by_user_id::text,
+				created_at,
+				updated_at
+		`,
+			referenceID,
+			totalPrice,
+			item.PresetName,
+			item.SuggestedQuantity,
+			item.PartsItemPresetID,
+			item.PreferredSupplierID,
+			createdByUserID,
+		).Scan(
+			&created.PartsPurchaseRequestID,
+			&created.ReferenceID,
+			&created.Source,
+			&created.SourceURL,
+			&created.Status,
+			&created.TotalPrice,
+			&created.ItemName,
+			&created.Quantity,
+			&created.PartsItemPresetID,
+			&created.SupplierID,
+			&created.PurchaseOrderID,
+			&created.ReceivedAt,
+			&created.CreatedByUserID,
+			&created.CreatedAt,
+			&created.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		created.SupplierName = item.PreferredSupplierName
+		out = append(out, created)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,34 @@
+package inventory
+
+import (
+	"humphreys/api/internal/middleware"
+	"humphreys/api/internal/permissions"
+)
+
+const (
+	permRead        = "inventory:read"
+	permCreate      = "inventory:create"
+	permUpdate      = "inventory:update"
+	permPartsCreate = "parts_purchase_requests:create"
+)
+
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("inventory", "Parts inventory stock levels and movements", permissions.StandardActions()...)
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
+	group := authed.Group("/inventory")
+	group.GET("", middleware.RequirePermission(permRead), h.ListInventory)
+	group.GET("/low-stock", middleware.RequirePermission(permRead), h.LowStockReport)
+	group.POST(
+		"/low-stock/draft-requests",
+		middleware.RequirePermission(permCreate),
+		middleware.RequirePermission(permPartsCreate),
+		h.DraftLowStockRequests,
+	)
+	group.GET("/:parts_item_preset_id", middleware.RequirePermission(permRead), h.GetInventoryItem)
+	group.PATCH("/:parts_item_preset_id", middleware.RequirePermission(permUpdate), h.UpdateInventoryItem)
+	group.GET("/:parts_item_preset_id/movements", middleware.RequirePermission(permRead), h.ListMovements)
+	group.POST("/:parts_item_preset_id/adjustments", middleware.RequirePermission(permUpdate), h.AdjustStock)
+}
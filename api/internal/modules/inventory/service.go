@@ -0,0 +1,157 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"humphreys/api/internal/domain"
+)
+
+var ErrInventoryItemNotFound = errors.New("inventory item not found")
+var ErrInvalidReorderPoint = errors.New("reorder point must be zero or greater")
+var ErrInvalidReorderQuantity = errors.New("reorder quantity must be zero or greater")
+var ErrInvalidUnitCost = errors.New("unit cost must be zero or greater")
+var ErrInvalidAdjustment = errors.New("adjustment quantity must not be zero")
+var ErrAdjustmentNoteRequired = errors.New("adjustment note is required")
+var ErrSKUTaken = errors.New("sku already exists")
+var ErrLocationNotFound = errors.New("location not found")
+var ErrSupplierNotFound = errors.New("supplier not found")
+var ErrWorkOrderNotFound = errors.New("work order not found")
+var ErrNothingToReorder = errors.New("no low-stock items need reordering")
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+type UpdateInventoryItemInput struct {
+	SKU                 *string
+	TrackInventory      bool
+	ReorderPoint        int32
+	ReorderQuantity     int32
+	UnitCost            *float64
+	LocationID          *int64
+	PreferredSupplierID *int64
+}
+
+type AdjustStockInput struct {
+	QuantityDelta   int32
+	Note            string
+	CreatedByUserID string
+}
+
+// DraftLowStockRequestsInput drafts restock requests for shelf stock unless
+// ReferenceID names a work order to buy them for.
+type DraftLowStockRequestsInput struct {
+	ReferenceID        *int
+	PartsItemPresetIDs []int64
+	CreatedByUserID    string
+}
+
+func (s *Service) ListInventory(ctx context.Context, query string, trackedOnly bool) ([]domain.InventoryItem, error) {
+	return s.repo.ListInventory(ctx, strings.TrimSpace(query), trackedOnly)
+}
+
+func (s *Service) GetInventoryItem(ctx context.Context, partsItemPresetID int64) (domain.InventoryItem, error) {
+	return s.repo.GetInventoryItem(ctx, partsItemPresetID)
+}
+
+func (s *Service) UpdateInventoryItem(ctx context.Context, partsItemPresetID int64, input UpdateInventoryItemInput) (domain.InventoryItem, error) {
+	if input.ReorderPoint < 0 {
+		return domain.InventoryItem{}, ErrInvalidReorderPoint
+	}
+	if input.ReorderQuantity < 0 {
+		return domain.InventoryItem{}, ErrInvalidReorderQuantity
+	}
+	if input.UnitCost != nil && *input.UnitCost < 0 {
+		return domain.InventoryItem{}, ErrInvalidUnitCost
+	}
+	if input.SKU != nil {
+		sku := strings.TrimSpace(*input.SKU)
+		if sku == "" {
+			input.SKU = nil
+		} else {
+			input.SKU = &sku
+		}
+	}
+	return s.repo.UpdateInventoryItem(ctx, partsItemPresetID, input)
+}
+
+func (s *Service) AdjustStock(ctx context.Context, partsItemPresetID int64, input AdjustStockInput) (domain.InventoryItem, error) {
+	if input.QuantityDelta == 0 {
+		return domain.InventoryItem{}, ErrInvalidAdjustment
+	}
+	input.Note = strings.TrimSpace(input.Note)
+	if input.Note == "" {
+		return domain.InventoryItem{}, ErrAdjustmentNoteRequired
+	}
+	return s.repo.AdjustStock(ctx, partsItemPresetID, input)
+}
+
+func (s *Service) ListMovements(ctx context.Context, partsItemPresetID int64, limit int) ([]domain.StockMovement, error) {
+	if limit < 1 || limit > 500 {
+		limit = 100
+	}
+	return s.repo.ListMovements(ctx, partsItemPresetID, limit)
+}
+
+func (s *Service) LowStockReport(ctx context.Context) ([]domain.LowStockItem, error) {
+	items, err := s.repo.ListLowStock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].SuggestedQuantity = suggestedReorderQuantity(items[i])
+	}
+	return items, nil
+}
+
+func (s *Service) DraftLowStockRequests(ctx context.Context, input DraftLowStockRequestsInput) ([]domain.PartsPurchaseRequest, error) {
+	if input.ReferenceID != nil && *input.ReferenceID <= 0 {
+		return nil, ErrWorkOrderNotFound
+	}
+	report, err := s.LowStockReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[int64]struct{}, len(input.PartsItemPresetIDs))
+	for _, id := range input.PartsItemPresetIDs {
+		selected[id] = struct{}{}
+	}
+	drafts := make([]domain.LowStockItem, 0, len(report))
+	for _, item := range report {
+		if len(selected) > 0 {
+			if _, ok := selected[item.PartsItemPresetID]; !ok {
+				continue
+			}
+		}
+		if item.SuggestedQuantity < 1 {
+			continue
+		}
+		drafts = append(drafts, item)
+	}
+	if len(drafts) == 0 {
+		return nil, ErrNothingToReorder
+	}
+	return s.repo.CreateDraftRequests(ctx, input.ReferenceID, input.CreatedByUserID, drafts)
+}
+
+// suggestedReorderQuantity tops stock back above the reorder point, honouring the
+// preset's usual order size and anything already requested but not yet received.
+func suggestedReorderQuantity(item domain.LowStockItem) int32 {
+	shortfall := item.ReorderPoint - item.OnHandQuantity + 1
+	quantity := item.ReorderQuantity
+	if shortfall > quantity {
+		quantity = shortfall
+	}
+	quantity -= item.OnOrderQuantity
+	if quantity < 0 {
+		return 0
+	}
+	return quantity
+}
@@ -0,0 +1,53 @@
+package inventory
+
+import (
+	"testing"
+
+	"humphreys/api/internal/domain"
+)
+
+func TestSuggestedReorderQuantity(t *testing.T) {
+	cases := []struct {
+		name     string
+		item     domain.LowStockItem
+		expected int32
+	}{
+		{
+			name:     "uses reorder quantity when larger than shortfall",
+			item:     lowStockItem(2, 3, 10, 0),
+			expected: 10,
+		},
+		{
+			name:     "covers shortfall above reorder point",
+			item:     lowStockItem(0, 5, 2, 0),
+			expected: 6,
+		},
+		{
+			name:     "subtracts quantity already on order",
+			item:     lowStockItem(1, 3, 5, 2),
+			expected: 3,
+		},
+		{
+			name:     "never negative",
+			item:     lowStockItem(1, 3, 5, 9),
+			expected: 0,
+		},
+	}
+
+	for _, tc := range cases {
+		if got := suggestedReorderQuantity(tc.item); got != tc.expected {
+			t.Fatalf("%s: expected %d, got %d", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func lowStockItem(onHand, reorderPoint, reorderQuantity, onOrder int32) domain.LowStockItem {
+	return domain.LowStockItem{
+		InventoryItem: domain.InventoryItem{
+			OnHandQuantity:  onHand,
+			ReorderPoint:    reorderPoint,
+			ReorderQuantity: reorderQuantity,
+		},
+		OnOrderQuantity: onOrder,
+	}
+}
@@ -0,0 +1,80 @@
+package notifications
+
+import (
+	"context"
+
+	"humphreys/api/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) List(ctx context.Context, userID string, unreadOnly bool, limit int) ([]domain.Notification, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			n.notification_id,
+			n.notification_type,
+			n.reference_id,
+			n.title,
+			n.body,
+			n.read_at,
+			n.created_at
+		FROM public.notifications n
+		WHERE n.recipient_user_id = $1::uuid
+			AND (NOT $2::boolean OR n.read_at IS NULL)
+		ORDER BY n.created_at DESC, n.notification_id DESC
+		LIMIT $3
+	`, userID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Notification, 0)
+	for rows.Next() {
+		var item domain.Notification
+		if err := rows.Scan(
+			&item.NotificationID,
+			&item.NotificationType,
+			&item.ReferenceID,
+			&item.Title,
+			&item.Body,
+			&item.ReadAt,
+			&item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *Repository) MarkRead(ctx context.Context, userID string, notificationID int64) (bool, error) {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE public.notifications
+		SET read_at = COALESCE(read_at, now())
+		WHERE notification_id = $1
+			AND recipient_user_id = $2::uuid
+	`, notificationID, userID)
+	if err != nil {
+		return false, err
+	}
+	return cmd.RowsAffected() > 0, nil
+}
+
+func (r *Repository) MarkAllRead(ctx context.Context, userID string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE public.notifications
+		SET read_at = now()
+		WHERE recipient_user_id = $1::uuid
+			AND read_at IS NULL
+	`, userID)
+	return err
+}
@@ -0,0 +1,10 @@
+package notifications
+
+import "humphreys/api/internal/middleware"
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
+	group := authed.Group("/notifications")
+	group.GET("", h.List)
+	group.POST("/read-all", h.MarkAllRead)
+	group.PATCH("/:notification_id/read", h.MarkRead)
+}
@@ -0,0 +1,133 @@
+package partsmarkup
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func New(db *pgxpool.Pool) *Handler {
+	return &Handler{
+		service: NewService(NewRepository(db)),
+	}
+}
+
+func NewWithService(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+type ruleRequest struct {
+	RuleName      string   `json:"rule_name" binding:"required"`
+	SupplierID    *int64   `json:"supplier_id"`
+	MinUnitCost   *float64 `json:"min_unit_cost"`
+	MaxUnitCost   *float64 `json:"max_unit_cost"`
+	MarkupPercent float64  `json:"markup_percent"`
+	IsActive      *bool    `json:"is_active"`
+}
+
+func (req ruleRequest) input() RuleInput {
+	return RuleInput{
+		RuleName:      req.RuleName,
+		SupplierID:    req.SupplierID,
+		MinUnitCost:   req.MinUnitCost,
+		MaxUnitCost:   req.MaxUnitCost,
+		MarkupPercent: req.MarkupPercent,
+		IsActive:      req.IsActive,
+	}
+}
+
+func (h *Handler) ListRules(c *gin.Context) {
+	items, err := h.service.ListRules(c.Request.Context(), c.Query("include_inactive") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list markup rules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) GetRule(c *gin.Context) {
+	markupRuleID, ok := parseRuleID(c)
+	if !ok {
+		return
+	}
+	item, err := h.service.GetRule(c.Request.Context(), markupRuleID)
+	if err != nil {
+		writeRuleError(c, err, "failed to load markup rule")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) CreateRule(c *gin.Context) {
+	var req ruleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.CreateRule(c.Request.Context(), req.input())
+	if err != nil {
+		writeRuleError(c, err, "failed to create markup rule")
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *Handler) UpdateRule(c *gin.Context) {
+	markupRuleID, ok := parseRuleID(c)
+	if !ok {
+		return
+	}
+	var req ruleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.UpdateRule(c.Request.Context(), markupRuleID, req.input())
+	if err != nil {
+		writeRuleError(c, err, "failed to update markup rule")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) DeleteRule(c *gin.Context) {
+	markupRuleID, ok := parseRuleID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteRule(c.Request.Context(), markupRuleID); err != nil {
+		writeRuleError(c, err, "failed to delete markup rule")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseRuleID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("markup_rule_id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid markup_rule_id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeRuleError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrMarkupRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidRuleName),
+		errors.Is(err, ErrInvalidMarkupPercent),
+		errors.Is(err, ErrInvalidCostBand),
+		errors.Is(err, ErrSupplierNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,270 @@
+package partsmarkup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"humphreys/api/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository interface {
+	ListRules(ctx context.Context, includeInactive bool) ([]domain.PartsMarkupRule, error)
+	GetRule(ctx context.Context, markupRuleID int64) (domain.PartsMarkupRule, error)
+	CreateRule(ctx context.Context, input RuleInput) (domain.PartsMarkupRule, error)
+	UpdateRule(ctx context.Context, markupRuleID int64, input RuleInput) (domain.PartsMarkupRule, error)
+	DeleteRule(ctx context.Context, markupRuleID int64) error
+}
+
+type storeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &storeRepository{db: db}
+}
+
+// SyncPartsLineItemTx keeps the customer line item linked to a parts request in
+// step with it. A used request gets a line item priced at cost plus the best
+// matching markup rule; once the request is no longer used the line is removed.
+// Callers are expected to recalculate the work order parts totals afterwards.
+// Restock requests have no work order and so never get a line item.
+func SyncPartsLineItemTx(ctx context.Context, tx pgx.Tx, partsPurchaseRequestID int64) error {
+	var referenceID *int32
+	var status string
+	var itemName string
+	var quantity int32
+	var totalCost float64
+	var supplierID *int64
+	err := tx.QueryRow(ctx, `
+		SELECT reference_id, status, item_name, quantity, total_price::double precision, supplier_id
+		FROM public.parts_purchase_requests
+		WHERE parts_purchase_request_id = $1
+	`, partsPurchaseRequestID).Scan(&referenceID, &status, &itemName, &quantity, &totalCost, &supplierID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if status != "used" || referenceID == nil {
+		_, err := tx.Exec(ctx, `DELETE FROM public.work_order_line_items WHERE parts_purchase_request_id = $1`, partsPurchaseRequestID)
+		return err
+	}
+
+	markupPercent, err := matchMarkupPercentTx(ctx, tx, supplierID, totalCost/float64(max(quantity, 1)))
+	if err != nil {
+		return err
+	}
+	unitPrice, lineTotal := markedUpPrices(totalCost, quantity, markupPercent)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO public.work_order_line_items(
+			reference_id,
+			item_name,
+			unit_price,
+			quantity_text,
+			line_total_text,
+			parts_purchase_request_id,
+			cost_total
+		)
+		VALUES($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (parts_purchase_request_id) WHERE parts_purchase_request_id IS NOT NULL
+		DO UPDATE SET
+			item_name = EXCLUDED.item_name,
+			unit_price = EXCLUDED.unit_price,
+			quantity_text = EXCLUDED.quantity_text,
+			line_total_text = EXCLUDED.line_total_text,
+			cost_total = EXCLUDED.cost_total
+	`,
+		*referenceID,
+		itemName,
+		unitPrice,
+		fmt.Sprintf("%d", quantity),
+		fmt.Sprintf("%.2f", lineTotal),
+		partsPurchaseRequestID,
+		totalCost,
+	)
+	return err
+}
+
+// matchMarkupPercentTx prefers a supplier-specific rule over a general one, and
+// within those the band with the highest floor. No match means no markup.
+func matchMarkupPercentTx(ctx context.Context, tx pgx.Tx, supplierID *int64, unitCost float64) (float64, error) {
+	var markupPercent float64
+	err := tx.QueryRow(ctx, `
+		SELECT markup_percent::double precision
+		FROM public.parts_markup_rules
+		WHERE is_active = true
+			AND (supplier_id IS NULL OR supplier_id = $1)
+			AND (min_unit_cost IS NULL OR $2 >= min_unit_cost)
+			AND (max_unit_cost IS NULL OR $2 < max_unit_cost)
+		ORDER BY (supplier_id IS NOT NULL) DESC, COALESCE(min_unit_cost, 0) DESC, markup_rule_id ASC
+		LIMIT 1
+	`, supplierID, unitCost).Scan(&markupPercent)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return markupPercent, err
+}
+
+const ruleSelect = `
+	SELECT
+		r.markup_rule_id,
+		r.rule_name,
+		r.supplier_id,
+		s.supplier_name,
+		r.min_unit_cost::double precision,
+		r.max_unit_cost::double precision,
+		r.markup_percent::double precision,
+		r.is_active,
+		r.created_at,
+		r.updated_at
+	FROM public.parts_markup_rules r
+	LEFT JOIN public.suppliers s ON s.supplier_id = r.supplier_id
+`
+
+func scanRule(row pgx.Row, item *domain.PartsMarkupRule) error {
+	return row.Scan(
+		&item.MarkupRuleID,
+		&item.RuleName,
+		&item.SupplierID,
+		&item.SupplierName,
+		&item.MinUnitCost,
+		&item.MaxUnitCost,
+		&item.MarkupPercent,
+		&item.IsActive,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+}
+
+func (r *storeRepository) ListRules(ctx context.Context, includeInactive bool) ([]domain.PartsMarkupRule, error) {
+	sql := ruleSelect
+	if !includeInactive {
+		sql += ` WHERE r.is_active = true`
+	}
+	sql += ` ORDER BY s.supplier_name ASC NULLS FIRST, COALESCE(r.min_unit_cost, 0) ASC, r.markup_rule_id ASC`
+
+	rows, err := r.db.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.PartsMarkupRule, 0)
+	for rows.Next() {
+		var item domain.PartsMarkupRule
+		if err := scanRule(rows, &item); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) GetRule(ctx context.Context, markupRuleID int64) (domain.PartsMarkupRule, error) {
+	var item domain.PartsMarkupRule
+	err := scanRule(r.db.QueryRow(ctx, ruleSelect+` WHERE r.markup_rule_id = $1`, markupRuleID), &item)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.PartsMarkupRule{}, ErrMarkupRuleNotFound
+	}
+	return item, err
+}
+
+func (r *storeRepository) CreateRule(ctx context.Context, input RuleInput) (domain.PartsMarkupRule, error) {
+	if err := r.ensureSupplierExists(ctx, input.SupplierID); err != nil {
+		return domain.PartsMarkupRule{}, err
+	}
+	isActive := true
+	if input.IsActive != nil {
+		isActive = *input.IsActive
+	}
+
+	var markupRuleID int64
+	if err := r.db.QueryRow(ctx, `
+		INSERT INTO public.parts_markup_rules(
+			rule_name,
+			supplier_id,
+			min_unit_cost,
+			max_unit_cost,
+			markup_percent,
+			is_active
+		)
+		VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING markup_rule_id
+	`,
+		input.RuleName,
+		input.SupplierID,
+		input.MinUnitCost,
+		input.MaxUnitCost,
+		input.MarkupPercent,
+		isActive,
+	).Scan(&markupRuleID); err != nil {
+		return domain.PartsMarkupRule{}, err
+	}
+	return r.GetRule(ctx, markupRuleID)
+}
+
+func (r *storeRepository) UpdateRule(ctx context.Context, markupRuleID int64, input RuleInput) (domain.PartsMarkupRule, error) {
+	if err := r.ensureSupplierExists(ctx, input.SupplierID); err != nil {
+		return domain.PartsMarkupRule{}, err
+	}
+
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE public.parts_markup_rules
+		SET
+			rule_name = $2,
+			supplier_id = $3,
+			min_unit_cost = $4,
+			max_unit_cost = $5,
+			markup_percent = $6,
+			is_active = COALESCE($7, is_active),
+			updated_at = now()
+		WHERE markup_rule_id = $1
+	`,
+		markupRuleID,
+		input.RuleName,
+		input.SupplierID,
+		input.MinUnitCost,
+		input.MaxUnitCost,
+		input.MarkupPercent,
+		input.IsActive,
+	)
+	if err != nil {
+		return domain.PartsMarkupRule{}, err
+	}
+	if cmd.RowsAffected() == 0 {
+		return domain.PartsMarkupRule{}, ErrMarkupRuleNotFound
+	}
+	return r.GetRule(ctx, markupRuleID)
+}
+
+func (r *storeRepository) DeleteRule(ctx context.Context, markupRuleID int64) error {
+	cmd, err := r.db.Exec(ctx, `DELETE FROM public.parts_markup_rules WHERE markup_rule_id = $1`, markupRuleID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrMarkupRuleNotFound
+	}
+	return nil
+}
+
+func (r *storeRepository) ensureSupplierExists(ctx context.Context, supplierID *int64) error {
+	if supplierID == nil {
+		return nil
+	}
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.suppliers WHERE supplier_id = $1)`, *supplierID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrSupplierNotFound
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package partsmarkup
+
+import (
+	"humphreys/api/internal/middleware"
+	"humphreys/api/internal/permissions"
+)
+
+const (
+	permRead   = "parts_markup_rules:read"
+	permCreate = "parts_markup_rules:create"
+	permUpdate = "parts_markup_rules:update"
+	permDelete = "parts_markup_rules:delete"
+)
+
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("parts_markup_rules", "Markup rules that price used parts onto customer line items", permissions.StandardActions()...)
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
+	group := authed.Group("/parts-markup-rules")
+	group.GET("", middleware.RequirePermission(permRead), h.ListRules)
+	group.POST("", middleware.RequirePermission(permCreate), h.CreateRule)
+	group.GET("/:markup_rule_id", middleware.RequirePermission(permRead), h.GetRule)
+	group.PATCH("/:markup_rule_id", middleware.RequirePermission(permUpdate), h.UpdateRule)
+	group.DELETE("/:markup_rule_id", middleware.RequirePermission(permDelete), h.DeleteRule)
+}
@@ -0,0 +1,97 @@
+package partsmarkup
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+
+	"humphreys/api/internal/domain"
+)
+
+var ErrMarkupRuleNotFound = errors.New("markup rule not found")
+var ErrInvalidRuleName = errors.New("rule name is required")
+var ErrInvalidMarkupPercent = errors.New("markup percent must be zero or greater")
+var ErrInvalidCostBand = errors.New("cost band must be zero or greater with min_unit_cost below max_unit_cost")
+var ErrSupplierNotFound = errors.New("supplier not found")
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+type RuleInput struct {
+	RuleName      string
+	SupplierID    *int64
+	MinUnitCost   *float64
+	MaxUnitCost   *float64
+	MarkupPercent float64
+	IsActive      *bool
+}
+
+func (s *Service) ListRules(ctx context.Context, includeInactive bool) ([]domain.PartsMarkupRule, error) {
+	return s.repo.ListRules(ctx, includeInactive)
+}
+
+func (s *Service) GetRule(ctx context.Context, markupRuleID int64) (domain.PartsMarkupRule, error) {
+	return s.repo.GetRule(ctx, markupRuleID)
+}
+
+func (s *Service) CreateRule(ctx context.Context, input RuleInput) (domain.PartsMarkupRule, error) {
+	normalized, err := normalizeRuleInput(input)
+	if err != nil {
+		return domain.PartsMarkupRule{}, err
+	}
+	return s.repo.CreateRule(ctx, normalized)
+}
+
+func (s *Service) UpdateRule(ctx context.Context, markupRuleID int64, input RuleInput) (domain.PartsMarkupRule, error) {
+	normalized, err := normalizeRuleInput(input)
+	if err != nil {
+		return domain.PartsMarkupRule{}, err
+	}
+	return s.repo.UpdateRule(ctx, markupRuleID, normalized)
+}
+
+func (s *Service) DeleteRule(ctx context.Context, markupRuleID int64) error {
+	return s.repo.DeleteRule(ctx, markupRuleID)
+}
+
+func normalizeRuleInput(input RuleInput) (RuleInput, error) {
+	input.RuleName = strings.TrimSpace(input.RuleName)
+	if input.RuleName == "" {
+		return RuleInput{}, ErrInvalidRuleName
+	}
+	if input.MarkupPercent < 0 {
+		return RuleInput{}, ErrInvalidMarkupPercent
+	}
+	if input.MinUnitCost != nil && *input.MinUnitCost < 0 {
+		return RuleInput{}, ErrInvalidCostBand
+	}
+	if input.MaxUnitCost != nil && *input.MaxUnitCost < 0 {
+		return RuleInput{}, ErrInvalidCostBand
+	}
+	if input.MinUnitCost != nil && input.MaxUnitCost != nil && *input.MinUnitCost >= *input.MaxUnitCost {
+		return RuleInput{}, ErrInvalidCostBand
+	}
+	return input, nil
+}
+
+// markedUpPrices turns what we paid for a parts request into the customer unit
+// price and line total. Both are rounded to cents, and the line total is built
+// from the rounded unit price so the invoice multiplies out.
+func markedUpPrices(totalCost float64, quantity int32, markupPercent float64) (float64, float64) {
+	if quantity < 1 {
+		quantity = 1
+	}
+	unitCost := totalCost / float64(quantity)
+	unitPrice := roundCents(unitCost * (1 + markupPercent/100))
+	return unitPrice, roundCents(unitPrice * float64(quantity))
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,26 @@
+package partsmarkup
+
+import "testing"
+
+func TestMarkedUpPrices(t *testing.T) {
+	cases := []struct {
+		name          string
+		totalCost     float64
+		quantity      int32
+		markupPercent float64
+		unitPrice     float64
+		lineTotal     float64
+	}{
+		{name: "no markup", totalCost: 20, quantity: 2, markupPercent: 0, unitPrice: 10, lineTotal: 20},
+		{name: "percent markup", totalCost: 40, quantity: 1, markupPercent: 25, unitPrice: 50, lineTotal: 50},
+		{name: "rounds unit price before totalling", totalCost: 10, quantity: 3, markupPercent: 10, unitPrice: 3.67, lineTotal: 11.01},
+		{name: "zero quantity treated as one", totalCost: 12, quantity: 0, markupPercent: 50, unitPrice: 18, lineTotal: 18},
+	}
+
+	for _, tc := range cases {
+		unitPrice, lineTotal := markedUpPrices(tc.totalCost, tc.quantity, tc.markupPercent)
+		if unitPrice != tc.unitPrice || lineTotal != tc.lineTotal {
+			t.Fatalf("%s: expected %.2f/%.2f, got %.2f/%.2f", tc.name, tc.unitPrice, tc.lineTotal, unitPrice, lineTotal)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package purchasing
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"humphreys/api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func New(db *pgxpool.Pool) *Handler {
+	return &Handler{
+		service: NewService(NewRepository(db)),
+	}
+}
+
+func NewWithService(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+type supplierRequest struct {
+	SupplierName  string  `json:"supplier_name" binding:"required"`
+	ContactName   *string `json:"contact_name"`
+	Email         *string `json:"email"`
+	Phone         *string `json:"phone"`
+	Website       *string `json:"website"`
+	AccountNumber *string `json:"account_number"`
+	Notes         *string `json:"notes"`
+	IsActive      *bool   `json:"is_active"`
+}
+
+type createPurchaseOrderRequest struct {
+	OrderNumber             *string `json:"order_number"`
+	SupplierID              int64   `json:"supplier_id" binding:"required"`
+	ShippingCost            float64 `json:"shipping_cost"`
+	TrackingNumber          *string `json:"tracking_number"`
+	TrackingURL             *string `json:"tracking_url"`
+	Notes                   *string `json:"notes"`
+	PartsPurchaseRequestIDs []int64 `json:"parts_purchase_request_ids"`
+}
+
+type updatePurchaseOrderRequest struct {
+	OrderNumber    *string  `json:"order_number"`
+	Status         *string  `json:"status"`
+	ShippingCost   *float64 `json:"shipping_cost"`
+	TrackingNumber *string  `json:"tracking_number"`
+	TrackingURL    *string  `json:"tracking_url"`
+	Notes          *string  `json:"notes"`
+}
+
+type purchaseOrderItemsRequest struct {
+	PartsPurchaseRequestIDs []int64 `json:"parts_purchase_request_ids"`
+}
+
+func (h *Handler) ListSuppliers(c *gin.Context) {
+	items, err := h.service.ListSuppliers(c.Request.Context(), c.Query("q"), c.Query("include_inactive") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list suppliers"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) GetSupplier(c *gin.Context) {
+	supplierID, ok := parseIDParam(c, "supplier_id")
+	if !ok {
+		return
+	}
+	item, err := h.service.GetSupplier(c.Request.Context(), supplierID)
+	if errors.Is(err, ErrSupplierNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load supplier"})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) CreateSupplier(c *gin.Context) {
+	var req supplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.CreateSupplier(c.Request.Context(), supplierInputFromRequest(req))
+	if err != nil {
+		writeSupplierError(c, err, "failed to create supplier")
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *Handler) UpdateSupplier(c *gin.Context) {
+	supplierID, ok := parseIDParam(c, "supplier_id")
+	if !ok {
+		return
+	}
+	var req supplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.UpdateSupplier(c.Request.Context(), supplierID, supplierInputFromRequest(req))
+	if err != nil {
+		writeSupplierError(c, err, "failed to update supplier")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) DeleteSupplier(c *gin.Context) {
+	supplierID, ok := parseIDParam(c, "supplier_id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSupplier(c.Request.Context(), supplierID); err != nil {
+		writeSupplierError(c, err, "failed to delete supplier")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ListPurchaseOrders(c *gin.Context) {
+	filters := PurchaseOrderListFilters{}
+	if status := c.Query("status"); status != "" {
+		filters.Status = &status
+	}
+	if raw := c.Query("supplier_id"); raw != "" {
+		supplierID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || supplierID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid supplier_id"})
+			return
+		}
+		filters.SupplierID = &supplierID
+	}
+	items, err := h.service.ListPurchaseOrders(c.Request.Context(), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list purchase orders"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) GetPurchaseOrder(c *gin.Context) {
+	purchaseOrderID, ok := parseIDParam(c, "purchase_order_id")
+	if !ok {
+		return
+	}
+	item, err := h.service.GetPurchaseOrder(c.Request.Context(), purchaseOrderID)
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to load purchase order")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) CreatePurchaseOrder(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok || claims.UserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+
+	var req createPurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	item, err := h.service.CreatePurchaseOrder(c.Request.Context(), CreatePurchaseOrderInput{
+		OrderNumber:             req.OrderNumber,
+		SupplierID:              req.SupplierID,
+		ShippingCost:            req.ShippingCost,
+		TrackingNumber:          req.TrackingNumber,
+		TrackingURL:             req.TrackingURL,
+		Notes:                   req.Notes,
+		PartsPurchaseRequestIDs: req.PartsPurchaseRequestIDs,
+		CreatedByUserID:         claims.UserID,
+	})
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to create purchase order")
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *Handler) UpdatePurchaseOrder(c *gin.Context) {
+	purchaseOrderID, ok := parseIDParam(c, "purchase_order_id")
+	if !ok {
+		return
+	}
+	var req updatePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.UpdatePurchaseOrder(c.Request.Context(), purchaseOrderID, UpdatePurchaseOrderInput{
+		OrderNumber:    req.OrderNumber,
+		Status:         req.Status,
+		ShippingCost:   req.ShippingCost,
+		TrackingNumber: req.TrackingNumber,
+		TrackingURL:    req.TrackingURL,
+		Notes:          req.Notes,
+	})
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to update purchase order")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) SetPurchaseOrderItems(c *gin.Context) {
+	purchaseOrderID, ok := parseIDParam(c, "purchase_order_id")
+	if !ok {
+		return
+	}
+	var req purchaseOrderItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.SetPurchaseOrderItems(c.Request.Context(), purchaseOrderID, req.PartsPurchaseRequestIDs)
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to update purchase order items")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) ReceivePurchaseOrder(c *gin.Context) {
+	purchaseOrderID, ok := parseIDParam(c, "purchase_order_id")
+	if !ok {
+		return
+	}
+	claims, ok := middleware.Claims(c)
+	if !ok || claims.UserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+	var req purchaseOrderItemsRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+	}
+	item, err := h.service.ReceivePurchaseOrder(c.Request.Context(), purchaseOrderID, ReceivePurchaseOrderInput{
+		PartsPurchaseRequestIDs: req.PartsPurchaseRequestIDs,
+		ReceivedByUserID:        claims.UserID,
+	})
+	if err != nil {
+		writePurchaseOrderError(c, err, "failed to receive purchase order")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) DeletePurchaseOrder(c *gin.Context) {
+	purchaseOrderID, ok := parseIDParam(c, "purchase_order_id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeletePurchaseOrder(c.Request.Context(), purchaseOrderID); err != nil {
+		writePurchaseOrderError(c, err, "failed to delete purchase order")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func parseIDParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+func supplierInputFromRequest(req supplierRequest) SupplierInput {
+	return SupplierInput{
+		SupplierName:  req.SupplierName,
+		ContactName:   req.ContactName,
+		Email:         req.Email,
+		Phone:         req.Phone,
+		Website:       req.Website,
+		AccountNumber: req.AccountNumber,
+		Notes:         req.Notes,
+		IsActive:      req.IsActive,
+	}
+}
+
+func writeSupplierError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrSupplierNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidSupplierName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrSupplierNameTaken), errors.Is(err, ErrSupplierInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func writePurchaseOrderError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrPurchaseOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrSupplierNotFound),
+		errors.Is(err, ErrPartsRequestNotFound),
+		errors.Is(err, ErrPartsRequestNotOnOrder),
+		errors.Is(err, ErrInvalidShippingCost),
+		errors.Is(err, ErrInvalidPurchaseOrderStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrOrderNumberTaken),
+		errors.Is(err, ErrInvalidPurchaseOrderTransition),
+		errors.Is(err, ErrPurchaseOrderClosed),
+		errors.Is(err, ErrPurchaseOrderNotOrdered),
+		errors.Is(err, ErrPurchaseOrderEmpty),
+		errors.Is(err, ErrPurchaseOrderHasReceivedItems),
+		errors.Is(err, ErrPartsRequestUnavailable),
+		errors.Is(err, ErrPartsRequestAlreadyReceived),
+		errors.Is(err, ErrPartsRequestNeedsApproval):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,924 @@
+package purchasing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"humphreys/api/internal/domain"
+	"humphreys/api/internal/modules/inventory"
+	"humphreys/api/internal/modules/workorders"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository interface {
+	ListSuppliers(ctx context.Context, query string, includeInactive bool) ([]domain.Supplier, error)
+	GetSupplier(ctx context.Context, supplierID int64) (domain.Supplier, error)
+	CreateSupplier(ctx context.Context, input SupplierInput) (domain.Supplier, error)
+	UpdateSupplier(ctx context.Context, supplierID int64, input SupplierInput) (domain.Supplier, error)
+	DeleteSupplier(ctx context.Context, supplierID int64) error
+	ListPurchaseOrders(ctx context.Context, filters PurchaseOrderListFilters) ([]domain.PurchaseOrder, error)
+	GetPurchaseOrder(ctx context.Context, purchaseOrderID int64) (domain.PurchaseOrder, error)
+	CreatePurchaseOrder(ctx context.Context, input CreatePurchaseOrderInput) (domain.PurchaseOrder, error)
+	UpdatePurchaseOrder(ctx context.Context, purchaseOrderID int64, input UpdatePurchaseOrderInput) (domain.PurchaseOrder, error)
+	SetPurchaseOrderItems(ctx context.Context, purchaseOrderID int64, partsPurchaseRequestIDs []int64) (domain.PurchaseOrder, error)
+	ReceivePurchaseOrder(ctx context.Context, purchaseOrderID int64, partsPurchaseRequestIDs []int64, receivedByUserID string) (domain.PurchaseOrder, error)
+	DeletePurchaseOrder(ctx context.Context, purchaseOrderID int64) error
+}
+
+type storeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &storeRepository{db: db}
+}
+
+const supplierColumns = `
+	supplier_id,
+	supplier_name,
+	contact_name,
+	email,
+	phone,
+	website,
+	account_number,
+	notes,
+	is_active,
+	created_at,
+	updated_at
+`
+
+func scanSupplier(row pgx.Row, item *domain.Supplier) error {
+	return row.Scan(
+		&item.SupplierID,
+		&item.SupplierName,
+		&item.ContactName,
+		&item.Email,
+		&item.Phone,
+		&item.Website,
+		&item.AccountNumber,
+		&item.Notes,
+		&item.IsActive,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+}
+
+func (r *storeRepository) ListSuppliers(ctx context.Context, query string, includeInactive bool) ([]domain.Supplier, error) {
+	clauses := make([]string, 0, 2)
+	args := make([]any, 0, 1)
+	if !includeInactive {
+		clauses = append(clauses, "is_active = true")
+	}
+	if query != "" {
+		args = append(args, "%"+query+"%")
+		clauses = append(clauses, "(supplier_name ILIKE $1 OR contact_name ILIKE $1 OR email ILIKE $1)")
+	}
+	sql := `SELECT ` + supplierColumns + ` FROM public.suppliers`
+	if len(clauses) > 0 {
+		sql += ` WHERE ` + strings.Join(clauses, " AND ")
+	}
+	sql += ` ORDER BY supplier_name ASC`
+
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Supplier, 0)
+	for rows.Next() {
+		var item domain.Supplier
+		if err := scanSupplier(rows, &item); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) GetSupplier(ctx context.Context, supplierID int64) (domain.Supplier, error) {
+	var item domain.Supplier
+	err := scanSupplier(r.db.QueryRow(ctx, `SELECT `+supplierColumns+` FROM public.suppliers WHERE supplier_id = $1`, supplierID), &item)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Supplier{}, ErrSupplierNotFound
+	}
+	return item, err
+}
+
+func (r *storeRepository) CreateSupplier(ctx context.Context, input SupplierInput) (domain.Supplier, error) {
+	if err := r.ensureSupplierNameAvailable(ctx, input.SupplierName, 0); err != nil {
+		return domain.Supplier{}, err
+	}
+	isActive := true
+	if input.IsActive != nil {
+		isActive = *input.IsActive
+	}
+
+	var item domain.Supplier
+	err := scanSupplier(r.db.QueryRow(ctx, `
+		INSERT INTO public.suppliers(
+			supplier_name,
+			contact_name,
+			email,
+			phone,
+			website,
+			account_number,
+			notes,
+			is_active
+		)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+supplierColumns,
+		input.SupplierName,
+		input.ContactName,
+		input.Email,
+		input.Phone,
+		input.Website,
+		input.AccountNumber,
+		input.Notes,
+		isActive,
+	), &item)
+	return item, err
+}
+
+func (r *storeRepository) UpdateSupplier(ctx context.Context, supplierID int64, input SupplierInput) (domain.Supplier, error) {
+	if err := r.ensureSupplierNameAvailable(ctx, input.SupplierName, supplierID); err != nil {
+		return domain.Supplier{}, err
+	}
+
+	var item domain.Supplier
+	err := scanSupplier(r.db.QueryRow(ctx, `
+		UPDATE public.suppliers
+		SET
+			supplier_name = $2,
+			contact_name = $3,
+			email = $4,
+			phone = $5,
+			website = $6,
+			account_number = $7,
+			notes = $8,
+			is_active = COALESCE($9, is_active),
+			updated_at = now()
+		WHERE supplier_id = $1
+		RETURNING `+supplierColumns,
+		supplierID,
+		input.SupplierName,
+		input.ContactName,
+		input.Email,
+		input.Phone,
+		input.Website,
+		input.AccountNumber,
+		input.Notes,
+		input.IsActive,
+	), &item)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Supplier{}, ErrSupplierNotFound
+	}
+	return item, err
+}
+
+func (r *storeRepository) DeleteSupplier(ctx context.Context, supplierID int64) error {
+	var inUse bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.purchase_orders WHERE supplier_id = $1)`, supplierID).Scan(&inUse); err != nil {
+		return err
+	}
+	if inUse {
+		return ErrSupplierInUse
+	}
+	cmd, err := r.db.Exec(ctx, `DELETE FROM public.suppliers WHERE supplier_id = $1`, supplierID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrSupplierNotFound
+	}
+	return nil
+}
+
+func (r *storeRepository) ensureSupplierNameAvailable(ctx context.Context, name string, supplierID int64) error {
+	var taken bool
+	if err := r.db.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM public.suppliers
+			WHERE LOWER(BTRIM(supplier_name)) = LOWER($1)
+				AND supplier_id <> $2
+		)
+	`, name, supplierID).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return ErrSupplierNameTaken
+	}
+	return nil
+}
+
+const purchaseOrderSelect = `
+	SELECT
+		po.purchase_order_id,
+		po.order_number,
+		po.supplier_id,
+		s.supplier_name,
+		po.status,
+		po.shipping_cost::double precision,
+		COALESCE(items.items_total, 0)::double precision,
+		po.tracking_number,
+		po.tracking_url,
+		po.notes,
+		po.ordered_at,
+		po.received_at,
+		po.created_by_user_id::text,
+		u.full_name,
+		po.created_at,
+		po.updated_at
+	FROM public.purchase_orders po
+	JOIN public.suppliers s ON s.supplier_id = po.supplier_id
+	LEFT JOIN public.users u ON u.id = po.created_by_user_id
+	LEFT JOIN LATERAL (
+		SELECT SUM(ppr.total_price) AS items_total
+		FROM public.parts_purchase_requests ppr
+		WHERE ppr.purchase_order_id = po.purchase_order_id
+	) items ON TRUE
+`
+
+func scanPurchaseOrder(row pgx.Row, item *domain.PurchaseOrder) error {
+	return row.Scan(
+		&item.PurchaseOrderID,
+		&item.OrderNumber,
+		&item.SupplierID,
+		&item.SupplierName,
+		&item.Status,
+		&item.ShippingCost,
+		&item.ItemsTotal,
+		&item.TrackingNumber,
+		&item.TrackingURL,
+		&item.Notes,
+		&item.OrderedAt,
+		&item.ReceivedAt,
+		&item.CreatedByUserID,
+		&item.CreatedByName,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+}
+
+func (r *storeRepository) ListPurchaseOrders(ctx context.Context, filters PurchaseOrderListFilters) ([]domain.PurchaseOrder, error) {
+	clauses := make([]string, 0, 2)
+	args := make([]any, 0, 2)
+	argPos := 1
+	if filters.Status != nil {
+		clauses = append(clauses, fmt.Sprintf("po.status = $%d", argPos))
+		args = append(args, *filters.Status)
+		argPos++
+	}
+	if filters.SupplierID != nil {
+		clauses = append(clauses, fmt.Sprintf("po.supplier_id = $%d", argPos))
+		args = append(args, *filters.SupplierID)
+	}
+	sql := purchaseOrderSelect
+	if len(clauses) > 0 {
+		sql += ` WHERE ` + strings.Join(clauses, " AND ")
+	}
+	sql += ` ORDER BY po.created_at DESC, po.purchase_order_id DESC`
+
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.PurchaseOrder, 0)
+	for rows.Next() {
+		var item domain.PurchaseOrder
+		if err := scanPurchaseOrder(rows, &item); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) GetPurchaseOrder(ctx context.Context, purchaseOrderID int64) (domain.PurchaseOrder, error) {
+	var item domain.PurchaseOrder
+	err := scanPurchaseOrder(r.db.QueryRow(ctx, purchaseOrderSelect+` WHERE po.purchase_order_id = $1`, purchaseOrderID), &item)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.PurchaseOrder{}, ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	items, err := r.listPurchaseOrderItems(ctx, purchaseOrderID)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	item.Items = items
+	return item, nil
+}
+
+func (r *storeRepository) listPurchaseOrderItems(ctx context.Context, purchaseOrderID int64) ([]domain.PartsPurchaseRequest, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			ppr.parts_purchase_request_id,
+			ppr.reference_id,
+			ppr.source,
+			ppr.source_url,
+			ppr.status,
+			ppr.total_price::double precision,
+			ppr.item_name,
+			ppr.quantity,
+			ppr.parts_item_preset_id,
+			ppr.supplier_id,
+			s.supplier_name,
+			ppr.purchase_order_id,
+			ppr.received_at,
+			ppr.approved_by_user_id::text,
+			ppr.approved_at,
+			ppr.created_by_user_id::text,
+			u.full_name,
+			ppr.created_at,
+			ppr.updated_at
+		FROM public.parts_purchase_requests ppr
+		LEFT JOIN public.users u ON u.id = ppr.created_by_user_id
+		LEFT JOIN public.suppliers s ON s.supplier_id = ppr.supplier_id
+		WHERE ppr.purchase_order_id = $1
+		ORDER BY ppr.reference_id ASC, ppr.parts_purchase_request_id ASC
+	`, purchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.PartsPurchaseRequest, 0)
+	for rows.Next() {
+		var item domain.PartsPurchaseRequest
+		if err := rows.Scan(
+			&item.PartsPurchaseRequestID,
+			&item.ReferenceID,
+			&item.Source,
+			&item.SourceURL,
+			&item.Status,
+			&item.TotalPrice,
+			&item.ItemName,
+			&item.Quantity,
+			&item.PartsItemPresetID,
+			&item.SupplierID,
+			&item.SupplierName,
+			&item.PurchaseOrderID,
+			&item.ReceivedAt,
+			&item.ApprovedByUserID,
+			&item.ApprovedAt,
+			&item.CreatedByUserID,
+			&item.CreatedByName,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) CreatePurchaseOrder(ctx context.Context, input CreatePurchaseOrderInput) (domain.PurchaseOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var supplierExists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.suppliers WHERE supplier_id = $1 AND is_active = true)`, input.SupplierID).Scan(&supplierExists); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	if !supplierExists {
+		return domain.PurchaseOrder{}, ErrSupplierNotFound
+	}
+
+	var purchaseOrderID int64
+	if err := tx.QueryRow(ctx, `SELECT nextval(pg_get_serial_sequence('public.purchase_orders', 'purchase_order_id'))`).Scan(&purchaseOrderID); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	orderNumber := fmt.Sprintf("PO-%05d", purchaseOrderID)
+	if input.OrderNumber != nil {
+		orderNumber = *input.OrderNumber
+	}
+	if err := ensureOrderNumberAvailableTx(ctx, tx, orderNumber, purchaseOrderID); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO public.purchase_orders(
+			purchase_order_id,
+			order_number,
+			supplier_id,
+			shipping_cost,
+			tracking_number,
+			tracking_url,
+			notes,
+			created_by_user_id
+		)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8::uuid)
+	`,
+		purchaseOrderID,
+		orderNumber,
+		input.SupplierID,
+		input.ShippingCost,
+		input.TrackingNumber,
+		input.TrackingURL,
+		input.Notes,
+		input.CreatedByUserID,
+	); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	if err := attachPartsRequestsTx(ctx, tx, purchaseOrderID, input.SupplierID, false, input.PartsPurchaseRequestIDs); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	return r.GetPurchaseOrder(ctx, purchaseOrderID)
+}
+
+func (r *storeRepository) UpdatePurchaseOrder(ctx context.Context, purchaseOrderID int64, input UpdatePurchaseOrderInput) (domain.PurchaseOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	current, err := lockPurchaseOrderTx(ctx, tx, purchaseOrderID)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	if input.OrderNumber != nil {
+		if err := ensureOrderNumberAvailableTx(ctx, tx, *input.OrderNumber, purchaseOrderID); err != nil {
+			return domain.PurchaseOrder{}, err
+		}
+	}
+
+	nextStatus := current.status
+	if input.Status != nil && *input.Status != current.status {
+		nextStatus = *input.Status
+		requests, err := lockOrderPartsRequestsTx(ctx, tx, purchaseOrderID)
+		if err != nil {
+			return domain.PurchaseOrder{}, err
+		}
+		threshold, err := partsApprovalThresholdTx(ctx, tx)
+		if err != nil {
+			return domain.PurchaseOrder{}, err
+		}
+		changes, err := planStatusChange(current.status, nextStatus, requests, threshold)
+		if err != nil {
+			return domain.PurchaseOrder{}, err
+		}
+		if err := applyPartsRequestStatusesTx(ctx, tx, changes, nextStatus == "cancelled"); err != nil {
+			return domain.PurchaseOrder{}, err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE public.purchase_orders
+		SET
+			order_number = COALESCE($2, order_number),
+			status = $3,
+			shipping_cost = COALESCE($4, shipping_cost),
+			tracking_number = CASE WHEN $5::boolean THEN $6 ELSE tracking_number END,
+			tracking_url = CASE WHEN $7::boolean THEN $8 ELSE tracking_url END,
+			notes = CASE WHEN $9::boolean THEN $10 ELSE notes END,
+			ordered_at = CASE WHEN $3 = 'ordered' AND ordered_at IS NULL THEN now() ELSE ordered_at END,
+			updated_at = now()
+		WHERE purchase_order_id = $1
+	`,
+		purchaseOrderID,
+		input.OrderNumber,
+		nextStatus,
+		input.ShippingCost,
+		input.TrackingNumber != nil,
+		input.TrackingNumber,
+		input.TrackingURL != nil,
+		input.TrackingURL,
+		input.Notes != nil,
+		input.Notes,
+	); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	return r.GetPurchaseOrder(ctx, purchaseOrderID)
+}
+
+func (r *storeRepository) SetPurchaseOrderItems(ctx context.Context, purchaseOrderID int64, partsPurchaseRequestIDs []int64) (domain.PurchaseOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	current, err := lockPurchaseOrderTx(ctx, tx, purchaseOrderID)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	if current.status == "received" || current.status == "cancelled" {
+		return domain.PurchaseOrder{}, ErrPurchaseOrderClosed
+	}
+
+	var removedReceived bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM public.parts_purchase_requests
+			WHERE purchase_order_id = $1
+				AND received_at IS NOT NULL
+				AND NOT (parts_purchase_request_id = ANY($2))
+		)
+	`, purchaseOrderID, partsPurchaseRequestIDs).Scan(&removedReceived); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	if removedReceived {
+		return domain.PurchaseOrder{}, ErrPartsRequestAlreadyReceived
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE public.parts_purchase_requests
+		SET purchase_order_id = NULL, updated_at = now()
+		WHERE purchase_order_id = $1
+			AND NOT (parts_purchase_request_id = ANY($2))
+	`, purchaseOrderID, partsPurchaseRequestIDs); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	markOrdered := current.status != "draft"
+	if err := attachPartsRequestsTx(ctx, tx, purchaseOrderID, current.supplierID, markOrdered, partsPurchaseRequestIDs); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	if current.status == "partially_received" {
+		if err := syncReceiptStatusTx(ctx, tx, purchaseOrderID); err != nil {
+			return domain.PurchaseOrder{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	return r.GetPurchaseOrder(ctx, purchaseOrderID)
+}
+
+func (r *storeRepository) ReceivePurchaseOrder(ctx context.Context, purchaseOrderID int64, partsPurchaseRequestIDs []int64, receivedByUserID string) (domain.PurchaseOrder, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	current, err := lockPurchaseOrderTx(ctx, tx, purchaseOrderID)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	requests, err := lockOrderPartsRequestsTx(ctx, tx, purchaseOrderID)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	partsPurchaseRequestIDs, nextStatus, err := planReceipt(current.status, requests, partsPurchaseRequestIDs)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE public.parts_purchase_requests
+		SET
+			status = CASE WHEN status = 'used' THEN status ELSE 'received' END,
+			received_at = now(),
+			updated_at = now()
+		WHERE purchase_order_id = $1
+			AND parts_purchase_request_id = ANY($2)
+			AND received_at IS NULL
+		RETURNING parts_purchase_request_id
+	`, purchaseOrderID, partsPurchaseRequestIDs)
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	receivedIDs, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	for _, id := range receivedIDs {
+		if err := inventory.RecordPartsRequestMovementTx(ctx, tx, id, "received", receivedByUserID); err != nil {
+			return domain.PurchaseOrder{}, err
+		}
+	}
+	if err := setReceiptStatusTx(ctx, tx, purchaseOrderID, nextStatus); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	if err := notifyPartsReceivedTx(ctx, tx, receivedIDs); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.PurchaseOrder{}, err
+	}
+	return r.GetPurchaseOrder(ctx, purchaseOrderID)
+}
+
+func (r *storeRepository) DeletePurchaseOrder(ctx context.Context, purchaseOrderID int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if _, err := lockPurchaseOrderTx(ctx, tx, purchaseOrderID); err != nil {
+		return err
+	}
+	var hasReceived bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM public.parts_purchase_requests
+			WHERE purchase_order_id = $1
+				AND received_at IS NOT NULL
+		)
+	`, purchaseOrderID).Scan(&hasReceived); err != nil {
+		return err
+	}
+	if hasReceived {
+		return ErrPurchaseOrderHasReceivedItems
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE public.parts_purchase_requests
+		SET purchase_order_id = NULL, updated_at = now()
+		WHERE purchase_order_id = $1
+	`, purchaseOrderID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM public.purchase_orders WHERE purchase_order_id = $1`, purchaseOrderID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// lockOrderPartsRequestsTx locks the requests on a purchase order so status
+// changes are planned against rows no one else can move meanwhile.
+func lockOrderPartsRequestsTx(ctx context.Context, tx pgx.Tx, purchaseOrderID int64) ([]orderPartsRequest, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT
+			parts_purchase_request_id,
+			status,
+			total_price::float8,
+			approved_total_price::float8,
+			received_at IS NOT NULL
+		FROM public.parts_purchase_requests
+		WHERE purchase_order_id = $1
+		ORDER BY parts_purchase_request_id ASC
+		FOR UPDATE
+	`, purchaseOrderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]orderPartsRequest, 0)
+	for rows.Next() {
+		var item orderPartsRequest
+		if err := rows.Scan(&item.PartsPurchaseRequestID, &item.Status, &item.TotalPrice, &item.ApprovedTotalPrice, &item.Received); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+// partsApprovalThresholdTx returns nil when no threshold is configured.
+func partsApprovalThresholdTx(ctx context.Context, tx pgx.Tx) (*float64, error) {
+	var threshold *float64
+	err := tx.QueryRow(ctx, `
+		SELECT setting_value::numeric::float8
+		FROM public.app_settings
+		WHERE setting_key = $1
+	`, workorders.AppSettingPartsApprovalThreshold).Scan(&threshold)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return threshold, err
+}
+
+// applyPartsRequestStatusesTx writes planned request statuses, detaching the
+// requests from their purchase order when detach is set.
+func applyPartsRequestStatusesTx(ctx context.Context, tx pgx.Tx, statuses map[int64]string, detach bool) error {
+	if len(statuses) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(statuses))
+	values := make([]string, 0, len(statuses))
+	for id, status := range statuses {
+		ids = append(ids, id)
+		values = append(values, status)
+	}
+	_, err := tx.Exec(ctx, `
+		UPDATE public.parts_purchase_requests ppr
+		SET
+			status = planned.status,
+			purchase_order_id = CASE WHEN $3::boolean THEN NULL ELSE ppr.purchase_order_id END,
+			updated_at = now()
+		FROM unnest($1::bigint[], $2::text[]) AS planned(parts_purchase_request_id, status)
+		WHERE ppr.parts_purchase_request_id = planned.parts_purchase_request_id
+	`, ids, values, detach)
+	return err
+}
+
+type lockedPurchaseOrder struct {
+	status     string
+	supplierID int64
+}
+
+func lockPurchaseOrderTx(ctx context.Context, tx pgx.Tx, purchaseOrderID int64) (lockedPurchaseOrder, error) {
+	var locked lockedPurchaseOrder
+	err := tx.QueryRow(ctx, `
+		SELECT status, supplier_id
+		FROM public.purchase_orders
+		WHERE purchase_order_id = $1
+		FOR UPDATE
+	`, purchaseOrderID).Scan(&locked.status, &locked.supplierID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return lockedPurchaseOrder{}, ErrPurchaseOrderNotFound
+	}
+	return locked, err
+}
+
+func ensureOrderNumberAvailableTx(ctx context.Context, tx pgx.Tx, orderNumber string, purchaseOrderID int64) error {
+	var taken bool
+	if err := tx.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM public.purchase_orders
+			WHERE order_number = $1
+				AND purchase_order_id <> $2
+		)
+	`, orderNumber, purchaseOrderID).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return ErrOrderNumberTaken
+	}
+	return nil
+}
+
+// ensurePartsRequestsApprovedTx returns ErrPartsRequestNeedsApproval when a
+// draft or waiting_approval request on the purchase order, or among
+// partsPurchaseRequestIDs, is above the approval threshold without an
+// approval covering its total. Those are the requests ordering would move to
+// ordered.
+func ensurePartsRequestsApprovedTx(ctx context.Context, tx pgx.Tx, purchaseOrderID int64, partsPurchaseRequestIDs []int64) error {
+	var unapprovedCount int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM public.parts_purchase_requests ppr
+		JOIN public.app_settings s ON s.setting_key = $2
+		WHERE (ppr.purchase_order_id = $1 OR ppr.parts_purchase_request_id = ANY($3::bigint[]))
+			AND ppr.status IN ('draft', 'waiting_approval')
+			AND ppr.total_price > s.setting_value::numeric
+			AND (ppr.approved_total_price IS NULL OR ppr.total_price > ppr.approved_total_price)
+	`, purchaseOrderID, workorders.AppSettingPartsApprovalThreshold, partsPurchaseRequestIDs).Scan(&unapprovedCount); err != nil {
+		return err
+	}
+	if unapprovedCount > 0 {
+		return ErrPartsRequestNeedsApproval
+	}
+	return nil
+}
+
+func attachPartsRequestsTx(ctx context.Context, tx pgx.Tx, purchaseOrderID, supplierID int64, markOrdered bool, partsPurchaseRequestIDs []int64) error {
+	if len(partsPurchaseRequestIDs) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT parts_purchase_request_id, purchase_order_id, status, received_at IS NOT NULL
+		FROM public.parts_purchase_requests
+		WHERE parts_purchase_request_id = ANY($1)
+		FOR UPDATE
+	`, partsPurchaseRequestIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	found := 0
+	for rows.Next() {
+		var id int64
+		var currentOrderID *int64
+		var status string
+		var received bool
+		if err := rows.Scan(&id, &currentOrderID, &status, &received); err != nil {
+			return err
+		}
+		found++
+		if currentOrderID != nil && *currentOrderID == purchaseOrderID {
+			continue
+		}
+		if currentOrderID != nil || received || status == "received" || status == "used" {
+			return ErrPartsRequestUnavailable
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	if found != len(partsPurchaseRequestIDs) {
+		return ErrPartsRequestNotFound
+	}
+	// Attaching to a purchase order that has gone out orders the request, so
+	// it needs the same approval as ordering a draft purchase order.
+	if markOrdered {
+		if err := ensurePartsRequestsApprovedTx(ctx, tx, purchaseOrderID, partsPurchaseRequestIDs); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE public.parts_purchase_requests
+		SET
+			purchase_order_id = $1,
+			supplier_id = $2,
+			status = CASE
+				WHEN $3::boolean AND status IN ('draft', 'waiting_approval') THEN 'ordered'
+				ELSE status
+			END,
+			updated_at = now()
+		WHERE parts_purchase_request_id = ANY($4)
+			AND purchase_order_id IS DISTINCT FROM $1
+	`, purchaseOrderID, supplierID, markOrdered, partsPurchaseRequestIDs)
+	return err
+}
+
+func syncReceiptStatusTx(ctx context.Context, tx pgx.Tx, purchaseOrderID int64) error {
+	requests, err := lockOrderPartsRequestsTx(ctx, tx, purchaseOrderID)
+	if err != nil {
+		return err
+	}
+	return setReceiptStatusTx(ctx, tx, purchaseOrderID, receiptStatus(requests))
+}
+
+func setReceiptStatusTx(ctx context.Context, tx pgx.Tx, purchaseOrderID int64, status string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE public.purchase_orders
+		SET
+			status = $2,
+			received_at = CASE WHEN $2 = 'received' THEN now() ELSE NULL END,
+			updated_at = now()
+		WHERE purchase_order_id = $1
+	`, purchaseOrderID, status)
+	return err
+}
+
+func notifyPartsReceivedTx(ctx context.Context, tx pgx.Tx, partsPurchaseRequestIDs []int64) error {
+	if len(partsPurchaseRequestIDs) == 0 {
+		return nil
+	}
+
+	// Technicians are assigned as workers; they are notified through the user
+	// account linked to the worker, since notifications are read per user.
+	// The requester is notified too, once even when also assigned.
+	_, err := tx.Exec(ctx, `
+		INSERT INTO public.notifications(recipient_user_id, notification_type, reference_id, title, body)
+		SELECT
+			recipient.user_id,
+			'parts_received',
+			ppr.reference_id,
+			COALESCE('Parts received for job #' || ppr.reference_id, 'Parts received for stock'),
+			ppr.item_name || ' x' || ppr.quantity
+		FROM public.parts_purchase_requests ppr
+		CROSS JOIN LATERAL (
+			SELECT u.id AS user_id
+			FROM public.work_orders wo
+			JOIN public.users u ON u.worker_id = ANY(wo.worker_ids)
+			WHERE wo.reference_id = ppr.reference_id
+				AND u.deleted_at IS NULL
+			UNION
+			SELECT ppr.created_by_user_id
+			WHERE ppr.created_by_user_id IS NOT NULL
+		) recipient
+		WHERE ppr.parts_purchase_request_id = ANY($1)
+	`, partsPurchaseRequestIDs)
+	return err
+}
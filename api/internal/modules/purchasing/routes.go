@@ -0,0 +1,41 @@
+package purchasing
+
+import (
+	"humphreys/api/internal/middleware"
+	"humphreys/api/internal/permissions"
+)
+
+const (
+	permSuppliersRead        = "suppliers:read"
+	permSuppliersCreate      = "suppliers:create"
+	permSuppliersUpdate      = "suppliers:update"
+	permSuppliersDelete      = "suppliers:delete"
+	permPurchaseOrdersRead   = "purchase_orders:read"
+	permPurchaseOrdersCreate = "purchase_orders:create"
+	permPurchaseOrdersUpdate = "purchase_orders:update"
+	permPurchaseOrdersDelete = "purchase_orders:delete"
+)
+
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("suppliers", "Supplier directory for parts purchasing", permissions.StandardActions()...)
+	reg.Register("purchase_orders", "Purchase orders grouping parts purchase requests", permissions.StandardActions()...)
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
+	suppliers := authed.Group("/suppliers")
+	suppliers.GET("", middleware.RequirePermission(permSuppliersRead), h.ListSuppliers)
+	suppliers.POST("", middleware.RequirePermission(permSuppliersCreate), h.CreateSupplier)
+	suppliers.GET("/:supplier_id", middleware.RequirePermission(permSuppliersRead), h.GetSupplier)
+	suppliers.PATCH("/:supplier_id", middleware.RequirePermission(permSuppliersUpdate), h.UpdateSupplier)
+	suppliers.DELETE("/:supplier_id", middleware.RequirePermission(permSuppliersDelete), h.DeleteSupplier)
+
+	orders := authed.Group("/purchase-orders")
+	orders.GET("", middleware.RequirePermission(permPurchaseOrdersRead), h.ListPurchaseOrders)
+	orders.POST("", middleware.RequirePermission(permPurchaseOrdersCreate), h.CreatePurchaseOrder)
+	orders.GET("/:purchase_order_id", middleware.RequirePermission(permPurchaseOrdersRead), h.GetPurchaseOrder)
+	orders.PATCH("/:purchase_order_id", middleware.RequirePermission(permPurchaseOrdersUpdate), h.UpdatePurchaseOrder)
+	orders.DELETE("/:purchase_order_id", middleware.RequirePermission(permPurchaseOrdersDelete), h.DeletePurchaseOrder)
+	orders.PATCH("/:purchase_order_id/items", middleware.RequirePermission(permPurchaseOrdersUpdate), h.SetPurchaseOrderItems)
+	orders.POST("/:purchase_order_id/receive", middleware.RequirePermission(permPurchaseOrdersUpdate), h.ReceivePurchaseOrder)
+}
@@ -0,0 +1,326 @@
+package purchasing
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"humphreys/api/internal/domain"
+)
+
+var ErrSupplierNotFound = errors.New("supplier not found")
+var ErrInvalidSupplierName = errors.New("supplier name is required")
+var ErrSupplierNameTaken = errors.New("supplier name already exists")
+var ErrSupplierInUse = errors.New("supplier has purchase orders and cannot be deleted")
+var ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+var ErrOrderNumberTaken = errors.New("order number already exists")
+var ErrInvalidShippingCost = errors.New("shipping cost must be zero or greater")
+var ErrInvalidPurchaseOrderStatus = errors.New("purchase order status must be draft, ordered, or cancelled")
+var ErrInvalidPurchaseOrderTransition = errors.New("purchase order cannot move to that status")
+var ErrPurchaseOrderClosed = errors.New("purchase order is already received or cancelled")
+var ErrPurchaseOrderNotOrdered = errors.New("purchase order must be ordered before items are received")
+var ErrPurchaseOrderEmpty = errors.New("purchase order has no parts requests")
+var ErrPurchaseOrderHasReceivedItems = errors.New("purchase order has received items and cannot be deleted")
+var ErrPartsRequestNotFound = errors.New("parts purchase request not found")
+var ErrPartsRequestUnavailable = errors.New("parts purchase request is already on another purchase order or has been received")
+var ErrPartsRequestNotOnOrder = errors.New("parts purchase request is not on this purchase order")
+var ErrPartsRequestAlreadyReceived = errors.New("received parts purchase requests cannot be removed from the order")
+var ErrPartsRequestNeedsApproval = errors.New("purchase order has parts requests above the spending limit that have not been approved")
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+type SupplierInput struct {
+	SupplierName  string
+	ContactName   *string
+	Email         *string
+	Phone         *string
+	Website       *string
+	AccountNumber *string
+	Notes         *string
+	IsActive      *bool
+}
+
+type PurchaseOrderListFilters struct {
+	Status     *string
+	SupplierID *int64
+}
+
+type CreatePurchaseOrderInput struct {
+	OrderNumber             *string
+	SupplierID              int64
+	ShippingCost            float64
+	TrackingNumber          *string
+	TrackingURL             *string
+	Notes                   *string
+	PartsPurchaseRequestIDs []int64
+	CreatedByUserID         string
+}
+
+type UpdatePurchaseOrderInput struct {
+	OrderNumber    *string
+	Status         *string
+	ShippingCost   *float64
+	TrackingNumber *string
+	TrackingURL    *string
+	Notes          *string
+}
+
+type ReceivePurchaseOrderInput struct {
+	PartsPurchaseRequestIDs []int64
+	ReceivedByUserID        string
+}
+
+func (s *Service) ListSuppliers(ctx context.Context, query string, includeInactive bool) ([]domain.Supplier, error) {
+	return s.repo.ListSuppliers(ctx, strings.TrimSpace(query), includeInactive)
+}
+
+func (s *Service) GetSupplier(ctx context.Context, supplierID int64) (domain.Supplier, error) {
+	return s.repo.GetSupplier(ctx, supplierID)
+}
+
+func (s *Service) CreateSupplier(ctx context.Context, input SupplierInput) (domain.Supplier, error) {
+	normalized, err := normalizeSupplierInput(input)
+	if err != nil {
+		return domain.Supplier{}, err
+	}
+	return s.repo.CreateSupplier(ctx, normalized)
+}
+
+func (s *Service) UpdateSupplier(ctx context.Context, supplierID int64, input SupplierInput) (domain.Supplier, error) {
+	normalized, err := normalizeSupplierInput(input)
+	if err != nil {
+		return domain.Supplier{}, err
+	}
+	return s.repo.UpdateSupplier(ctx, supplierID, normalized)
+}
+
+func (s *Service) DeleteSupplier(ctx context.Context, supplierID int64) error {
+	return s.repo.DeleteSupplier(ctx, supplierID)
+}
+
+func (s *Service) ListPurchaseOrders(ctx context.Context, filters PurchaseOrderListFilters) ([]domain.PurchaseOrder, error) {
+	if filters.Status != nil {
+		status := strings.TrimSpace(strings.ToLower(*filters.Status))
+		if status == "" {
+			filters.Status = nil
+		} else {
+			filters.Status = &status
+		}
+	}
+	return s.repo.ListPurchaseOrders(ctx, filters)
+}
+
+func (s *Service) GetPurchaseOrder(ctx context.Context, purchaseOrderID int64) (domain.PurchaseOrder, error) {
+	return s.repo.GetPurchaseOrder(ctx, purchaseOrderID)
+}
+
+func (s *Service) CreatePurchaseOrder(ctx context.Context, input CreatePurchaseOrderInput) (domain.PurchaseOrder, error) {
+	if input.SupplierID <= 0 {
+		return domain.PurchaseOrder{}, ErrSupplierNotFound
+	}
+	if input.ShippingCost < 0 {
+		return domain.PurchaseOrder{}, ErrInvalidShippingCost
+	}
+	input.OrderNumber = trimStringPtr(input.OrderNumber)
+	input.TrackingNumber = trimStringPtr(input.TrackingNumber)
+	input.TrackingURL = trimStringPtr(input.TrackingURL)
+	input.Notes = trimStringPtr(input.Notes)
+	input.PartsPurchaseRequestIDs = uniqueIDs(input.PartsPurchaseRequestIDs)
+	return s.repo.CreatePurchaseOrder(ctx, input)
+}
+
+func (s *Service) UpdatePurchaseOrder(ctx context.Context, purchaseOrderID int64, input UpdatePurchaseOrderInput) (domain.PurchaseOrder, error) {
+	if input.ShippingCost != nil && *input.ShippingCost < 0 {
+		return domain.PurchaseOrder{}, ErrInvalidShippingCost
+	}
+	if input.Status != nil {
+		status := strings.TrimSpace(strings.ToLower(*input.Status))
+		if status != "draft" && status != "ordered" && status != "cancelled" {
+			return domain.PurchaseOrder{}, ErrInvalidPurchaseOrderStatus
+		}
+		input.Status = &status
+	}
+	input.OrderNumber = trimStringPtr(input.OrderNumber)
+	input.TrackingNumber = trimStringPtr(input.TrackingNumber)
+	input.TrackingURL = trimStringPtr(input.TrackingURL)
+	input.Notes = trimStringPtr(input.Notes)
+	return s.repo.UpdatePurchaseOrder(ctx, purchaseOrderID, input)
+}
+
+func (s *Service) SetPurchaseOrderItems(ctx context.Context, purchaseOrderID int64, partsPurchaseRequestIDs []int64) (domain.PurchaseOrder, error) {
+	return s.repo.SetPurchaseOrderItems(ctx, purchaseOrderID, uniqueIDs(partsPurchaseRequestIDs))
+}
+
+func (s *Service) ReceivePurchaseOrder(ctx context.Context, purchaseOrderID int64, input ReceivePurchaseOrderInput) (domain.PurchaseOrder, error) {
+	return s.repo.ReceivePurchaseOrder(ctx, purchaseOrderID, uniqueIDs(input.PartsPurchaseRequestIDs), input.ReceivedByUserID)
+}
+
+func (s *Service) DeletePurchaseOrder(ctx context.Context, purchaseOrderID int64) error {
+	return s.repo.DeletePurchaseOrder(ctx, purchaseOrderID)
+}
+
+// orderPartsRequest is a parts request on a purchase order, as the status
+// rules below see it.
+type orderPartsRequest struct {
+	PartsPurchaseRequestID int64
+	Status                 string
+	TotalPrice             float64
+	ApprovedTotalPrice     *float64
+	Received               bool
+}
+
+// needsApproval reports a request above the approval threshold that no
+// approval covers. A nil threshold means no approval is required.
+func (r orderPartsRequest) needsApproval(threshold *float64) bool {
+	if threshold == nil || r.TotalPrice <= *threshold {
+		return false
+	}
+	return r.ApprovedTotalPrice == nil || r.TotalPrice > *r.ApprovedTotalPrice
+}
+
+// planStatusChange validates moving a purchase order from current to next and
+// returns the statuses its requests move to, keyed by request ID. Requests
+// that keep their status are left out; on cancel, every unreceived request is
+// included because it is detached from the order.
+func planStatusChange(current, next string, requests []orderPartsRequest, threshold *float64) (map[int64]string, error) {
+	changes := make(map[int64]string)
+	switch {
+	case current == next:
+		return changes, nil
+	case current == "draft" && next == "ordered":
+		if len(requests) == 0 {
+			return nil, ErrPurchaseOrderEmpty
+		}
+		for _, request := range requests {
+			if request.Status != "draft" && request.Status != "waiting_approval" {
+				continue
+			}
+			if request.needsApproval(threshold) {
+				return nil, ErrPartsRequestNeedsApproval
+			}
+			changes[request.PartsPurchaseRequestID] = "ordered"
+		}
+		return changes, nil
+	case next == "cancelled" && current != "received":
+		// Unreceived requests go back to the pool so they can be ordered
+		// elsewhere, as drafts again unless they still need approval.
+		for _, request := range requests {
+			if request.Received {
+				continue
+			}
+			status := request.Status
+			if status == "ordered" {
+				status = "draft"
+				if request.needsApproval(threshold) {
+					status = "waiting_approval"
+				}
+			}
+			changes[request.PartsPurchaseRequestID] = status
+		}
+		return changes, nil
+	default:
+		return nil, ErrInvalidPurchaseOrderTransition
+	}
+}
+
+// planReceipt returns the requests to mark received and the purchase order's
+// status afterwards. No IDs receives everything still outstanding.
+func planReceipt(current string, requests []orderPartsRequest, partsPurchaseRequestIDs []int64) ([]int64, string, error) {
+	if current != "ordered" && current != "partially_received" {
+		return nil, "", ErrPurchaseOrderNotOrdered
+	}
+
+	byID := make(map[int64]orderPartsRequest, len(requests))
+	for _, request := range requests {
+		byID[request.PartsPurchaseRequestID] = request
+	}
+	receiving := make(map[int64]struct{})
+	if len(partsPurchaseRequestIDs) == 0 {
+		for _, request := range requests {
+			if !request.Received {
+				receiving[request.PartsPurchaseRequestID] = struct{}{}
+			}
+		}
+		if len(receiving) == 0 {
+			return nil, "", ErrPurchaseOrderEmpty
+		}
+	} else {
+		for _, id := range partsPurchaseRequestIDs {
+			request, ok := byID[id]
+			if !ok {
+				return nil, "", ErrPartsRequestNotOnOrder
+			}
+			if !request.Received {
+				receiving[id] = struct{}{}
+			}
+		}
+	}
+
+	received := make([]int64, 0, len(receiving))
+	after := make([]orderPartsRequest, 0, len(requests))
+	for _, request := range requests {
+		if _, ok := receiving[request.PartsPurchaseRequestID]; ok {
+			received = append(received, request.PartsPurchaseRequestID)
+			request.Received = true
+		}
+		after = append(after, request)
+	}
+	return received, receiptStatus(after), nil
+}
+
+// receiptStatus is "received" once every request on the order has arrived.
+func receiptStatus(requests []orderPartsRequest) string {
+	for _, request := range requests {
+		if !request.Received {
+			return "partially_received"
+		}
+	}
+	return "received"
+}
+
+func normalizeSupplierInput(input SupplierInput) (SupplierInput, error) {
+	input.SupplierName = strings.TrimSpace(input.SupplierName)
+	if input.SupplierName == "" {
+		return SupplierInput{}, ErrInvalidSupplierName
+	}
+	input.ContactName = trimStringPtr(input.ContactName)
+	input.Email = trimStringPtr(input.Email)
+	input.Phone = trimStringPtr(input.Phone)
+	input.Website = trimStringPtr(input.Website)
+	input.AccountNumber = trimStringPtr(input.AccountNumber)
+	input.Notes = trimStringPtr(input.Notes)
+	return input, nil
+}
+
+func trimStringPtr(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
+
+func uniqueIDs(ids []int64) []int64 {
+	seen := make(map[int64]struct{}, len(ids))
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
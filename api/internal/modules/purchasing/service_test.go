@@ -0,0 +1,124 @@
+package purchasing
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlanStatusChangeOrdersDraftRequests(t *testing.T) {
+	threshold := 100.0
+	approved := 150.0
+	requests := []orderPartsRequest{
+		{PartsPurchaseRequestID: 1, Status: "draft", TotalPrice: 40},
+		{PartsPurchaseRequestID: 2, Status: "waiting_approval", TotalPrice: 150, ApprovedTotalPrice: &approved},
+		{PartsPurchaseRequestID: 3, Status: "used", TotalPrice: 20},
+	}
+
+	changes, err := planStatusChange("draft", "ordered", requests, &threshold)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[int64]string{1: "ordered", 2: "ordered"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("expected %v, got %v", want, changes)
+	}
+}
+
+func TestPlanStatusChangeBlocksOrderingUnapprovedOrEmptyOrders(t *testing.T) {
+	threshold := 100.0
+	approved := 120.0
+	requests := []orderPartsRequest{
+		{PartsPurchaseRequestID: 1, Status: "draft", TotalPrice: 40},
+		{PartsPurchaseRequestID: 2, Status: "waiting_approval", TotalPrice: 150, ApprovedTotalPrice: &approved},
+	}
+
+	if _, err := planStatusChange("draft", "ordered", requests, &threshold); !errors.Is(err, ErrPartsRequestNeedsApproval) {
+		t.Fatalf("expected ErrPartsRequestNeedsApproval, got %v", err)
+	}
+	if _, err := planStatusChange("draft", "ordered", requests, nil); err != nil {
+		t.Fatalf("expected no approval without a threshold, got %v", err)
+	}
+	if _, err := planStatusChange("draft", "ordered", nil, &threshold); !errors.Is(err, ErrPurchaseOrderEmpty) {
+		t.Fatalf("expected ErrPurchaseOrderEmpty, got %v", err)
+	}
+}
+
+func TestPlanStatusChangeCancelReturnsUnreceivedRequestsToThePool(t *testing.T) {
+	threshold := 100.0
+	approved := 150.0
+	requests := []orderPartsRequest{
+		{PartsPurchaseRequestID: 1, Status: "ordered", TotalPrice: 40},
+		{PartsPurchaseRequestID: 2, Status: "ordered", TotalPrice: 200, ApprovedTotalPrice: &approved},
+		{PartsPurchaseRequestID: 3, Status: "ordered", TotalPrice: 150, ApprovedTotalPrice: &approved},
+		{PartsPurchaseRequestID: 4, Status: "received", TotalPrice: 30, Received: true},
+	}
+
+	changes, err := planStatusChange("partially_received", "cancelled", requests, &threshold)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[int64]string{1: "draft", 2: "waiting_approval", 3: "draft"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("expected %v, got %v", want, changes)
+	}
+}
+
+func TestPlanStatusChangeRejectsInvalidTransitions(t *testing.T) {
+	cases := []struct {
+		current string
+		next    string
+	}{
+		{current: "received", next: "cancelled"},
+		{current: "ordered", next: "draft"},
+		{current: "cancelled", next: "ordered"},
+		{current: "partially_received", next: "ordered"},
+	}
+
+	for _, tc := range cases {
+		if _, err := planStatusChange(tc.current, tc.next, nil, nil); !errors.Is(err, ErrInvalidPurchaseOrderTransition) {
+			t.Fatalf("%s -> %s: expected ErrInvalidPurchaseOrderTransition, got %v", tc.current, tc.next, err)
+		}
+	}
+}
+
+func TestPlanReceiptPartiallyReceivesSelectedRequests(t *testing.T) {
+	requests := []orderPartsRequest{
+		{PartsPurchaseRequestID: 1, Status: "ordered"},
+		{PartsPurchaseRequestID: 2, Status: "ordered"},
+		{PartsPurchaseRequestID: 3, Status: "ordered"},
+	}
+
+	received, status, err := planReceipt("ordered", requests, []int64{2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(received, []int64{2}) || status != "partially_received" {
+		t.Fatalf("expected [2] partially_received, got %v %s", received, status)
+	}
+
+	requests[1].Received = true
+	received, status, err = planReceipt("partially_received", requests, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(received, []int64{1, 3}) || status != "received" {
+		t.Fatalf("expected [1 3] received, got %v %s", received, status)
+	}
+}
+
+func TestPlanReceiptRejectsInvalidReceipts(t *testing.T) {
+	requests := []orderPartsRequest{
+		{PartsPurchaseRequestID: 1, Status: "received", Received: true},
+	}
+
+	if _, _, err := planReceipt("draft", requests, nil); !errors.Is(err, ErrPurchaseOrderNotOrdered) {
+		t.Fatalf("expected ErrPurchaseOrderNotOrdered, got %v", err)
+	}
+	if _, _, err := planReceipt("ordered", requests, []int64{9}); !errors.Is(err, ErrPartsRequestNotOnOrder) {
+		t.Fatalf("expected ErrPartsRequestNotOnOrder, got %v", err)
+	}
+	if _, _, err := planReceipt("partially_received", requests, nil); !errors.Is(err, ErrPurchaseOrderEmpty) {
+		t.Fatalf("expected ErrPurchaseOrderEmpty, got %v", err)
+	}
+}
@@ -25,6 +25,7 @@ func NewWithService(service *Service) *Handler {
 type rolePayload struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description"`
+	MFARequired *bool  `json:"mfa_required"`
 }
 
 type rolePermissionsPayload struct {
@@ -46,7 +47,7 @@ func (h *Handler) CreateRole(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
-	role, err := h.service.CreateRole(c.Request.Context(), req.Name, req.Description)
+	role, err := h.service.CreateRole(c.Request.Context(), req.Name, req.Description, req.MFARequired)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -69,7 +70,7 @@ func (h *Handler) UpdateRole(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
-	role, err := h.service.UpdateRole(c.Request.Context(), c.Param("id"), req.Name, req.Description)
+	role, err := h.service.UpdateRole(c.Request.Context(), c.Param("id"), req.Name, req.Description, req.MFARequired)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -102,3 +103,115 @@ func (h *Handler) SetRolePermissions(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, role)
 }
+
+type cloneRolePayload struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+type roleTemplatePayload struct {
+	TemplateKey string `json:"template_key" binding:"required"`
+}
+
+func (h *Handler) ListTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"items": h.service.ListTemplates()})
+}
+
+func (h *Handler) CloneRole(c *gin.Context) {
+	var req cloneRolePayload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	role, err := h.service.CloneRole(c.Request.Context(), c.Param("id"), req.Name, req.Description)
+	if err != nil {
+		writeRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, role)
+}
+
+func (h *Handler) ExportRole(c *gin.Context) {
+	def, err := h.service.ExportRole(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, def)
+}
+
+func (h *Handler) ImportRole(c *gin.Context) {
+	var req RoleDefinition
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	role, err := h.service.ImportRole(c.Request.Context(), req)
+	if err != nil {
+		writeRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, role)
+}
+
+func (h *Handler) ApplyTemplate(c *gin.Context) {
+	var req roleTemplatePayload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	role, err := h.service.ApplyTemplate(c.Request.Context(), c.Param("id"), req.TemplateKey)
+	if err != nil {
+		writeRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+func (h *Handler) ReapplyTemplate(c *gin.Context) {
+	roles, err := h.service.ReapplyTemplate(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		writeRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": roles})
+}
+
+// DiffRole compares a role with another role (?role_id=) or with a built-in
+// template (?template=).
+func (h *Handler) DiffRole(c *gin.Context) {
+	otherID := c.Query("role_id")
+	templateKey := c.Query("template")
+	if (otherID == "") == (templateKey == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pass exactly one of role_id or template"})
+		return
+	}
+	var (
+		diff RoleDiff
+		err  error
+	)
+	if otherID != "" {
+		diff, err = h.service.DiffRoles(c.Request.Context(), c.Param("id"), otherID)
+	} else {
+		diff, err = h.service.DiffRoleWithTemplate(c.Request.Context(), c.Param("id"), templateKey)
+	}
+	if err != nil {
+		writeRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+func writeRoleError(c *gin.Context, err error) {
+	var unknown *UnknownPermissionsError
+	switch {
+	case errors.Is(err, ErrRoleNotFound), errors.Is(err, ErrTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRoleNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.As(err, &unknown):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "unknown_permissions": unknown.Codes})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
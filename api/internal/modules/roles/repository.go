@@ -2,21 +2,25 @@ package roles
 
 import (
 	"context"
-	"errors"
 	"strings"
 
 	"humphreys/api/internal/domain"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Repository interface {
 	ListRoles(ctx context.Context) ([]domain.Role, error)
-	CreateRole(ctx context.Context, name, description string) (domain.Role, error)
+	CreateRole(ctx context.Context, name, description string, mfaRequired *bool) (domain.Role, error)
 	GetRole(ctx context.Context, id string) (domain.Role, error)
-	UpdateRole(ctx context.Context, id, name, description string) (domain.Role, error)
+	UpdateRole(ctx context.Context, id, name, description string, mfaRequired *bool) (domain.Role, error)
 	DeleteRole(ctx context.Context, id string) error
 	ReplaceRolePermissions(ctx context.Context, roleID string, permissionIDs []string) error
+	ResolvePermissionCodes(ctx context.Context, codes []string) (ids []string, unknown []string, err error)
+	CreateRoleWithPermissions(ctx context.Context, def RoleDefinition, templateKey *string, permissionIDs []string) (domain.Role, error)
+	ApplyRoleTemplate(ctx context.Context, roleID, templateKey string, permissionIDs []string) error
+	ListRoleIDsByTemplate(ctx context.Context, templateKey string) ([]string, error)
 }
 
 type storeRepository struct {
@@ -28,7 +32,7 @@ func NewRepository(db *pgxpool.Pool) Repository {
 }
 
 func (r *storeRepository) ListRoles(ctx context.Context) ([]domain.Role, error) {
-	rows, err := r.db.Query(ctx, `SELECT id, name, description, is_system FROM roles ORDER BY name`)
+	rows, err := r.db.Query(ctx, `SELECT id, name, description, is_system, mfa_required, template_key FROM roles ORDER BY name`)
 	if err != nil {
 		return nil, err
 	}
@@ -37,7 +41,7 @@ func (r *storeRepository) ListRoles(ctx context.Context) ([]domain.Role, error)
 	out := make([]domain.Role, 0)
 	for rows.Next() {
 		var role domain.Role
-		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem); err != nil {
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem, &role.MFARequired, &role.TemplateKey); err != nil {
 			return nil, err
 		}
 		perms, err := r.listPermissionsByRoleID(ctx, role.ID)
@@ -50,9 +54,9 @@ func (r *storeRepository) ListRoles(ctx context.Context) ([]domain.Role, error)
 	return out, rows.Err()
 }
 
-func (r *storeRepository) CreateRole(ctx context.Context, name, description string) (domain.Role, error) {
+func (r *storeRepository) CreateRole(ctx context.Context, name, description string, mfaRequired *bool) (domain.Role, error) {
 	var id string
-	err := r.db.QueryRow(ctx, `INSERT INTO roles(name, description, is_system) VALUES($1,$2,FALSE) RETURNING id`, name, description).Scan(&id)
+	err := r.db.QueryRow(ctx, `INSERT INTO roles(name, description, is_system, mfa_required) VALUES($1,$2,FALSE,COALESCE($3, FALSE)) RETURNING id`, name, description, mfaRequired).Scan(&id)
 	if err != nil {
 		return domain.Role{}, err
 	}
@@ -61,7 +65,7 @@ func (r *storeRepository) CreateRole(ctx context.Context, name, description stri
 
 func (r *storeRepository) GetRole(ctx context.Context, id string) (domain.Role, error) {
 	var role domain.Role
-	err := r.db.QueryRow(ctx, `SELECT id, name, description, is_system FROM roles WHERE id=$1`, id).Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem)
+	err := r.db.QueryRow(ctx, `SELECT id, name, description, is_system, mfa_required, template_key FROM roles WHERE id=$1`, id).Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem, &role.MFARequired, &role.TemplateKey)
 	if err != nil {
 		return role, err
 	}
@@ -73,8 +77,8 @@ func (r *storeRepository) GetRole(ctx context.Context, id string) (domain.Role,
 	return role, nil
 }
 
-func (r *storeRepository) UpdateRole(ctx context.Context, id, name, description string) (domain.Role, error) {
-	_, err := r.db.Exec(ctx, `UPDATE roles SET name=$1, description=$2, updated_at=now() WHERE id=$3`, name, description, id)
+func (r *storeRepository) UpdateRole(ctx context.Context, id, name, description string, mfaRequired *bool) (domain.Role, error) {
+	_, err := r.db.Exec(ctx, `UPDATE roles SET name=$1, description=$2, mfa_required=COALESCE($4, mfa_required), updated_at=now() WHERE id=$3`, name, description, id, mfaRequired)
 	if err != nil {
 		return domain.Role{}, err
 	}
@@ -82,35 +86,178 @@ func (r *storeRepository) UpdateRole(ctx context.Context, id, name, description
 }
 
 func (r *storeRepository) DeleteRole(ctx context.Context, id string) error {
-	_, err := r.db.Exec(ctx, `DELETE FROM roles WHERE id=$1 AND is_system=FALSE`, id)
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Members must be bumped before the cascade removes their user_roles rows.
+	if err := bumpRoleMembersTx(ctx, tx, id); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM roles WHERE id=$1 AND is_system=FALSE`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	return tx.Commit(ctx)
+}
+
+// bumpRoleMembersTx makes the access tokens of everyone holding the role stale.
+func bumpRoleMembersTx(ctx context.Context, tx pgx.Tx, roleID string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE users
+		SET permissions_version=permissions_version+1
+		WHERE id IN (SELECT user_id FROM user_roles WHERE role_id=$1)
+	`, roleID)
 	return err
 }
 
 func (r *storeRepository) ReplaceRolePermissions(ctx context.Context, roleID string, permissionIDs []string) error {
-	var roleName string
-	if err := r.db.QueryRow(ctx, `SELECT name FROM roles WHERE id=$1`, roleID).Scan(&roleName); err != nil {
+	if err := r.ensurePermissionsEditable(ctx, roleID); err != nil {
 		return err
 	}
-	if strings.EqualFold(roleName, "owner") {
-		return errors.New("owner permissions cannot be changed")
-	}
 
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
+	if err := replaceRolePermissionsTx(ctx, tx, roleID, permissionIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *storeRepository) ensurePermissionsEditable(ctx context.Context, roleID string) error {
+	var roleName string
+	if err := r.db.QueryRow(ctx, `SELECT name FROM roles WHERE id=$1`, roleID).Scan(&roleName); err != nil {
+		return err
+	}
+	if strings.EqualFold(roleName, "owner") {
+		return ErrOwnerPermissionsLocked
+	}
+	return nil
+}
+
+func replaceRolePermissionsTx(ctx context.Context, tx pgx.Tx, roleID string, permissionIDs []string) error {
 	if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role_id=$1`, roleID); err != nil {
 		return err
 	}
+	if err := bumpRoleMembersTx(ctx, tx, roleID); err != nil {
+		return err
+	}
 	for _, permissionID := range permissionIDs {
 		if _, err := tx.Exec(ctx, `INSERT INTO role_permissions(role_id, permission_id) VALUES($1,$2) ON CONFLICT DO NOTHING`, roleID, permissionID); err != nil {
 			return err
 		}
 	}
+	return nil
+}
+
+func (r *storeRepository) ResolvePermissionCodes(ctx context.Context, codes []string) ([]string, []string, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, code FROM permissions WHERE code = ANY($1)`, codes)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]string, len(codes))
+	for rows.Next() {
+		var id, code string
+		if err := rows.Scan(&id, &code); err != nil {
+			return nil, nil, err
+		}
+		found[code] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]string, 0, len(codes))
+	unknown := make([]string, 0)
+	for _, code := range codes {
+		if id, ok := found[code]; ok {
+			ids = append(ids, id)
+		} else {
+			unknown = append(unknown, code)
+		}
+	}
+	return ids, unknown, nil
+}
+
+func (r *storeRepository) CreateRoleWithPermissions(ctx context.Context, def RoleDefinition, templateKey *string, permissionIDs []string) (domain.Role, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM roles WHERE lower(name)=lower($1))`, def.Name).Scan(&exists); err != nil {
+		return domain.Role{}, err
+	}
+	if exists {
+		return domain.Role{}, ErrRoleNameTaken
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.Role{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO roles(name, description, is_system, mfa_required, template_key)
+		VALUES($1,$2,FALSE,$3,$4)
+		RETURNING id
+	`, def.Name, def.Description, def.MFARequired, templateKey).Scan(&id); err != nil {
+		return domain.Role{}, err
+	}
+	if err := replaceRolePermissionsTx(ctx, tx, id, permissionIDs); err != nil {
+		return domain.Role{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.Role{}, err
+	}
+	return r.GetRole(ctx, id)
+}
+
+func (r *storeRepository) ApplyRoleTemplate(ctx context.Context, roleID, templateKey string, permissionIDs []string) error {
+	if err := r.ensurePermissionsEditable(ctx, roleID); err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `UPDATE roles SET template_key=$2, updated_at=now() WHERE id=$1`, roleID, templateKey); err != nil {
+		return err
+	}
+	if err := replaceRolePermissionsTx(ctx, tx, roleID, permissionIDs); err != nil {
+		return err
+	}
 	return tx.Commit(ctx)
 }
 
+func (r *storeRepository) ListRoleIDsByTemplate(ctx context.Context, templateKey string) ([]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT id FROM roles WHERE template_key=$1 ORDER BY name`, templateKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
 func (r *storeRepository) listPermissionsByRoleID(ctx context.Context, roleID string) ([]domain.Permission, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT p.id, p.code, res.name, p.action
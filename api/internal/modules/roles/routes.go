@@ -2,8 +2,7 @@ package roles
 
 import (
 	"humphreys/api/internal/middleware"
-
-	"github.com/gin-gonic/gin"
+	"humphreys/api/internal/permissions"
 )
 
 const (
@@ -14,12 +13,24 @@ const (
 	permAssign = "roles:assign"
 )
 
-func RegisterRoutes(authed *gin.RouterGroup, h *Handler) {
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("roles", "Role management", permissions.StandardActions()...)
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
 	roles := authed.Group("/roles")
 	roles.GET("", middleware.RequirePermission(permRead), h.ListRoles)
 	roles.POST("", middleware.RequirePermission(permCreate), h.CreateRole)
+	roles.GET("/templates", middleware.RequirePermission(permRead), h.ListTemplates)
+	roles.POST("/templates/:key/reapply", middleware.RequirePermission(permAssign), h.ReapplyTemplate)
+	roles.POST("/import", middleware.RequirePermission(permCreate), middleware.RequirePermission(permAssign), h.ImportRole)
 	roles.GET("/:id", middleware.RequirePermission(permRead), h.GetRole)
 	roles.PATCH("/:id", middleware.RequirePermission(permUpdate), h.UpdateRole)
 	roles.DELETE("/:id", middleware.RequirePermission(permDelete), h.DeleteRole)
 	roles.PATCH("/:id/permissions", middleware.RequirePermission(permAssign), h.SetRolePermissions)
+	roles.PUT("/:id/template", middleware.RequirePermission(permAssign), h.ApplyTemplate)
+	roles.POST("/:id/clone", middleware.RequirePermission(permCreate), middleware.RequirePermission(permAssign), h.CloneRole)
+	roles.GET("/:id/export", middleware.RequirePermission(permRead), h.ExportRole)
+	roles.GET("/:id/diff", middleware.RequirePermission(permRead), h.DiffRole)
 }
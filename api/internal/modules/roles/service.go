@@ -3,13 +3,29 @@ package roles
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"humphreys/api/internal/domain"
 
 	"github.com/jackc/pgx/v5"
 )
 
-var ErrRoleNotFound = errors.New("role not found")
+var (
+	ErrRoleNotFound           = errors.New("role not found")
+	ErrRoleNameTaken          = errors.New("a role with that name already exists")
+	ErrTemplateNotFound       = errors.New("role template not found")
+	ErrOwnerPermissionsLocked = errors.New("owner permissions cannot be changed")
+)
+
+// UnknownPermissionsError lists permission codes in a definition or template
+// that this database does not have, usually because a migration has not run.
+type UnknownPermissionsError struct {
+	Codes []string
+}
+
+func (e *UnknownPermissionsError) Error() string {
+	return "unknown permissions: " + strings.Join(e.Codes, ", ")
+}
 
 type Service struct {
 	repo Repository
@@ -23,16 +39,16 @@ func (s *Service) ListRoles(ctx context.Context) ([]domain.Role, error) {
 	return s.repo.ListRoles(ctx)
 }
 
-func (s *Service) CreateRole(ctx context.Context, name, description string) (domain.Role, error) {
-	return s.repo.CreateRole(ctx, name, description)
+func (s *Service) CreateRole(ctx context.Context, name, description string, mfaRequired *bool) (domain.Role, error) {
+	return s.repo.CreateRole(ctx, name, description, mfaRequired)
 }
 
 func (s *Service) GetRole(ctx context.Context, id string) (domain.Role, error) {
 	return s.repo.GetRole(ctx, id)
 }
 
-func (s *Service) UpdateRole(ctx context.Context, id, name, description string) (domain.Role, error) {
-	return s.repo.UpdateRole(ctx, id, name, description)
+func (s *Service) UpdateRole(ctx context.Context, id, name, description string, mfaRequired *bool) (domain.Role, error) {
+	return s.repo.UpdateRole(ctx, id, name, description, mfaRequired)
 }
 
 func (s *Service) DeleteRole(ctx context.Context, id string) error {
@@ -49,3 +65,136 @@ func (s *Service) SetRolePermissions(ctx context.Context, roleID string, permiss
 	}
 	return role, err
 }
+
+func (s *Service) ListTemplates() []RoleTemplate {
+	return builtInTemplates
+}
+
+func (s *Service) CloneRole(ctx context.Context, id, name, description string) (domain.Role, error) {
+	source, err := s.getRole(ctx, id)
+	if err != nil {
+		return domain.Role{}, err
+	}
+	ids := make([]string, 0, len(source.Permissions))
+	for _, permission := range source.Permissions {
+		ids = append(ids, permission.ID)
+	}
+	return s.repo.CreateRoleWithPermissions(ctx, RoleDefinition{
+		Name:        name,
+		Description: description,
+		MFARequired: source.MFARequired,
+	}, source.TemplateKey, ids)
+}
+
+func (s *Service) ExportRole(ctx context.Context, id string) (RoleDefinition, error) {
+	role, err := s.getRole(ctx, id)
+	if err != nil {
+		return RoleDefinition{}, err
+	}
+	return RoleDefinition{
+		Name:        role.Name,
+		Description: role.Description,
+		MFARequired: role.MFARequired,
+		Permissions: permissionCodes(role),
+	}, nil
+}
+
+// ImportRole creates a new role from an exported definition. Every permission
+// code must exist here; nothing is created when any is missing.
+func (s *Service) ImportRole(ctx context.Context, def RoleDefinition) (domain.Role, error) {
+	ids, err := s.resolvePermissionCodes(ctx, def.Permissions)
+	if err != nil {
+		return domain.Role{}, err
+	}
+	return s.repo.CreateRoleWithPermissions(ctx, def, nil, ids)
+}
+
+// ApplyTemplate resets a role's permissions to the template's and links the
+// role to it for later reapplication.
+func (s *Service) ApplyTemplate(ctx context.Context, roleID, templateKey string) (domain.Role, error) {
+	template, ok := findTemplate(templateKey)
+	if !ok {
+		return domain.Role{}, ErrTemplateNotFound
+	}
+	ids, err := s.resolvePermissionCodes(ctx, template.Permissions)
+	if err != nil {
+		return domain.Role{}, err
+	}
+	if err := s.repo.ApplyRoleTemplate(ctx, roleID, template.Key, ids); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Role{}, ErrRoleNotFound
+		}
+		return domain.Role{}, err
+	}
+	return s.getRole(ctx, roleID)
+}
+
+// ReapplyTemplate resets every role linked to the template, discarding any
+// permissions edited by hand since.
+func (s *Service) ReapplyTemplate(ctx context.Context, templateKey string) ([]domain.Role, error) {
+	if _, ok := findTemplate(templateKey); !ok {
+		return nil, ErrTemplateNotFound
+	}
+	roleIDs, err := s.repo.ListRoleIDsByTemplate(ctx, templateKey)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.Role, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		role, err := s.ApplyTemplate(ctx, roleID, templateKey)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, role)
+	}
+	return out, nil
+}
+
+func (s *Service) DiffRoles(ctx context.Context, id, otherID string) (RoleDiff, error) {
+	role, err := s.getRole(ctx, id)
+	if err != nil {
+		return RoleDiff{}, err
+	}
+	other, err := s.getRole(ctx, otherID)
+	if err != nil {
+		return RoleDiff{}, err
+	}
+	diff := diffPermissionCodes(permissionCodes(role), permissionCodes(other))
+	diff.Role = role.Name
+	diff.Other = other.Name
+	return diff, nil
+}
+
+func (s *Service) DiffRoleWithTemplate(ctx context.Context, id, templateKey string) (RoleDiff, error) {
+	template, ok := findTemplate(templateKey)
+	if !ok {
+		return RoleDiff{}, ErrTemplateNotFound
+	}
+	role, err := s.getRole(ctx, id)
+	if err != nil {
+		return RoleDiff{}, err
+	}
+	diff := diffPermissionCodes(permissionCodes(role), template.Permissions)
+	diff.Role = role.Name
+	diff.Other = template.Key
+	return diff, nil
+}
+
+func (s *Service) getRole(ctx context.Context, id string) (domain.Role, error) {
+	role, err := s.repo.GetRole(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Role{}, ErrRoleNotFound
+	}
+	return role, err
+}
+
+func (s *Service) resolvePermissionCodes(ctx context.Context, codes []string) ([]string, error) {
+	ids, unknown, err := s.repo.ResolvePermissionCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		return nil, &UnknownPermissionsError{Codes: unknown}
+	}
+	return ids, nil
+}
@@ -0,0 +1,132 @@
+package roles
+
+import (
+	"sort"
+
+	"humphreys/api/internal/domain"
+)
+
+// RoleTemplate is a built-in starting point for a role. When a migration adds
+// a permission a template should grant, add it here and reapply the template.
+type RoleTemplate struct {
+	Key         string   `json:"key"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+var builtInTemplates = []RoleTemplate{
+	{
+		Key:         "front_desk",
+		Name:        "Front desk",
+		Description: "Books jobs in, talks to customers and hands equipment back",
+		Permissions: []string{
+			"work_orders:create",
+			"work_orders:read",
+			"work_orders:update",
+			"work_orders_status:update",
+			"work_orders_sensitive:read",
+			"repair_logs:read",
+			"work_order_comments:create",
+			"work_order_comments:read",
+			"parts_purchase_requests:read",
+			"uploads:create",
+		},
+	},
+	{
+		Key:         "technician",
+		Name:        "Technician",
+		Description: "Works on assigned jobs, logs repairs and requests parts",
+		Permissions: []string{
+			"work_orders:read:assigned",
+			"work_orders_status:update",
+			"repair_logs:create:assigned",
+			"repair_logs:read:assigned",
+			"repair_logs:update:assigned",
+			"work_order_comments:create",
+			"work_order_comments:read:assigned",
+			"parts_purchase_requests:create:assigned",
+			"parts_purchase_requests:read:assigned",
+			"parts_purchase_requests:update:assigned",
+			"inventory:read",
+			"uploads:create",
+		},
+	},
+	{
+		Key:         "bookkeeper",
+		Name:        "Bookkeeper",
+		Description: "Reads pricing, purchasing and costing data",
+		Permissions: []string{
+			"work_orders:read",
+			"work_orders_sensitive:read",
+			"parts_purchase_requests:read",
+			"purchase_orders:read",
+			"suppliers:read",
+			"inventory:read",
+			"parts_markup_rules:read",
+			"costing:read",
+		},
+	},
+}
+
+func findTemplate(key string) (RoleTemplate, bool) {
+	for _, template := range builtInTemplates {
+		if template.Key == key {
+			return template, true
+		}
+	}
+	return RoleTemplate{}, false
+}
+
+// RoleDefinition is the portable form of a role used for export and import.
+// Permissions are codes rather than IDs so definitions move between databases.
+type RoleDefinition struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	MFARequired bool     `json:"mfa_required"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleDiff struct {
+	Role        string   `json:"role"`
+	Other       string   `json:"other"`
+	OnlyInRole  []string `json:"only_in_role"`
+	OnlyInOther []string `json:"only_in_other"`
+	InBoth      []string `json:"in_both"`
+}
+
+func diffPermissionCodes(role, other []string) RoleDiff {
+	inRole := make(map[string]struct{}, len(role))
+	for _, code := range role {
+		inRole[code] = struct{}{}
+	}
+	inOther := make(map[string]struct{}, len(other))
+	for _, code := range other {
+		inOther[code] = struct{}{}
+	}
+	diff := RoleDiff{OnlyInRole: []string{}, OnlyInOther: []string{}, InBoth: []string{}}
+	for code := range inRole {
+		if _, ok := inOther[code]; ok {
+			diff.InBoth = append(diff.InBoth, code)
+		} else {
+			diff.OnlyInRole = append(diff.OnlyInRole, code)
+		}
+	}
+	for code := range inOther {
+		if _, ok := inRole[code]; !ok {
+			diff.OnlyInOther = append(diff.OnlyInOther, code)
+		}
+	}
+	sort.Strings(diff.OnlyInRole)
+	sort.Strings(diff.OnlyInOther)
+	sort.Strings(diff.InBoth)
+	return diff
+}
+
+func permissionCodes(role domain.Role) []string {
+	codes := make([]string, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		codes = append(codes, permission.Code)
+	}
+	return codes
+}
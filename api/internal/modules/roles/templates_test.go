@@ -0,0 +1,42 @@
+package roles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffPermissionCodes(t *testing.T) {
+	diff := diffPermissionCodes(
+		[]string{"work_orders:read", "repair_logs:create", "work_orders:read"},
+		[]string{"work_orders_sensitive:read", "work_orders:read"},
+	)
+	if !reflect.DeepEqual(diff.OnlyInRole, []string{"repair_logs:create"}) {
+		t.Fatalf("unexpected only_in_role: %v", diff.OnlyInRole)
+	}
+	if !reflect.DeepEqual(diff.OnlyInOther, []string{"work_orders_sensitive:read"}) {
+		t.Fatalf("unexpected only_in_other: %v", diff.OnlyInOther)
+	}
+	if !reflect.DeepEqual(diff.InBoth, []string{"work_orders:read"}) {
+		t.Fatalf("unexpected in_both: %v", diff.InBoth)
+	}
+}
+
+func TestBuiltInTemplatesAreWellFormed(t *testing.T) {
+	seen := map[string]bool{}
+	for _, template := range builtInTemplates {
+		if seen[template.Key] {
+			t.Fatalf("duplicate template key %q", template.Key)
+		}
+		seen[template.Key] = true
+		if got, ok := findTemplate(template.Key); !ok || got.Name != template.Name {
+			t.Fatalf("findTemplate(%q) did not return the template", template.Key)
+		}
+		codes := map[string]bool{}
+		for _, code := range template.Permissions {
+			if codes[code] {
+				t.Fatalf("template %q lists %q twice", template.Key, code)
+			}
+			codes[code] = true
+		}
+	}
+}
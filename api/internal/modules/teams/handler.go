@@ -0,0 +1,139 @@
+package teams
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func New(db *pgxpool.Pool) *Handler {
+	return &Handler{
+		service: NewService(NewRepository(db)),
+	}
+}
+
+func NewWithService(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+type teamRequest struct {
+	TeamName    string  `json:"team_name" binding:"required"`
+	Description *string `json:"description"`
+}
+
+type teamMembersRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+func (h *Handler) ListTeams(c *gin.Context) {
+	items, err := h.service.ListTeams(c.Request.Context(), c.Query("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list teams"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) GetTeam(c *gin.Context) {
+	teamID, ok := parseIDParam(c, "team_id")
+	if !ok {
+		return
+	}
+	item, err := h.service.GetTeam(c.Request.Context(), teamID)
+	if err != nil {
+		writeTeamError(c, err, "failed to load team")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) CreateTeam(c *gin.Context) {
+	var req teamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.CreateTeam(c.Request.Context(), TeamInput{TeamName: req.TeamName, Description: req.Description})
+	if err != nil {
+		writeTeamError(c, err, "failed to create team")
+		return
+	}
+	c.JSON(http.StatusCreated, item)
+}
+
+func (h *Handler) UpdateTeam(c *gin.Context) {
+	teamID, ok := parseIDParam(c, "team_id")
+	if !ok {
+		return
+	}
+	var req teamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.UpdateTeam(c.Request.Context(), teamID, TeamInput{TeamName: req.TeamName, Description: req.Description})
+	if err != nil {
+		writeTeamError(c, err, "failed to update team")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) DeleteTeam(c *gin.Context) {
+	teamID, ok := parseIDParam(c, "team_id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteTeam(c.Request.Context(), teamID); err != nil {
+		writeTeamError(c, err, "failed to delete team")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) SetMembers(c *gin.Context) {
+	teamID, ok := parseIDParam(c, "team_id")
+	if !ok {
+		return
+	}
+	var req teamMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	item, err := h.service.SetMembers(c.Request.Context(), teamID, req.UserIDs)
+	if err != nil {
+		writeTeamError(c, err, "failed to update team members")
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+func parseIDParam(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return id, true
+}
+
+func writeTeamError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrTeamNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidTeamName), errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTeamNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,257 @@
+package teams
+
+import (
+	"context"
+	"errors"
+
+	"humphreys/api/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository interface {
+	ListTeams(ctx context.Context, memberUserID string) ([]domain.Team, error)
+	GetTeam(ctx context.Context, teamID int64) (domain.Team, error)
+	CreateTeam(ctx context.Context, input TeamInput) (domain.Team, error)
+	UpdateTeam(ctx context.Context, teamID int64, input TeamInput) (domain.Team, error)
+	DeleteTeam(ctx context.Context, teamID int64) error
+	SetMembers(ctx context.Context, teamID int64, userIDs []string) error
+}
+
+type storeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &storeRepository{db: db}
+}
+
+const teamColumns = `
+	team_id,
+	team_name,
+	description,
+	created_at,
+	updated_at
+`
+
+func scanTeam(row pgx.Row, item *domain.Team) error {
+	return row.Scan(
+		&item.TeamID,
+		&item.TeamName,
+		&item.Description,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+}
+
+// ListTeams returns every team, or only the teams memberUserID belongs to
+// when it is set.
+func (r *storeRepository) ListTeams(ctx context.Context, memberUserID string) ([]domain.Team, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+teamColumns+`
+		FROM public.teams t
+		WHERE $1::text = '' OR EXISTS (
+			SELECT 1
+			FROM public.team_members tm
+			WHERE tm.team_id = t.team_id
+			  AND tm.user_id::text = $1::text
+		)
+		ORDER BY team_name ASC
+	`, memberUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Team, 0)
+	index := make(map[int64]int)
+	for rows.Next() {
+		item := domain.Team{Members: make([]domain.TeamMember, 0)}
+		if err := scanTeam(rows, &item); err != nil {
+			return nil, err
+		}
+		index[item.TeamID] = len(out)
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	teamIDs := make([]int64, 0, len(out))
+	for _, item := range out {
+		teamIDs = append(teamIDs, item.TeamID)
+	}
+	members, err := r.listMembers(ctx, teamIDs)
+	if err != nil {
+		return nil, err
+	}
+	for teamID, teamMembers := range members {
+		out[index[teamID]].Members = teamMembers
+	}
+	return out, nil
+}
+
+func (r *storeRepository) GetTeam(ctx context.Context, teamID int64) (domain.Team, error) {
+	item := domain.Team{Members: make([]domain.TeamMember, 0)}
+	err := scanTeam(r.db.QueryRow(ctx, `SELECT `+teamColumns+` FROM public.teams WHERE team_id = $1`, teamID), &item)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.Team{}, ErrTeamNotFound
+	}
+	if err != nil {
+		return domain.Team{}, err
+	}
+	members, err := r.listMembers(ctx, []int64{teamID})
+	if err != nil {
+		return domain.Team{}, err
+	}
+	if teamMembers, ok := members[teamID]; ok {
+		item.Members = teamMembers
+	}
+	return item, nil
+}
+
+func (r *storeRepository) listMembers(ctx context.Context, teamIDs []int64) (map[int64][]domain.TeamMember, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT tm.team_id, u.id::text, u.email, u.full_name
+		FROM public.team_members tm
+		JOIN public.users u ON u.id = tm.user_id
+		WHERE tm.team_id = ANY($1::bigint[])
+		  AND u.deleted_at IS NULL
+		ORDER BY u.full_name ASC, u.email ASC
+	`, teamIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[int64][]domain.TeamMember)
+	for rows.Next() {
+		var teamID int64
+		var member domain.TeamMember
+		if err := rows.Scan(&teamID, &member.UserID, &member.Email, &member.FullName); err != nil {
+			return nil, err
+		}
+		out[teamID] = append(out[teamID], member)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) CreateTeam(ctx context.Context, input TeamInput) (domain.Team, error) {
+	if err := r.ensureTeamNameAvailable(ctx, input.TeamName, 0); err != nil {
+		return domain.Team{}, err
+	}
+
+	item := domain.Team{Members: make([]domain.TeamMember, 0)}
+	err := scanTeam(r.db.QueryRow(ctx, `
+		INSERT INTO public.teams(team_name, description)
+		VALUES($1, $2)
+		RETURNING `+teamColumns,
+		input.TeamName,
+		input.Description,
+	), &item)
+	return item, err
+}
+
+func (r *storeRepository) UpdateTeam(ctx context.Context, teamID int64, input TeamInput) (domain.Team, error) {
+	if err := r.ensureTeamNameAvailable(ctx, input.TeamName, teamID); err != nil {
+		return domain.Team{}, err
+	}
+
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE public.teams
+		SET
+			team_name = $2,
+			description = $3,
+			updated_at = now()
+		WHERE team_id = $1
+	`,
+		teamID,
+		input.TeamName,
+		input.Description,
+	)
+	if err != nil {
+		return domain.Team{}, err
+	}
+	if cmd.RowsAffected() == 0 {
+		return domain.Team{}, ErrTeamNotFound
+	}
+	return r.GetTeam(ctx, teamID)
+}
+
+func (r *storeRepository) DeleteTeam(ctx context.Context, teamID int64) error {
+	cmd, err := r.db.Exec(ctx, `DELETE FROM public.teams WHERE team_id = $1`, teamID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrTeamNotFound
+	}
+	return nil
+}
+
+func (r *storeRepository) SetMembers(ctx context.Context, teamID int64, userIDs []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.teams WHERE team_id = $1)`, teamID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrTeamNotFound
+	}
+
+	var found int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM public.users
+		WHERE id::text = ANY($1::text[])
+		  AND deleted_at IS NULL
+	`, userIDs).Scan(&found); err != nil {
+		return err
+	}
+	if found != len(userIDs) {
+		return ErrUserNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM public.team_members WHERE team_id = $1`, teamID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO public.team_members(team_id, user_id)
+		SELECT $1, id
+		FROM public.users
+		WHERE id::text = ANY($2::text[])
+	`, teamID, userIDs); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE public.teams SET updated_at = now() WHERE team_id = $1`, teamID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *storeRepository) ensureTeamNameAvailable(ctx context.Context, name string, teamID int64) error {
+	var taken bool
+	if err := r.db.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM public.teams
+			WHERE LOWER(BTRIM(team_name)) = LOWER($1)
+				AND team_id <> $2
+		)
+	`, name, teamID).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return ErrTeamNameTaken
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package teams
+
+import (
+	"humphreys/api/internal/middleware"
+	"humphreys/api/internal/permissions"
+)
+
+const (
+	permRead   = "teams:read"
+	permCreate = "teams:create"
+	permUpdate = "teams:update"
+	permDelete = "teams:delete"
+	permAssign = "teams:assign"
+)
+
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("teams", "Bench teams that share ownership of work orders", permissions.StandardActions()...)
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
+	group := authed.Group("/teams")
+	group.GET("", middleware.RequirePermission(permRead), h.ListTeams)
+	group.POST("", middleware.RequirePermission(permCreate), h.CreateTeam)
+	group.GET("/:team_id", middleware.RequirePermission(permRead), h.GetTeam)
+	group.PATCH("/:team_id", middleware.RequirePermission(permUpdate), h.UpdateTeam)
+	group.DELETE("/:team_id", middleware.RequirePermission(permDelete), h.DeleteTeam)
+	group.PUT("/:team_id/members", middleware.RequirePermission(permAssign), h.SetMembers)
+}
@@ -0,0 +1,92 @@
+package teams
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"humphreys/api/internal/domain"
+)
+
+var ErrTeamNotFound = errors.New("team not found")
+var ErrInvalidTeamName = errors.New("team name is required")
+var ErrTeamNameTaken = errors.New("team name already exists")
+var ErrUserNotFound = errors.New("user not found")
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+type TeamInput struct {
+	TeamName    string
+	Description *string
+}
+
+func (s *Service) ListTeams(ctx context.Context, memberUserID string) ([]domain.Team, error) {
+	return s.repo.ListTeams(ctx, strings.TrimSpace(memberUserID))
+}
+
+func (s *Service) GetTeam(ctx context.Context, teamID int64) (domain.Team, error) {
+	return s.repo.GetTeam(ctx, teamID)
+}
+
+func (s *Service) CreateTeam(ctx context.Context, input TeamInput) (domain.Team, error) {
+	normalized, err := normalizeTeamInput(input)
+	if err != nil {
+		return domain.Team{}, err
+	}
+	return s.repo.CreateTeam(ctx, normalized)
+}
+
+func (s *Service) UpdateTeam(ctx context.Context, teamID int64, input TeamInput) (domain.Team, error) {
+	normalized, err := normalizeTeamInput(input)
+	if err != nil {
+		return domain.Team{}, err
+	}
+	return s.repo.UpdateTeam(ctx, teamID, normalized)
+}
+
+func (s *Service) DeleteTeam(ctx context.Context, teamID int64) error {
+	return s.repo.DeleteTeam(ctx, teamID)
+}
+
+// SetMembers replaces the team's membership with userIDs.
+func (s *Service) SetMembers(ctx context.Context, teamID int64, userIDs []string) (domain.Team, error) {
+	seen := make(map[string]struct{}, len(userIDs))
+	normalized := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		normalized = append(normalized, id)
+	}
+	if err := s.repo.SetMembers(ctx, teamID, normalized); err != nil {
+		return domain.Team{}, err
+	}
+	return s.repo.GetTeam(ctx, teamID)
+}
+
+func normalizeTeamInput(input TeamInput) (TeamInput, error) {
+	input.TeamName = strings.TrimSpace(input.TeamName)
+	if input.TeamName == "" {
+		return TeamInput{}, ErrInvalidTeamName
+	}
+	if input.Description != nil {
+		description := strings.TrimSpace(*input.Description)
+		if description == "" {
+			input.Description = nil
+		} else {
+			input.Description = &description
+		}
+	}
+	return input, nil
+}
@@ -1,10 +1,16 @@
 package uploads
 
 import (
-	"github.com/gin-gonic/gin"
+	"humphreys/api/internal/middleware"
+	"humphreys/api/internal/permissions"
 )
 
-func RegisterRoutes(authed *gin.RouterGroup, h *Handler) {
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("uploads", "File upload management", permissions.StandardActions()...)
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
 	authed.POST("/uploads/markdown-image", h.UploadMarkdownImage)
 	authed.DELETE("/uploads/markdown-image", h.DeleteMarkdownImage)
 }
@@ -1,8 +1,8 @@
 package userpreferences
 
-import "github.com/gin-gonic/gin"
+import "humphreys/api/internal/middleware"
 
-func RegisterRoutes(authed *gin.RouterGroup, h *Handler) {
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
 	group := authed.Group("/user-preferences")
 	group.GET("/:key", h.Get)
 	group.PATCH("/:key", h.Save)
@@ -0,0 +1,80 @@
+package users
+
+import (
+	"context"
+	"errors"
+
+	"humphreys/api/internal/middleware"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PermissionGrant is one permission reaching a user through one role.
+type PermissionGrant struct {
+	Code     string
+	Resource string
+	Action   string
+	RoleID   string
+	RoleName string
+}
+
+type GrantingRole struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type EffectivePermission struct {
+	Code      string         `json:"code"`
+	Resource  string         `json:"resource"`
+	Action    string         `json:"action"`
+	GrantedBy []GrantingRole `json:"granted_by"`
+	// Routes lists the routes this permission helps open that the user can
+	// actually call, i.e. their other grants cover the route's remaining
+	// requirements.
+	Routes []string `json:"routes"`
+}
+
+func (s *Service) EffectivePermissions(ctx context.Context, id string, routes []middleware.RoutePermission) ([]EffectivePermission, error) {
+	if _, err := s.repo.GetUserByID(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	grants, err := s.repo.ListPermissionGrants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return buildEffectivePermissions(grants, routes), nil
+}
+
+// buildEffectivePermissions expects grants ordered by code.
+func buildEffectivePermissions(grants []PermissionGrant, routes []middleware.RoutePermission) []EffectivePermission {
+	scope := make(map[string]struct{}, len(grants))
+	out := make([]EffectivePermission, 0)
+	for _, grant := range grants {
+		scope[grant.Code] = struct{}{}
+		if len(out) == 0 || out[len(out)-1].Code != grant.Code {
+			out = append(out, EffectivePermission{
+				Code:      grant.Code,
+				Resource:  grant.Resource,
+				Action:    grant.Action,
+				GrantedBy: []GrantingRole{},
+				Routes:    []string{},
+			})
+		}
+		last := &out[len(out)-1]
+		last.GrantedBy = append(last.GrantedBy, GrantingRole{ID: grant.RoleID, Name: grant.RoleName})
+	}
+	for _, route := range routes {
+		if !route.SatisfiedBy(scope) {
+			continue
+		}
+		for i := range out {
+			if route.Uses(out[i].Code) {
+				out[i].Routes = append(out[i].Routes, route.String())
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,36 @@
+package users
+
+import (
+	"reflect"
+	"testing"
+
+	"humphreys/api/internal/middleware"
+)
+
+func TestBuildEffectivePermissions(t *testing.T) {
+	grants := []PermissionGrant{
+		{Code: "roles:create", Resource: "roles", Action: "create", RoleID: "r1", RoleName: "admin"},
+		{Code: "work_orders:read:assigned", Resource: "work_orders", Action: "read:assigned", RoleID: "r1", RoleName: "admin"},
+		{Code: "work_orders:read:assigned", Resource: "work_orders", Action: "read:assigned", RoleID: "r2", RoleName: "technician"},
+	}
+	routes := []middleware.RoutePermission{
+		{Method: "GET", Path: "/work-orders", Requirements: [][]string{{"work_orders:read", "work_orders:read:assigned"}}},
+		{Method: "POST", Path: "/roles", Requirements: [][]string{{"roles:create"}}},
+		{Method: "POST", Path: "/roles/:id/clone", Requirements: [][]string{{"roles:create"}, {"roles:assign"}}},
+	}
+
+	got := buildEffectivePermissions(grants, routes)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 permissions, got %+v", got)
+	}
+	if !reflect.DeepEqual(got[0].Routes, []string{"POST /roles"}) {
+		t.Fatalf("expected roles:create to unlock only POST /roles, got %v", got[0].Routes)
+	}
+	wantRoles := []GrantingRole{{ID: "r1", Name: "admin"}, {ID: "r2", Name: "technician"}}
+	if !reflect.DeepEqual(got[1].GrantedBy, wantRoles) {
+		t.Fatalf("expected both granting roles, got %v", got[1].GrantedBy)
+	}
+	if !reflect.DeepEqual(got[1].Routes, []string{"GET /work-orders"}) {
+		t.Fatalf("unexpected routes for assigned read: %v", got[1].Routes)
+	}
+}
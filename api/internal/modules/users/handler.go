@@ -5,17 +5,24 @@ import (
 	"net/http"
 	"strconv"
 
+	"humphreys/api/internal/domain"
+	"humphreys/api/internal/middleware"
+	"humphreys/api/internal/modules/auth"
+	authsecurity "humphreys/api/internal/modules/auth/security"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Handler struct {
 	service *Service
+	auth    *auth.Handler
+	routes  []middleware.RoutePermission
 }
 
-func New(db *pgxpool.Pool) *Handler {
+func New(db *pgxpool.Pool, passwords authsecurity.PasswordPolicy) *Handler {
 	return &Handler{
-		service: NewService(NewRepository(db)),
+		service: NewService(NewRepository(db), passwords),
 	}
 }
 
@@ -23,14 +30,29 @@ func NewWithService(service *Service) *Handler {
 	return &Handler{service: service}
 }
 
+func (h *Handler) SetAuthHandler(authHandler *auth.Handler) {
+	h.auth = authHandler
+}
+
+// SetRoutePermissions supplies the route index behind effective-permissions.
+func (h *Handler) SetRoutePermissions(routes []middleware.RoutePermission) {
+	h.routes = routes
+}
+
 type createUserRequest struct {
 	Email    string   `json:"email" binding:"required,email"`
-	Password string   `json:"password" binding:"required,min=8"`
+	Password string   `json:"password" binding:"required"`
 	FullName string   `json:"full_name" binding:"required"`
 	Status   string   `json:"status"`
 	RoleIDs  []string `json:"role_ids"`
 }
 
+type inviteUserRequest struct {
+	Email    string   `json:"email" binding:"required,email"`
+	FullName string   `json:"full_name" binding:"required"`
+	RoleIDs  []string `json:"role_ids"`
+}
+
 type updateUserRequest struct {
 	Email    string  `json:"email" binding:"required,email"`
 	FullName string  `json:"full_name" binding:"required"`
@@ -41,6 +63,10 @@ type updateStatusRequest struct {
 	Status string `json:"status" binding:"required"`
 }
 
+type setWorkerRequest struct {
+	WorkerID int64 `json:"worker_id" binding:"required,min=1"`
+}
+
 type setRolesRequest struct {
 	RoleIDs []string `json:"role_ids"`
 }
@@ -67,13 +93,67 @@ func (h *Handler) CreateUser(c *gin.Context) {
 	}
 
 	user, err := h.service.CreateUser(c.Request.Context(), req.Email, req.Password, req.FullName, req.Status, req.RoleIDs)
+	if err != nil {
+		writePasswordError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+func (h *Handler) InviteUser(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+	var req inviteUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if h.auth == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "invitations are not available"})
+		return
+	}
+
+	user, err := h.service.InviteUser(c.Request.Context(), req.Email, req.FullName, req.RoleIDs)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := h.auth.SendInvitation(c.Request.Context(), user.ID, claims.UserID); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "user created but invitation email failed", "detail": err.Error(), "user": user})
+		return
+	}
 	c.JSON(http.StatusCreated, user)
 }
 
+func (h *Handler) ResendInvitation(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+	if h.auth == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "invitations are not available"})
+		return
+	}
+	err := h.auth.SendInvitation(c.Request.Context(), c.Param("id"), claims.UserID)
+	if errors.Is(err, auth.ErrAuthenticatedUserAbsent) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if errors.Is(err, auth.ErrUserNotInvited) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to send invitation", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sent": true})
+}
+
 func (h *Handler) GetUser(c *gin.Context) {
 	user, err := h.service.GetUser(c.Request.Context(), c.Param("id"))
 	if err != nil {
@@ -91,7 +171,7 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 	}
 	user, err := h.service.UpdateUser(c.Request.Context(), c.Param("id"), req.Email, req.FullName, req.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writePasswordError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, user)
@@ -132,3 +212,188 @@ func (h *Handler) SetUserRoles(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, user)
 }
+
+func (h *Handler) SetUserWorker(c *gin.Context) {
+	var req setWorkerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	user, err := h.service.SetUserWorker(c.Request.Context(), c.Param("id"), &req.WorkerID)
+	writeUserWorkerResult(c, user, err)
+}
+
+func (h *Handler) UnlinkUserWorker(c *gin.Context) {
+	_, err := h.service.SetUserWorker(c.Request.Context(), c.Param("id"), nil)
+	if err == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	writeUserWorkerResult(c, domain.User{}, err)
+}
+
+func writeUserWorkerResult(c *gin.Context, user domain.User, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, ErrWorkerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrWorkerAlreadyLinked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update worker link"})
+	default:
+		c.JSON(http.StatusOK, user)
+	}
+}
+
+func (h *Handler) ResetUserMFA(c *gin.Context) {
+	err := h.service.ResetUserMFA(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset mfa"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ListUserSessions(c *gin.Context) {
+	sessions, err := h.service.ListUserSessions(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": sessions})
+}
+
+func (h *Handler) RevokeUserSession(c *gin.Context) {
+	err := h.service.RevokeUserSession(c.Request.Context(), c.Param("id"), c.Param("family_id"))
+	if errors.Is(err, ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke session"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) RevokeUserSessions(c *gin.Context) {
+	err := h.service.RevokeUserSessions(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ListLoginEvents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	events, err := h.service.ListLoginEvents(c.Request.Context(), c.Param("id"), limit)
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list login events"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": events})
+}
+
+func (h *Handler) UnlockLogin(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+	err := h.service.UnlockLogin(c.Request.Context(), c.Param("id"), claims.UserID)
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlock user"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Impersonate returns an access token for acting as the user. The response
+// carries no refresh token; the web client keeps its own session cookie and
+// goes back to it once the token expires or is discarded.
+func (h *Handler) Impersonate(c *gin.Context) {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+	if h.auth == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "impersonation is not available"})
+		return
+	}
+	result, err := h.auth.Impersonate(c.Request.Context(), claims, c.Param("id"), c.ClientIP(), c.GetHeader("User-Agent"))
+	switch {
+	case errors.Is(err, auth.ErrAuthenticatedUserAbsent):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	case errors.Is(err, auth.ErrImpersonateSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, auth.ErrUserNotActive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, auth.ErrImpersonationEscalates), errors.Is(err, auth.ErrAlreadyImpersonating):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to impersonate user"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  result.AccessToken,
+		"expires_in":    result.ExpiresIn,
+		"scope":         result.Scope,
+		"user":          result.User,
+		"impersonating": true,
+		"impersonated_by": gin.H{
+			"id": claims.UserID,
+		},
+	})
+}
+
+// writePasswordError spells out each failed password rule so the form can
+// list them; other create/update failures keep their plain message.
+func writePasswordError(c *gin.Context, err error) {
+	var policyErr *authsecurity.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "password_errors": policyErr.Problems})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+func (h *Handler) EffectivePermissions(c *gin.Context) {
+	items, err := h.service.EffectivePermissions(c.Request.Context(), c.Param("id"), h.routes)
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list effective permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
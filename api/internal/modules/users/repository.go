@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"humphreys/api/internal/domain"
+	"humphreys/api/internal/modules/auth"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -14,13 +15,24 @@ import (
 
 type Repository interface {
 	ListUsers(ctx context.Context, query, status string, page, pageSize int) ([]domain.User, error)
-	CreateUser(ctx context.Context, email, passwordHash, fullName, status string, roleIDs []string) (domain.User, error)
+	CreateUser(ctx context.Context, email, passwordHash, fullName, status string, roleIDs []string, mustChangePassword bool) (domain.User, error)
 	GetUserByID(ctx context.Context, id string) (domain.User, error)
 	UpdateUser(ctx context.Context, id, email, fullName string, passwordHash *string) (domain.User, error)
 	SetUserStatus(ctx context.Context, id, status string) (domain.User, error)
 	SetUserRoles(ctx context.Context, userID string, roleIDs []string) error
+	ResetUserMFA(ctx context.Context, userID string) error
+	ListUserSessions(ctx context.Context, userID string) ([]domain.Session, error)
+	RevokeUserSession(ctx context.Context, userID, familyID string) (bool, error)
+	RevokeUserSessions(ctx context.Context, userID string) error
+	ListLoginEvents(ctx context.Context, userID string, limit int) ([]domain.LoginEvent, error)
+	UnlockLogin(ctx context.Context, userID, actorUserID string) error
+	SetUserWorker(ctx context.Context, userID string, workerID *int64) error
+	ListPermissionGrants(ctx context.Context, userID string) ([]PermissionGrant, error)
 }
 
+const userWorkerColumns = `worker_id,
+			(SELECT w.worker_name FROM public.workers w WHERE w.worker_id = users.worker_id)`
+
 type storeRepository struct {
 	db *pgxpool.Pool
 }
@@ -55,12 +67,12 @@ func (r *storeRepository) ListUsers(ctx context.Context, query, status string, p
 	args = append(args, pageSize, offset)
 
 	rows, err := r.db.Query(ctx, fmt.Sprintf(`
-		SELECT id, email, full_name, status, created_at, updated_at
+		SELECT id, email, full_name, status, created_at, updated_at, %s
 		FROM users
 		WHERE %s
 		ORDER BY created_at DESC
 		LIMIT $%d OFFSET $%d
-	`, where, argPos, argPos+1), args...)
+	`, userWorkerColumns, where, argPos, argPos+1), args...)
 	if err != nil {
 		return nil, err
 	}
@@ -69,7 +81,7 @@ func (r *storeRepository) ListUsers(ctx context.Context, query, status string, p
 	users := make([]domain.User, 0)
 	for rows.Next() {
 		var u domain.User
-		if err := rows.Scan(&u.ID, &u.Email, &u.FullName, &u.Status, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if err := rows.Scan(&u.ID, &u.Email, &u.FullName, &u.Status, &u.CreatedAt, &u.UpdatedAt, &u.WorkerID, &u.WorkerName); err != nil {
 			return nil, err
 		}
 		roles, err := r.listRolesByUserID(ctx, u.ID)
@@ -82,7 +94,7 @@ func (r *storeRepository) ListUsers(ctx context.Context, query, status string, p
 	return users, rows.Err()
 }
 
-func (r *storeRepository) CreateUser(ctx context.Context, email, passwordHash, fullName, status string, roleIDs []string) (domain.User, error) {
+func (r *storeRepository) CreateUser(ctx context.Context, email, passwordHash, fullName, status string, roleIDs []string, mustChangePassword bool) (domain.User, error) {
 	if status == "" {
 		status = "active"
 	}
@@ -94,8 +106,8 @@ func (r *storeRepository) CreateUser(ctx context.Context, email, passwordHash, f
 
 	var id string
 	if err := tx.QueryRow(ctx,
-		`INSERT INTO users(email,password_hash,full_name,status) VALUES($1,$2,$3,$4) RETURNING id`,
-		strings.ToLower(email), passwordHash, fullName, status,
+		`INSERT INTO users(email,password_hash,full_name,status,must_change_password) VALUES($1,$2,$3,$4,$5) RETURNING id`,
+		strings.ToLower(email), passwordHash, fullName, status, mustChangePassword,
 	).Scan(&id); err != nil {
 		return domain.User{}, err
 	}
@@ -116,10 +128,10 @@ func (r *storeRepository) CreateUser(ctx context.Context, email, passwordHash, f
 func (r *storeRepository) GetUserByID(ctx context.Context, id string) (domain.User, error) {
 	var u domain.User
 	err := r.db.QueryRow(ctx, `
-		SELECT id, email, full_name, status, created_at, updated_at
+		SELECT id, email, full_name, status, created_at, updated_at, `+userWorkerColumns+`
 		FROM users
 		WHERE id=$1 AND deleted_at IS NULL
-	`, id).Scan(&u.ID, &u.Email, &u.FullName, &u.Status, &u.CreatedAt, &u.UpdatedAt)
+	`, id).Scan(&u.ID, &u.Email, &u.FullName, &u.Status, &u.CreatedAt, &u.UpdatedAt, &u.WorkerID, &u.WorkerName)
 	if err != nil {
 		return u, err
 	}
@@ -135,7 +147,7 @@ func (r *storeRepository) UpdateUser(ctx context.Context, id, email, fullName st
 	if passwordHash == nil {
 		_, err := r.db.Exec(ctx, `
 			UPDATE users
-			SET email=$1, full_name=$2, updated_at=now()
+			SET email=$1, full_name=$2, permissions_version=permissions_version+1, updated_at=now()
 			WHERE id=$3 AND deleted_at IS NULL
 		`, strings.ToLower(email), fullName, id)
 		if err != nil {
@@ -144,7 +156,7 @@ func (r *storeRepository) UpdateUser(ctx context.Context, id, email, fullName st
 	} else {
 		_, err := r.db.Exec(ctx, `
 			UPDATE users
-			SET email=$1, full_name=$2, password_hash=$3, updated_at=now()
+			SET email=$1, full_name=$2, password_hash=$3, must_change_password=TRUE, permissions_version=permissions_version+1, updated_at=now()
 			WHERE id=$4 AND deleted_at IS NULL
 		`, strings.ToLower(email), fullName, *passwordHash, id)
 		if err != nil {
@@ -156,11 +168,11 @@ func (r *storeRepository) UpdateUser(ctx context.Context, id, email, fullName st
 
 func (r *storeRepository) SetUserStatus(ctx context.Context, id, status string) (domain.User, error) {
 	if status == "deleted" {
-		if _, err := r.db.Exec(ctx, `UPDATE users SET status='deleted', deleted_at=now(), updated_at=now() WHERE id=$1 AND deleted_at IS NULL`, id); err != nil {
+		if _, err := r.db.Exec(ctx, `UPDATE users SET status='deleted', deleted_at=now(), permissions_version=permissions_version+1, updated_at=now() WHERE id=$1 AND deleted_at IS NULL`, id); err != nil {
 			return domain.User{}, err
 		}
 	} else {
-		if _, err := r.db.Exec(ctx, `UPDATE users SET status=$1, updated_at=now() WHERE id=$2 AND deleted_at IS NULL`, status, id); err != nil {
+		if _, err := r.db.Exec(ctx, `UPDATE users SET status=$1, permissions_version=permissions_version+1, updated_at=now() WHERE id=$2 AND deleted_at IS NULL`, status, id); err != nil {
 			return domain.User{}, err
 		}
 	}
@@ -184,9 +196,74 @@ func (r *storeRepository) SetUserRoles(ctx context.Context, userID string, roleI
 	return tx.Commit(ctx)
 }
 
+// SetUserWorker links the user to a catalog worker, or unlinks them when
+// workerID is nil. A worker belongs to at most one user.
+func (r *storeRepository) SetUserWorker(ctx context.Context, userID string, workerID *int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if workerID != nil {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.workers WHERE worker_id = $1)`, *workerID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrWorkerNotFound
+		}
+		var linked bool
+		if err := tx.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM users WHERE worker_id = $1 AND id::text <> $2)
+		`, *workerID, userID).Scan(&linked); err != nil {
+			return err
+		}
+		if linked {
+			return ErrWorkerAlreadyLinked
+		}
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users SET worker_id=$1, updated_at=now()
+		WHERE id::text=$2 AND deleted_at IS NULL
+	`, workerID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *storeRepository) ResetUserMFA(ctx context.Context, userID string) error {
+	return auth.DeleteUserMFA(ctx, r.db, userID)
+}
+
+func (r *storeRepository) ListUserSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	return auth.ListUserSessions(ctx, r.db, userID)
+}
+
+func (r *storeRepository) RevokeUserSession(ctx context.Context, userID, familyID string) (bool, error) {
+	return auth.RevokeUserSession(ctx, r.db, userID, familyID)
+}
+
+func (r *storeRepository) RevokeUserSessions(ctx context.Context, userID string) error {
+	return auth.RevokeUserSessions(ctx, r.db, userID)
+}
+
+func (r *storeRepository) ListLoginEvents(ctx context.Context, userID string, limit int) ([]domain.LoginEvent, error) {
+	return auth.ListLoginEvents(ctx, r.db, userID, limit)
+}
+
+func (r *storeRepository) UnlockLogin(ctx context.Context, userID, actorUserID string) error {
+	return auth.UnlockLogin(ctx, r.db, userID, actorUserID)
+}
+
 func (r *storeRepository) listRolesByUserID(ctx context.Context, userID string) ([]domain.Role, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT r.id, r.name, r.description, r.is_system
+		SELECT r.id, r.name, r.description, r.is_system, r.mfa_required
 		FROM roles r
 		JOIN user_roles ur ON ur.role_id = r.id
 		WHERE ur.user_id = $1
@@ -200,7 +277,7 @@ func (r *storeRepository) listRolesByUserID(ctx context.Context, userID string)
 	roles := make([]domain.Role, 0)
 	for rows.Next() {
 		var role domain.Role
-		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem); err != nil {
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.IsSystem, &role.MFARequired); err != nil {
 			return nil, err
 		}
 		roles = append(roles, role)
@@ -258,6 +335,9 @@ func replaceUserRoles(ctx context.Context, tx pgx.Tx, userID string, roleIDs []s
 	if _, err := tx.Exec(ctx, `DELETE FROM user_roles WHERE user_id=$1`, userID); err != nil {
 		return err
 	}
+	if err := auth.BumpPermissionsVersionTx(ctx, tx, userID); err != nil {
+		return err
+	}
 	for _, roleID := range roleIDs {
 		if _, err := tx.Exec(ctx, `INSERT INTO user_roles(user_id, role_id) VALUES($1,$2) ON CONFLICT DO NOTHING`, userID, roleID); err != nil {
 			return err
@@ -265,3 +345,30 @@ func replaceUserRoles(ctx context.Context, tx pgx.Tx, userID string, roleIDs []s
 	}
 	return nil
 }
+
+func (r *storeRepository) ListPermissionGrants(ctx context.Context, userID string) ([]PermissionGrant, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT p.code, res.name, p.action, ro.id, ro.name
+		FROM user_roles ur
+		JOIN roles ro ON ro.id = ur.role_id
+		JOIN role_permissions rp ON rp.role_id = ro.id
+		JOIN permissions p ON p.id = rp.permission_id
+		JOIN resources res ON res.id = p.resource_id
+		WHERE ur.user_id = $1
+		ORDER BY p.code, ro.name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]PermissionGrant, 0)
+	for rows.Next() {
+		var grant PermissionGrant
+		if err := rows.Scan(&grant.Code, &grant.Resource, &grant.Action, &grant.RoleID, &grant.RoleName); err != nil {
+			return nil, err
+		}
+		out = append(out, grant)
+	}
+	return out, rows.Err()
+}
@@ -2,8 +2,7 @@ package users
 
 import (
 	"humphreys/api/internal/middleware"
-
-	"github.com/gin-gonic/gin"
+	"humphreys/api/internal/permissions"
 )
 
 const (
@@ -11,14 +10,33 @@ const (
 	permCreate = "users:create"
 	permUpdate = "users:update"
 	permAssign = "users:assign"
+
+	permImpersonate = "users:impersonate"
 )
 
-func RegisterRoutes(authed *gin.RouterGroup, h *Handler) {
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("users", "User management", permissions.StandardActions("impersonate")...)
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
 	users := authed.Group("/users")
 	users.GET("", middleware.RequirePermission(permRead), h.ListUsers)
 	users.POST("", middleware.RequirePermission(permCreate), h.CreateUser)
+	users.POST("/invitations", middleware.RequirePermission(permCreate), h.InviteUser)
 	users.GET("/:id", middleware.RequirePermission(permRead), h.GetUser)
 	users.PATCH("/:id", middleware.RequirePermission(permUpdate), h.UpdateUser)
 	users.PATCH("/:id/status", middleware.RequirePermission(permUpdate), h.UpdateUserStatus)
+	users.POST("/:id/invitation", middleware.RequirePermission(permCreate), h.ResendInvitation)
 	users.PATCH("/:id/roles", middleware.RequirePermission(permAssign), h.SetUserRoles)
+	users.PUT("/:id/worker", middleware.RequirePermission(permUpdate), h.SetUserWorker)
+	users.DELETE("/:id/worker", middleware.RequirePermission(permUpdate), h.UnlinkUserWorker)
+	users.DELETE("/:id/mfa", middleware.RequirePermission(permUpdate), h.ResetUserMFA)
+	users.GET("/:id/login-events", middleware.RequirePermission(permRead), h.ListLoginEvents)
+	users.GET("/:id/effective-permissions", middleware.RequirePermission(permRead), h.EffectivePermissions)
+	users.POST("/:id/unlock", middleware.RequirePermission(permUpdate), h.UnlockLogin)
+	users.GET("/:id/sessions", middleware.RequirePermission(permRead), h.ListUserSessions)
+	users.DELETE("/:id/sessions", middleware.RequirePermission(permUpdate), h.RevokeUserSessions)
+	users.DELETE("/:id/sessions/:family_id", middleware.RequirePermission(permUpdate), h.RevokeUserSession)
+	users.POST("/:id/impersonate", middleware.RequirePermission(permImpersonate), middleware.RejectAPIKeys(), middleware.RejectImpersonation(), h.Impersonate)
 }
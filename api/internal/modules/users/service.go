@@ -11,16 +11,21 @@ import (
 )
 
 var (
-	ErrInvalidStatus = errors.New("invalid status")
-	ErrUserNotFound  = errors.New("user not found")
+	ErrInvalidStatus   = errors.New("invalid status")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrSessionNotFound = errors.New("session not found")
+
+	ErrWorkerNotFound      = errors.New("worker not found")
+	ErrWorkerAlreadyLinked = errors.New("worker is already linked to another user")
 )
 
 type Service struct {
-	repo Repository
+	repo      Repository
+	passwords authsecurity.PasswordPolicy
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repository, passwords authsecurity.PasswordPolicy) *Service {
+	return &Service{repo: repo, passwords: passwords}
 }
 
 func (s *Service) ListUsers(ctx context.Context, query, status string, page, pageSize int) ([]domain.User, error) {
@@ -31,11 +36,30 @@ func (s *Service) CreateUser(ctx context.Context, email, password, fullName, sta
 	if status == "" {
 		status = "active"
 	}
+	if err := s.passwords.Validate(password, email); err != nil {
+		return domain.User{}, err
+	}
 	hash, err := authsecurity.HashPassword(password)
 	if err != nil {
 		return domain.User{}, err
 	}
-	return s.repo.CreateUser(ctx, email, hash, fullName, status, roleIDs)
+	// An admin-chosen password is known to someone other than the user, so
+	// it only lasts until their first sign-in.
+	return s.repo.CreateUser(ctx, email, hash, fullName, status, roleIDs, true)
+}
+
+// InviteUser creates an account in the invited state with an unusable random
+// password; the invitee chooses their own when accepting the emailed link.
+func (s *Service) InviteUser(ctx context.Context, email, fullName string, roleIDs []string) (domain.User, error) {
+	placeholder, err := authsecurity.NewRefreshToken()
+	if err != nil {
+		return domain.User{}, err
+	}
+	hash, err := authsecurity.HashPassword(placeholder)
+	if err != nil {
+		return domain.User{}, err
+	}
+	return s.repo.CreateUser(ctx, email, hash, fullName, "invited", roleIDs, false)
 }
 
 func (s *Service) GetUser(ctx context.Context, id string) (domain.User, error) {
@@ -45,6 +69,9 @@ func (s *Service) GetUser(ctx context.Context, id string) (domain.User, error) {
 func (s *Service) UpdateUser(ctx context.Context, id, email, fullName string, password *string) (domain.User, error) {
 	var passHash *string
 	if password != nil && *password != "" {
+		if err := s.passwords.Validate(*password, email); err != nil {
+			return domain.User{}, err
+		}
 		hash, err := authsecurity.HashPassword(*password)
 		if err != nil {
 			return domain.User{}, err
@@ -58,7 +85,16 @@ func (s *Service) UpdateUserStatus(ctx context.Context, id, status string) (doma
 	if status != "active" && status != "disabled" && status != "deleted" {
 		return domain.User{}, ErrInvalidStatus
 	}
-	return s.repo.SetUserStatus(ctx, id, status)
+	user, err := s.repo.SetUserStatus(ctx, id, status)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if status != "active" {
+		if err := s.repo.RevokeUserSessions(ctx, id); err != nil {
+			return domain.User{}, err
+		}
+	}
+	return user, nil
 }
 
 func (s *Service) SetUserRoles(ctx context.Context, id string, roleIDs []string) (domain.User, error) {
@@ -71,3 +107,75 @@ func (s *Service) SetUserRoles(ctx context.Context, id string, roleIDs []string)
 	}
 	return user, err
 }
+
+func (s *Service) ResetUserMFA(ctx context.Context, id string) error {
+	if _, err := s.repo.GetUserByID(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return s.repo.ResetUserMFA(ctx, id)
+}
+
+func (s *Service) ListUserSessions(ctx context.Context, id string) ([]domain.Session, error) {
+	if _, err := s.repo.GetUserByID(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return s.repo.ListUserSessions(ctx, id)
+}
+
+func (s *Service) RevokeUserSession(ctx context.Context, id, familyID string) error {
+	revoked, err := s.repo.RevokeUserSession(ctx, id, familyID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *Service) RevokeUserSessions(ctx context.Context, id string) error {
+	if _, err := s.repo.GetUserByID(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return s.repo.RevokeUserSessions(ctx, id)
+}
+
+func (s *Service) ListLoginEvents(ctx context.Context, id string, limit int) ([]domain.LoginEvent, error) {
+	if _, err := s.repo.GetUserByID(ctx, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.ListLoginEvents(ctx, id, limit)
+}
+
+func (s *Service) UnlockLogin(ctx context.Context, id, actorUserID string) error {
+	err := s.repo.UnlockLogin(ctx, id, actorUserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+func (s *Service) SetUserWorker(ctx context.Context, id string, workerID *int64) (domain.User, error) {
+	if err := s.repo.SetUserWorker(ctx, id, workerID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.User{}, ErrUserNotFound
+		}
+		return domain.User{}, err
+	}
+	return s.GetUser(ctx, id)
+}
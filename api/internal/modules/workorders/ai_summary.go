@@ -71,7 +71,8 @@ func (h *Handler) GenerateAISummary(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
 		return
 	}
-	cacheKey := fmt.Sprintf("work-order:%d", referenceID)
+	visible := visibleWorkOrderFields(c)
+	cacheKey := fmt.Sprintf("work-order:%d:%s", referenceID, visible.Key())
 	if h.aiSummaryCache != nil {
 		if cached := h.aiSummaryCache.Get(cacheKey); cached != nil {
 			value := cached.Value()
@@ -102,12 +103,13 @@ func (h *Handler) GenerateAISummary(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch work order"})
 		return
 	}
-	repairLogs, err := h.service.ListRepairLogs(c.Request.Context(), referenceID)
+	workOrderFieldPolicy.Apply(&item, visible)
+	repairLogs, err := h.service.ListRepairLogs(c.Request.Context(), referenceID, WorkOrderAccess{})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch repair logs"})
 		return
 	}
-	partsRequests, err := h.service.ListPartsPurchaseRequests(c.Request.Context(), referenceID)
+	partsRequests, err := h.service.ListPartsPurchaseRequests(c.Request.Context(), referenceID, WorkOrderAccess{})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch parts purchase requests"})
 		return
@@ -177,7 +179,7 @@ func (h *Handler) GenerateAIWorkDoneFromRepairLogs(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch work order"})
 		return
 	}
-	repairLogs, err := h.service.ListRepairLogs(c.Request.Context(), referenceID)
+	repairLogs, err := h.service.ListRepairLogs(c.Request.Context(), referenceID, WorkOrderAccess{})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch repair logs"})
 		return
@@ -248,13 +250,14 @@ func (h *Handler) generateAIMarkdown(c *gin.Context, referenceID *int) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch work order"})
 			return
 		}
+		redactWorkOrderFields(c, &detail)
 		item = &detail
-		repairLogs, err = h.service.ListRepairLogs(c.Request.Context(), *referenceID)
+		repairLogs, err = h.service.ListRepairLogs(c.Request.Context(), *referenceID, WorkOrderAccess{})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch repair logs"})
 			return
 		}
-		partsRequests, err = h.service.ListPartsPurchaseRequests(c.Request.Context(), *referenceID)
+		partsRequests, err = h.service.ListPartsPurchaseRequests(c.Request.Context(), *referenceID, WorkOrderAccess{})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch parts purchase requests"})
 			return
@@ -521,6 +524,7 @@ func buildWorkOrderAISummaryPrompt(template string, item domain.WorkOrderDetail,
 - Work Done: %s
 - Technicians: %s
 - Parts Total: %s
+- Rush Surcharge: %s
 - Labour Total: %s
 - Delivery Total: %s
 - Deposit: %.2f
@@ -543,6 +547,7 @@ func buildWorkOrderAISummaryPrompt(template string, item domain.WorkOrderDetail,
 		orUnknown(item.WorkDone),
 		orUnknown(joinOrEmpty(item.WorkerNames)),
 		formatMoney(item.PartsTotal),
+		formatMoney(item.RushSurchargeTotal),
 		formatMoney(item.LabourTotal),
 		formatMoney(item.DeliveryTotal),
 		item.Deposit,
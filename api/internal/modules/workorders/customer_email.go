@@ -115,7 +115,7 @@ func emailJobDetails(item domain.WorkOrderDetail) string {
 	}
 
 	if item.PartsTotal != nil || item.DeliveryTotal != nil || item.LabourTotal != nil {
-		total := float64Value(item.PartsTotal) + float64Value(item.DeliveryTotal) + float64Value(item.LabourTotal)
+		total := float64Value(item.PartsTotal) + float64Value(item.DeliveryTotal) + float64Value(item.LabourTotal) + float64Value(item.RushSurchargeTotal)
 		details = append(details, fmt.Sprintf("Estimated total before deposit: %s", formatEmailCurrency(total*1.13)))
 	}
 
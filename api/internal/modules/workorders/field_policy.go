@@ -0,0 +1,60 @@
+package workorders
+
+import (
+	"strings"
+
+	"humphreys/api/internal/fieldpolicy"
+	"humphreys/api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	fieldClassCustomerContact = "customer_contact"
+	fieldClassFinancials      = "financials"
+	fieldClassPaymentMethods  = "payment_methods"
+	fieldClassLineItems       = "line_items"
+)
+
+// workOrderFieldPolicy decides which classified fields of work order
+// responses, AI prompts and customer emails a caller may see. Every class
+// sits behind work_orders_sensitive:read today; giving one its own
+// permission only means changing its entry here.
+var workOrderFieldPolicy = fieldpolicy.New(map[string]string{
+	fieldClassCustomerContact: permSensitiveRead,
+	fieldClassFinancials:      permSensitiveRead,
+	fieldClassPaymentMethods:  permSensitiveRead,
+	fieldClassLineItems:       permSensitiveRead,
+})
+
+func visibleWorkOrderFields(c *gin.Context) fieldpolicy.Visibility {
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		return workOrderFieldPolicy.Visible(nil)
+	}
+	return workOrderFieldPolicy.Visible(claims.Scope)
+}
+
+// redactWorkOrderFields applies the field policy to a work order response
+// (or a slice of them) in place.
+func redactWorkOrderFields(c *gin.Context, target any) {
+	workOrderFieldPolicy.Apply(target, visibleWorkOrderFields(c))
+}
+
+// redactCustomerLookupOptions also rebuilds labels that were hidden along
+// with the contact details they embed.
+func redactCustomerLookupOptions(c *gin.Context, items []CustomerLookupOption) {
+	redactWorkOrderFields(c, &items)
+	for i := range items {
+		if items[i].Label != "" {
+			continue
+		}
+		first := strings.TrimSpace(stringValue(items[i].FirstName))
+		last := strings.TrimSpace(stringValue(items[i].LastName))
+		name := strings.TrimSpace(strings.Join([]string{first, last}, " "))
+		if name == "" {
+			name = "Unknown"
+		}
+		items[i].Label = name
+	}
+}
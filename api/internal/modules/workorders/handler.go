@@ -49,6 +49,21 @@ type updateStatusRequest struct {
 	StatusID *int64 `json:"status_id"`
 }
 
+type updateTeamRequest struct {
+	TeamID *int64 `json:"team_id"`
+}
+
+type updateScheduleRequest struct {
+	Priority string     `json:"priority"`
+	DueAt    *time.Time `json:"due_at"`
+	IsRush   *bool      `json:"is_rush"`
+	Reason   *string    `json:"reason"`
+}
+
+type setRushSettingsRequest struct {
+	Surcharge *float64 `json:"surcharge"`
+}
+
 type updateWorkNotesRequest struct {
 	ProblemDescription *string `json:"problem_description"`
 	WorkerIDs          []int32 `json:"worker_ids"`
@@ -140,6 +155,9 @@ type createWorkOrderRequest struct {
 	AlbumCDCassetteQty     int32                           `json:"album_cd_cassette_qty" binding:"gte=0"`
 	Deposit                float64                         `json:"deposit" binding:"gte=0"`
 	DepositPaymentMethodID *int64                          `json:"deposit_payment_method_id"`
+	Priority               string                          `json:"priority"`
+	DueAt                  *time.Time                      `json:"due_at"`
+	IsRush                 bool                            `json:"is_rush"`
 }
 
 type createRepairLogRequest struct {
@@ -149,12 +167,18 @@ type createRepairLogRequest struct {
 }
 
 type createPartsPurchaseRequest struct {
-	Source     string  `json:"source" binding:"required"`
-	SourceURL  *string `json:"source_url"`
-	Status     *string `json:"status"`
-	TotalPrice float64 `json:"total_price"`
-	ItemName   string  `json:"item_name" binding:"required"`
-	Quantity   int32   `json:"quantity" binding:"required,gte=1"`
+	Source            string  `json:"source" binding:"required"`
+	SourceURL         *string `json:"source_url"`
+	Status            *string `json:"status"`
+	TotalPrice        float64 `json:"total_price"`
+	ItemName          string  `json:"item_name" binding:"required"`
+	Quantity          int32   `json:"quantity" binding:"required,gte=1"`
+	PartsItemPresetID *int64  `json:"parts_item_preset_id"`
+	SupplierID        *int64  `json:"supplier_id"`
+}
+
+type commentRequest struct {
+	Body string `json:"body" binding:"required"`
 }
 
 type updateRepairLogRequest struct {
@@ -164,21 +188,38 @@ type updateRepairLogRequest struct {
 }
 
 type updatePartsPurchaseRequest struct {
-	Source     string  `json:"source" binding:"required"`
-	SourceURL  *string `json:"source_url"`
-	Status     string  `json:"status" binding:"required"`
-	TotalPrice float64 `json:"total_price"`
-	ItemName   string  `json:"item_name" binding:"required"`
-	Quantity   int32   `json:"quantity" binding:"required,gte=1"`
+	Source            string  `json:"source" binding:"required"`
+	SourceURL         *string `json:"source_url"`
+	Status            string  `json:"status" binding:"required"`
+	TotalPrice        float64 `json:"total_price"`
+	ItemName          string  `json:"item_name" binding:"required"`
+	Quantity          int32   `json:"quantity" binding:"required,gte=1"`
+	PartsItemPresetID *int64  `json:"parts_item_preset_id"`
+	SupplierID        *int64  `json:"supplier_id"`
+}
+
+type decidePartsPurchaseRequest struct {
+	Decision string  `json:"decision" binding:"required"`
+	Comment  *string `json:"comment"`
+}
+
+type setPartsApprovalThresholdRequest struct {
+	Threshold *float64 `json:"threshold"`
+}
+
+type setPartsApprovalLimitRequest struct {
+	MaxTotalPrice *float64 `json:"max_total_price" binding:"required"`
 }
 
 type dashboardResponse struct {
-	ReadyTotal       int64                             `json:"ready_total"`
-	OverdueTotal     int64                             `json:"overdue_total"`
-	ReadyItems       []domain.DashboardWorkOrderItem   `json:"ready_items"`
-	OverdueItems     []domain.DashboardOverdueItem     `json:"overdue_items"`
-	PartsReviewItems []domain.DashboardPartsReviewItem `json:"parts_review_items"`
-	ActivityItems    []domain.DashboardActivityItem    `json:"activity_items"`
+	ReadyTotal          int64                             `json:"ready_total"`
+	OverdueTotal        int64                             `json:"overdue_total"`
+	PromiseOverdueTotal int64                             `json:"promise_overdue_total"`
+	ReadyItems          []domain.DashboardWorkOrderItem   `json:"ready_items"`
+	OverdueItems        []domain.DashboardOverdueItem     `json:"overdue_items"`
+	PromiseOverdueItems []domain.DashboardPromiseItem     `json:"promise_overdue_items"`
+	PartsReviewItems    []domain.DashboardPartsReviewItem `json:"parts_review_items"`
+	ActivityItems       []domain.DashboardActivityItem    `json:"activity_items"`
 }
 
 func New(db *pgxpool.Pool) *Handler {
@@ -246,6 +287,11 @@ func (h *Handler) ListWorkOrders(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid created_to (expected YYYY-MM-DD)"})
 		return
 	}
+	teamID, err := parsePositiveInt64Query(c.Query("team_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid team_id"})
+		return
+	}
 	filters := WorkOrderListFilters{
 		CustomerID:  customerID,
 		StatusID:    statusID,
@@ -253,10 +299,11 @@ func (h *Handler) ListWorkOrders(c *gin.Context) {
 		ItemID:      itemID,
 		CreatedFrom: createdFrom,
 		CreatedTo:   createdTo,
+		TeamID:      teamID,
 	}
-	includeSensitive := hasPermission(c, permSensitiveRead)
+	visible := visibleWorkOrderFields(c)
 
-	items, err := h.service.ListWorkOrders(c.Request.Context(), query, filters, includeSensitive, page, pageSize)
+	items, err := h.service.ListWorkOrders(c.Request.Context(), query, filters, workOrderAccess(c, permRead), visible.Allows(fieldClassCustomerContact), page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":  "failed to list work orders",
@@ -264,12 +311,7 @@ func (h *Handler) ListWorkOrders(c *gin.Context) {
 		})
 		return
 	}
-	if !includeSensitive {
-		for i := range items {
-			items[i].CustomerEmail = nil
-			items[i].LabourTotal = nil
-		}
-	}
+	workOrderFieldPolicy.Apply(&items, visible)
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
 
@@ -285,9 +327,18 @@ func (h *Handler) Dashboard(c *gin.Context) {
 	readyPageSize := parsePositiveIntOrDefault(c.Query("ready_page_size"), 10)
 	overduePage := parsePositiveIntOrDefault(c.Query("overdue_page"), 1)
 	overduePageSize := parsePositiveIntOrDefault(c.Query("overdue_page_size"), 10)
+	teamID, err := parsePositiveInt64Query(c.Query("team_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid team_id"})
+		return
+	}
 
-	includeParts := hasPermission(c, permPartsRead) && hasPermission(c, permSensitiveRead)
+	includeParts := hasPermission(c, permPartsRead) && visibleWorkOrderFields(c).Allows(fieldClassFinancials)
 	includeActivity := hasPermission(c, permRepairLogsRead)
+	var approverUserID *string
+	if claims, ok := middleware.Claims(c); ok && hasPermission(c, permPartsApprove) {
+		approverUserID = &claims.UserID
+	}
 
 	data, err := h.service.GetDashboardData(c.Request.Context(), DashboardQueryInput{
 		RangeStart:      rangeStart,
@@ -297,6 +348,8 @@ func (h *Handler) Dashboard(c *gin.Context) {
 		OverduePageSize: overduePageSize,
 		IncludeParts:    includeParts,
 		IncludeActivity: includeActivity,
+		ApproverUserID:  approverUserID,
+		TeamID:          teamID,
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load dashboard"})
@@ -305,12 +358,14 @@ func (h *Handler) Dashboard(c *gin.Context) {
 	h.signDashboardActivityMarkdown(c.Request.Context(), data.ActivityItems)
 
 	c.JSON(http.StatusOK, dashboardResponse{
-		ReadyTotal:       data.ReadyTotal,
-		OverdueTotal:     data.OverdueTotal,
-		ReadyItems:       data.ReadyItems,
-		OverdueItems:     data.OverdueItems,
-		PartsReviewItems: data.PartsReviewItems,
-		ActivityItems:    data.ActivityItems,
+		ReadyTotal:          data.ReadyTotal,
+		OverdueTotal:        data.OverdueTotal,
+		PromiseOverdueTotal: data.PromiseOverdueTotal,
+		ReadyItems:          data.ReadyItems,
+		OverdueItems:        data.OverdueItems,
+		PromiseOverdueItems: data.PromiseOverdueItems,
+		PartsReviewItems:    data.PartsReviewItems,
+		ActivityItems:       data.ActivityItems,
 	})
 }
 
@@ -375,7 +430,7 @@ func (h *Handler) GetWorkOrder(c *gin.Context) {
 		return
 	}
 
-	item, err := h.service.GetWorkOrderDetail(c.Request.Context(), referenceID)
+	item, err := h.service.GetAccessibleWorkOrderDetail(c.Request.Context(), referenceID, workOrderAccess(c, permRead))
 	if errors.Is(err, ErrWorkOrderNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "work order not found"})
 		return
@@ -384,9 +439,7 @@ func (h *Handler) GetWorkOrder(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch work order"})
 		return
 	}
-	if !hasPermission(c, permSensitiveRead) {
-		item = sanitizeWorkOrderDetail(item)
-	}
+	redactWorkOrderFields(c, &item)
 	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
 	c.JSON(http.StatusOK, item)
 }
@@ -413,6 +466,7 @@ func (h *Handler) SendCustomerEmail(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch work order"})
 		return
 	}
+	redactWorkOrderFields(c, &item)
 
 	msg, err := buildCustomerEmailMessage(item, strings.TrimSpace(req.Template))
 	if err != nil {
@@ -455,26 +509,7 @@ func (h *Handler) ListCustomers(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list customers"})
 		return
 	}
-	if !hasPermission(c, permSensitiveRead) {
-		for i := range items {
-			first := strings.TrimSpace(stringValue(items[i].FirstName))
-			last := strings.TrimSpace(stringValue(items[i].LastName))
-			name := strings.TrimSpace(strings.Join([]string{first, last}, " "))
-			if name == "" {
-				name = "Unknown"
-			}
-			items[i].Label = name
-			items[i].Email = nil
-			items[i].HomePhone = nil
-			items[i].WorkPhone = nil
-			items[i].PostalCode = nil
-			items[i].Remark = nil
-			items[i].AddressLine1 = nil
-			items[i].AddressLine2 = nil
-			items[i].City = nil
-			items[i].Province = nil
-		}
-	}
+	redactCustomerLookupOptions(c, items)
 	c.JSON(http.StatusOK, gin.H{"items": items})
 }
 
@@ -535,6 +570,9 @@ func (h *Handler) CreateWorkOrder(c *gin.Context) {
 		AlbumCDCassetteQty:     req.AlbumCDCassetteQty,
 		Deposit:                req.Deposit,
 		DepositPaymentMethodID: req.DepositPaymentMethodID,
+		Priority:               req.Priority,
+		DueAt:                  req.DueAt,
+		IsRush:                 req.IsRush,
 	})
 	if err != nil {
 		if errors.Is(err, ErrInvalidCustomerSelection) ||
@@ -552,13 +590,15 @@ func (h *Handler) CreateWorkOrder(c *gin.Context) {
 			errors.Is(err, ErrPhoneDigitsOnly) ||
 			errors.Is(err, ErrCustomerNotFound) ||
 			errors.Is(err, ErrPaymentMethodNotFound) ||
-			errors.Is(err, ErrLocationNotFound) {
+			errors.Is(err, ErrLocationNotFound) ||
+			errors.Is(err, ErrInvalidPriority) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create work order", "detail": err.Error()})
 		return
 	}
+	redactWorkOrderFields(c, &item)
 	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
 	c.JSON(http.StatusCreated, item)
 }
@@ -647,6 +687,7 @@ func (h *Handler) UpdateEquipment(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update work order"})
 		return
 	}
+	redactWorkOrderFields(c, &item)
 	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
 	c.JSON(http.StatusOK, item)
 }
@@ -675,9 +716,193 @@ func (h *Handler) UpdateStatus(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update status"})
 		return
 	}
-	if !hasPermission(c, permSensitiveRead) {
-		item = sanitizeWorkOrderDetail(item)
+	redactWorkOrderFields(c, &item)
+	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
+	c.JSON(http.StatusOK, item)
+}
+
+// Queue lists the caller's open work orders in the order they should be
+// picked up. Callers with full read access can pass worker_id to see another
+// technician's queue.
+func (h *Handler) Queue(c *gin.Context) {
+	workerID, err := parsePositiveInt64Query(c.Query("worker_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid worker_id"})
+		return
+	}
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
 	}
+
+	resolvedWorkerID, items, err := h.service.GetQueue(c.Request.Context(), claims.UserID, workerID, workOrderAccess(c, permRead))
+	if errors.Is(err, ErrWorkerNotLinked) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load queue"})
+		return
+	}
+	redactWorkOrderFields(c, &items)
+	c.JSON(http.StatusOK, gin.H{"worker_id": resolvedWorkerID, "items": items})
+}
+
+func (h *Handler) UpdateSchedule(c *gin.Context) {
+	referenceID, err := strconv.Atoi(c.Param("reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
+		return
+	}
+
+	var req updateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	item, err := h.service.UpdateSchedule(c.Request.Context(), referenceID, ScheduleUpdateInput{
+		Priority:        req.Priority,
+		DueAt:           req.DueAt,
+		IsRush:          req.IsRush,
+		Reason:          req.Reason,
+		ChangedByUserID: claims.UserID,
+	})
+	if errors.Is(err, ErrWorkOrderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "work order not found"})
+		return
+	}
+	if errors.Is(err, ErrInvalidPriority) || errors.Is(err, ErrDueDateChangeReasonRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update schedule"})
+		return
+	}
+	redactWorkOrderFields(c, &item)
+	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) ListDueDateChanges(c *gin.Context) {
+	referenceID, err := strconv.Atoi(c.Param("reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
+		return
+	}
+
+	items, err := h.service.ListDueDateChanges(c.Request.Context(), referenceID)
+	if errors.Is(err, ErrWorkOrderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "work order not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list due date changes"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) PromiseReport(c *gin.Context) {
+	from, err := time.Parse("2006-01-02", strings.TrimSpace(c.Query("from")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date (YYYY-MM-DD)"})
+		return
+	}
+	to, err := time.Parse("2006-01-02", strings.TrimSpace(c.Query("to")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date (YYYY-MM-DD)"})
+		return
+	}
+
+	report, err := h.service.GetPromiseReport(c.Request.Context(), from, to)
+	if errors.Is(err, ErrInvalidReportPeriod) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load promise report"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Handler) AssignSelf(c *gin.Context) {
+	h.setSelfAssignment(c, true)
+}
+
+func (h *Handler) UnassignSelf(c *gin.Context) {
+	h.setSelfAssignment(c, false)
+}
+
+func (h *Handler) setSelfAssignment(c *gin.Context, assigned bool) {
+	referenceID, err := strconv.Atoi(c.Param("reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
+		return
+	}
+	claims, ok := middleware.Claims(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	item, err := h.service.SetSelfAssignment(c.Request.Context(), referenceID, claims.UserID, assigned, workOrderAccess(c, permRead))
+	if errors.Is(err, ErrWorkOrderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "work order not found"})
+		return
+	}
+	if errors.Is(err, ErrWorkerNotLinked) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update assignment"})
+		return
+	}
+	redactWorkOrderFields(c, &item)
+	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) UpdateTeam(c *gin.Context) {
+	referenceID, err := strconv.Atoi(c.Param("reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
+		return
+	}
+
+	var req updateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.TeamID != nil && *req.TeamID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid team_id"})
+		return
+	}
+
+	item, err := h.service.UpdateTeam(c.Request.Context(), referenceID, req.TeamID)
+	if errors.Is(err, ErrWorkOrderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "work order not found"})
+		return
+	}
+	if errors.Is(err, ErrTeamNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update team"})
+		return
+	}
+	redactWorkOrderFields(c, &item)
 	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
 	c.JSON(http.StatusOK, item)
 }
@@ -762,6 +987,7 @@ func (h *Handler) UpdateWorkNotes(c *gin.Context) {
 			pointerStringValue(item.WorkDone),
 		)
 	}
+	redactWorkOrderFields(c, &item)
 	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
 	c.JSON(http.StatusOK, item)
 }
@@ -796,6 +1022,7 @@ func (h *Handler) UpdateTotals(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update work order"})
 		return
 	}
+	redactWorkOrderFields(c, &item)
 	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
 	c.JSON(http.StatusOK, item)
 }
@@ -837,6 +1064,7 @@ func (h *Handler) UpdateLineItems(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update line items"})
 		return
 	}
+	redactWorkOrderFields(c, &item)
 	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
 	c.JSON(http.StatusOK, item)
 }
@@ -875,6 +1103,7 @@ func (h *Handler) UpdateCustomer(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update work order"})
 		return
 	}
+	redactWorkOrderFields(c, &item)
 	h.signWorkOrderDetailMarkdown(c.Request.Context(), &item)
 	c.JSON(http.StatusOK, item)
 }
@@ -885,7 +1114,7 @@ func (h *Handler) ListRepairLogs(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
 		return
 	}
-	items, err := h.service.ListRepairLogs(c.Request.Context(), referenceID)
+	items, err := h.service.ListRepairLogs(c.Request.Context(), referenceID, workOrderAccess(c, permRepairLogsRead))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list repair logs"})
 		return
@@ -895,7 +1124,7 @@ func (h *Handler) ListRepairLogs(c *gin.Context) {
 }
 
 func (h *Handler) ListAllPartsPurchaseRequests(c *gin.Context) {
-	items, err := h.service.ListAllPartsPurchaseRequests(c.Request.Context())
+	items, err := h.service.ListAllPartsPurchaseRequests(c.Request.Context(), workOrderAccess(c, permPartsRead))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list parts purchase requests"})
 		return
@@ -921,7 +1150,12 @@ func (h *Handler) CreateRepairLog(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
+	access := workOrderAccess(c, permRepairLogsCreate)
 	if h.uploads != nil {
+		if err := h.service.CheckWorkOrderAccess(c.Request.Context(), referenceID, access); err != nil {
+			writeWorkOrderLookupError(c, err)
+			return
+		}
 		promoted, err := h.uploads.PromoteTempImagesInMarkdown(c.Request.Context(), req.Details, referenceID, claims.UserID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process markdown images", "detail": err.Error()})
@@ -935,8 +1169,13 @@ func (h *Handler) CreateRepairLog(c *gin.Context) {
 		HoursUsed:       req.HoursUsed,
 		Details:         req.Details,
 		CreatedByUserID: claims.UserID,
+		Access:          access,
 	})
 	if err != nil {
+		if errors.Is(err, ErrWorkOrderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		if errors.Is(err, ErrInvalidRepairLogDetails) || errors.Is(err, ErrInvalidRepairLogHoursUsed) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -966,6 +1205,7 @@ func (h *Handler) UpdateRepairLog(c *gin.Context) {
 		return
 	}
 
+	access := workOrderAccess(c, permRepairLogsUpdate)
 	previousDetails := ""
 	if h.uploads != nil {
 		claims, ok := middleware.Claims(c)
@@ -974,7 +1214,11 @@ func (h *Handler) UpdateRepairLog(c *gin.Context) {
 			return
 		}
 
-		logs, err := h.service.ListRepairLogs(c.Request.Context(), referenceID)
+		if err := h.service.CheckWorkOrderAccess(c.Request.Context(), referenceID, access); err != nil {
+			writeWorkOrderLookupError(c, err)
+			return
+		}
+		logs, err := h.service.ListRepairLogs(c.Request.Context(), referenceID, access)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch repair logs"})
 			return
@@ -1001,6 +1245,7 @@ func (h *Handler) UpdateRepairLog(c *gin.Context) {
 		RepairDate: req.RepairDate,
 		HoursUsed:  req.HoursUsed,
 		Details:    req.Details,
+		Access:     access,
 	})
 	if err != nil {
 		if errors.Is(err, ErrRepairLogNotFound) {
@@ -1033,9 +1278,10 @@ func (h *Handler) DeleteRepairLog(c *gin.Context) {
 		return
 	}
 
+	access := workOrderAccess(c, permRepairLogsDelete)
 	repairLogDetails := ""
 	if h.uploads != nil {
-		logs, err := h.service.ListRepairLogs(c.Request.Context(), referenceID)
+		logs, err := h.service.ListRepairLogs(c.Request.Context(), referenceID, access)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch repair logs"})
 			return
@@ -1048,7 +1294,7 @@ func (h *Handler) DeleteRepairLog(c *gin.Context) {
 		}
 	}
 
-	if err := h.service.DeleteRepairLog(c.Request.Context(), referenceID, repairLogID); err != nil {
+	if err := h.service.DeleteRepairLog(c.Request.Context(), referenceID, repairLogID, access); err != nil {
 		if errors.Is(err, ErrRepairLogNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
@@ -1062,13 +1308,249 @@ func (h *Handler) DeleteRepairLog(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+func (h *Handler) ListComments(c *gin.Context) {
+	referenceID, err := strconv.Atoi(c.Param("reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
+		return
+	}
+	items, err := h.service.ListComments(c.Request.Context(), referenceID, workOrderAccess(c, permCommentsRead))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list comments"})
+		return
+	}
+	for i := range items {
+		items[i].Body = h.signMarkdownForResponse(c.Request.Context(), items[i].Body)
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) CreateComment(c *gin.Context) {
+	referenceID, err := strconv.Atoi(c.Param("reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
+		return
+	}
+
+	claims, ok := middleware.Claims(c)
+	if !ok || claims.UserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+
+	var req commentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	access := workOrderAccess(c, permCommentsRead)
+	if h.uploads != nil {
+		if err := h.service.CheckWorkOrderAccess(c.Request.Context(), referenceID, access); err != nil {
+			writeCommentError(c, err, "failed to fetch work order")
+			return
+		}
+		promoted, err := h.uploads.PromoteTempImagesInMarkdown(c.Request.Context(), req.Body, referenceID, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process markdown images", "detail": err.Error()})
+			return
+		}
+		req.Body = h.uploads.NormalizeMarkdownImageURLsForStorage(promoted)
+	}
+
+	item, err := h.service.CreateComment(c.Request.Context(), referenceID, CommentInput{
+		Body:   req.Body,
+		UserID: claims.UserID,
+		Access: access,
+	})
+	if err != nil {
+		writeCommentError(c, err, "failed to create comment")
+		return
+	}
+	item.Body = h.signMarkdownForResponse(c.Request.Context(), item.Body)
+	c.JSON(http.StatusCreated, item)
+}
+
+// UpdateComment keeps images dropped from the body in storage, because the
+// comment's revisions still show them. They are removed with the comment.
+func (h *Handler) UpdateComment(c *gin.Context) {
+	referenceID, commentID, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.Claims(c)
+	if !ok || claims.UserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+
+	var req commentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	access := workOrderAccess(c, permCommentsRead)
+	if h.uploads != nil {
+		if err := h.service.CheckWorkOrderAccess(c.Request.Context(), referenceID, access); err != nil {
+			writeCommentError(c, err, "failed to fetch work order")
+			return
+		}
+		promoted, err := h.uploads.PromoteTempImagesInMarkdown(c.Request.Context(), req.Body, referenceID, claims.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process markdown images", "detail": err.Error()})
+			return
+		}
+		req.Body = h.uploads.NormalizeMarkdownImageURLsForStorage(promoted)
+	}
+
+	item, err := h.service.UpdateComment(c.Request.Context(), referenceID, commentID, CommentInput{
+		Body:            req.Body,
+		UserID:          claims.UserID,
+		Access:          access,
+		MayChangeOthers: hasPermission(c, permCommentsUpdate),
+	})
+	if err != nil {
+		writeCommentError(c, err, "failed to update comment")
+		return
+	}
+	item.Body = h.signMarkdownForResponse(c.Request.Context(), item.Body)
+	c.JSON(http.StatusOK, item)
+}
+
+func (h *Handler) DeleteComment(c *gin.Context) {
+	referenceID, commentID, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+
+	claims, ok := middleware.Claims(c)
+	if !ok || claims.UserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+
+	access := workOrderAccess(c, permCommentsRead)
+	markdown := make([]string, 0)
+	if h.uploads != nil {
+		comment, err := h.service.GetComment(c.Request.Context(), referenceID, commentID, access)
+		if err != nil {
+			writeCommentError(c, err, "failed to fetch comment")
+			return
+		}
+		revisions, err := h.service.ListCommentRevisions(c.Request.Context(), referenceID, commentID, access)
+		if err != nil {
+			writeCommentError(c, err, "failed to fetch comment revisions")
+			return
+		}
+		markdown = append(markdown, comment.Body)
+		for _, revision := range revisions {
+			markdown = append(markdown, revision.Body)
+		}
+	}
+
+	if err := h.service.DeleteComment(c.Request.Context(), referenceID, commentID, CommentInput{
+		UserID:          claims.UserID,
+		Access:          access,
+		MayChangeOthers: hasPermission(c, permCommentsDelete),
+	}); err != nil {
+		writeCommentError(c, err, "failed to delete comment")
+		return
+	}
+	if h.uploads != nil {
+		h.uploads.DeleteManagedImagesInMarkdown(c.Request.Context(), strings.Join(markdown, "\n\n"))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ListCommentRevisions(c *gin.Context) {
+	referenceID, commentID, ok := parseCommentParams(c)
+	if !ok {
+		return
+	}
+	items, err := h.service.ListCommentRevisions(c.Request.Context(), referenceID, commentID, workOrderAccess(c, permCommentsRead))
+	if err != nil {
+		writeCommentError(c, err, "failed to list comment revisions")
+		return
+	}
+	for i := range items {
+		items[i].Body = h.signMarkdownForResponse(c.Request.Context(), items[i].Body)
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) ListMentionableUsers(c *gin.Context) {
+	referenceID, err := strconv.Atoi(c.Param("reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
+		return
+	}
+	items, err := h.service.ListMentionableUsers(c.Request.Context(), referenceID, c.Query("q"), workOrderAccess(c, permCommentsRead))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list users"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func parseCommentParams(c *gin.Context) (int, int64, bool) {
+	referenceID, err := strconv.Atoi(c.Param("reference_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
+		return 0, 0, false
+	}
+	commentID, err := strconv.ParseInt(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid comment_id"})
+		return 0, 0, false
+	}
+	return referenceID, commentID, true
+}
+
+func writeCommentError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, ErrWorkOrderNotFound), errors.Is(err, ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidCommentBody):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrCommentNotAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// parsePartsReferenceID reads the work order of a parts request route. Routes
+// outside /work-orders address restock requests, which have no work order and
+// are looked up with a zero reference ID.
+func parsePartsReferenceID(c *gin.Context) (int, bool) {
+	raw := c.Param("reference_id")
+	if raw == "" {
+		return 0, true
+	}
+	referenceID, err := strconv.Atoi(raw)
+	if err != nil || referenceID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
+		return 0, false
+	}
+	return referenceID, true
+}
+
+// writeWorkOrderLookupError answers a failed CheckWorkOrderAccess.
+func writeWorkOrderLookupError(c *gin.Context, err error) {
+	if errors.Is(err, ErrWorkOrderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch work order"})
+}
+
 func (h *Handler) ListPartsPurchaseRequests(c *gin.Context) {
 	referenceID, err := strconv.Atoi(c.Param("reference_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reference_id"})
 		return
 	}
-	items, err := h.service.ListPartsPurchaseRequests(c.Request.Context(), referenceID)
+	items, err := h.service.ListPartsPurchaseRequests(c.Request.Context(), referenceID, workOrderAccess(c, permPartsRead))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list parts purchase requests"})
 		return
@@ -1096,23 +1578,36 @@ func (h *Handler) CreatePartsPurchaseRequest(c *gin.Context) {
 	}
 
 	item, err := h.service.CreatePartsPurchaseRequest(c.Request.Context(), referenceID, CreatePartsPurchaseRequestInput{
-		Source:          req.Source,
-		SourceURL:       req.SourceURL,
-		Status:          req.Status,
-		TotalPrice:      req.TotalPrice,
-		ItemName:        req.ItemName,
-		Quantity:        req.Quantity,
-		CreatedByUserID: claims.UserID,
+		Source:            req.Source,
+		SourceURL:         req.SourceURL,
+		Status:            req.Status,
+		TotalPrice:        req.TotalPrice,
+		ItemName:          req.ItemName,
+		Quantity:          req.Quantity,
+		PartsItemPresetID: req.PartsItemPresetID,
+		SupplierID:        req.SupplierID,
+		CreatedByUserID:   claims.UserID,
+		Access:            workOrderAccess(c, permPartsCreate),
 	})
 	if err != nil {
+		if errors.Is(err, ErrWorkOrderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		if errors.Is(err, ErrInvalidPartsSource) ||
 			errors.Is(err, ErrInvalidPartsStatus) ||
 			errors.Is(err, ErrInvalidPartsItemName) ||
 			errors.Is(err, ErrInvalidPartsQuantity) ||
-			errors.Is(err, ErrInvalidPartsTotalPrice) {
+			errors.Is(err, ErrInvalidPartsTotalPrice) ||
+			errors.Is(err, ErrSupplierNotFound) ||
+			errors.Is(err, ErrPartsItemPresetNotFound) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, ErrPartsApprovalRequired) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create parts purchase request"})
 		return
 	}
@@ -1131,6 +1626,12 @@ func (h *Handler) UpdatePartsPurchaseRequest(c *gin.Context) {
 		return
 	}
 
+	claims, ok := middleware.Claims(c)
+	if !ok || claims.UserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+
 	var req updatePartsPurchaseRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
@@ -1138,12 +1639,16 @@ func (h *Handler) UpdatePartsPurchaseRequest(c *gin.Context) {
 	}
 
 	item, err := h.service.UpdatePartsPurchaseRequest(c.Request.Context(), referenceID, partsPurchaseRequestID, UpdatePartsPurchaseRequestInput{
-		Source:     req.Source,
-		SourceURL:  req.SourceURL,
-		Status:     req.Status,
-		TotalPrice: req.TotalPrice,
-		ItemName:   req.ItemName,
-		Quantity:   req.Quantity,
+		Source:            req.Source,
+		SourceURL:         req.SourceURL,
+		Status:            req.Status,
+		TotalPrice:        req.TotalPrice,
+		ItemName:          req.ItemName,
+		Quantity:          req.Quantity,
+		PartsItemPresetID: req.PartsItemPresetID,
+		SupplierID:        req.SupplierID,
+		UpdatedByUserID:   claims.UserID,
+		Access:            workOrderAccess(c, permPartsUpdate),
 	})
 	if err != nil {
 		if errors.Is(err, ErrPartsPurchaseRequestNotFound) {
@@ -1154,10 +1659,16 @@ func (h *Handler) UpdatePartsPurchaseRequest(c *gin.Context) {
 			errors.Is(err, ErrInvalidPartsStatus) ||
 			errors.Is(err, ErrInvalidPartsItemName) ||
 			errors.Is(err, ErrInvalidPartsQuantity) ||
-			errors.Is(err, ErrInvalidPartsTotalPrice) {
+			errors.Is(err, ErrInvalidPartsTotalPrice) ||
+			errors.Is(err, ErrSupplierNotFound) ||
+			errors.Is(err, ErrPartsItemPresetNotFound) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, ErrPartsApprovalRequired) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update parts purchase request"})
 		return
 	}
@@ -1176,8 +1687,8 @@ func (h *Handler) DeletePartsPurchaseRequest(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeletePartsPurchaseRequest(c.Request.Context(), referenceID, partsPurchaseRequestID); err != nil {
-		if errors.Is(err, ErrPartsPurchaseRequestNotFound) {
+	if err := h.service.DeletePartsPurchaseRequest(c.Request.Context(), referenceID, partsPurchaseRequestID, workOrderAccess(c, permPartsDelete)); err != nil {
+		if errors.Is(err, ErrWorkOrderNotFound) || errors.Is(err, ErrPartsPurchaseRequestNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
@@ -1187,6 +1698,167 @@ func (h *Handler) DeletePartsPurchaseRequest(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+func (h *Handler) ListPartsPurchaseRequestApprovals(c *gin.Context) {
+	referenceID, ok := parsePartsReferenceID(c)
+	if !ok {
+		return
+	}
+	partsPurchaseRequestID, err := strconv.ParseInt(c.Param("parts_purchase_request_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parts_purchase_request_id"})
+		return
+	}
+
+	items, err := h.service.ListPartsPurchaseRequestApprovals(c.Request.Context(), referenceID, partsPurchaseRequestID)
+	if err != nil {
+		if errors.Is(err, ErrPartsPurchaseRequestNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list parts purchase request approvals"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) DecidePartsPurchaseRequest(c *gin.Context) {
+	referenceID, ok := parsePartsReferenceID(c)
+	if !ok {
+		return
+	}
+	partsPurchaseRequestID, err := strconv.ParseInt(c.Param("parts_purchase_request_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parts_purchase_request_id"})
+		return
+	}
+
+	claims, ok := middleware.Claims(c)
+	if !ok || claims.UserID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
+		return
+	}
+
+	var req decidePartsPurchaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	approval, err := h.service.DecidePartsPurchaseRequest(c.Request.Context(), referenceID, partsPurchaseRequestID, DecidePartsPurchaseRequestInput{
+		Decision:       req.Decision,
+		Comment:        req.Comment,
+		ApproverUserID: claims.UserID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPartsPurchaseRequestNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrInvalidApprovalDecision),
+			errors.Is(err, ErrApprovalCommentRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrSelfApprovalNotAllowed),
+			errors.Is(err, ErrApprovalLimitExceeded):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrPartsRequestNotAwaitingApproval):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record parts purchase request approval"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, approval)
+}
+
+func (h *Handler) GetPartsApprovalSettings(c *gin.Context) {
+	settings, err := h.service.GetPartsApprovalSettings(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load parts approval settings"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) SetPartsApprovalThreshold(c *gin.Context) {
+	var req setPartsApprovalThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	settings, err := h.service.SetPartsApprovalThreshold(c.Request.Context(), req.Threshold)
+	if err != nil {
+		if errors.Is(err, ErrInvalidApprovalAmount) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update parts approval threshold"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) GetRushSettings(c *gin.Context) {
+	settings, err := h.service.GetRushSettings(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load rush settings"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) SetRushSettings(c *gin.Context) {
+	var req setRushSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	settings, err := h.service.SetRushSurcharge(c.Request.Context(), req.Surcharge)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRushSurcharge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update rush settings"})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *Handler) SetPartsApprovalLimit(c *gin.Context) {
+	var req setPartsApprovalLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	limit, err := h.service.SetPartsApprovalLimit(c.Request.Context(), c.Param("user_id"), *req.MaxTotalPrice)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrInvalidApprovalAmount):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update parts approval limit"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, limit)
+}
+
+func (h *Handler) DeletePartsApprovalLimit(c *gin.Context) {
+	if err := h.service.DeletePartsApprovalLimit(c.Request.Context(), c.Param("user_id")); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete parts approval limit"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
 func hasPermission(c *gin.Context, permission string) bool {
 	claims, ok := middleware.Claims(c)
 	if !ok {
@@ -1200,20 +1872,15 @@ func hasPermission(c *gin.Context, permission string) bool {
 	return false
 }
 
-func sanitizeWorkOrderDetail(detail domain.WorkOrderDetail) domain.WorkOrderDetail {
-	detail.Customer = domain.WorkOrderCustomer{
-		CustomerID: detail.Customer.CustomerID,
-		FirstName:  detail.Customer.FirstName,
-		LastName:   detail.Customer.LastName,
-	}
-	detail.PartsTotal = nil
-	detail.DeliveryTotal = nil
-	detail.LabourTotal = nil
-	detail.Deposit = 0
-	detail.LineItems = []domain.WorkOrderLineItem{}
-	detail.PaymentMethodIDs = []int64{}
-	detail.PaymentMethodNames = []string{}
-	return detail
+// workOrderAccess is unrestricted when the caller holds permission outright
+// and limited to their assigned work orders when they only hold its
+// ":assigned" form (RequirePermissionOrAssigned has checked one of the two).
+func workOrderAccess(c *gin.Context, permission string) WorkOrderAccess {
+	if hasPermission(c, permission) {
+		return WorkOrderAccess{}
+	}
+	claims, _ := middleware.Claims(c)
+	return WorkOrderAccess{AssignedUserID: claims.UserID}
 }
 
 func pointerStringValue(value *string) string {
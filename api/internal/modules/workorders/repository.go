@@ -6,37 +6,87 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"humphreys/api/internal/domain"
+	"humphreys/api/internal/modules/inventory"
+	"humphreys/api/internal/modules/partsmarkup"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Repository interface {
-	ListWorkOrders(ctx context.Context, query string, filters WorkOrderListFilters, includeSensitive bool, page, pageSize int) ([]domain.WorkOrderListItem, error)
-	GetWorkOrderDetail(ctx context.Context, referenceID int) (domain.WorkOrderDetail, error)
+	ListWorkOrders(ctx context.Context, query string, filters WorkOrderListFilters, access WorkOrderAccess, searchContact bool, page, pageSize int) ([]domain.WorkOrderListItem, error)
+	GetWorkOrderDetail(ctx context.Context, referenceID int, access WorkOrderAccess) (domain.WorkOrderDetail, error)
 	ListCustomers(ctx context.Context, query string) ([]CustomerLookupOption, error)
 	CreateWorkOrder(ctx context.Context, input CreateWorkOrderInput) (domain.WorkOrderDetail, error)
 	DeleteWorkOrder(ctx context.Context, referenceID int) error
 	UpdateStatus(ctx context.Context, referenceID int, statusID *int64) error
+	UpdateTeam(ctx context.Context, referenceID int, teamID *int64) error
+	UpdateSchedule(ctx context.Context, referenceID int, input ScheduleUpdateInput) error
+	ListDueDateChanges(ctx context.Context, referenceID int) ([]domain.WorkOrderDueDateChange, error)
+	GetRushSettings(ctx context.Context) (domain.RushSettings, error)
+	SetRushSurcharge(ctx context.Context, surcharge *float64) error
+	ListPromises(ctx context.Context, from, before time.Time) ([]domain.PromiseReportItem, error)
+	GetUserWorkerID(ctx context.Context, userID string) (*int64, error)
+	SetWorkerAssignment(ctx context.Context, referenceID int, workerID int64, assigned bool, access WorkOrderAccess) error
+	ListQueue(ctx context.Context, workerID int64, access WorkOrderAccess) ([]domain.WorkOrderQueueItem, error)
 	UpdateEquipment(ctx context.Context, referenceID int, input EquipmentUpdateInput) error
 	UpdateWorkNotes(ctx context.Context, referenceID int, input WorkNotesUpdateInput) error
 	UpdateLineItems(ctx context.Context, referenceID int, lineItems []LineItemUpsertInput) error
 	UpdateTotals(ctx context.Context, referenceID int, input TotalsUpdateInput) error
 	UpdateCustomer(ctx context.Context, referenceID int, input CustomerUpdateInput) error
-	ListAllPartsPurchaseRequests(ctx context.Context) ([]domain.PartsPurchaseRequest, error)
-	ListRepairLogs(ctx context.Context, referenceID int) ([]domain.RepairLog, error)
-	CreateRepairLog(ctx context.Context, referenceID int, repairDate *string, hoursUsed *float64, details, createdByUserID string) (domain.RepairLog, error)
-	UpdateRepairLog(ctx context.Context, referenceID int, repairLogID int64, repairDate *string, hoursUsed *float64, details *string) (domain.RepairLog, error)
-	DeleteRepairLog(ctx context.Context, referenceID int, repairLogID int64) error
-	ListPartsPurchaseRequests(ctx context.Context, referenceID int) ([]domain.PartsPurchaseRequest, error)
+	ListAllPartsPurchaseRequests(ctx context.Context, access WorkOrderAccess) ([]domain.PartsPurchaseRequest, error)
+	ListRepairLogs(ctx context.Context, referenceID int, access WorkOrderAccess) ([]domain.RepairLog, error)
+	CheckWorkOrderAccess(ctx context.Context, referenceID int, access WorkOrderAccess) error
+	CreateRepairLog(ctx context.Context, referenceID int, repairDate *string, hoursUsed *float64, details, createdByUserID string, access WorkOrderAccess) (domain.RepairLog, error)
+	UpdateRepairLog(ctx context.Context, referenceID int, repairLogID int64, repairDate *string, hoursUsed *float64, details *string, access WorkOrderAccess) (domain.RepairLog, error)
+	DeleteRepairLog(ctx context.Context, referenceID int, repairLogID int64, access WorkOrderAccess) error
+	ListComments(ctx context.Context, referenceID int, access WorkOrderAccess) ([]domain.WorkOrderComment, error)
+	GetComment(ctx context.Context, referenceID int, commentID int64, access WorkOrderAccess) (domain.WorkOrderComment, error)
+	CreateComment(ctx context.Context, referenceID int, body, userID string, mentionEmails []string, access WorkOrderAccess) (domain.WorkOrderComment, error)
+	UpdateComment(ctx context.Context, referenceID int, commentID int64, body, userID string, mentionEmails []string) (domain.WorkOrderComment, error)
+	DeleteComment(ctx context.Context, referenceID int, commentID int64) error
+	ListCommentRevisions(ctx context.Context, commentID int64) ([]domain.WorkOrderCommentRevision, error)
+	ListMentionableUsers(ctx context.Context, referenceID int, query string, access WorkOrderAccess) ([]domain.CommentUser, error)
+	ListPartsPurchaseRequests(ctx context.Context, referenceID int, access WorkOrderAccess) ([]domain.PartsPurchaseRequest, error)
 	CreatePartsPurchaseRequest(ctx context.Context, referenceID int, input CreatePartsPurchaseRequestInput) (domain.PartsPurchaseRequest, error)
 	UpdatePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64, input UpdatePartsPurchaseRequestInput) (domain.PartsPurchaseRequest, error)
-	DeletePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64) error
+	DeletePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64, access WorkOrderAccess) error
+	GetPartsApprovalThreshold(ctx context.Context) (*float64, error)
+	ListPartsPurchaseRequestApprovals(ctx context.Context, referenceID int, partsPurchaseRequestID int64) ([]domain.PartsPurchaseRequestApproval, error)
+	DecidePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64, input DecidePartsPurchaseRequestInput) (domain.PartsPurchaseRequestApproval, error)
+	GetPartsApprovalSettings(ctx context.Context) (domain.PartsApprovalSettings, error)
+	SetPartsApprovalThreshold(ctx context.Context, threshold *float64) error
+	SetPartsApprovalLimit(ctx context.Context, userID string, maxTotalPrice float64) (domain.PartsApprovalLimit, error)
+	DeletePartsApprovalLimit(ctx context.Context, userID string) error
 	GetDashboardData(ctx context.Context, input DashboardQueryInput) (domain.DashboardData, error)
 }
 
+// AppSettingPartsApprovalThreshold holds the parts request total above which
+// an approval is needed before the request can be ordered. Unset means no limit.
+const AppSettingPartsApprovalThreshold = "parts_approval_threshold"
+
+// AppSettingRushSurcharge holds the amount added as a line item to rush jobs.
+const AppSettingRushSurcharge = "rush_surcharge"
+
+const rushSurchargeItemName = "Rush surcharge"
+
+// completedAtSQL keeps work_orders.completed_at as the time the job first
+// reached a finished status group (staged or completed), given the SQL for
+// the new status id. Moving back to the bench clears it.
+func completedAtSQL(statusIDExpr string) string {
+	return fmt.Sprintf(`CASE
+				WHEN (
+					SELECT COALESCE(s.status_group, 'to_do')
+					FROM public.work_order_statuses s
+					WHERE s.status_id = %s
+				) IN ('staged', 'completed') THEN COALESCE(completed_at, now())
+				ELSE NULL
+			END`, statusIDExpr)
+}
+
 type storeRepository struct {
 	db *pgxpool.Pool
 }
@@ -45,7 +95,96 @@ func NewRepository(db *pgxpool.Pool) Repository {
 	return &storeRepository{db: db}
 }
 
-func (r *storeRepository) ListWorkOrders(ctx context.Context, query string, filters WorkOrderListFilters, includeSensitive bool, page, pageSize int) ([]domain.WorkOrderListItem, error) {
+// assignedToFilter limits rows to work orders whose worker_ids include the
+// worker linked to the user bound at $pos, or that belong to a team the user
+// is a member of. An empty user id disables it, so callers can always bind
+// WorkOrderAccess.AssignedUserID.
+func assignedToFilter(referenceIDExpr string, pos int) string {
+	return fmt.Sprintf(`($%[1]d::text = '' OR EXISTS (
+			SELECT 1
+			FROM public.work_orders awo
+			JOIN public.users au ON au.id::text = $%[1]d::text
+			WHERE awo.reference_id = %[2]s
+			  AND (
+				(au.worker_id IS NOT NULL AND au.worker_id = ANY(awo.worker_ids))
+				OR EXISTS (
+					SELECT 1
+					FROM public.team_members atm
+					WHERE atm.team_id = awo.team_id
+					  AND atm.user_id = au.id
+				)
+			  )
+		))`, pos, referenceIDExpr)
+}
+
+// rowQuerier is met by both the pool and a transaction.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// checkWorkOrderAccess reports work orders outside access as not found, so
+// callers limited to their assigned jobs cannot probe for other reference IDs.
+func checkWorkOrderAccess(ctx context.Context, q rowQuerier, referenceID int, access WorkOrderAccess) error {
+	var exists bool
+	if err := q.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM public.work_orders wo
+			WHERE wo.reference_id = $1
+			  AND `+assignedToFilter("wo.reference_id", 2)+`
+		)
+	`, referenceID, access.AssignedUserID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrWorkOrderNotFound
+	}
+	return nil
+}
+
+func (r *storeRepository) CheckWorkOrderAccess(ctx context.Context, referenceID int, access WorkOrderAccess) error {
+	return checkWorkOrderAccess(ctx, r.db, referenceID, access)
+}
+
+// permCommentsReadAssigned is the ":assigned" form of permCommentsRead, which
+// middleware.RequirePermissionOrAssigned accepts on the comment routes.
+const permCommentsReadAssigned = permCommentsRead + ":assigned"
+
+// commentReaderFilter matches users whose roles let them read the comments
+// of the work order: work_order_comments:read, or its ":assigned" form when
+// the job is assigned to them or their team.
+func commentReaderFilter(userIDExpr, referenceIDExpr string) string {
+	return fmt.Sprintf(`EXISTS (
+			SELECT 1
+			FROM public.user_roles cur
+			JOIN public.role_permissions crp ON crp.role_id = cur.role_id
+			JOIN public.permissions cp ON cp.id = crp.permission_id
+			WHERE cur.user_id = %[1]s
+			  AND (
+				cp.code = '%[3]s'
+				OR (
+					cp.code = '%[4]s'
+					AND EXISTS (
+						SELECT 1
+						FROM public.work_orders cwo
+						JOIN public.users cu ON cu.id = %[1]s
+						WHERE cwo.reference_id = %[2]s
+						  AND (
+							(cu.worker_id IS NOT NULL AND cu.worker_id = ANY(cwo.worker_ids))
+							OR EXISTS (
+								SELECT 1
+								FROM public.team_members ctm
+								WHERE ctm.team_id = cwo.team_id
+								  AND ctm.user_id = cu.id
+							)
+						  )
+					)
+				)
+			  )
+		)`, userIDExpr, referenceIDExpr, permCommentsRead, permCommentsReadAssigned)
+}
+
+func (r *storeRepository) ListWorkOrders(ctx context.Context, query string, filters WorkOrderListFilters, access WorkOrderAccess, searchContact bool, page, pageSize int) ([]domain.WorkOrderListItem, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -96,7 +235,7 @@ func (r *storeRepository) ListWorkOrders(ctx context.Context, query string, filt
 					COALESCE(c.full_name_search, '') ILIKE $%d
 				)
 		)`, argPos, argPos, argPos))
-		if includeSensitive {
+		if searchContact {
 			searchTerms = append(searchTerms, fmt.Sprintf(`EXISTS (
 				SELECT 1
 				FROM public.customers c
@@ -118,7 +257,7 @@ func (r *storeRepository) ListWorkOrders(ctx context.Context, query string, filt
 			args = append(args, brandIDs)
 			argPos++
 		}
-		if includeSensitive && digits != "" {
+		if searchContact && digits != "" {
 			searchTerms = append(searchTerms, fmt.Sprintf(`EXISTS (
 				SELECT 1
 				FROM public.customers c
@@ -164,6 +303,16 @@ func (r *storeRepository) ListWorkOrders(ctx context.Context, query string, filt
 		args = append(args, strings.TrimSpace(*filters.CreatedTo))
 		argPos++
 	}
+	if filters.TeamID != nil && *filters.TeamID > 0 {
+		clauses = append(clauses, fmt.Sprintf("wo.team_id = $%d", argPos))
+		args = append(args, *filters.TeamID)
+		argPos++
+	}
+	if access.AssignedUserID != "" {
+		clauses = append(clauses, assignedToFilter("wo.reference_id", argPos))
+		args = append(args, access.AssignedUserID)
+		argPos++
+	}
 
 	where := ""
 	if len(clauses) > 0 {
@@ -174,9 +323,6 @@ func (r *storeRepository) ListWorkOrders(ctx context.Context, query string, filt
 	customerNameSelect := "c.full_name_search AS customer_name"
 	customerEmailSelect := "c.email"
 	customerJoin := "LEFT JOIN public.customers c ON c.customer_id = wo.customer_id"
-	if !includeSensitive {
-		customerEmailSelect = "NULL::text AS email"
-	}
 	querySQL := fmt.Sprintf(`
 		WITH paged_work_orders AS (
 			SELECT
@@ -198,6 +344,11 @@ func (r *storeRepository) ListWorkOrders(ctx context.Context, query string, filt
 			loc.location_code,
 			loc.shelf,
 			loc.floor,
+			wo.team_id,
+			tm.team_name,
+			wo.priority,
+			wo.due_at,
+			wo.is_rush,
 			%s,
 			%s,
 			i.item_name,
@@ -216,6 +367,7 @@ func (r *storeRepository) ListWorkOrders(ctx context.Context, query string, filt
 		JOIN public.work_orders wo ON wo.reference_id = p.reference_id
 		%s
 		LEFT JOIN public.locations loc ON loc.location_id = wo.location_id
+		LEFT JOIN public.teams tm ON tm.team_id = wo.team_id
 		LEFT JOIN public.items i ON i.item_id = wo.item_id
 		LEFT JOIN public.work_order_statuses st ON st.status_id = wo.status_id
 		LEFT JOIN public.job_types jt ON jt.job_type_id = wo.job_type_id
@@ -242,6 +394,11 @@ func (r *storeRepository) ListWorkOrders(ctx context.Context, query string, filt
 			&item.LocationCode,
 			&item.LocationShelf,
 			&item.LocationFloor,
+			&item.TeamID,
+			&item.TeamName,
+			&item.Priority,
+			&item.DueAt,
+			&item.IsRush,
 			&item.CustomerName,
 			&item.CustomerEmail,
 			&item.ItemName,
@@ -257,7 +414,7 @@ func (r *storeRepository) ListWorkOrders(ctx context.Context, query string, filt
 	return items, rows.Err()
 }
 
-func (r *storeRepository) GetWorkOrderDetail(ctx context.Context, referenceID int) (domain.WorkOrderDetail, error) {
+func (r *storeRepository) GetWorkOrderDetail(ctx context.Context, referenceID int, access WorkOrderAccess) (domain.WorkOrderDetail, error) {
 	detail := domain.WorkOrderDetail{
 		BrandIDs:           make([]int64, 0),
 		BrandNames:         make([]string, 0),
@@ -295,6 +452,12 @@ func (r *storeRepository) GetWorkOrderDetail(ctx context.Context, referenceID in
 			loc.location_code,
 			loc.shelf,
 			loc.floor,
+			wo.team_id,
+			tm.team_name,
+			wo.priority,
+			wo.due_at,
+			wo.is_rush,
+			wo.completed_at,
 			c.customer_id,
 			c.first_name,
 			c.last_name,
@@ -347,19 +510,23 @@ func (r *storeRepository) GetWorkOrderDetail(ctx context.Context, referenceID in
 				ARRAY[]::TEXT[]
 			),
 			wo.parts_total::double precision,
+			wo.parts_cost_total::double precision,
+			(wo.parts_total - wo.parts_cost_total)::double precision,
+			wo.rush_surcharge_total::double precision,
 			wo.delivery_total::double precision,
 			wo.labour_total::double precision,
 			wo.deposit::double precision
 		FROM public.work_orders wo
 		LEFT JOIN public.customers c ON c.customer_id = wo.customer_id
 		LEFT JOIN public.locations loc ON loc.location_id = wo.location_id
+		LEFT JOIN public.teams tm ON tm.team_id = wo.team_id
 		LEFT JOIN public.items i ON i.item_id = wo.item_id
 		LEFT JOIN public.work_order_statuses st ON st.status_id = wo.status_id
 		LEFT JOIN public.job_types jt ON jt.job_type_id = wo.job_type_id
 		WHERE wo.reference_id = $1
-	`
+		  AND ` + assignedToFilter("wo.reference_id", 2)
 
-	if err := r.db.QueryRow(ctx, mainSQL, referenceID).Scan(
+	if err := r.db.QueryRow(ctx, mainSQL, referenceID, access.AssignedUserID).Scan(
 		&detail.ReferenceID,
 		&detail.OriginalJobID,
 		&detail.WarrantyJobIDs,
@@ -377,6 +544,12 @@ func (r *storeRepository) GetWorkOrderDetail(ctx context.Context, referenceID in
 		&detail.LocationCode,
 		&detail.LocationShelf,
 		&detail.LocationFloor,
+		&detail.TeamID,
+		&detail.TeamName,
+		&detail.Priority,
+		&detail.DueAt,
+		&detail.IsRush,
+		&detail.CompletedAt,
 		&detail.Customer.CustomerID,
 		&detail.Customer.FirstName,
 		&detail.Customer.LastName,
@@ -408,6 +581,9 @@ func (r *storeRepository) GetWorkOrderDetail(ctx context.Context, referenceID in
 		&detail.PaymentMethodIDs,
 		&detail.PaymentMethodNames,
 		&detail.PartsTotal,
+		&detail.PartsCostTotal,
+		&detail.PartsMargin,
+		&detail.RushSurchargeTotal,
 		&detail.DeliveryTotal,
 		&detail.LabourTotal,
 		&detail.Deposit,
@@ -416,7 +592,15 @@ func (r *storeRepository) GetWorkOrderDetail(ctx context.Context, referenceID in
 	}
 
 	lineItemsSQL := `
-		SELECT line_item_id, item_name, unit_price::double precision, quantity_text, line_total_text
+		SELECT
+			line_item_id,
+			item_name,
+			unit_price::double precision,
+			quantity_text,
+			line_total_text,
+			parts_purchase_request_id,
+			cost_total::double precision,
+			is_rush_surcharge
 		FROM public.work_order_line_items
 		WHERE reference_id = $1
 		ORDER BY line_item_id
@@ -435,6 +619,9 @@ func (r *storeRepository) GetWorkOrderDetail(ctx context.Context, referenceID in
 			&lineItem.UnitPrice,
 			&lineItem.QuantityText,
 			&lineItem.LineTotalText,
+			&lineItem.PartsPurchaseRequestID,
+			&lineItem.CostTotal,
+			&lineItem.IsRushSurcharge,
 		); err != nil {
 			return domain.WorkOrderDetail{}, err
 		}
@@ -798,6 +985,9 @@ func (r *storeRepository) CreateWorkOrder(ctx context.Context, input CreateWorkO
 			worker_ids,
 			model_number,
 			serial_number,
+			priority,
+			due_at,
+			is_rush,
 			created_at,
 			updated_at,
 			status_updated_at
@@ -810,6 +1000,9 @@ func (r *storeRepository) CreateWorkOrder(ctx context.Context, input CreateWorkO
 			COALESCE($16::integer[], ARRAY[]::integer[]),
 			$17,
 			$18,
+			$19,
+			$20,
+			$21,
 			now(), now(), now()
 		)
 	`,
@@ -831,15 +1024,23 @@ func (r *storeRepository) CreateWorkOrder(ctx context.Context, input CreateWorkO
 		[]int32{},
 		nullableString(input.ModelNumber),
 		nullableString(input.SerialNumber),
+		input.Priority,
+		input.DueAt,
+		input.IsRush,
 	); err != nil {
 		return domain.WorkOrderDetail{}, err
 	}
+	if input.IsRush {
+		if err := r.syncRushSurchargeTx(ctx, tx, referenceID); err != nil {
+			return domain.WorkOrderDetail{}, err
+		}
+	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return domain.WorkOrderDetail{}, err
 	}
 
-	return r.GetWorkOrderDetail(ctx, referenceID)
+	return r.GetWorkOrderDetail(ctx, referenceID, WorkOrderAccess{})
 }
 
 func (r *storeRepository) UpdateEquipment(ctx context.Context, referenceID int, input EquipmentUpdateInput) error {
@@ -873,6 +1074,7 @@ func (r *storeRepository) UpdateEquipment(ctx context.Context, referenceID int,
 				THEN now()
 				ELSE status_updated_at
 			END,
+			completed_at = `+completedAtSQL("$1")+`,
 			job_type_id = $2,
 			location_id = $3,
 			item_id = $4,
@@ -929,6 +1131,7 @@ func (r *storeRepository) UpdateStatus(ctx context.Context, referenceID int, sta
 				) THEN now()
 				ELSE status_updated_at
 			END,
+			completed_at = `+completedAtSQL("$1")+`,
 			updated_at = now()
 		WHERE reference_id = $2
 	`,
@@ -944,21 +1147,24 @@ func (r *storeRepository) UpdateStatus(ctx context.Context, referenceID int, sta
 	return nil
 }
 
-func (r *storeRepository) UpdateWorkNotes(ctx context.Context, referenceID int, input WorkNotesUpdateInput) error {
+func (r *storeRepository) UpdateTeam(ctx context.Context, referenceID int, teamID *int64) error {
+	if teamID != nil {
+		var exists bool
+		if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.teams WHERE team_id = $1)`, *teamID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrTeamNotFound
+		}
+	}
 	cmd, err := r.db.Exec(ctx, `
 		UPDATE public.work_orders
 		SET
-			problem_description = $1,
-			worker_ids = $2,
-			work_done = $3,
-			payment_method_ids = $4,
+			team_id = $1,
 			updated_at = now()
-		WHERE reference_id = $5
+		WHERE reference_id = $2
 	`,
-		nullableString(input.ProblemDescription),
-		input.WorkerIDs,
-		nullableString(input.WorkDone),
-		input.PaymentMethodIDs,
+		teamID,
 		referenceID,
 	)
 	if err != nil {
@@ -970,7 +1176,7 @@ func (r *storeRepository) UpdateWorkNotes(ctx context.Context, referenceID int,
 	return nil
 }
 
-func (r *storeRepository) UpdateLineItems(ctx context.Context, referenceID int, lineItems []LineItemUpsertInput) error {
+func (r *storeRepository) UpdateSchedule(ctx context.Context, referenceID int, input ScheduleUpdateInput) error {
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return err
@@ -979,206 +1185,636 @@ func (r *storeRepository) UpdateLineItems(ctx context.Context, referenceID int,
 		_ = tx.Rollback(ctx)
 	}()
 
-	var exists bool
-	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM public.work_orders WHERE reference_id = $1)`, referenceID).Scan(&exists); err != nil {
-		return err
-	}
-	if !exists {
+	var currentDueAt *time.Time
+	err = tx.QueryRow(ctx, `SELECT due_at FROM public.work_orders WHERE reference_id = $1 FOR UPDATE`, referenceID).Scan(&currentDueAt)
+	if errors.Is(err, pgx.ErrNoRows) {
 		return ErrWorkOrderNotFound
 	}
-
-	rows, err := tx.Query(ctx, `SELECT line_item_id FROM public.work_order_line_items WHERE reference_id = $1`, referenceID)
 	if err != nil {
 		return err
 	}
-	existingIDs := make(map[int64]struct{})
-	for rows.Next() {
-		var id int64
-		if scanErr := rows.Scan(&id); scanErr != nil {
-			rows.Close()
-			return scanErr
-		}
-		existingIDs[id] = struct{}{}
-	}
-	if err := rows.Err(); err != nil {
-		rows.Close()
-		return err
-	}
-	rows.Close()
-
-	keptIDs := make(map[int64]struct{})
-	for _, line := range lineItems {
-		if line.LineItemID != nil && *line.LineItemID > 0 {
-			lineID := *line.LineItemID
-			if _, ok := existingIDs[lineID]; !ok {
-				return ErrLineItemNotFound
-			}
-			cmd, execErr := tx.Exec(ctx, `
-				UPDATE public.work_order_line_items
-				SET
-					item_name = $1,
-					unit_price = $2,
-					quantity_text = $3,
-					line_total_text = $4
-				WHERE reference_id = $5 AND line_item_id = $6
-			`,
-				nullableString(line.ItemName),
-				line.UnitPrice,
-				nullableString(line.QuantityText),
-				nullableString(line.LineTotalText),
-				referenceID,
-				lineID,
-			)
-			if execErr != nil {
-				return execErr
-			}
-			if cmd.RowsAffected() == 0 {
-				return ErrLineItemNotFound
-			}
-			keptIDs[lineID] = struct{}{}
-			continue
-		}
-
-		var newID int64
-		if err := tx.QueryRow(ctx, `
-			INSERT INTO public.work_order_line_items(reference_id, item_name, unit_price, quantity_text, line_total_text)
-			VALUES($1, $2, $3, $4, $5)
-			RETURNING line_item_id
-		`,
-			referenceID,
-			nullableString(line.ItemName),
-			line.UnitPrice,
-			nullableString(line.QuantityText),
-			nullableString(line.LineTotalText),
-		).Scan(&newID); err != nil {
-			return err
-		}
-		keptIDs[newID] = struct{}{}
-	}
 
-	if len(keptIDs) == 0 {
-		if _, err := tx.Exec(ctx, `DELETE FROM public.work_order_line_items WHERE reference_id = $1`, referenceID); err != nil {
-			return err
-		}
-	} else {
-		idList := make([]int64, 0, len(keptIDs))
-		for id := range keptIDs {
-			idList = append(idList, id)
+	if !sameInstant(currentDueAt, input.DueAt) {
+		if currentDueAt != nil && input.Reason == nil {
+			return ErrDueDateChangeReasonRequired
 		}
-		sort.Slice(idList, func(i, j int) bool { return idList[i] < idList[j] })
-		if _, err := tx.Exec(ctx, `DELETE FROM public.work_order_line_items WHERE reference_id = $1 AND NOT (line_item_id = ANY($2))`, referenceID, idList); err != nil {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO public.work_order_due_date_changes(reference_id, previous_due_at, new_due_at, reason, changed_by_user_id)
+			VALUES($1, $2, $3, $4, NULLIF($5, '')::uuid)
+		`, referenceID, currentDueAt, input.DueAt, input.Reason, input.ChangedByUserID); err != nil {
 			return err
 		}
 	}
 
-	if err := r.recalculatePartsTotalTx(ctx, tx, referenceID); err != nil {
-		return err
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return err
-	}
-	return nil
-}
-
-func (r *storeRepository) UpdateTotals(ctx context.Context, referenceID int, input TotalsUpdateInput) error {
-	cmd, err := r.db.Exec(ctx, `
+	if _, err := tx.Exec(ctx, `
 		UPDATE public.work_orders
 		SET
-			delivery_total = $1,
-			labour_total = $2,
-			deposit = $3,
+			priority = $1,
+			due_at = $2,
+			is_rush = COALESCE($3, is_rush),
 			updated_at = now()
 		WHERE reference_id = $4
 	`,
-		input.DeliveryTotal,
-		input.LabourTotal,
-		input.Deposit,
+		input.Priority,
+		input.DueAt,
+		input.IsRush,
 		referenceID,
-	)
-	if err != nil {
+	); err != nil {
 		return err
 	}
-	if cmd.RowsAffected() == 0 {
-		return ErrWorkOrderNotFound
+	if input.IsRush != nil {
+		if err := r.syncRushSurchargeTx(ctx, tx, referenceID); err != nil {
+			return err
+		}
 	}
-	return nil
+
+	return tx.Commit(ctx)
 }
 
-func (r *storeRepository) UpdateCustomer(ctx context.Context, referenceID int, input CustomerUpdateInput) error {
-	cmd, err := r.db.Exec(ctx, `
-		UPDATE public.customers c
-		SET
-			first_name = $1,
-			last_name = $2,
-			email = $3,
-			address_line_1 = $4,
-			address_line_2 = $5,
-			city = $6,
-			province = $7,
-			home_phone = $8,
-			work_phone = $9,
-			postal_code = $10,
-			remark = $11
-		FROM public.work_orders wo
-		WHERE wo.reference_id = $12
-			AND wo.customer_id = c.customer_id
-	`,
-		nullableString(input.FirstName),
-		nullableString(input.LastName),
-		nullableString(input.Email),
-		nullableString(input.AddressLine1),
-		nullableString(input.AddressLine2),
-		nullableString(input.City),
-		nullableString(input.Province),
-		nullableString(input.HomePhone),
-		nullableString(input.WorkPhone),
-		nullableString(input.PostalCode),
-		nullableString(input.Remark),
-		referenceID,
-	)
+func sameInstant(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// syncRushSurchargeTx adds the configured surcharge line to a rush job that
+// does not have one, and removes it from a job that is no longer rush. An
+// existing line keeps whatever price it was given.
+func (r *storeRepository) syncRushSurchargeTx(ctx context.Context, tx pgx.Tx, referenceID int) error {
+	var isRush bool
+	err := tx.QueryRow(ctx, `SELECT is_rush FROM public.work_orders WHERE reference_id = $1`, referenceID).Scan(&isRush)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrWorkOrderNotFound
+	}
 	if err != nil {
 		return err
 	}
-	if cmd.RowsAffected() == 0 {
-		return ErrWorkOrderNotFound
+
+	if !isRush {
+		if _, err := tx.Exec(ctx, `DELETE FROM public.work_order_line_items WHERE reference_id = $1 AND is_rush_surcharge`, referenceID); err != nil {
+			return err
+		}
+		return r.recalculatePartsTotalTx(ctx, tx, referenceID)
 	}
-	_, err = r.db.Exec(ctx, `
-		UPDATE public.work_orders
-		SET updated_at = now()
-		WHERE reference_id = $1
-	`, referenceID)
-	return err
-}
 
-func nullableString(value *string) any {
-	if value == nil {
+	var surcharge float64
+	err = tx.QueryRow(ctx, `
+		SELECT setting_value::double precision
+		FROM public.app_settings
+		WHERE setting_key = $1
+	`, AppSettingRushSurcharge).Scan(&surcharge)
+	if errors.Is(err, pgx.ErrNoRows) {
 		return nil
 	}
-	trimmed := strings.TrimSpace(*value)
-	if trimmed == "" {
-		return nil
+	if err != nil {
+		return err
 	}
-	return trimmed
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO public.work_order_line_items(reference_id, item_name, unit_price, quantity_text, line_total_text, is_rush_surcharge)
+		VALUES($1, $2, $3, '1', $4, true)
+		ON CONFLICT (reference_id) WHERE is_rush_surcharge
+		DO NOTHING
+	`,
+		referenceID,
+		rushSurchargeItemName,
+		surcharge,
+		fmt.Sprintf("%.2f", surcharge),
+	); err != nil {
+		return err
+	}
+	return r.recalculatePartsTotalTx(ctx, tx, referenceID)
 }
 
-func splitCustomerName(name string) (string, string) {
-	trimmed := strings.TrimSpace(name)
-	if trimmed == "" {
-		return "", ""
+func (r *storeRepository) ListDueDateChanges(ctx context.Context, referenceID int) ([]domain.WorkOrderDueDateChange, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.work_orders WHERE reference_id = $1)`, referenceID).Scan(&exists); err != nil {
+		return nil, err
 	}
-	parts := strings.Fields(trimmed)
-	if len(parts) == 1 {
-		return parts[0], ""
+	if !exists {
+		return nil, ErrWorkOrderNotFound
 	}
-	firstName := parts[0]
-	lastName := strings.Join(parts[1:], " ")
-	return firstName, lastName
-}
 
-func resolveJobTypeIDForCreationMode(ctx context.Context, tx pgx.Tx, mode string) (*int64, error) {
-	normalizedMode := strings.TrimSpace(strings.ToLower(mode))
-	if normalizedMode == "" {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			ch.due_date_change_id,
+			ch.reference_id,
+			ch.previous_due_at,
+			ch.new_due_at,
+			ch.reason,
+			ch.changed_by_user_id::text,
+			u.full_name,
+			ch.created_at
+		FROM public.work_order_due_date_changes ch
+		LEFT JOIN public.users u ON u.id = ch.changed_by_user_id
+		WHERE ch.reference_id = $1
+		ORDER BY ch.created_at DESC, ch.due_date_change_id DESC
+	`, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]domain.WorkOrderDueDateChange, 0)
+	for rows.Next() {
+		var item domain.WorkOrderDueDateChange
+		if err := rows.Scan(
+			&item.DueDateChangeID,
+			&item.ReferenceID,
+			&item.PreviousDueAt,
+			&item.NewDueAt,
+			&item.Reason,
+			&item.ChangedByUserID,
+			&item.ChangedByName,
+			&item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *storeRepository) GetRushSettings(ctx context.Context) (domain.RushSettings, error) {
+	var surcharge float64
+	err := r.db.QueryRow(ctx, `
+		SELECT setting_value::double precision
+		FROM public.app_settings
+		WHERE setting_key = $1
+	`, AppSettingRushSurcharge).Scan(&surcharge)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.RushSettings{}, nil
+	}
+	if err != nil {
+		return domain.RushSettings{}, err
+	}
+	return domain.RushSettings{Surcharge: &surcharge}, nil
+}
+
+func (r *storeRepository) SetRushSurcharge(ctx context.Context, surcharge *float64) error {
+	if surcharge == nil {
+		_, err := r.db.Exec(ctx, `DELETE FROM public.app_settings WHERE setting_key = $1`, AppSettingRushSurcharge)
+		return err
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO public.app_settings(setting_key, setting_value, updated_at)
+		VALUES($1, $2::text, now())
+		ON CONFLICT (setting_key)
+		DO UPDATE SET
+			setting_value = EXCLUDED.setting_value,
+			updated_at = now()
+	`, AppSettingRushSurcharge, *surcharge)
+	return err
+}
+
+// ListPromises returns work orders first promised for [from, before).
+// OriginalDueAt is the date before the first move, or the current due date
+// when it never moved; DueDateChanges holds the moves, oldest first.
+func (r *storeRepository) ListPromises(ctx context.Context, from, before time.Time) ([]domain.PromiseReportItem, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT reference_id, item_name, is_rush, original_due_at, due_at, completed_at
+		FROM (
+			SELECT
+				wo.reference_id,
+				i.item_name,
+				wo.is_rush,
+				COALESCE(
+					(
+						SELECT ch.previous_due_at
+						FROM public.work_order_due_date_changes ch
+						WHERE ch.reference_id = wo.reference_id
+						  AND ch.previous_due_at IS NOT NULL
+						ORDER BY ch.created_at ASC, ch.due_date_change_id ASC
+						LIMIT 1
+					),
+					wo.due_at
+				) AS original_due_at,
+				wo.due_at,
+				wo.completed_at
+			FROM public.work_orders wo
+			LEFT JOIN public.items i ON i.item_id = wo.item_id
+		) promises
+		WHERE original_due_at >= $1
+		  AND original_due_at < $2
+		ORDER BY original_due_at ASC, reference_id ASC
+	`, from, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]domain.PromiseReportItem, 0)
+	index := make(map[int32]int)
+	referenceIDs := make([]int32, 0)
+	for rows.Next() {
+		var item domain.PromiseReportItem
+		if err := rows.Scan(
+			&item.ReferenceID,
+			&item.ItemName,
+			&item.IsRush,
+			&item.OriginalDueAt,
+			&item.DueAt,
+			&item.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		item.DueDateChanges = make([]domain.WorkOrderDueDateChange, 0)
+		index[item.ReferenceID] = len(items)
+		referenceIDs = append(referenceIDs, item.ReferenceID)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	changeRows, err := r.db.Query(ctx, `
+		SELECT
+			ch.due_date_change_id,
+			ch.reference_id,
+			ch.previous_due_at,
+			ch.new_due_at,
+			ch.reason,
+			ch.changed_by_user_id::text,
+			u.full_name,
+			ch.created_at
+		FROM public.work_order_due_date_changes ch
+		LEFT JOIN public.users u ON u.id = ch.changed_by_user_id
+		WHERE ch.reference_id = ANY($1::int[])
+		  AND ch.previous_due_at IS NOT NULL
+		ORDER BY ch.created_at ASC, ch.due_date_change_id ASC
+	`, referenceIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer changeRows.Close()
+	for changeRows.Next() {
+		var change domain.WorkOrderDueDateChange
+		if err := changeRows.Scan(
+			&change.DueDateChangeID,
+			&change.ReferenceID,
+			&change.PreviousDueAt,
+			&change.NewDueAt,
+			&change.Reason,
+			&change.ChangedByUserID,
+			&change.ChangedByName,
+			&change.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		item := &items[index[change.ReferenceID]]
+		item.DueDateChanges = append(item.DueDateChanges, change)
+	}
+	return items, changeRows.Err()
+}
+
+func (r *storeRepository) GetUserWorkerID(ctx context.Context, userID string) (*int64, error) {
+	var workerID *int64
+	err := r.db.QueryRow(ctx, `
+		SELECT worker_id
+		FROM public.users
+		WHERE id::text = $1
+		  AND deleted_at IS NULL
+	`, userID).Scan(&workerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return workerID, err
+}
+
+// SetWorkerAssignment adds workerID to the work order's worker_ids (once) or
+// removes it. Work orders outside access are reported as not found.
+func (r *storeRepository) SetWorkerAssignment(ctx context.Context, referenceID int, workerID int64, assigned bool, access WorkOrderAccess) error {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE public.work_orders wo
+		SET
+			worker_ids = CASE
+				WHEN NOT $3::boolean THEN array_remove(wo.worker_ids, $2::int)
+				WHEN $2::int = ANY(COALESCE(wo.worker_ids, ARRAY[]::int[])) THEN wo.worker_ids
+				ELSE array_append(COALESCE(wo.worker_ids, ARRAY[]::int[]), $2::int)
+			END,
+			updated_at = now()
+		WHERE wo.reference_id = $1
+		  AND `+assignedToFilter("wo.reference_id", 4),
+		referenceID,
+		workerID,
+		assigned,
+		access.AssignedUserID,
+	)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrWorkOrderNotFound
+	}
+	return nil
+}
+
+// ListQueue returns the worker's to-do and in-progress work orders: earliest
+// due date first (undated last), then by priority, then oldest first.
+func (r *storeRepository) ListQueue(ctx context.Context, workerID int64, access WorkOrderAccess) ([]domain.WorkOrderQueueItem, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			wo.reference_id,
+			COALESCE(st.display_name, 'Unknown') AS status_name,
+			st.status_group,
+			wo.priority,
+			wo.due_at,
+			wo.is_rush,
+			COALESCE(wo.due_at < now(), false) AS promise_overdue,
+			wo.created_at,
+			COALESCE(FLOOR(EXTRACT(EPOCH FROM (now() - wo.created_at)) / 86400)::int, 0) AS age_days,
+			c.full_name_search AS customer_name,
+			i.item_name,
+			tm.team_name
+		FROM public.work_orders wo
+		LEFT JOIN public.work_order_statuses st ON st.status_id = wo.status_id
+		LEFT JOIN public.customers c ON c.customer_id = wo.customer_id
+		LEFT JOIN public.items i ON i.item_id = wo.item_id
+		LEFT JOIN public.teams tm ON tm.team_id = wo.team_id
+		WHERE $1::int = ANY(COALESCE(wo.worker_ids, ARRAY[]::int[]))
+		  AND COALESCE(st.status_group, 'to_do') IN ('to_do', 'in_progress')
+		  AND `+assignedToFilter("wo.reference_id", 2)+`
+		ORDER BY
+			wo.due_at ASC NULLS LAST,
+			`+priorityRankSQL("wo.priority")+` ASC,
+			wo.created_at ASC NULLS LAST,
+			wo.reference_id ASC
+	`, workerID, access.AssignedUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]domain.WorkOrderQueueItem, 0)
+	for rows.Next() {
+		var item domain.WorkOrderQueueItem
+		if err := rows.Scan(
+			&item.ReferenceID,
+			&item.Status,
+			&item.StatusGroup,
+			&item.Priority,
+			&item.DueAt,
+			&item.IsRush,
+			&item.PromiseOverdue,
+			&item.CreatedAt,
+			&item.AgeDays,
+			&item.CustomerName,
+			&item.ItemName,
+			&item.TeamName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// priorityRankSQL sorts urgent work first.
+func priorityRankSQL(column string) string {
+	return fmt.Sprintf(`CASE %s WHEN 'urgent' THEN 0 WHEN 'high' THEN 1 WHEN 'normal' THEN 2 ELSE 3 END`, column)
+}
+
+func (r *storeRepository) UpdateWorkNotes(ctx context.Context, referenceID int, input WorkNotesUpdateInput) error {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE public.work_orders
+		SET
+			problem_description = $1,
+			worker_ids = $2,
+			work_done = $3,
+			payment_method_ids = $4,
+			updated_at = now()
+		WHERE reference_id = $5
+	`,
+		nullableString(input.ProblemDescription),
+		input.WorkerIDs,
+		nullableString(input.WorkDone),
+		input.PaymentMethodIDs,
+		referenceID,
+	)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrWorkOrderNotFound
+	}
+	return nil
+}
+
+func (r *storeRepository) UpdateLineItems(ctx context.Context, referenceID int, lineItems []LineItemUpsertInput) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM public.work_orders WHERE reference_id = $1)`, referenceID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrWorkOrderNotFound
+	}
+
+	rows, err := tx.Query(ctx, `SELECT line_item_id FROM public.work_order_line_items WHERE reference_id = $1`, referenceID)
+	if err != nil {
+		return err
+	}
+	existingIDs := make(map[int64]struct{})
+	for rows.Next() {
+		var id int64
+		if scanErr := rows.Scan(&id); scanErr != nil {
+			rows.Close()
+			return scanErr
+		}
+		existingIDs[id] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	keptIDs := make(map[int64]struct{})
+	for _, line := range lineItems {
+		if line.LineItemID != nil && *line.LineItemID > 0 {
+			lineID := *line.LineItemID
+			if _, ok := existingIDs[lineID]; !ok {
+				return ErrLineItemNotFound
+			}
+			cmd, execErr := tx.Exec(ctx, `
+				UPDATE public.work_order_line_items
+				SET
+					item_name = $1,
+					unit_price = $2,
+					quantity_text = $3,
+					line_total_text = $4
+				WHERE reference_id = $5 AND line_item_id = $6
+			`,
+				nullableString(line.ItemName),
+				line.UnitPrice,
+				nullableString(line.QuantityText),
+				nullableString(line.LineTotalText),
+				referenceID,
+				lineID,
+			)
+			if execErr != nil {
+				return execErr
+			}
+			if cmd.RowsAffected() == 0 {
+				return ErrLineItemNotFound
+			}
+			keptIDs[lineID] = struct{}{}
+			continue
+		}
+
+		var newID int64
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO public.work_order_line_items(reference_id, item_name, unit_price, quantity_text, line_total_text)
+			VALUES($1, $2, $3, $4, $5)
+			RETURNING line_item_id
+		`,
+			referenceID,
+			nullableString(line.ItemName),
+			line.UnitPrice,
+			nullableString(line.QuantityText),
+			nullableString(line.LineTotalText),
+		).Scan(&newID); err != nil {
+			return err
+		}
+		keptIDs[newID] = struct{}{}
+	}
+
+	// The rush surcharge line follows is_rush, not the editor: leaving it out
+	// of the list does not remove it, though it can be repriced by its ID.
+	if len(keptIDs) == 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM public.work_order_line_items WHERE reference_id = $1 AND NOT is_rush_surcharge`, referenceID); err != nil {
+			return err
+		}
+	} else {
+		idList := make([]int64, 0, len(keptIDs))
+		for id := range keptIDs {
+			idList = append(idList, id)
+		}
+		sort.Slice(idList, func(i, j int) bool { return idList[i] < idList[j] })
+		if _, err := tx.Exec(ctx, `DELETE FROM public.work_order_line_items WHERE reference_id = $1 AND NOT (line_item_id = ANY($2)) AND NOT is_rush_surcharge`, referenceID, idList); err != nil {
+			return err
+		}
+	}
+
+	if err := r.recalculatePartsTotalTx(ctx, tx, referenceID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *storeRepository) UpdateTotals(ctx context.Context, referenceID int, input TotalsUpdateInput) error {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE public.work_orders
+		SET
+			delivery_total = $1,
+			labour_total = $2,
+			deposit = $3,
+			updated_at = now()
+		WHERE reference_id = $4
+	`,
+		input.DeliveryTotal,
+		input.LabourTotal,
+		input.Deposit,
+		referenceID,
+	)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrWorkOrderNotFound
+	}
+	return nil
+}
+
+func (r *storeRepository) UpdateCustomer(ctx context.Context, referenceID int, input CustomerUpdateInput) error {
+	cmd, err := r.db.Exec(ctx, `
+		UPDATE public.customers c
+		SET
+			first_name = $1,
+			last_name = $2,
+			email = $3,
+			address_line_1 = $4,
+			address_line_2 = $5,
+			city = $6,
+			province = $7,
+			home_phone = $8,
+			work_phone = $9,
+			postal_code = $10,
+			remark = $11
+		FROM public.work_orders wo
+		WHERE wo.reference_id = $12
+			AND wo.customer_id = c.customer_id
+	`,
+		nullableString(input.FirstName),
+		nullableString(input.LastName),
+		nullableString(input.Email),
+		nullableString(input.AddressLine1),
+		nullableString(input.AddressLine2),
+		nullableString(input.City),
+		nullableString(input.Province),
+		nullableString(input.HomePhone),
+		nullableString(input.WorkPhone),
+		nullableString(input.PostalCode),
+		nullableString(input.Remark),
+		referenceID,
+	)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrWorkOrderNotFound
+	}
+	_, err = r.db.Exec(ctx, `
+		UPDATE public.work_orders
+		SET updated_at = now()
+		WHERE reference_id = $1
+	`, referenceID)
+	return err
+}
+
+func nullableString(value *string) any {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return trimmed
+}
+
+func splitCustomerName(name string) (string, string) {
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" {
+		return "", ""
+	}
+	parts := strings.Fields(trimmed)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	firstName := parts[0]
+	lastName := strings.Join(parts[1:], " ")
+	return firstName, lastName
+}
+
+func resolveJobTypeIDForCreationMode(ctx context.Context, tx pgx.Tx, mode string) (*int64, error) {
+	normalizedMode := strings.TrimSpace(strings.ToLower(mode))
+	if normalizedMode == "" {
 		normalizedMode = "new_job"
 	}
 
@@ -1248,26 +1884,34 @@ func stringOrEmpty(value *string) string {
 	return *value
 }
 
+// recalculatePartsTotalTx keeps parts_total as what the customer is charged and
+// parts_cost_total as what we paid for the parts behind linked line items. The
+// rush surcharge line is not a part and goes to rush_surcharge_total instead.
 func (r *storeRepository) recalculatePartsTotalTx(ctx context.Context, tx pgx.Tx, referenceID int) error {
 	cmd, err := tx.Exec(ctx, `
 		UPDATE public.work_orders wo
 		SET
 			parts_total = sums.parts_total,
+			parts_cost_total = sums.parts_cost_total,
+			rush_surcharge_total = sums.rush_surcharge_total,
 			updated_at = now()
 		FROM (
 			SELECT
-				COALESCE(
-					SUM(
-						CASE
-							WHEN regexp_replace(COALESCE(li.line_total_text, ''), '[^0-9.\-]', '', 'g') ~ '^-?[0-9]+(\.[0-9]+)?$'
-								THEN (regexp_replace(COALESCE(li.line_total_text, ''), '[^0-9.\-]', '', 'g'))::numeric
-							ELSE 0::numeric
-						END
-					),
-					0::numeric
-				) AS parts_total
-			FROM public.work_order_line_items li
-			WHERE li.reference_id = $1
+				COALESCE(SUM(lines.line_total) FILTER (WHERE NOT lines.is_rush_surcharge), 0::numeric) AS parts_total,
+				COALESCE(SUM(lines.cost_total) FILTER (WHERE NOT lines.is_rush_surcharge), 0::numeric) AS parts_cost_total,
+				COALESCE(SUM(lines.line_total) FILTER (WHERE lines.is_rush_surcharge), 0::numeric) AS rush_surcharge_total
+			FROM (
+				SELECT
+					li.is_rush_surcharge,
+					li.cost_total,
+					CASE
+						WHEN regexp_replace(COALESCE(li.line_total_text, ''), '[^0-9.\-]', '', 'g') ~ '^-?[0-9]+(\.[0-9]+)?$'
+							THEN (regexp_replace(COALESCE(li.line_total_text, ''), '[^0-9.\-]', '', 'g'))::numeric
+						ELSE 0::numeric
+					END AS line_total
+				FROM public.work_order_line_items li
+				WHERE li.reference_id = $1
+			) lines
 		) sums
 		WHERE wo.reference_id = $1
 	`, referenceID)
@@ -1280,7 +1924,7 @@ func (r *storeRepository) recalculatePartsTotalTx(ctx context.Context, tx pgx.Tx
 	return nil
 }
 
-func (r *storeRepository) ListRepairLogs(ctx context.Context, referenceID int) ([]domain.RepairLog, error) {
+func (r *storeRepository) ListRepairLogs(ctx context.Context, referenceID int, access WorkOrderAccess) ([]domain.RepairLog, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT
 			rl.repair_log_id,
@@ -1290,13 +1934,17 @@ func (r *storeRepository) ListRepairLogs(ctx context.Context, referenceID int) (
 			rl.details,
 			rl.created_by_user_id::text,
 			u.full_name,
+			u.worker_id,
+			w.worker_name,
 			rl.created_at,
 			rl.updated_at
 		FROM public.repair_logs rl
 		LEFT JOIN public.users u ON u.id = rl.created_by_user_id
+		LEFT JOIN public.workers w ON w.worker_id = u.worker_id
 		WHERE rl.reference_id = $1
+		  AND `+assignedToFilter("rl.reference_id", 2)+`
 		ORDER BY rl.repair_date DESC, rl.repair_log_id DESC
-	`, referenceID)
+	`, referenceID, access.AssignedUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -1313,6 +1961,8 @@ func (r *storeRepository) ListRepairLogs(ctx context.Context, referenceID int) (
 			&item.Details,
 			&item.CreatedByUserID,
 			&item.CreatedByName,
+			&item.CreatedByWorkerID,
+			&item.CreatedByWorkerName,
 			&item.CreatedAt,
 			&item.UpdatedAt,
 		); err != nil {
@@ -1323,7 +1973,7 @@ func (r *storeRepository) ListRepairLogs(ctx context.Context, referenceID int) (
 	return out, rows.Err()
 }
 
-func (r *storeRepository) ListAllPartsPurchaseRequests(ctx context.Context) ([]domain.PartsPurchaseRequest, error) {
+func (r *storeRepository) ListAllPartsPurchaseRequests(ctx context.Context, access WorkOrderAccess) ([]domain.PartsPurchaseRequest, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT
 			pr.parts_purchase_request_id,
@@ -1334,14 +1984,23 @@ func (r *storeRepository) ListAllPartsPurchaseRequests(ctx context.Context) ([]d
 			pr.total_price::double precision,
 			pr.item_name,
 			pr.quantity,
+			pr.parts_item_preset_id,
+			pr.supplier_id,
+			s.supplier_name,
+			pr.purchase_order_id,
+			pr.received_at,
+			pr.approved_by_user_id::text,
+			pr.approved_at,
 			pr.created_by_user_id,
 			u.full_name,
 			pr.created_at,
 			pr.updated_at
 		FROM public.parts_purchase_requests pr
 		LEFT JOIN public.users u ON u.id = pr.created_by_user_id
+		LEFT JOIN public.suppliers s ON s.supplier_id = pr.supplier_id
+		WHERE `+assignedToFilter("pr.reference_id", 1)+`
 		ORDER BY pr.created_at DESC NULLS LAST, pr.parts_purchase_request_id DESC
-	`)
+	`, access.AssignedUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -1359,6 +2018,13 @@ func (r *storeRepository) ListAllPartsPurchaseRequests(ctx context.Context) ([]d
 			&item.TotalPrice,
 			&item.ItemName,
 			&item.Quantity,
+			&item.PartsItemPresetID,
+			&item.SupplierID,
+			&item.SupplierName,
+			&item.PurchaseOrderID,
+			&item.ReceivedAt,
+			&item.ApprovedByUserID,
+			&item.ApprovedAt,
 			&item.CreatedByUserID,
 			&item.CreatedByName,
 			&item.CreatedAt,
@@ -1393,10 +2059,11 @@ func (r *storeRepository) GetDashboardData(ctx context.Context, input DashboardQ
 	overdueOffset := (overduePage - 1) * overduePageSize
 
 	out := domain.DashboardData{
-		ReadyItems:       make([]domain.DashboardWorkOrderItem, 0),
-		OverdueItems:     make([]domain.DashboardOverdueItem, 0),
-		PartsReviewItems: make([]domain.DashboardPartsReviewItem, 0),
-		ActivityItems:    make([]domain.DashboardActivityItem, 0),
+		ReadyItems:          make([]domain.DashboardWorkOrderItem, 0),
+		OverdueItems:        make([]domain.DashboardOverdueItem, 0),
+		PromiseOverdueItems: make([]domain.DashboardPromiseItem, 0),
+		PartsReviewItems:    make([]domain.DashboardPartsReviewItem, 0),
+		ActivityItems:       make([]domain.DashboardActivityItem, 0),
 	}
 
 	baseFilter := `
@@ -1407,13 +2074,14 @@ func (r *storeRepository) GetDashboardData(ctx context.Context, input DashboardQ
 		LEFT JOIN public.items i ON i.item_id = wo.item_id
 		WHERE COALESCE(wo.status_updated_at, wo.updated_at, wo.created_at) >= $1::date
 		  AND COALESCE(jt.display_name, '') NOT ILIKE '%stock%'
+		  AND ($2::bigint IS NULL OR wo.team_id = $2::bigint)
 	`
 
-	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) `+baseFilter+` AND COALESCE(st.status_group, 'to_do') = 'staged'`, input.RangeStart).Scan(&out.ReadyTotal); err != nil {
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) `+baseFilter+` AND COALESCE(st.status_group, 'to_do') = 'staged'`, input.RangeStart, input.TeamID).Scan(&out.ReadyTotal); err != nil {
 		return domain.DashboardData{}, err
 	}
 
-	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) `+baseFilter+` AND COALESCE(st.status_group, 'to_do') = 'staged' AND wo.status_updated_at IS NOT NULL AND (now() - wo.status_updated_at) > interval '14 days'`, input.RangeStart).Scan(&out.OverdueTotal); err != nil {
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) `+baseFilter+` AND COALESCE(st.status_group, 'to_do') = 'staged' AND wo.status_updated_at IS NOT NULL AND (now() - wo.status_updated_at) > interval '14 days'`, input.RangeStart, input.TeamID).Scan(&out.OverdueTotal); err != nil {
 		return domain.DashboardData{}, err
 	}
 
@@ -1427,8 +2095,8 @@ func (r *storeRepository) GetDashboardData(ctx context.Context, input DashboardQ
 		`+baseFilter+`
 		  AND COALESCE(st.status_group, 'to_do') = 'staged'
 		ORDER BY COALESCE(wo.status_updated_at, wo.updated_at, wo.created_at) DESC, wo.reference_id DESC
-		LIMIT $2 OFFSET $3
-	`, input.RangeStart, readyPageSize, readyOffset)
+		LIMIT $3 OFFSET $4
+	`, input.RangeStart, input.TeamID, readyPageSize, readyOffset)
 	if err != nil {
 		return domain.DashboardData{}, err
 	}
@@ -1456,8 +2124,8 @@ func (r *storeRepository) GetDashboardData(ctx context.Context, input DashboardQ
 		  AND wo.status_updated_at IS NOT NULL
 		  AND (now() - wo.status_updated_at) > interval '14 days'
 		ORDER BY wo.status_updated_at DESC, wo.reference_id DESC
-		LIMIT $2 OFFSET $3
-	`, input.RangeStart, overduePageSize, overdueOffset)
+		LIMIT $3 OFFSET $4
+	`, input.RangeStart, input.TeamID, overduePageSize, overdueOffset)
 	if err != nil {
 		return domain.DashboardData{}, err
 	}
@@ -1473,6 +2141,49 @@ func (r *storeRepository) GetDashboardData(ctx context.Context, input DashboardQ
 		return domain.DashboardData{}, err
 	}
 
+	// Broken promises are not limited to the range: a job due long ago that is
+	// still open is the one most worth seeing.
+	promiseFilter := `
+		FROM public.work_orders wo
+		LEFT JOIN public.work_order_statuses st ON st.status_id = wo.status_id
+		LEFT JOIN public.job_types jt ON jt.job_type_id = wo.job_type_id
+		LEFT JOIN public.customers c ON c.customer_id = wo.customer_id
+		LEFT JOIN public.items i ON i.item_id = wo.item_id
+		WHERE wo.due_at < now()
+		  AND COALESCE(st.status_group, 'to_do') IN ('to_do', 'in_progress')
+		  AND COALESCE(jt.display_name, '') NOT ILIKE '%stock%'
+		  AND ($1::bigint IS NULL OR wo.team_id = $1::bigint)
+	`
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) `+promiseFilter, input.TeamID).Scan(&out.PromiseOverdueTotal); err != nil {
+		return domain.DashboardData{}, err
+	}
+	promiseRows, err := r.db.Query(ctx, `
+		SELECT
+			wo.reference_id,
+			c.full_name_search AS customer_name,
+			i.item_name,
+			wo.priority,
+			wo.due_at,
+			FLOOR(EXTRACT(EPOCH FROM (now() - wo.due_at)) / 86400)::int AS late_days
+		`+promiseFilter+`
+		ORDER BY wo.due_at ASC, `+priorityRankSQL("wo.priority")+` ASC, wo.reference_id ASC
+		LIMIT 10
+	`, input.TeamID)
+	if err != nil {
+		return domain.DashboardData{}, err
+	}
+	defer promiseRows.Close()
+	for promiseRows.Next() {
+		var item domain.DashboardPromiseItem
+		if err := promiseRows.Scan(&item.ReferenceID, &item.CustomerName, &item.ItemName, &item.Priority, &item.DueAt, &item.LateDays); err != nil {
+			return domain.DashboardData{}, err
+		}
+		out.PromiseOverdueItems = append(out.PromiseOverdueItems, item)
+	}
+	if err := promiseRows.Err(); err != nil {
+		return domain.DashboardData{}, err
+	}
+
 	if input.IncludeParts {
 		rows, err := r.db.Query(ctx, `
 			SELECT
@@ -1480,20 +2191,48 @@ func (r *storeRepository) GetDashboardData(ctx context.Context, input DashboardQ
 				ppr.reference_id,
 				ppr.item_name,
 				ppr.total_price::double precision,
+				COALESCE(ppr.total_price > threshold.value AND (ppr.approved_total_price IS NULL OR ppr.total_price > ppr.approved_total_price), false),
 				ppr.created_at
 			FROM public.parts_purchase_requests ppr
+			LEFT JOIN LATERAL (
+				SELECT s.setting_value::numeric AS value
+				FROM public.app_settings s
+				WHERE s.setting_key = $3
+			) threshold ON TRUE
 			WHERE ppr.status = 'waiting_approval'
 			  AND ppr.created_at >= $1::date
+			  AND (
+				$4::bigint IS NULL
+				OR EXISTS (
+					SELECT 1
+					FROM public.work_orders wo
+					WHERE wo.reference_id = ppr.reference_id
+					  AND wo.team_id = $4::bigint
+				)
+			  )
+			  AND (
+				$2::text IS NULL
+				OR (
+					ppr.created_by_user_id::text <> $2::text
+					AND (ppr.approved_total_price IS NULL OR ppr.total_price > ppr.approved_total_price)
+					AND NOT EXISTS (
+						SELECT 1
+						FROM public.parts_approval_limits l
+						WHERE l.user_id::text = $2::text
+						  AND ppr.total_price > l.max_total_price
+					)
+				)
+			  )
 			ORDER BY ppr.created_at DESC, ppr.parts_purchase_request_id DESC
 			LIMIT 5
-		`, input.RangeStart)
+		`, input.RangeStart, input.ApproverUserID, AppSettingPartsApprovalThreshold, input.TeamID)
 		if err != nil {
 			return domain.DashboardData{}, err
 		}
 		defer rows.Close()
 		for rows.Next() {
 			var item domain.DashboardPartsReviewItem
-			if err := rows.Scan(&item.PartsPurchaseRequestID, &item.ReferenceID, &item.ItemName, &item.TotalPrice, &item.CreatedAt); err != nil {
+			if err := rows.Scan(&item.PartsPurchaseRequestID, &item.ReferenceID, &item.ItemName, &item.TotalPrice, &item.RequiresApproval, &item.CreatedAt); err != nil {
 				return domain.DashboardData{}, err
 			}
 			out.PartsReviewItems = append(out.PartsReviewItems, item)
@@ -1518,6 +2257,7 @@ func (r *storeRepository) GetDashboardData(ctx context.Context, input DashboardQ
 				LEFT JOIN public.job_types jt ON jt.job_type_id = wo.job_type_id
 				WHERE COALESCE(jt.display_name, '') NOT ILIKE '%stock%'
 				  AND COALESCE(rl.updated_at, rl.created_at, rl.repair_date::timestamp) >= $1::date
+				  AND ($2::bigint IS NULL OR wo.team_id = $2::bigint)
 			),
 			latest_per_person AS (
 				SELECT DISTINCT ON (person_id)
@@ -1533,7 +2273,7 @@ func (r *storeRepository) GetDashboardData(ctx context.Context, input DashboardQ
 			FROM latest_per_person
 			ORDER BY logged_at DESC
 			LIMIT 6
-		`, input.RangeStart)
+		`, input.RangeStart, input.TeamID)
 		if err != nil {
 			return domain.DashboardData{}, err
 		}
@@ -1553,17 +2293,20 @@ func (r *storeRepository) GetDashboardData(ctx context.Context, input DashboardQ
 	return out, nil
 }
 
-func (r *storeRepository) CreateRepairLog(ctx context.Context, referenceID int, repairDate *string, hoursUsed *float64, details, createdByUserID string) (domain.RepairLog, error) {
+// CreateRepairLog reports work orders outside access as not found.
+func (r *storeRepository) CreateRepairLog(ctx context.Context, referenceID int, repairDate *string, hoursUsed *float64, details, createdByUserID string, access WorkOrderAccess) (domain.RepairLog, error) {
 	var inserted domain.RepairLog
 	err := r.db.QueryRow(ctx, `
 		INSERT INTO public.repair_logs(reference_id, repair_date, hours_used, details, created_by_user_id)
-		VALUES(
-			$1,
+		SELECT
+			wo.reference_id,
 			COALESCE(NULLIF(BTRIM($2), '')::date, CURRENT_DATE),
 			COALESCE($3::numeric, 0::numeric),
 			BTRIM($4),
 			$5::uuid
-		)
+		FROM public.work_orders wo
+		WHERE wo.reference_id = $1
+		  AND `+assignedToFilter("wo.reference_id", 6)+`
 		RETURNING
 			repair_log_id,
 			reference_id,
@@ -1579,6 +2322,7 @@ func (r *storeRepository) CreateRepairLog(ctx context.Context, referenceID int,
 		hoursUsed,
 		details,
 		createdByUserID,
+		access.AssignedUserID,
 	).Scan(
 		&inserted.RepairLogID,
 		&inserted.ReferenceID,
@@ -1589,26 +2333,53 @@ func (r *storeRepository) CreateRepairLog(ctx context.Context, referenceID int,
 		&inserted.CreatedAt,
 		&inserted.UpdatedAt,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.RepairLog{}, ErrWorkOrderNotFound
+	}
 	if err != nil {
 		return domain.RepairLog{}, err
 	}
 
-	if err := r.db.QueryRow(ctx, `SELECT full_name FROM public.users WHERE id = $1::uuid`, createdByUserID).Scan(&inserted.CreatedByName); err != nil {
+	if err := r.loadRepairLogAuthor(ctx, &inserted); err != nil {
 		return domain.RepairLog{}, err
 	}
+	if inserted.CreatedByWorkerID != nil {
+		var assigned bool
+		if err := r.db.QueryRow(ctx, `
+			SELECT $2::bigint = ANY(COALESCE(worker_ids::bigint[], ARRAY[]::bigint[]))
+			FROM public.work_orders
+			WHERE reference_id = $1
+		`, referenceID, *inserted.CreatedByWorkerID).Scan(&assigned); err != nil {
+			return domain.RepairLog{}, err
+		}
+		if !assigned {
+			inserted.SuggestedWorkerID = inserted.CreatedByWorkerID
+		}
+	}
 	return inserted, nil
 }
 
-func (r *storeRepository) UpdateRepairLog(ctx context.Context, referenceID int, repairLogID int64, repairDate *string, hoursUsed *float64, details *string) (domain.RepairLog, error) {
+func (r *storeRepository) loadRepairLogAuthor(ctx context.Context, item *domain.RepairLog) error {
+	return r.db.QueryRow(ctx, `
+		SELECT u.full_name, u.worker_id, w.worker_name
+		FROM public.users u
+		LEFT JOIN public.workers w ON w.worker_id = u.worker_id
+		WHERE u.id = $1::uuid
+	`, item.CreatedByUserID).Scan(&item.CreatedByName, &item.CreatedByWorkerID, &item.CreatedByWorkerName)
+}
+
+func (r *storeRepository) UpdateRepairLog(ctx context.Context, referenceID int, repairLogID int64, repairDate *string, hoursUsed *float64, details *string, access WorkOrderAccess) (domain.RepairLog, error) {
 	var updated domain.RepairLog
 	cmdErr := r.db.QueryRow(ctx, `
-		UPDATE public.repair_logs
+		UPDATE public.repair_logs rl
 		SET
 			repair_date = COALESCE(NULLIF(BTRIM($3), '')::date, repair_date),
 			hours_used = COALESCE($4::numeric, hours_used),
 			details = COALESCE(NULLIF(BTRIM($5), ''), details),
 			updated_at = now()
-		WHERE reference_id = $1 AND repair_log_id = $2
+		WHERE rl.reference_id = $1
+		  AND rl.repair_log_id = $2
+		  AND `+assignedToFilter("rl.reference_id", 6)+`
 		RETURNING
 			repair_log_id,
 			reference_id,
@@ -1624,6 +2395,7 @@ func (r *storeRepository) UpdateRepairLog(ctx context.Context, referenceID int,
 		stringOrNil(repairDate),
 		hoursUsed,
 		stringOrNil(details),
+		access.AssignedUserID,
 	).Scan(
 		&updated.RepairLogID,
 		&updated.ReferenceID,
@@ -1640,24 +2412,343 @@ func (r *storeRepository) UpdateRepairLog(ctx context.Context, referenceID int,
 		}
 		return domain.RepairLog{}, cmdErr
 	}
-	if err := r.db.QueryRow(ctx, `SELECT full_name FROM public.users WHERE id = $1::uuid`, updated.CreatedByUserID).Scan(&updated.CreatedByName); err != nil {
+	if err := r.loadRepairLogAuthor(ctx, &updated); err != nil {
 		return domain.RepairLog{}, err
 	}
 	return updated, nil
 }
 
-func (r *storeRepository) DeleteRepairLog(ctx context.Context, referenceID int, repairLogID int64) error {
-	cmd, err := r.db.Exec(ctx, `DELETE FROM public.repair_logs WHERE reference_id = $1 AND repair_log_id = $2`, referenceID, repairLogID)
+func (r *storeRepository) DeleteRepairLog(ctx context.Context, referenceID int, repairLogID int64, access WorkOrderAccess) error {
+	cmd, err := r.db.Exec(ctx, `
+		DELETE FROM public.repair_logs rl
+		WHERE rl.reference_id = $1
+		  AND rl.repair_log_id = $2
+		  AND `+assignedToFilter("rl.reference_id", 3)+`
+	`, referenceID, repairLogID, access.AssignedUserID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrRepairLogNotFound
+	}
+	return nil
+}
+
+const commentSelectSQL = `
+	SELECT
+		c.comment_id,
+		c.reference_id,
+		c.body,
+		c.created_by_user_id::text,
+		u.full_name,
+		c.created_at,
+		c.updated_at,
+		c.edited_at
+	FROM public.work_order_comments c
+	LEFT JOIN public.users u ON u.id = c.created_by_user_id
+`
+
+func scanComment(row pgx.Row, item *domain.WorkOrderComment) error {
+	return row.Scan(
+		&item.CommentID,
+		&item.ReferenceID,
+		&item.Body,
+		&item.CreatedByUserID,
+		&item.CreatedByName,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+		&item.EditedAt,
+	)
+}
+
+func (r *storeRepository) ListComments(ctx context.Context, referenceID int, access WorkOrderAccess) ([]domain.WorkOrderComment, error) {
+	rows, err := r.db.Query(ctx, commentSelectSQL+`
+		WHERE c.reference_id = $1
+		  AND `+assignedToFilter("c.reference_id", 2)+`
+		ORDER BY c.created_at ASC, c.comment_id ASC
+	`, referenceID, access.AssignedUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.WorkOrderComment, 0)
+	for rows.Next() {
+		var item domain.WorkOrderComment
+		if err := scanComment(rows, &item); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.loadCommentMentions(ctx, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *storeRepository) GetComment(ctx context.Context, referenceID int, commentID int64, access WorkOrderAccess) (domain.WorkOrderComment, error) {
+	var item domain.WorkOrderComment
+	err := scanComment(r.db.QueryRow(ctx, commentSelectSQL+`
+		WHERE c.reference_id = $1
+		  AND c.comment_id = $2
+		  AND `+assignedToFilter("c.reference_id", 3)+`
+	`, referenceID, commentID, access.AssignedUserID), &item)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.WorkOrderComment{}, ErrCommentNotFound
+	}
+	if err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+	items := []domain.WorkOrderComment{item}
+	if err := r.loadCommentMentions(ctx, items); err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+	return items[0], nil
+}
+
+// loadCommentMentions fills in the mentioned users of every comment in one
+// query.
+func (r *storeRepository) loadCommentMentions(ctx context.Context, items []domain.WorkOrderComment) error {
+	if len(items) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(items))
+	index := make(map[int64]int, len(items))
+	for i := range items {
+		items[i].Mentions = make([]domain.CommentUser, 0)
+		ids = append(ids, items[i].CommentID)
+		index[items[i].CommentID] = i
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT m.comment_id, u.id::text, u.email, u.full_name
+		FROM public.work_order_comment_mentions m
+		JOIN public.users u ON u.id = m.user_id
+		WHERE m.comment_id = ANY($1::bigint[])
+		ORDER BY u.full_name ASC, u.email ASC
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentID int64
+		var user domain.CommentUser
+		if err := rows.Scan(&commentID, &user.UserID, &user.Email, &user.FullName); err != nil {
+			return err
+		}
+		if i, ok := index[commentID]; ok {
+			items[i].Mentions = append(items[i].Mentions, user)
+		}
+	}
+	return rows.Err()
+}
+
+// CreateComment reports work orders outside access as not found, so callers
+// limited to their assigned jobs cannot probe for other reference IDs.
+func (r *storeRepository) CreateComment(ctx context.Context, referenceID int, body, userID string, mentionEmails []string, access WorkOrderAccess) (domain.WorkOrderComment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := checkWorkOrderAccess(ctx, tx, referenceID, access); err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+
+	var commentID int64
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO public.work_order_comments(reference_id, body, created_by_user_id)
+		VALUES($1, $2, $3::uuid)
+		RETURNING comment_id
+	`, referenceID, body, userID).Scan(&commentID); err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+	if err := syncCommentMentionsTx(ctx, tx, referenceID, commentID, body, userID, mentionEmails); err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+	return r.GetComment(ctx, referenceID, commentID, WorkOrderAccess{})
+}
+
+func (r *storeRepository) UpdateComment(ctx context.Context, referenceID int, commentID int64, body, userID string, mentionEmails []string) (domain.WorkOrderComment, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var previousBody string
+	err = tx.QueryRow(ctx, `
+		SELECT body
+		FROM public.work_order_comments
+		WHERE reference_id = $1 AND comment_id = $2
+		FOR UPDATE
+	`, referenceID, commentID).Scan(&previousBody)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.WorkOrderComment{}, ErrCommentNotFound
+	}
+	if err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+
+	// An unchanged body is not an edit: no revision, no new edited_at.
+	if previousBody != body {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO public.work_order_comment_revisions(comment_id, body, edited_by_user_id)
+			VALUES($1, $2, $3::uuid)
+		`, commentID, previousBody, userID); err != nil {
+			return domain.WorkOrderComment{}, err
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE public.work_order_comments
+			SET body = $2, edited_at = now(), updated_at = now()
+			WHERE comment_id = $1
+		`, commentID, body); err != nil {
+			return domain.WorkOrderComment{}, err
+		}
+	}
+	if err := syncCommentMentionsTx(ctx, tx, referenceID, commentID, body, userID, mentionEmails); err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+	return r.GetComment(ctx, referenceID, commentID, WorkOrderAccess{})
+}
+
+// syncCommentMentionsTx makes the comment's mentions match mentionEmails,
+// ignoring emails that do not belong to an active user who can read the
+// work order's comments, since the notification quotes the comment. Only
+// users newly mentioned are notified, so re-saving a comment does not notify
+// anyone twice, and nobody is notified for mentioning themselves.
+func syncCommentMentionsTx(ctx context.Context, tx pgx.Tx, referenceID int, commentID int64, body, actorUserID string, mentionEmails []string) error {
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM public.work_order_comment_mentions m
+		USING public.users u
+		WHERE m.comment_id = $1
+		  AND u.id = m.user_id
+		  AND NOT (LOWER(u.email) = ANY($2::text[]))
+	`, commentID, mentionEmails); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		WITH added AS (
+			INSERT INTO public.work_order_comment_mentions(comment_id, user_id)
+			SELECT $1, u.id
+			FROM public.users u
+			WHERE LOWER(u.email) = ANY($2::text[])
+			  AND u.status = 'active'
+			  AND u.deleted_at IS NULL
+			  AND `+commentReaderFilter("u.id", "$3")+`
+			ON CONFLICT DO NOTHING
+			RETURNING user_id
+		)
+		INSERT INTO public.notifications(recipient_user_id, notification_type, reference_id, title, body)
+		SELECT
+			added.user_id,
+			'comment_mention',
+			$3,
+			COALESCE(actor.full_name, 'Someone') || ' mentioned you on job #' || $3::text,
+			LEFT($4, 280)
+		FROM added
+		LEFT JOIN public.users actor ON actor.id = $5::uuid
+		WHERE added.user_id <> $5::uuid
+	`, commentID, mentionEmails, referenceID, body, actorUserID)
+	return err
+}
+
+// DeleteComment removes the comment with its revisions and mentions.
+func (r *storeRepository) DeleteComment(ctx context.Context, referenceID int, commentID int64) error {
+	cmd, err := r.db.Exec(ctx, `DELETE FROM public.work_order_comments WHERE reference_id = $1 AND comment_id = $2`, referenceID, commentID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+func (r *storeRepository) ListCommentRevisions(ctx context.Context, commentID int64) ([]domain.WorkOrderCommentRevision, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			rev.revision_id,
+			rev.comment_id,
+			rev.body,
+			rev.edited_by_user_id::text,
+			u.full_name,
+			rev.created_at
+		FROM public.work_order_comment_revisions rev
+		LEFT JOIN public.users u ON u.id = rev.edited_by_user_id
+		WHERE rev.comment_id = $1
+		ORDER BY rev.created_at DESC, rev.revision_id DESC
+	`, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]domain.WorkOrderCommentRevision, 0)
+	for rows.Next() {
+		var item domain.WorkOrderCommentRevision
+		if err := rows.Scan(
+			&item.RevisionID,
+			&item.CommentID,
+			&item.Body,
+			&item.EditedByUserID,
+			&item.EditedByName,
+			&item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ListMentionableUsers lists the users a comment on the work order can
+// mention. A work order outside access has nobody to mention.
+func (r *storeRepository) ListMentionableUsers(ctx context.Context, referenceID int, query string, access WorkOrderAccess) ([]domain.CommentUser, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT u.id::text, u.email, u.full_name
+		FROM public.users u
+		WHERE u.status = 'active'
+		  AND u.deleted_at IS NULL
+		  AND (
+			$2 = ''
+			OR u.full_name ILIKE '%' || $2 || '%'
+			OR u.email ILIKE '%' || $2 || '%'
+		  )
+		  AND `+commentReaderFilter("u.id", "$1::int")+`
+		  AND `+assignedToFilter("$1::int", 3)+`
+		ORDER BY u.full_name ASC, u.email ASC
+		LIMIT 20
+	`, referenceID, query, access.AssignedUserID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if cmd.RowsAffected() == 0 {
-		return ErrRepairLogNotFound
+	defer rows.Close()
+
+	items := make([]domain.CommentUser, 0)
+	for rows.Next() {
+		var item domain.CommentUser
+		if err := rows.Scan(&item.UserID, &item.Email, &item.FullName); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
 	}
-	return nil
+	return items, rows.Err()
 }
 
-func (r *storeRepository) ListPartsPurchaseRequests(ctx context.Context, referenceID int) ([]domain.PartsPurchaseRequest, error) {
+func (r *storeRepository) ListPartsPurchaseRequests(ctx context.Context, referenceID int, access WorkOrderAccess) ([]domain.PartsPurchaseRequest, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT
 			ppr.parts_purchase_request_id,
@@ -1668,15 +2759,24 @@ func (r *storeRepository) ListPartsPurchaseRequests(ctx context.Context, referen
 			ppr.total_price::double precision,
 			ppr.item_name,
 			ppr.quantity,
+			ppr.parts_item_preset_id,
+			ppr.supplier_id,
+			s.supplier_name,
+			ppr.purchase_order_id,
+			ppr.received_at,
+			ppr.approved_by_user_id::text,
+			ppr.approved_at,
 			ppr.created_by_user_id::text,
 			u.full_name,
 			ppr.created_at,
 			ppr.updated_at
 		FROM public.parts_purchase_requests ppr
 		LEFT JOIN public.users u ON u.id = ppr.created_by_user_id
+		LEFT JOIN public.suppliers s ON s.supplier_id = ppr.supplier_id
 		WHERE ppr.reference_id = $1
+		  AND `+assignedToFilter("ppr.reference_id", 2)+`
 		ORDER BY ppr.created_at DESC, ppr.parts_purchase_request_id DESC
-	`, referenceID)
+	`, referenceID, access.AssignedUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -1694,6 +2794,13 @@ func (r *storeRepository) ListPartsPurchaseRequests(ctx context.Context, referen
 			&item.TotalPrice,
 			&item.ItemName,
 			&item.Quantity,
+			&item.PartsItemPresetID,
+			&item.SupplierID,
+			&item.SupplierName,
+			&item.PurchaseOrderID,
+			&item.ReceivedAt,
+			&item.ApprovedByUserID,
+			&item.ApprovedAt,
 			&item.CreatedByUserID,
 			&item.CreatedByName,
 			&item.CreatedAt,
@@ -1707,8 +2814,27 @@ func (r *storeRepository) ListPartsPurchaseRequests(ctx context.Context, referen
 }
 
 func (r *storeRepository) CreatePartsPurchaseRequest(ctx context.Context, referenceID int, input CreatePartsPurchaseRequestInput) (domain.PartsPurchaseRequest, error) {
+	if err := r.ensureSupplierExists(ctx, input.SupplierID); err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+	if err := r.ensurePartsItemPresetExists(ctx, input.PartsItemPresetID); err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err := checkWorkOrderAccess(ctx, tx, referenceID, input.Access); err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+
 	var inserted domain.PartsPurchaseRequest
-	err := r.db.QueryRow(ctx, `
+	err = tx.QueryRow(ctx, `
 		INSERT INTO public.parts_purchase_requests(
 			reference_id,
 			source,
@@ -1717,6 +2843,8 @@ func (r *storeRepository) CreatePartsPurchaseRequest(ctx context.Context, refere
 			total_price,
 			item_name,
 			quantity,
+			parts_item_preset_id,
+			supplier_id,
 			created_by_user_id
 		)
 		VALUES(
@@ -1727,7 +2855,9 @@ func (r *storeRepository) CreatePartsPurchaseRequest(ctx context.Context, refere
 			$5,
 			BTRIM($6),
 			$7,
-			$8::uuid
+			$8,
+			$9,
+			$10::uuid
 		)
 		RETURNING
 			parts_purchase_request_id,
@@ -1738,6 +2868,12 @@ func (r *storeRepository) CreatePartsPurchaseRequest(ctx context.Context, refere
 			total_price::double precision,
 			item_name,
 			quantity,
+			parts_item_preset_id,
+			supplier_id,
+			purchase_order_id,
+			received_at,
+			approved_by_user_id::text,
+			approved_at,
 			created_by_user_id::text,
 			created_at,
 			updated_at
@@ -1749,6 +2885,8 @@ func (r *storeRepository) CreatePartsPurchaseRequest(ctx context.Context, refere
 		input.TotalPrice,
 		input.ItemName,
 		input.Quantity,
+		input.PartsItemPresetID,
+		input.SupplierID,
 		input.CreatedByUserID,
 	).Scan(
 		&inserted.PartsPurchaseRequestID,
@@ -1759,6 +2897,12 @@ func (r *storeRepository) CreatePartsPurchaseRequest(ctx context.Context, refere
 		&inserted.TotalPrice,
 		&inserted.ItemName,
 		&inserted.Quantity,
+		&inserted.PartsItemPresetID,
+		&inserted.SupplierID,
+		&inserted.PurchaseOrderID,
+		&inserted.ReceivedAt,
+		&inserted.ApprovedByUserID,
+		&inserted.ApprovedAt,
 		&inserted.CreatedByUserID,
 		&inserted.CreatedAt,
 		&inserted.UpdatedAt,
@@ -1767,15 +2911,56 @@ func (r *storeRepository) CreatePartsPurchaseRequest(ctx context.Context, refere
 		return domain.PartsPurchaseRequest{}, err
 	}
 
+	if inserted.Status == "received" || inserted.Status == "used" {
+		if err := inventory.RecordPartsRequestMovementTx(ctx, tx, inserted.PartsPurchaseRequestID, inserted.Status, input.CreatedByUserID); err != nil {
+			return domain.PartsPurchaseRequest{}, err
+		}
+	}
+	if inserted.Status == "used" {
+		if err := r.syncPartsLineItemTx(ctx, tx, referenceID, inserted.PartsPurchaseRequestID); err != nil {
+			return domain.PartsPurchaseRequest{}, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+
 	if err := r.db.QueryRow(ctx, `SELECT full_name FROM public.users WHERE id = $1::uuid`, input.CreatedByUserID).Scan(&inserted.CreatedByName); err != nil {
 		return domain.PartsPurchaseRequest{}, err
 	}
+	if err := r.loadPartsSupplierName(ctx, &inserted); err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
 	return inserted, nil
 }
 
 func (r *storeRepository) UpdatePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64, input UpdatePartsPurchaseRequestInput) (domain.PartsPurchaseRequest, error) {
+	if err := r.ensureSupplierExists(ctx, input.SupplierID); err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+	if err := r.ensurePartsItemPresetExists(ctx, input.PartsItemPresetID); err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	previous, err := lockPartsApprovalStateTx(ctx, tx, referenceID, partsPurchaseRequestID, input.Access)
+	if err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+	if partsUpdateNeedsApproval(previous, input.Status, input.TotalPrice) {
+		return domain.PartsPurchaseRequest{}, ErrPartsApprovalRequired
+	}
+	previousStatus := previous.Status
+
 	var updated domain.PartsPurchaseRequest
-	cmdErr := r.db.QueryRow(ctx, `
+	err = tx.QueryRow(ctx, `
 		UPDATE public.parts_purchase_requests
 		SET
 			source = $3,
@@ -1784,6 +2969,15 @@ func (r *storeRepository) UpdatePartsPurchaseRequest(ctx context.Context, refere
 			total_price = $6,
 			item_name = BTRIM($7),
 			quantity = $8,
+			parts_item_preset_id = $9,
+			supplier_id = $10,
+			received_at = CASE
+				WHEN $5 = 'received' AND received_at IS NULL THEN now()
+				ELSE received_at
+			END,
+			approved_by_user_id = CASE WHEN $6 > approved_total_price THEN NULL ELSE approved_by_user_id END,
+			approved_at = CASE WHEN $6 > approved_total_price THEN NULL ELSE approved_at END,
+			approved_total_price = CASE WHEN $6 > approved_total_price THEN NULL ELSE approved_total_price END,
 			updated_at = now()
 		WHERE reference_id = $1 AND parts_purchase_request_id = $2
 		RETURNING
@@ -1795,6 +2989,12 @@ func (r *storeRepository) UpdatePartsPurchaseRequest(ctx context.Context, refere
 			total_price::double precision,
 			item_name,
 			quantity,
+			parts_item_preset_id,
+			supplier_id,
+			purchase_order_id,
+			received_at,
+			approved_by_user_id::text,
+			approved_at,
 			created_by_user_id::text,
 			created_at,
 			updated_at
@@ -1807,6 +3007,8 @@ func (r *storeRepository) UpdatePartsPurchaseRequest(ctx context.Context, refere
 		input.TotalPrice,
 		input.ItemName,
 		input.Quantity,
+		input.PartsItemPresetID,
+		input.SupplierID,
 	).Scan(
 		&updated.PartsPurchaseRequestID,
 		&updated.ReferenceID,
@@ -1816,30 +3018,445 @@ func (r *storeRepository) UpdatePartsPurchaseRequest(ctx context.Context, refere
 		&updated.TotalPrice,
 		&updated.ItemName,
 		&updated.Quantity,
+		&updated.PartsItemPresetID,
+		&updated.SupplierID,
+		&updated.PurchaseOrderID,
+		&updated.ReceivedAt,
+		&updated.ApprovedByUserID,
+		&updated.ApprovedAt,
 		&updated.CreatedByUserID,
 		&updated.CreatedAt,
 		&updated.UpdatedAt,
 	)
-	if cmdErr != nil {
-		if cmdErr == pgx.ErrNoRows {
-			return domain.PartsPurchaseRequest{}, ErrPartsPurchaseRequestNotFound
+	if err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+
+	if updated.Status != previousStatus && (updated.Status == "received" || updated.Status == "used") {
+		if err := inventory.RecordPartsRequestMovementTx(ctx, tx, updated.PartsPurchaseRequestID, updated.Status, input.UpdatedByUserID); err != nil {
+			return domain.PartsPurchaseRequest{}, err
+		}
+	}
+	if updated.Status == "used" || previousStatus == "used" {
+		if err := r.syncPartsLineItemTx(ctx, tx, referenceID, updated.PartsPurchaseRequestID); err != nil {
+			return domain.PartsPurchaseRequest{}, err
 		}
-		return domain.PartsPurchaseRequest{}, cmdErr
 	}
+	if err := tx.Commit(ctx); err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
+
 	if err := r.db.QueryRow(ctx, `SELECT full_name FROM public.users WHERE id = $1::uuid`, updated.CreatedByUserID).Scan(&updated.CreatedByName); err != nil {
 		return domain.PartsPurchaseRequest{}, err
 	}
+	if err := r.loadPartsSupplierName(ctx, &updated); err != nil {
+		return domain.PartsPurchaseRequest{}, err
+	}
 	return updated, nil
 }
 
-func (r *storeRepository) DeletePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64) error {
-	cmd, err := r.db.Exec(ctx, `DELETE FROM public.parts_purchase_requests WHERE reference_id = $1 AND parts_purchase_request_id = $2`, referenceID, partsPurchaseRequestID)
+func (r *storeRepository) syncPartsLineItemTx(ctx context.Context, tx pgx.Tx, referenceID int, partsPurchaseRequestID int64) error {
+	if err := partsmarkup.SyncPartsLineItemTx(ctx, tx, partsPurchaseRequestID); err != nil {
+		return err
+	}
+	return r.recalculatePartsTotalTx(ctx, tx, referenceID)
+}
+
+func (r *storeRepository) ensureSupplierExists(ctx context.Context, supplierID *int64) error {
+	if supplierID == nil {
+		return nil
+	}
+	if *supplierID <= 0 {
+		return ErrSupplierNotFound
+	}
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.suppliers WHERE supplier_id = $1 AND is_active = true)`, *supplierID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrSupplierNotFound
+	}
+	return nil
+}
+
+func (r *storeRepository) ensurePartsItemPresetExists(ctx context.Context, partsItemPresetID *int64) error {
+	if partsItemPresetID == nil {
+		return nil
+	}
+	if *partsItemPresetID <= 0 {
+		return ErrPartsItemPresetNotFound
+	}
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM public.parts_item_presets WHERE parts_item_preset_id = $1)`, *partsItemPresetID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrPartsItemPresetNotFound
+	}
+	return nil
+}
+
+func (r *storeRepository) loadPartsSupplierName(ctx context.Context, item *domain.PartsPurchaseRequest) error {
+	if item.SupplierID == nil {
+		return nil
+	}
+	return r.db.QueryRow(ctx, `SELECT supplier_name FROM public.suppliers WHERE supplier_id = $1`, *item.SupplierID).Scan(&item.SupplierName)
+}
+
+// DeletePartsPurchaseRequest also drops the line item the request priced, so
+// the job's parts total stops charging for it.
+func (r *storeRepository) DeletePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64, access WorkOrderAccess) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err := checkWorkOrderAccess(ctx, tx, referenceID, access); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM public.work_order_line_items
+		WHERE reference_id = $1 AND parts_purchase_request_id = $2
+	`, referenceID, partsPurchaseRequestID); err != nil {
+		return err
+	}
+	cmd, err := tx.Exec(ctx, `DELETE FROM public.parts_purchase_requests WHERE reference_id = $1 AND parts_purchase_request_id = $2`, referenceID, partsPurchaseRequestID)
 	if err != nil {
 		return err
 	}
 	if cmd.RowsAffected() == 0 {
 		return ErrPartsPurchaseRequestNotFound
 	}
+	if err := r.recalculatePartsTotalTx(ctx, tx, referenceID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *storeRepository) GetPartsApprovalThreshold(ctx context.Context) (*float64, error) {
+	var threshold float64
+	err := r.db.QueryRow(ctx, `
+		SELECT setting_value::double precision
+		FROM public.app_settings
+		WHERE setting_key = $1
+	`, AppSettingPartsApprovalThreshold).Scan(&threshold)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &threshold, nil
+}
+
+// lockPartsApprovalStateTx locks the request so the approval check and the
+// update that follows it see the same totals. Requests on work orders outside
+// access are reported as not found.
+func lockPartsApprovalStateTx(ctx context.Context, tx pgx.Tx, referenceID int, partsPurchaseRequestID int64, access WorkOrderAccess) (PartsApprovalState, error) {
+	var state PartsApprovalState
+	err := tx.QueryRow(ctx, `
+		SELECT
+			ppr.status,
+			ppr.total_price::double precision,
+			ppr.approved_total_price::double precision,
+			(
+				SELECT s.setting_value::double precision
+				FROM public.app_settings s
+				WHERE s.setting_key = $3
+			)
+		FROM public.parts_purchase_requests ppr
+		WHERE ppr.reference_id = $1
+		  AND ppr.parts_purchase_request_id = $2
+		  AND `+assignedToFilter("ppr.reference_id", 4)+`
+		FOR UPDATE OF ppr
+	`, referenceID, partsPurchaseRequestID, AppSettingPartsApprovalThreshold, access.AssignedUserID).Scan(&state.Status, &state.TotalPrice, &state.ApprovedTotalPrice, &state.Threshold)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return PartsApprovalState{}, ErrPartsPurchaseRequestNotFound
+		}
+		return PartsApprovalState{}, err
+	}
+	return state, nil
+}
+
+func (r *storeRepository) ListPartsPurchaseRequestApprovals(ctx context.Context, referenceID int, partsPurchaseRequestID int64) ([]domain.PartsPurchaseRequestApproval, error) {
+	var exists bool
+	if err := r.db.QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM public.parts_purchase_requests
+			WHERE reference_id IS NOT DISTINCT FROM NULLIF($1::int, 0) AND parts_purchase_request_id = $2
+		)
+	`, referenceID, partsPurchaseRequestID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrPartsPurchaseRequestNotFound
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			a.approval_id,
+			a.parts_purchase_request_id,
+			a.approver_user_id::text,
+			u.full_name,
+			a.decision,
+			a.total_price::double precision,
+			a.comment,
+			a.created_at
+		FROM public.parts_purchase_request_approvals a
+		LEFT JOIN public.users u ON u.id = a.approver_user_id
+		WHERE a.parts_purchase_request_id = $1
+		ORDER BY a.created_at DESC, a.approval_id DESC
+	`, partsPurchaseRequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.PartsPurchaseRequestApproval, 0)
+	for rows.Next() {
+		var item domain.PartsPurchaseRequestApproval
+		if err := rows.Scan(
+			&item.ApprovalID,
+			&item.PartsPurchaseRequestID,
+			&item.ApproverUserID,
+			&item.ApproverName,
+			&item.Decision,
+			&item.TotalPrice,
+			&item.Comment,
+			&item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) DecidePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64, input DecidePartsPurchaseRequestInput) (domain.PartsPurchaseRequestApproval, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return domain.PartsPurchaseRequestApproval{}, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var status string
+	var createdByUserID string
+	var totalPrice float64
+	if err := tx.QueryRow(ctx, `
+		SELECT status, created_by_user_id::text, total_price::double precision
+		FROM public.parts_purchase_requests
+		WHERE reference_id IS NOT DISTINCT FROM NULLIF($1::int, 0) AND parts_purchase_request_id = $2
+		FOR UPDATE
+	`, referenceID, partsPurchaseRequestID).Scan(&status, &createdByUserID, &totalPrice); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.PartsPurchaseRequestApproval{}, ErrPartsPurchaseRequestNotFound
+		}
+		return domain.PartsPurchaseRequestApproval{}, err
+	}
+	if status != "draft" && status != "waiting_approval" {
+		return domain.PartsPurchaseRequestApproval{}, ErrPartsRequestNotAwaitingApproval
+	}
+	if createdByUserID == input.ApproverUserID {
+		return domain.PartsPurchaseRequestApproval{}, ErrSelfApprovalNotAllowed
+	}
+
+	if input.Decision == "approved" {
+		var limit float64
+		err := tx.QueryRow(ctx, `
+			SELECT max_total_price::double precision
+			FROM public.parts_approval_limits
+			WHERE user_id = $1::uuid
+		`, input.ApproverUserID).Scan(&limit)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return domain.PartsPurchaseRequestApproval{}, err
+		}
+		if err == nil && totalPrice > limit {
+			return domain.PartsPurchaseRequestApproval{}, ErrApprovalLimitExceeded
+		}
+	}
+
+	var approval domain.PartsPurchaseRequestApproval
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO public.parts_purchase_request_approvals(
+			parts_purchase_request_id,
+			approver_user_id,
+			decision,
+			total_price,
+			comment
+		)
+		VALUES($1, $2::uuid, $3, $4, $5)
+		RETURNING
+			approval_id,
+			parts_purchase_request_id,
+			approver_user_id::text,
+			decision,
+			total_price::double precision,
+			comment,
+			created_at
+	`, partsPurchaseRequestID, input.ApproverUserID, input.Decision, totalPrice, input.Comment).Scan(
+		&approval.ApprovalID,
+		&approval.PartsPurchaseRequestID,
+		&approval.ApproverUserID,
+		&approval.Decision,
+		&approval.TotalPrice,
+		&approval.Comment,
+		&approval.CreatedAt,
+	); err != nil {
+		return domain.PartsPurchaseRequestApproval{}, err
+	}
+
+	if input.Decision == "approved" {
+		_, err = tx.Exec(ctx, `
+			UPDATE public.parts_purchase_requests
+			SET
+				approved_by_user_id = $2::uuid,
+				approved_at = now(),
+				approved_total_price = total_price,
+				updated_at = now()
+			WHERE parts_purchase_request_id = $1
+		`, partsPurchaseRequestID, input.ApproverUserID)
+	} else {
+		// A rejection sends the request back to its requester to revise.
+		_, err = tx.Exec(ctx, `
+			UPDATE public.parts_purchase_requests
+			SET
+				status = 'draft',
+				approved_by_user_id = NULL,
+				approved_at = NULL,
+				approved_total_price = NULL,
+				updated_at = now()
+			WHERE parts_purchase_request_id = $1
+		`, partsPurchaseRequestID)
+	}
+	if err != nil {
+		return domain.PartsPurchaseRequestApproval{}, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO public.notifications(recipient_user_id, notification_type, reference_id, title, body)
+		SELECT
+			ppr.created_by_user_id,
+			'parts_request_' || $2::text,
+			ppr.reference_id,
+			'Parts request ' || $2::text || COALESCE(' for job #' || ppr.reference_id, ' for stock'),
+			COALESCE(ppr.item_name || ': ' || $3::text, ppr.item_name)
+		FROM public.parts_purchase_requests ppr
+		WHERE ppr.parts_purchase_request_id = $1
+	`, partsPurchaseRequestID, input.Decision, input.Comment); err != nil {
+		return domain.PartsPurchaseRequestApproval{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return domain.PartsPurchaseRequestApproval{}, err
+	}
+
+	if err := r.db.QueryRow(ctx, `SELECT full_name FROM public.users WHERE id = $1::uuid`, input.ApproverUserID).Scan(&approval.ApproverName); err != nil {
+		return domain.PartsPurchaseRequestApproval{}, err
+	}
+	return approval, nil
+}
+
+func (r *storeRepository) GetPartsApprovalSettings(ctx context.Context) (domain.PartsApprovalSettings, error) {
+	threshold, err := r.GetPartsApprovalThreshold(ctx)
+	if err != nil {
+		return domain.PartsApprovalSettings{}, err
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			l.user_id::text,
+			u.full_name,
+			l.max_total_price::double precision,
+			l.updated_at
+		FROM public.parts_approval_limits l
+		JOIN public.users u ON u.id = l.user_id
+		ORDER BY u.full_name ASC, l.user_id ASC
+	`)
+	if err != nil {
+		return domain.PartsApprovalSettings{}, err
+	}
+	defer rows.Close()
+
+	out := domain.PartsApprovalSettings{
+		Threshold: threshold,
+		Limits:    make([]domain.PartsApprovalLimit, 0),
+	}
+	for rows.Next() {
+		var item domain.PartsApprovalLimit
+		if err := rows.Scan(&item.UserID, &item.FullName, &item.MaxTotalPrice, &item.UpdatedAt); err != nil {
+			return domain.PartsApprovalSettings{}, err
+		}
+		out.Limits = append(out.Limits, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *storeRepository) SetPartsApprovalThreshold(ctx context.Context, threshold *float64) error {
+	if threshold == nil {
+		_, err := r.db.Exec(ctx, `DELETE FROM public.app_settings WHERE setting_key = $1`, AppSettingPartsApprovalThreshold)
+		return err
+	}
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO public.app_settings(setting_key, setting_value, updated_at)
+		VALUES($1, $2::text, now())
+		ON CONFLICT (setting_key)
+		DO UPDATE SET
+			setting_value = EXCLUDED.setting_value,
+			updated_at = now()
+	`, AppSettingPartsApprovalThreshold, *threshold)
+	return err
+}
+
+func (r *storeRepository) SetPartsApprovalLimit(ctx context.Context, userID string, maxTotalPrice float64) (domain.PartsApprovalLimit, error) {
+	var item domain.PartsApprovalLimit
+	err := r.db.QueryRow(ctx, `
+		WITH target_user AS (
+			SELECT id, full_name
+			FROM public.users
+			WHERE id::text = $1
+		), upserted AS (
+			INSERT INTO public.parts_approval_limits(user_id, max_total_price, updated_at)
+			SELECT id, $2, now()
+			FROM target_user
+			ON CONFLICT (user_id)
+			DO UPDATE SET
+				max_total_price = EXCLUDED.max_total_price,
+				updated_at = now()
+			RETURNING user_id, max_total_price, updated_at
+		)
+		SELECT
+			up.user_id::text,
+			tu.full_name,
+			up.max_total_price::double precision,
+			up.updated_at
+		FROM upserted up
+		JOIN target_user tu ON tu.id = up.user_id
+	`, userID, maxTotalPrice).Scan(&item.UserID, &item.FullName, &item.MaxTotalPrice, &item.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.PartsApprovalLimit{}, ErrUserNotFound
+		}
+		return domain.PartsApprovalLimit{}, err
+	}
+	return item, nil
+}
+
+func (r *storeRepository) DeletePartsApprovalLimit(ctx context.Context, userID string) error {
+	cmd, err := r.db.Exec(ctx, `DELETE FROM public.parts_approval_limits WHERE user_id::text = $1`, userID)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
 	return nil
 }
 
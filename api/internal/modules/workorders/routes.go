@@ -2,7 +2,7 @@ package workorders
 
 import (
 	"humphreys/api/internal/middleware"
-	"net/http"
+	"humphreys/api/internal/permissions"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,39 +11,77 @@ const (
 	permCreate           = "work_orders:create"
 	permRead             = "work_orders:read"
 	permUpdate           = "work_orders:update"
+	permAssign           = "work_orders:assign"
 	permStatusUpdate     = "work_orders_status:update"
 	permSensitiveRead    = "work_orders_sensitive:read"
 	permRepairLogsRead   = "repair_logs:read"
 	permRepairLogsCreate = "repair_logs:create"
 	permRepairLogsUpdate = "repair_logs:update"
 	permRepairLogsDelete = "repair_logs:delete"
+	permCommentsRead     = "work_order_comments:read"
+	permCommentsCreate   = "work_order_comments:create"
+	permCommentsUpdate   = "work_order_comments:update"
+	permCommentsDelete   = "work_order_comments:delete"
 	permPartsRead        = "parts_purchase_requests:read"
 	permPartsCreate      = "parts_purchase_requests:create"
 	permPartsUpdate      = "parts_purchase_requests:update"
 	permPartsDelete      = "parts_purchase_requests:delete"
+	permPartsApprove     = "parts_purchase_requests:approve"
+	permPartsAssign      = "parts_purchase_requests:assign"
 )
 
-func RegisterRoutes(authed *gin.RouterGroup, h *Handler) {
+// RegisterPermissions declares the RBAC resources this module owns.
+func RegisterPermissions(reg *permissions.Registry) {
+	reg.Register("work_orders", "Work order management", permissions.StandardActions("read"+middleware.AssignedScopeSuffix)...)
+	reg.Register("work_orders_status", "Work order status updates", permissions.StandardActions()...)
+	reg.Register("work_orders_sensitive", "Sensitive work order details (customer, line items, pricing)", permissions.StandardActions()...)
+	reg.Register("repair_logs", "Repair log entries linked to work orders", permissions.StandardActions(assignedActions("read", "create", "update", "delete")...)...)
+	reg.Register("work_order_comments", "Discussion comments on work orders", permissions.StandardActions("read"+middleware.AssignedScopeSuffix)...)
+	reg.Register("parts_purchase_requests", "Parts purchasing requests linked to work orders", permissions.StandardActions(append([]string{"approve"}, assignedActions("read", "create", "update", "delete")...)...)...)
+}
+
+// assignedActions returns the ":assigned" form of each action, granting it
+// only on work orders assigned to the caller or their team.
+func assignedActions(actions ...string) []string {
+	scoped := make([]string, 0, len(actions))
+	for _, action := range actions {
+		scoped = append(scoped, action+middleware.AssignedScopeSuffix)
+	}
+	return scoped
+}
+
+func RegisterRoutes(authed *middleware.Router, h *Handler) {
 	authed.GET(
 		"/parts-purchase-requests",
-		middleware.RequirePermission(permPartsRead),
+		middleware.RequirePermissionOrAssigned(permPartsRead),
 		middleware.RequirePermission(permSensitiveRead),
 		h.ListAllPartsPurchaseRequests,
 	)
+	// Restock requests have no work order, so they are approved here.
+	authed.GET("/parts-purchase-requests/:parts_purchase_request_id/approvals", middleware.RequirePermission(permPartsRead), h.ListPartsPurchaseRequestApprovals)
+	authed.POST("/parts-purchase-requests/:parts_purchase_request_id/approvals", middleware.RequirePermission(permPartsApprove), h.DecidePartsPurchaseRequest)
+	authed.GET("/parts-approval-settings", middleware.RequirePermission(permPartsApprove), h.GetPartsApprovalSettings)
+	authed.PATCH("/parts-approval-settings", middleware.RequirePermission(permPartsAssign), h.SetPartsApprovalThreshold)
+	authed.PATCH("/parts-approval-settings/limits/:user_id", middleware.RequirePermission(permPartsAssign), h.SetPartsApprovalLimit)
+	authed.DELETE("/parts-approval-settings/limits/:user_id", middleware.RequirePermission(permPartsAssign), h.DeletePartsApprovalLimit)
+	authed.GET("/rush-settings", middleware.RequirePermission(permRead), h.GetRushSettings)
+	authed.PATCH("/rush-settings", middleware.RequirePermission(permAssign), h.SetRushSettings)
 
 	group := authed.Group("/work-orders")
 	group.GET("/customers", middleware.RequirePermission(permRead), h.ListCustomers)
 	group.GET("/dashboard", middleware.RequirePermission(permRead), h.Dashboard)
+	group.GET("/queue", middleware.RequirePermissionOrAssigned(permRead), h.Queue)
+	group.GET("/promise-report", middleware.RequirePermission(permRead), h.PromiseReport)
 	group.POST("", middleware.RequirePermission(permCreate), h.CreateWorkOrder)
 	group.DELETE("/:reference_id", middleware.RequirePermission(permCreate), h.DeleteWorkOrder)
-	group.GET("", middleware.RequirePermission(permRead), h.ListWorkOrders)
+	group.GET("", middleware.RequirePermissionOrAssigned(permRead), h.ListWorkOrders)
 	group.POST(
 		"/ai-markdown",
 		middleware.RequirePermission(permCreate),
 		middleware.RequirePermission(permSensitiveRead),
 		h.GenerateAIMarkdownWithoutWorkOrder,
 	)
-	group.GET("/:reference_id", middleware.RequirePermission(permRead), h.GetWorkOrder)
+	group.GET("/:reference_id", middleware.RequirePermissionOrAssigned(permRead), h.GetWorkOrder)
 	group.POST(
 		"/:reference_id/ai-summary",
 		middleware.RequirePermission(permRead),
@@ -69,34 +107,40 @@ func RegisterRoutes(authed *gin.RouterGroup, h *Handler) {
 		h.SendCustomerEmail,
 	)
 	group.PATCH("/:reference_id/status", middleware.RequirePermission(permStatusUpdate), h.UpdateStatus)
+	group.PATCH("/:reference_id/team", middleware.RequirePermission(permAssign), h.UpdateTeam)
+	group.PATCH("/:reference_id/schedule", middleware.RequirePermission(permUpdate), h.UpdateSchedule)
+	group.GET("/:reference_id/due-date-changes", middleware.RequirePermission(permRead), h.ListDueDateChanges)
+	group.PUT("/:reference_id/workers/me", requireSelfAssignPermission(), h.AssignSelf)
+	group.DELETE("/:reference_id/workers/me", requireSelfAssignPermission(), h.UnassignSelf)
 	group.PATCH("/:reference_id/equipment", requireEquipmentUpdatePermission(), h.UpdateEquipment)
 	group.PATCH("/:reference_id/work-notes", middleware.RequirePermission(permUpdate), h.UpdateWorkNotes)
 	group.PATCH("/:reference_id/line-items", middleware.RequirePermission(permUpdate), h.UpdateLineItems)
 	group.PATCH("/:reference_id/totals", middleware.RequirePermission(permUpdate), h.UpdateTotals)
 	group.PATCH("/:reference_id/customer", middleware.RequirePermission(permUpdate), h.UpdateCustomer)
-	group.GET("/:reference_id/repair-logs", middleware.RequirePermission(permRepairLogsRead), h.ListRepairLogs)
-	group.POST("/:reference_id/repair-logs", middleware.RequirePermission(permRepairLogsCreate), h.CreateRepairLog)
-	group.PATCH("/:reference_id/repair-logs/:repair_log_id", middleware.RequirePermission(permRepairLogsUpdate), h.UpdateRepairLog)
-	group.DELETE("/:reference_id/repair-logs/:repair_log_id", middleware.RequirePermission(permRepairLogsDelete), h.DeleteRepairLog)
-	group.GET("/:reference_id/parts-purchase-requests", middleware.RequirePermission(permPartsRead), h.ListPartsPurchaseRequests)
-	group.POST("/:reference_id/parts-purchase-requests", middleware.RequirePermission(permPartsCreate), h.CreatePartsPurchaseRequest)
-	group.PATCH("/:reference_id/parts-purchase-requests/:parts_purchase_request_id", middleware.RequirePermission(permPartsUpdate), h.UpdatePartsPurchaseRequest)
-	group.DELETE("/:reference_id/parts-purchase-requests/:parts_purchase_request_id", middleware.RequirePermission(permPartsDelete), h.DeletePartsPurchaseRequest)
+	group.GET("/:reference_id/repair-logs", middleware.RequirePermissionOrAssigned(permRepairLogsRead), h.ListRepairLogs)
+	group.POST("/:reference_id/repair-logs", middleware.RequirePermissionOrAssigned(permRepairLogsCreate), h.CreateRepairLog)
+	group.PATCH("/:reference_id/repair-logs/:repair_log_id", middleware.RequirePermissionOrAssigned(permRepairLogsUpdate), h.UpdateRepairLog)
+	group.DELETE("/:reference_id/repair-logs/:repair_log_id", middleware.RequirePermissionOrAssigned(permRepairLogsDelete), h.DeleteRepairLog)
+	group.GET("/:reference_id/comments", middleware.RequirePermissionOrAssigned(permCommentsRead), h.ListComments)
+	group.POST("/:reference_id/comments", middleware.RequirePermission(permCommentsCreate), h.CreateComment)
+	group.PATCH("/:reference_id/comments/:comment_id", middleware.RequireAnyPermission(permCommentsCreate, permCommentsUpdate), h.UpdateComment)
+	group.DELETE("/:reference_id/comments/:comment_id", middleware.RequireAnyPermission(permCommentsCreate, permCommentsDelete), h.DeleteComment)
+	group.GET("/:reference_id/comments/mentionable-users", middleware.RequirePermission(permCommentsCreate), h.ListMentionableUsers)
+	group.GET("/:reference_id/comments/:comment_id/revisions", middleware.RequirePermissionOrAssigned(permCommentsRead), h.ListCommentRevisions)
+	group.GET("/:reference_id/parts-purchase-requests", middleware.RequirePermissionOrAssigned(permPartsRead), h.ListPartsPurchaseRequests)
+	group.POST("/:reference_id/parts-purchase-requests", middleware.RequirePermissionOrAssigned(permPartsCreate), h.CreatePartsPurchaseRequest)
+	group.PATCH("/:reference_id/parts-purchase-requests/:parts_purchase_request_id", middleware.RequirePermissionOrAssigned(permPartsUpdate), h.UpdatePartsPurchaseRequest)
+	group.DELETE("/:reference_id/parts-purchase-requests/:parts_purchase_request_id", middleware.RequirePermissionOrAssigned(permPartsDelete), h.DeletePartsPurchaseRequest)
+	group.GET("/:reference_id/parts-purchase-requests/:parts_purchase_request_id/approvals", middleware.RequirePermission(permPartsRead), h.ListPartsPurchaseRequestApprovals)
+	group.POST("/:reference_id/parts-purchase-requests/:parts_purchase_request_id/approvals", middleware.RequirePermission(permPartsApprove), h.DecidePartsPurchaseRequest)
+}
+
+// requireSelfAssignPermission lets technicians, who update status but do not
+// assign work to others, pick jobs up and put them down themselves.
+func requireSelfAssignPermission() gin.HandlerFunc {
+	return middleware.RequireAnyPermission(permAssign, permStatusUpdate)
 }
 
 func requireEquipmentUpdatePermission() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		claims, ok := middleware.Claims(c)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing auth context"})
-			return
-		}
-		for _, code := range claims.Scope {
-			if code == permUpdate || code == permStatusUpdate {
-				c.Next()
-				return
-			}
-		}
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-	}
+	return middleware.RequireAnyPermission(permUpdate, permStatusUpdate)
 }
@@ -6,6 +6,7 @@ import (
 	"net/mail"
 	"regexp"
 	"strings"
+	"time"
 
 	"humphreys/api/internal/domain"
 
@@ -28,17 +29,36 @@ var ErrLocationNotFound = errors.New("location not found")
 var ErrInvalidRepairLogDetails = errors.New("repair log details are required")
 var ErrInvalidRepairLogHoursUsed = errors.New("repair log hours_used must be zero or greater")
 var ErrInvalidPartsSource = errors.New("parts source must be online or supplier")
-var ErrInvalidPartsStatus = errors.New("parts status must be draft, waiting_approval, ordered, or used")
+var ErrInvalidPartsStatus = errors.New("parts status must be draft, waiting_approval, ordered, received, or used")
 var ErrInvalidPartsItemName = errors.New("parts item name is required")
 var ErrInvalidPartsQuantity = errors.New("parts quantity must be at least 1")
 var ErrInvalidPartsTotalPrice = errors.New("parts total price must be zero or greater")
 var ErrRepairLogNotFound = errors.New("repair log not found")
 var ErrPartsPurchaseRequestNotFound = errors.New("parts purchase request not found")
+var ErrSupplierNotFound = errors.New("supplier not found")
+var ErrPartsItemPresetNotFound = errors.New("parts item preset not found")
+var ErrPartsApprovalRequired = errors.New("parts purchase request is above the spending limit and must be approved before it is ordered")
+var ErrInvalidApprovalDecision = errors.New("approval decision must be approved or rejected")
+var ErrApprovalCommentRequired = errors.New("a comment is required when rejecting a parts purchase request")
+var ErrPartsRequestNotAwaitingApproval = errors.New("only draft or waiting_approval parts purchase requests can be approved or rejected")
+var ErrSelfApprovalNotAllowed = errors.New("you cannot approve or reject your own parts purchase request")
+var ErrApprovalLimitExceeded = errors.New("parts purchase request total is above your approval limit")
+var ErrInvalidApprovalAmount = errors.New("approval amounts must be zero or greater")
+var ErrUserNotFound = errors.New("user not found")
 var ErrInvalidCreationMode = errors.New("creation mode must be new_job or stock")
 var ErrStockJobTypeNotFound = errors.New("stock job type not found")
 var ErrJobTypeNotFound = errors.New("job type not found")
 var ErrOriginalJobNotFound = errors.New("original job not found")
 var ErrInvalidOriginalJobID = errors.New("original_job_id must be a positive integer")
+var ErrTeamNotFound = errors.New("team not found")
+var ErrInvalidPriority = errors.New("priority must be low, normal, high, or urgent")
+var ErrWorkerNotLinked = errors.New("your account is not linked to a worker")
+var ErrDueDateChangeReasonRequired = errors.New("a reason is required when moving or removing a promised date")
+var ErrInvalidRushSurcharge = errors.New("rush surcharge must be zero or greater")
+var ErrInvalidReportPeriod = errors.New("to must be on or after from")
+var ErrInvalidCommentBody = errors.New("comment body is required")
+var ErrCommentNotFound = errors.New("comment not found")
+var ErrCommentNotAuthor = errors.New("you can only change your own comments")
 
 type Service struct {
 	repo Repository
@@ -51,6 +71,14 @@ type WorkOrderListFilters struct {
 	ItemID      *int64
 	CreatedFrom *string
 	CreatedTo   *string
+	TeamID      *int64
+}
+
+// WorkOrderAccess narrows reads to work orders assigned to AssignedUserID,
+// for callers holding only the ":assigned" form of a read permission. The
+// zero value is unrestricted.
+type WorkOrderAccess struct {
+	AssignedUserID string
 }
 
 type DashboardQueryInput struct {
@@ -61,14 +89,18 @@ type DashboardQueryInput struct {
 	OverduePageSize int
 	IncludeParts    bool
 	IncludeActivity bool
+	// ApproverUserID narrows the parts review list to requests this user can approve.
+	ApproverUserID *string
+	// TeamID scopes every section to work orders owned by the team.
+	TeamID *int64
 }
 
 func NewService(repo Repository) *Service {
 	return &Service{repo: repo}
 }
 
-func (s *Service) ListWorkOrders(ctx context.Context, query string, filters WorkOrderListFilters, includeSensitive bool, page, pageSize int) ([]domain.WorkOrderListItem, error) {
-	return s.repo.ListWorkOrders(ctx, query, filters, includeSensitive, page, pageSize)
+func (s *Service) ListWorkOrders(ctx context.Context, query string, filters WorkOrderListFilters, access WorkOrderAccess, searchContact bool, page, pageSize int) ([]domain.WorkOrderListItem, error) {
+	return s.repo.ListWorkOrders(ctx, query, filters, access, searchContact, page, pageSize)
 }
 
 func (s *Service) GetDashboardData(ctx context.Context, input DashboardQueryInput) (domain.DashboardData, error) {
@@ -76,7 +108,13 @@ func (s *Service) GetDashboardData(ctx context.Context, input DashboardQueryInpu
 }
 
 func (s *Service) GetWorkOrderDetail(ctx context.Context, referenceID int) (domain.WorkOrderDetail, error) {
-	detail, err := s.repo.GetWorkOrderDetail(ctx, referenceID)
+	return s.GetAccessibleWorkOrderDetail(ctx, referenceID, WorkOrderAccess{})
+}
+
+// GetAccessibleWorkOrderDetail reports ErrWorkOrderNotFound for work orders
+// outside access, so restricted callers cannot probe for other jobs.
+func (s *Service) GetAccessibleWorkOrderDetail(ctx context.Context, referenceID int, access WorkOrderAccess) (domain.WorkOrderDetail, error) {
+	detail, err := s.repo.GetWorkOrderDetail(ctx, referenceID, access)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return domain.WorkOrderDetail{}, ErrWorkOrderNotFound
 	}
@@ -143,47 +181,81 @@ type CreateRepairLogInput struct {
 	HoursUsed       *float64
 	Details         string
 	CreatedByUserID string
+	Access          WorkOrderAccess
 }
 
 type CreatePartsPurchaseRequestInput struct {
-	Source          string
-	SourceURL       *string
-	Status          *string
-	TotalPrice      float64
-	ItemName        string
-	Quantity        int32
-	CreatedByUserID string
+	Source            string
+	SourceURL         *string
+	Status            *string
+	TotalPrice        float64
+	ItemName          string
+	Quantity          int32
+	PartsItemPresetID *int64
+	SupplierID        *int64
+	CreatedByUserID   string
+	Access            WorkOrderAccess
+}
+
+// CommentInput carries a comment body and the user writing it. Access limits
+// which work orders they may comment on, and MayChangeOthers lets moderators
+// edit or delete comments they did not write.
+type CommentInput struct {
+	Body            string
+	UserID          string
+	Access          WorkOrderAccess
+	MayChangeOthers bool
 }
 
 type UpdateRepairLogInput struct {
 	RepairDate *string
 	HoursUsed  *float64
 	Details    *string
+	Access     WorkOrderAccess
 }
 
 type UpdatePartsPurchaseRequestInput struct {
-	Source     string
-	SourceURL  *string
-	Status     string
-	TotalPrice float64
-	ItemName   string
-	Quantity   int32
+	Source            string
+	SourceURL         *string
+	Status            string
+	TotalPrice        float64
+	ItemName          string
+	Quantity          int32
+	PartsItemPresetID *int64
+	SupplierID        *int64
+	UpdatedByUserID   string
+	Access            WorkOrderAccess
+}
+
+type DecidePartsPurchaseRequestInput struct {
+	Decision       string
+	Comment        *string
+	ApproverUserID string
+}
+
+// PartsApprovalState is what the spending-limit check needs to know about a
+// request before letting it move past waiting_approval.
+type PartsApprovalState struct {
+	Status             string
+	TotalPrice         float64
+	ApprovedTotalPrice *float64
+	Threshold          *float64
 }
 
 type CustomerLookupOption struct {
-	ID           int64   `json:"id"`
-	Label        string  `json:"label"`
-	FirstName    *string `json:"first_name"`
-	LastName     *string `json:"last_name"`
-	Email        *string `json:"email"`
-	HomePhone    *string `json:"home_phone"`
-	WorkPhone    *string `json:"work_phone"`
-	PostalCode   *string `json:"postal_code"`
-	Remark       *string `json:"remark"`
-	AddressLine1 *string `json:"address_line_1"`
-	AddressLine2 *string `json:"address_line_2"`
-	City         *string `json:"city"`
-	Province     *string `json:"province"`
+	ID           int64   `json:"id" field:"public"`
+	Label        string  `json:"label" field:"customer_contact"`
+	FirstName    *string `json:"first_name" field:"public"`
+	LastName     *string `json:"last_name" field:"public"`
+	Email        *string `json:"email" field:"customer_contact"`
+	HomePhone    *string `json:"home_phone" field:"customer_contact"`
+	WorkPhone    *string `json:"work_phone" field:"customer_contact"`
+	PostalCode   *string `json:"postal_code" field:"customer_contact"`
+	Remark       *string `json:"remark" field:"customer_contact"`
+	AddressLine1 *string `json:"address_line_1" field:"customer_contact"`
+	AddressLine2 *string `json:"address_line_2" field:"customer_contact"`
+	City         *string `json:"city" field:"customer_contact"`
+	Province     *string `json:"province" field:"customer_contact"`
 }
 
 type CreateWorkOrderCustomerInput struct {
@@ -218,6 +290,9 @@ type CreateWorkOrderInput struct {
 	AlbumCDCassetteQty     int32
 	Deposit                float64
 	DepositPaymentMethodID *int64
+	Priority               string
+	DueAt                  *time.Time
+	IsRush                 bool
 }
 
 func (s *Service) UpdateEquipment(ctx context.Context, referenceID int, input EquipmentUpdateInput) (domain.WorkOrderDetail, error) {
@@ -250,6 +325,11 @@ func (s *Service) CreateWorkOrder(ctx context.Context, input CreateWorkOrderInpu
 	if input.Deposit > 0 && (input.DepositPaymentMethodID == nil || *input.DepositPaymentMethodID <= 0) {
 		return domain.WorkOrderDetail{}, ErrDepositPaymentMethodRequired
 	}
+	priority, err := normalizePriority(input.Priority)
+	if err != nil {
+		return domain.WorkOrderDetail{}, err
+	}
+	input.Priority = priority
 
 	if input.CreationMode == "stock" {
 		input.CustomerID = nil
@@ -364,6 +444,185 @@ func (s *Service) UpdateStatus(ctx context.Context, referenceID int, input Statu
 	return s.GetWorkOrderDetail(ctx, referenceID)
 }
 
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// ScheduleUpdateInput replaces the priority and due date. IsRush is left
+// alone when nil. Moving or clearing an existing due date needs a Reason,
+// which is kept in the work order's due date history.
+type ScheduleUpdateInput struct {
+	Priority        string
+	DueAt           *time.Time
+	IsRush          *bool
+	Reason          *string
+	ChangedByUserID string
+}
+
+// normalizePriority defaults a blank priority to normal.
+func normalizePriority(raw string) (string, error) {
+	priority := strings.ToLower(strings.TrimSpace(raw))
+	switch priority {
+	case "":
+		return PriorityNormal, nil
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent:
+		return priority, nil
+	default:
+		return "", ErrInvalidPriority
+	}
+}
+
+func (s *Service) UpdateSchedule(ctx context.Context, referenceID int, input ScheduleUpdateInput) (domain.WorkOrderDetail, error) {
+	priority, err := normalizePriority(input.Priority)
+	if err != nil {
+		return domain.WorkOrderDetail{}, err
+	}
+	input.Priority = priority
+	input.Reason = trimStringPtr(input.Reason)
+	if err := s.repo.UpdateSchedule(ctx, referenceID, input); err != nil {
+		return domain.WorkOrderDetail{}, err
+	}
+	return s.GetWorkOrderDetail(ctx, referenceID)
+}
+
+func (s *Service) ListDueDateChanges(ctx context.Context, referenceID int) ([]domain.WorkOrderDueDateChange, error) {
+	return s.repo.ListDueDateChanges(ctx, referenceID)
+}
+
+func (s *Service) GetRushSettings(ctx context.Context) (domain.RushSettings, error) {
+	return s.repo.GetRushSettings(ctx)
+}
+
+// SetRushSurcharge changes the surcharge for rush jobs booked from now on;
+// surcharge lines already on work orders keep their price.
+func (s *Service) SetRushSurcharge(ctx context.Context, surcharge *float64) (domain.RushSettings, error) {
+	if surcharge != nil && *surcharge < 0 {
+		return domain.RushSettings{}, ErrInvalidRushSurcharge
+	}
+	if err := s.repo.SetRushSurcharge(ctx, surcharge); err != nil {
+		return domain.RushSettings{}, err
+	}
+	return s.repo.GetRushSettings(ctx)
+}
+
+const (
+	PromiseKept    = "kept"
+	PromiseBroken  = "broken"
+	PromiseOverdue = "overdue"
+	PromisePending = "pending"
+)
+
+// promiseOutcome compares when a job was finished with the date promised.
+// Open jobs past their date are overdue: broken, but still fixable.
+func promiseOutcome(dueAt time.Time, completedAt *time.Time, now time.Time) string {
+	switch {
+	case completedAt != nil && !completedAt.After(dueAt):
+		return PromiseKept
+	case completedAt != nil:
+		return PromiseBroken
+	case now.After(dueAt):
+		return PromiseOverdue
+	default:
+		return PromisePending
+	}
+}
+
+// buildPromiseReport grades each promise against the date first promised, so
+// missing it and then moving it still counts as broken.
+func buildPromiseReport(items []domain.PromiseReportItem, now time.Time) domain.PromiseReport {
+	report := domain.PromiseReport{
+		Items: make([]domain.PromiseReportItem, 0, len(items)),
+		Moved: make([]domain.PromiseReportItem, 0),
+	}
+	for _, item := range items {
+		item.Outcome = promiseOutcome(item.OriginalDueAt, item.CompletedAt, now)
+		switch item.Outcome {
+		case PromiseKept:
+			report.Kept++
+		case PromiseBroken:
+			report.Broken++
+		case PromiseOverdue:
+			report.Overdue++
+		default:
+			report.Pending++
+		}
+		if len(item.DueDateChanges) > 0 {
+			report.Moved = append(report.Moved, item)
+		} else {
+			report.Items = append(report.Items, item)
+		}
+	}
+	if decided := report.Kept + report.Broken + report.Overdue; decided > 0 {
+		rate := float64(report.Kept) / float64(decided)
+		report.KeptRate = &rate
+	}
+	return report
+}
+
+// GetPromiseReport covers work orders first promised within the period, both
+// days inclusive.
+func (s *Service) GetPromiseReport(ctx context.Context, from, to time.Time) (domain.PromiseReport, error) {
+	if to.Before(from) {
+		return domain.PromiseReport{}, ErrInvalidReportPeriod
+	}
+	items, err := s.repo.ListPromises(ctx, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return domain.PromiseReport{}, err
+	}
+	report := buildPromiseReport(items, time.Now())
+	report.From = from.Format("2006-01-02")
+	report.To = to.Format("2006-01-02")
+	return report, nil
+}
+
+// SetSelfAssignment adds the worker linked to userID to the work order, or
+// removes them when assigned is false. Restricted callers can only pick up
+// work orders they can already read, such as their team's.
+func (s *Service) SetSelfAssignment(ctx context.Context, referenceID int, userID string, assigned bool, access WorkOrderAccess) (domain.WorkOrderDetail, error) {
+	workerID, err := s.repo.GetUserWorkerID(ctx, userID)
+	if err != nil {
+		return domain.WorkOrderDetail{}, err
+	}
+	if workerID == nil {
+		return domain.WorkOrderDetail{}, ErrWorkerNotLinked
+	}
+	if err := s.repo.SetWorkerAssignment(ctx, referenceID, *workerID, assigned, access); err != nil {
+		return domain.WorkOrderDetail{}, err
+	}
+	return s.GetWorkOrderDetail(ctx, referenceID)
+}
+
+// GetQueue returns the open work orders assigned to workerID, or to the
+// worker linked to userID when workerID is nil.
+func (s *Service) GetQueue(ctx context.Context, userID string, workerID *int64, access WorkOrderAccess) (int64, []domain.WorkOrderQueueItem, error) {
+	if workerID == nil {
+		linked, err := s.repo.GetUserWorkerID(ctx, userID)
+		if err != nil {
+			return 0, nil, err
+		}
+		if linked == nil {
+			return 0, nil, ErrWorkerNotLinked
+		}
+		workerID = linked
+	}
+	items, err := s.repo.ListQueue(ctx, *workerID, access)
+	if err != nil {
+		return 0, nil, err
+	}
+	return *workerID, items, nil
+}
+
+// UpdateTeam hands the work order to a team, or releases it when teamID is nil.
+func (s *Service) UpdateTeam(ctx context.Context, referenceID int, teamID *int64) (domain.WorkOrderDetail, error) {
+	if err := s.repo.UpdateTeam(ctx, referenceID, teamID); err != nil {
+		return domain.WorkOrderDetail{}, err
+	}
+	return s.GetWorkOrderDetail(ctx, referenceID)
+}
+
 func (s *Service) UpdateWorkNotes(ctx context.Context, referenceID int, input WorkNotesUpdateInput) (domain.WorkOrderDetail, error) {
 	// Keep payment methods ordered as [deposit, final], capped at 2 entries.
 	normalizedPaymentMethodIDs := make([]int32, 0, 2)
@@ -427,12 +686,18 @@ func (s *Service) UpdateCustomer(ctx context.Context, referenceID int, input Cus
 	return s.GetWorkOrderDetail(ctx, referenceID)
 }
 
-func (s *Service) ListRepairLogs(ctx context.Context, referenceID int) ([]domain.RepairLog, error) {
-	return s.repo.ListRepairLogs(ctx, referenceID)
+// CheckWorkOrderAccess reports a work order that does not exist or lies
+// outside access as ErrWorkOrderNotFound.
+func (s *Service) CheckWorkOrderAccess(ctx context.Context, referenceID int, access WorkOrderAccess) error {
+	return s.repo.CheckWorkOrderAccess(ctx, referenceID, access)
 }
 
-func (s *Service) ListAllPartsPurchaseRequests(ctx context.Context) ([]domain.PartsPurchaseRequest, error) {
-	return s.repo.ListAllPartsPurchaseRequests(ctx)
+func (s *Service) ListRepairLogs(ctx context.Context, referenceID int, access WorkOrderAccess) ([]domain.RepairLog, error) {
+	return s.repo.ListRepairLogs(ctx, referenceID, access)
+}
+
+func (s *Service) ListAllPartsPurchaseRequests(ctx context.Context, access WorkOrderAccess) ([]domain.PartsPurchaseRequest, error) {
+	return s.repo.ListAllPartsPurchaseRequests(ctx, access)
 }
 
 func (s *Service) CreateRepairLog(ctx context.Context, referenceID int, input CreateRepairLogInput) (domain.RepairLog, error) {
@@ -443,11 +708,11 @@ func (s *Service) CreateRepairLog(ctx context.Context, referenceID int, input Cr
 	if input.HoursUsed != nil && *input.HoursUsed < 0 {
 		return domain.RepairLog{}, ErrInvalidRepairLogHoursUsed
 	}
-	return s.repo.CreateRepairLog(ctx, referenceID, input.RepairDate, input.HoursUsed, details, input.CreatedByUserID)
+	return s.repo.CreateRepairLog(ctx, referenceID, input.RepairDate, input.HoursUsed, details, input.CreatedByUserID, input.Access)
 }
 
-func (s *Service) ListPartsPurchaseRequests(ctx context.Context, referenceID int) ([]domain.PartsPurchaseRequest, error) {
-	return s.repo.ListPartsPurchaseRequests(ctx, referenceID)
+func (s *Service) ListPartsPurchaseRequests(ctx context.Context, referenceID int, access WorkOrderAccess) ([]domain.PartsPurchaseRequest, error) {
+	return s.repo.ListPartsPurchaseRequests(ctx, referenceID, access)
 }
 
 func (s *Service) CreatePartsPurchaseRequest(ctx context.Context, referenceID int, input CreatePartsPurchaseRequestInput) (domain.PartsPurchaseRequest, error) {
@@ -463,7 +728,7 @@ func (s *Service) CreatePartsPurchaseRequest(ctx context.Context, referenceID in
 			status = normalized
 		}
 	}
-	if status != "draft" && status != "waiting_approval" && status != "ordered" && status != "used" {
+	if !isValidPartsStatus(status) {
 		return domain.PartsPurchaseRequest{}, ErrInvalidPartsStatus
 	}
 
@@ -477,15 +742,27 @@ func (s *Service) CreatePartsPurchaseRequest(ctx context.Context, referenceID in
 	if input.TotalPrice < 0 {
 		return domain.PartsPurchaseRequest{}, ErrInvalidPartsTotalPrice
 	}
+	if isPartsStatusPastApproval(status) {
+		threshold, err := s.repo.GetPartsApprovalThreshold(ctx)
+		if err != nil {
+			return domain.PartsPurchaseRequest{}, err
+		}
+		if partsApprovalRequired(threshold, input.TotalPrice, nil) {
+			return domain.PartsPurchaseRequest{}, ErrPartsApprovalRequired
+		}
+	}
 
 	return s.repo.CreatePartsPurchaseRequest(ctx, referenceID, CreatePartsPurchaseRequestInput{
-		Source:          source,
-		SourceURL:       input.SourceURL,
-		Status:          &status,
-		TotalPrice:      input.TotalPrice,
-		ItemName:        itemName,
-		Quantity:        input.Quantity,
-		CreatedByUserID: input.CreatedByUserID,
+		Source:            source,
+		SourceURL:         input.SourceURL,
+		Status:            &status,
+		TotalPrice:        input.TotalPrice,
+		ItemName:          itemName,
+		Quantity:          input.Quantity,
+		PartsItemPresetID: input.PartsItemPresetID,
+		SupplierID:        input.SupplierID,
+		CreatedByUserID:   input.CreatedByUserID,
+		Access:            input.Access,
 	})
 }
 
@@ -501,11 +778,91 @@ func (s *Service) UpdateRepairLog(ctx context.Context, referenceID int, repairLo
 	if input.HoursUsed != nil && *input.HoursUsed < 0 {
 		return domain.RepairLog{}, ErrInvalidRepairLogHoursUsed
 	}
-	return s.repo.UpdateRepairLog(ctx, referenceID, repairLogID, input.RepairDate, input.HoursUsed, details)
+	return s.repo.UpdateRepairLog(ctx, referenceID, repairLogID, input.RepairDate, input.HoursUsed, details, input.Access)
+}
+
+func (s *Service) DeleteRepairLog(ctx context.Context, referenceID int, repairLogID int64, access WorkOrderAccess) error {
+	return s.repo.DeleteRepairLog(ctx, referenceID, repairLogID, access)
+}
+
+func (s *Service) ListComments(ctx context.Context, referenceID int, access WorkOrderAccess) ([]domain.WorkOrderComment, error) {
+	return s.repo.ListComments(ctx, referenceID, access)
+}
+
+func (s *Service) GetComment(ctx context.Context, referenceID int, commentID int64, access WorkOrderAccess) (domain.WorkOrderComment, error) {
+	return s.repo.GetComment(ctx, referenceID, commentID, access)
+}
+
+func (s *Service) CreateComment(ctx context.Context, referenceID int, input CommentInput) (domain.WorkOrderComment, error) {
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return domain.WorkOrderComment{}, ErrInvalidCommentBody
+	}
+	return s.repo.CreateComment(ctx, referenceID, body, input.UserID, parseMentionEmails(body), input.Access)
+}
+
+func (s *Service) UpdateComment(ctx context.Context, referenceID int, commentID int64, input CommentInput) (domain.WorkOrderComment, error) {
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return domain.WorkOrderComment{}, ErrInvalidCommentBody
+	}
+	if err := s.ensureCommentChangeAllowed(ctx, referenceID, commentID, input); err != nil {
+		return domain.WorkOrderComment{}, err
+	}
+	return s.repo.UpdateComment(ctx, referenceID, commentID, body, input.UserID, parseMentionEmails(body))
+}
+
+func (s *Service) DeleteComment(ctx context.Context, referenceID int, commentID int64, input CommentInput) error {
+	if err := s.ensureCommentChangeAllowed(ctx, referenceID, commentID, input); err != nil {
+		return err
+	}
+	return s.repo.DeleteComment(ctx, referenceID, commentID)
+}
+
+func (s *Service) ensureCommentChangeAllowed(ctx context.Context, referenceID int, commentID int64, input CommentInput) error {
+	existing, err := s.repo.GetComment(ctx, referenceID, commentID, input.Access)
+	if err != nil {
+		return err
+	}
+	if input.MayChangeOthers {
+		return nil
+	}
+	if existing.CreatedByUserID == nil || *existing.CreatedByUserID != input.UserID {
+		return ErrCommentNotAuthor
+	}
+	return nil
+}
+
+func (s *Service) ListCommentRevisions(ctx context.Context, referenceID int, commentID int64, access WorkOrderAccess) ([]domain.WorkOrderCommentRevision, error) {
+	if _, err := s.repo.GetComment(ctx, referenceID, commentID, access); err != nil {
+		return nil, err
+	}
+	return s.repo.ListCommentRevisions(ctx, commentID)
+}
+
+func (s *Service) ListMentionableUsers(ctx context.Context, referenceID int, query string, access WorkOrderAccess) ([]domain.CommentUser, error) {
+	return s.repo.ListMentionableUsers(ctx, referenceID, strings.TrimSpace(query), access)
 }
 
-func (s *Service) DeleteRepairLog(ctx context.Context, referenceID int, repairLogID int64) error {
-	return s.repo.DeleteRepairLog(ctx, referenceID, repairLogID)
+// mentionPattern matches an @ followed by an email address, as in
+// "@jane@example.com". The leading @ must not follow a word character, so a
+// plain email address in the text is not taken for a mention.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+// parseMentionEmails returns the lowercased, de-duplicated emails mentioned
+// in body, in the order they first appear.
+func parseMentionEmails(body string) []string {
+	emails := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		email := strings.ToLower(match[1])
+		if _, ok := seen[email]; ok {
+			continue
+		}
+		seen[email] = struct{}{}
+		emails = append(emails, email)
+	}
+	return emails
 }
 
 func (s *Service) UpdatePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64, input UpdatePartsPurchaseRequestInput) (domain.PartsPurchaseRequest, error) {
@@ -514,7 +871,7 @@ func (s *Service) UpdatePartsPurchaseRequest(ctx context.Context, referenceID in
 		return domain.PartsPurchaseRequest{}, ErrInvalidPartsSource
 	}
 	status := strings.TrimSpace(strings.ToLower(input.Status))
-	if status != "draft" && status != "waiting_approval" && status != "ordered" && status != "used" {
+	if !isValidPartsStatus(status) {
 		return domain.PartsPurchaseRequest{}, ErrInvalidPartsStatus
 	}
 	itemName := strings.TrimSpace(input.ItemName)
@@ -528,15 +885,102 @@ func (s *Service) UpdatePartsPurchaseRequest(ctx context.Context, referenceID in
 		return domain.PartsPurchaseRequest{}, ErrInvalidPartsTotalPrice
 	}
 	return s.repo.UpdatePartsPurchaseRequest(ctx, referenceID, partsPurchaseRequestID, UpdatePartsPurchaseRequestInput{
-		Source:     source,
-		SourceURL:  input.SourceURL,
-		Status:     status,
-		TotalPrice: input.TotalPrice,
-		ItemName:   itemName,
-		Quantity:   input.Quantity,
+		Source:            source,
+		SourceURL:         input.SourceURL,
+		Status:            status,
+		TotalPrice:        input.TotalPrice,
+		ItemName:          itemName,
+		Quantity:          input.Quantity,
+		PartsItemPresetID: input.PartsItemPresetID,
+		SupplierID:        input.SupplierID,
+		UpdatedByUserID:   input.UpdatedByUserID,
+		Access:            input.Access,
 	})
 }
 
-func (s *Service) DeletePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64) error {
-	return s.repo.DeletePartsPurchaseRequest(ctx, referenceID, partsPurchaseRequestID)
+func isValidPartsStatus(status string) bool {
+	switch status {
+	case "draft", "waiting_approval", "ordered", "received", "used":
+		return true
+	}
+	return false
+}
+
+func isPartsStatusPastApproval(status string) bool {
+	return status == "ordered" || status == "received" || status == "used"
+}
+
+// partsUpdateNeedsApproval reports whether an edit leaves a request past
+// waiting_approval with a total its approval does not cover. Requests ordered
+// before the limit applied keep their status as long as the total is not
+// raised; raising it needs an approval whatever the status.
+func partsUpdateNeedsApproval(state PartsApprovalState, nextStatus string, nextTotal float64) bool {
+	if !isPartsStatusPastApproval(nextStatus) || !partsApprovalRequired(state.Threshold, nextTotal, state.ApprovedTotalPrice) {
+		return false
+	}
+	return !isPartsStatusPastApproval(state.Status) || nextTotal > state.TotalPrice
+}
+
+// partsApprovalRequired reports whether a request of totalPrice needs an
+// approval it does not already have. A nil threshold disables the check, and
+// an earlier approval only covers totals up to the amount that was approved.
+func partsApprovalRequired(threshold *float64, totalPrice float64, approvedTotalPrice *float64) bool {
+	if threshold == nil || totalPrice <= *threshold {
+		return false
+	}
+	return approvedTotalPrice == nil || totalPrice > *approvedTotalPrice
+}
+
+func (s *Service) DeletePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64, access WorkOrderAccess) error {
+	return s.repo.DeletePartsPurchaseRequest(ctx, referenceID, partsPurchaseRequestID, access)
+}
+
+func (s *Service) ListPartsPurchaseRequestApprovals(ctx context.Context, referenceID int, partsPurchaseRequestID int64) ([]domain.PartsPurchaseRequestApproval, error) {
+	return s.repo.ListPartsPurchaseRequestApprovals(ctx, referenceID, partsPurchaseRequestID)
+}
+
+func (s *Service) DecidePartsPurchaseRequest(ctx context.Context, referenceID int, partsPurchaseRequestID int64, input DecidePartsPurchaseRequestInput) (domain.PartsPurchaseRequestApproval, error) {
+	decision := strings.TrimSpace(strings.ToLower(input.Decision))
+	if decision != "approved" && decision != "rejected" {
+		return domain.PartsPurchaseRequestApproval{}, ErrInvalidApprovalDecision
+	}
+	var comment *string
+	if input.Comment != nil {
+		if trimmed := strings.TrimSpace(*input.Comment); trimmed != "" {
+			comment = &trimmed
+		}
+	}
+	if decision == "rejected" && comment == nil {
+		return domain.PartsPurchaseRequestApproval{}, ErrApprovalCommentRequired
+	}
+	return s.repo.DecidePartsPurchaseRequest(ctx, referenceID, partsPurchaseRequestID, DecidePartsPurchaseRequestInput{
+		Decision:       decision,
+		Comment:        comment,
+		ApproverUserID: input.ApproverUserID,
+	})
+}
+
+func (s *Service) GetPartsApprovalSettings(ctx context.Context) (domain.PartsApprovalSettings, error) {
+	return s.repo.GetPartsApprovalSettings(ctx)
+}
+
+func (s *Service) SetPartsApprovalThreshold(ctx context.Context, threshold *float64) (domain.PartsApprovalSettings, error) {
+	if threshold != nil && *threshold < 0 {
+		return domain.PartsApprovalSettings{}, ErrInvalidApprovalAmount
+	}
+	if err := s.repo.SetPartsApprovalThreshold(ctx, threshold); err != nil {
+		return domain.PartsApprovalSettings{}, err
+	}
+	return s.repo.GetPartsApprovalSettings(ctx)
+}
+
+func (s *Service) SetPartsApprovalLimit(ctx context.Context, userID string, maxTotalPrice float64) (domain.PartsApprovalLimit, error) {
+	if maxTotalPrice < 0 {
+		return domain.PartsApprovalLimit{}, ErrInvalidApprovalAmount
+	}
+	return s.repo.SetPartsApprovalLimit(ctx, userID, maxTotalPrice)
+}
+
+func (s *Service) DeletePartsApprovalLimit(ctx context.Context, userID string) error {
+	return s.repo.DeletePartsApprovalLimit(ctx, userID)
 }
@@ -0,0 +1,163 @@
+package workorders
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"humphreys/api/internal/domain"
+)
+
+func TestPartsApprovalRequired(t *testing.T) {
+	limit := 100.0
+	approved := 150.0
+	cases := []struct {
+		name      string
+		threshold *float64
+		total     float64
+		approved  *float64
+		expected  bool
+	}{
+		{name: "no threshold configured", threshold: nil, total: 500, expected: false},
+		{name: "at the threshold", threshold: &limit, total: 100, expected: false},
+		{name: "above the threshold without approval", threshold: &limit, total: 120, expected: true},
+		{name: "covered by earlier approval", threshold: &limit, total: 150, approved: &approved, expected: false},
+		{name: "total raised past approved amount", threshold: &limit, total: 180, approved: &approved, expected: true},
+	}
+
+	for _, tc := range cases {
+		if got := partsApprovalRequired(tc.threshold, tc.total, tc.approved); got != tc.expected {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestPartsUpdateNeedsApproval(t *testing.T) {
+	limit := 100.0
+	approved := 150.0
+	cases := []struct {
+		name     string
+		state    PartsApprovalState
+		status   string
+		total    float64
+		expected bool
+	}{
+		{name: "draft edit", state: PartsApprovalState{Status: "draft", TotalPrice: 120, Threshold: &limit}, status: "draft", total: 200, expected: false},
+		{name: "ordering without approval", state: PartsApprovalState{Status: "draft", TotalPrice: 120, Threshold: &limit}, status: "ordered", total: 120, expected: true},
+		{name: "ordered within approval", state: PartsApprovalState{Status: "ordered", TotalPrice: 150, ApprovedTotalPrice: &approved, Threshold: &limit}, status: "received", total: 150, expected: false},
+		{name: "ordered raised past approval", state: PartsApprovalState{Status: "ordered", TotalPrice: 150, ApprovedTotalPrice: &approved, Threshold: &limit}, status: "ordered", total: 180, expected: true},
+		{name: "ordered before the limit, unchanged", state: PartsApprovalState{Status: "ordered", TotalPrice: 300, Threshold: &limit}, status: "received", total: 300, expected: false},
+		{name: "ordered before the limit, raised", state: PartsApprovalState{Status: "received", TotalPrice: 300, Threshold: &limit}, status: "used", total: 320, expected: true},
+	}
+	for _, tc := range cases {
+		if got := partsUpdateNeedsApproval(tc.state, tc.status, tc.total); got != tc.expected {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestWorkOrderResponseFieldsAreClassified(t *testing.T) {
+	for _, sample := range []any{domain.WorkOrderDetail{}, domain.WorkOrderListItem{}, domain.WorkOrderQueueItem{}, CustomerLookupOption{}} {
+		if missing := workOrderFieldPolicy.Unclassified(sample); len(missing) > 0 {
+			t.Fatalf("%T has unclassified fields that every caller will see hidden: %v", sample, missing)
+		}
+	}
+}
+
+func TestNormalizePriority(t *testing.T) {
+	cases := map[string]string{"": PriorityNormal, " High ": PriorityHigh, "urgent": PriorityUrgent, "low": PriorityLow}
+	for raw, expected := range cases {
+		got, err := normalizePriority(raw)
+		if err != nil || got != expected {
+			t.Fatalf("%q: expected %q, got %q (%v)", raw, expected, got, err)
+		}
+	}
+	if _, err := normalizePriority("asap"); err != ErrInvalidPriority {
+		t.Fatalf("expected ErrInvalidPriority, got %v", err)
+	}
+}
+
+func TestBuildPromiseReport(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2026, 3, 5, 17, 0, 0, 0, time.UTC)
+	early := due.Add(-time.Hour)
+	late := due.Add(time.Hour)
+	report := buildPromiseReport([]domain.PromiseReportItem{
+		{ReferenceID: 1, OriginalDueAt: due, CompletedAt: &early},
+		{ReferenceID: 2, OriginalDueAt: due, CompletedAt: &due},
+		{ReferenceID: 3, OriginalDueAt: due, CompletedAt: &late},
+		{ReferenceID: 4, OriginalDueAt: due},
+		{ReferenceID: 5, OriginalDueAt: now.Add(24 * time.Hour)},
+	}, now)
+
+	outcomes := []string{PromiseKept, PromiseKept, PromiseBroken, PromiseOverdue, PromisePending}
+	for i, expected := range outcomes {
+		if report.Items[i].Outcome != expected {
+			t.Fatalf("item %d: expected %q, got %q", report.Items[i].ReferenceID, expected, report.Items[i].Outcome)
+		}
+	}
+	if report.Kept != 2 || report.Broken != 1 || report.Overdue != 1 || report.Pending != 1 {
+		t.Fatalf("unexpected counts: %+v", report)
+	}
+	if report.KeptRate == nil || *report.KeptRate != 0.5 {
+		t.Fatalf("expected kept rate 0.5, got %v", report.KeptRate)
+	}
+}
+
+func TestBuildPromiseReportGradesMovedPromisesAgainstTheOriginalDate(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	promised := time.Date(2026, 3, 5, 17, 0, 0, 0, time.UTC)
+	moved := time.Date(2026, 3, 9, 17, 0, 0, 0, time.UTC)
+	completed := time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)
+	reason := "waiting on a part"
+	report := buildPromiseReport([]domain.PromiseReportItem{
+		{ReferenceID: 1, OriginalDueAt: promised, CompletedAt: &completed},
+		{
+			ReferenceID:   2,
+			OriginalDueAt: promised,
+			DueAt:         &moved,
+			CompletedAt:   &completed,
+			DueDateChanges: []domain.WorkOrderDueDateChange{
+				{ReferenceID: 2, PreviousDueAt: &promised, NewDueAt: &moved, Reason: &reason},
+			},
+		},
+	}, now)
+
+	if report.Broken != 2 || report.Kept != 0 {
+		t.Fatalf("expected both promises broken, got %+v", report)
+	}
+	if len(report.Items) != 1 || report.Items[0].ReferenceID != 1 {
+		t.Fatalf("expected only the unmoved promise in items, got %+v", report.Items)
+	}
+	if len(report.Moved) != 1 || report.Moved[0].ReferenceID != 2 || report.Moved[0].Outcome != PromiseBroken {
+		t.Fatalf("expected the rescheduled promise under moved as broken, got %+v", report.Moved)
+	}
+	if got := report.Moved[0].DueDateChanges[0].Reason; got == nil || *got != reason {
+		t.Fatalf("expected the change reason to be reported, got %v", got)
+	}
+}
+
+func TestEmailJobDetailsChargesRushSurcharge(t *testing.T) {
+	parts := 100.0
+	surcharge := 20.0
+	details := emailJobDetails(domain.WorkOrderDetail{ReferenceID: 7, PartsTotal: &parts, RushSurchargeTotal: &surcharge})
+	if !strings.Contains(details, "Estimated total before deposit: $135.60 CAD") {
+		t.Fatalf("expected the surcharge in the estimated total, got %q", details)
+	}
+}
+
+func TestParseMentionEmails(t *testing.T) {
+	got := parseMentionEmails("@Jane@Example.com customer called, cc @bob@shop.ca. Quote sent to bill@customer.com; @jane@example.com again")
+	want := []string{"jane@example.com", "bob@shop.ca"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if got := parseMentionEmails("no mentions here"); got == nil || len(got) != 0 {
+		t.Fatalf("expected an empty, non-nil slice, got %#v", got)
+	}
+}
@@ -0,0 +1,96 @@
+// Package permissions is the code-side catalogue of RBAC resources and
+// actions. Each module registers what it owns and bootstrap.SyncPermissions
+// upserts the result into the resources and permissions tables at startup.
+package permissions
+
+import (
+	"sort"
+	"strings"
+)
+
+// StandardActions returns the create/read/update/delete/assign set every
+// resource seeded by the migrations got, followed by any extra actions.
+func StandardActions(extra ...string) []string {
+	return append([]string{"create", "read", "update", "delete", "assign"}, extra...)
+}
+
+type Resource struct {
+	Name        string
+	Description string
+	Actions     []string
+}
+
+type Registry struct {
+	resources []Resource
+	index     map[string]int
+}
+
+func NewRegistry() *Registry {
+	return &Registry{index: make(map[string]int)}
+}
+
+// Register adds a resource and its actions. Registering the same resource
+// again merges in any new actions.
+func (r *Registry) Register(name, description string, actions ...string) {
+	i, ok := r.index[name]
+	if !ok {
+		r.index[name] = len(r.resources)
+		r.resources = append(r.resources, Resource{Name: name, Description: description})
+		i = len(r.resources) - 1
+	}
+	resource := &r.resources[i]
+	if resource.Description == "" {
+		resource.Description = description
+	}
+	for _, action := range actions {
+		if !containsString(resource.Actions, action) {
+			resource.Actions = append(resource.Actions, action)
+		}
+	}
+}
+
+func (r *Registry) Resources() []Resource {
+	out := make([]Resource, len(r.resources))
+	copy(out, r.resources)
+	return out
+}
+
+func Code(resource, action string) string {
+	return resource + ":" + action
+}
+
+// Has reports whether code is a registered permission.
+func (r *Registry) Has(code string) bool {
+	name, action, ok := strings.Cut(code, ":")
+	if !ok {
+		return false
+	}
+	i, ok := r.index[name]
+	return ok && containsString(r.resources[i].Actions, action)
+}
+
+// Missing returns the codes that are not registered, sorted and deduplicated.
+func (r *Registry) Missing(codes ...string) []string {
+	seen := make(map[string]struct{}, len(codes))
+	missing := make([]string, 0)
+	for _, code := range codes {
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		if !r.Has(code) {
+			missing = append(missing, code)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
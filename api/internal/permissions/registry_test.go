@@ -0,0 +1,32 @@
+package permissions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("work_orders", "Work order management", StandardActions()...)
+	reg.Register("work_orders", "", "read:assigned", "read")
+
+	resources := reg.Resources()
+	if len(resources) != 1 {
+		t.Fatalf("expected one merged resource, got %d", len(resources))
+	}
+	if resources[0].Description != "Work order management" || len(resources[0].Actions) != 6 {
+		t.Fatalf("unexpected merged resource: %+v", resources[0])
+	}
+	if !reg.Has("work_orders:read:assigned") || !reg.Has(Code("work_orders", "assign")) {
+		t.Fatalf("expected registered codes to be found")
+	}
+	if reg.Has("work_orders") || reg.Has("repair_logs:read") {
+		t.Fatalf("expected unregistered codes to be rejected")
+	}
+
+	got := reg.Missing("repair_logs:read", "work_orders:read", "repair_logs:read", "api_keys:read")
+	want := []string{"api_keys:read", "repair_logs:read"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,122 @@
+CREATE TABLE IF NOT EXISTS public.suppliers (
+  supplier_id BIGSERIAL PRIMARY KEY,
+  supplier_name TEXT NOT NULL CHECK (BTRIM(supplier_name) <> ''),
+  contact_name TEXT,
+  email TEXT,
+  phone TEXT,
+  website TEXT,
+  account_number TEXT,
+  notes TEXT,
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT uq_suppliers_name UNIQUE (supplier_name)
+);
+
+CREATE TABLE IF NOT EXISTS public.purchase_orders (
+  purchase_order_id BIGSERIAL PRIMARY KEY,
+  order_number TEXT NOT NULL CHECK (BTRIM(order_number) <> ''),
+  supplier_id BIGINT NOT NULL
+    REFERENCES public.suppliers(supplier_id)
+    ON DELETE RESTRICT,
+  status TEXT NOT NULL DEFAULT 'draft'
+    CHECK (status IN ('draft', 'ordered', 'partially_received', 'received', 'cancelled')),
+  shipping_cost NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (shipping_cost >= 0),
+  tracking_number TEXT,
+  tracking_url TEXT,
+  notes TEXT,
+  ordered_at TIMESTAMPTZ,
+  received_at TIMESTAMPTZ,
+  created_by_user_id UUID NOT NULL
+    REFERENCES public.users(id)
+    ON DELETE RESTRICT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT uq_purchase_orders_order_number UNIQUE (order_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_supplier_id
+  ON public.purchase_orders(supplier_id);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_status
+  ON public.purchase_orders(status);
+
+ALTER TABLE public.parts_purchase_requests
+  ADD COLUMN IF NOT EXISTS supplier_id BIGINT
+    REFERENCES public.suppliers(supplier_id)
+    ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS purchase_order_id BIGINT
+    REFERENCES public.purchase_orders(purchase_order_id)
+    ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS received_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_parts_purchase_requests_purchase_order_id
+  ON public.parts_purchase_requests(purchase_order_id);
+
+ALTER TABLE public.parts_purchase_requests
+  DROP CONSTRAINT IF EXISTS parts_purchase_requests_status_check;
+
+ALTER TABLE public.parts_purchase_requests
+  DROP CONSTRAINT IF EXISTS chk_parts_purchase_requests_status;
+
+ALTER TABLE public.parts_purchase_requests
+  ADD CONSTRAINT chk_parts_purchase_requests_status
+  CHECK (status IN ('draft', 'waiting_approval', 'ordered', 'received', 'used'));
+
+CREATE TABLE IF NOT EXISTS public.notifications (
+  notification_id BIGSERIAL PRIMARY KEY,
+  recipient_user_id UUID
+    REFERENCES public.users(id)
+    ON DELETE CASCADE,
+  recipient_worker_id BIGINT,
+  notification_type TEXT NOT NULL,
+  reference_id INTEGER,
+  title TEXT NOT NULL,
+  body TEXT,
+  read_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT chk_notifications_recipient CHECK (recipient_user_id IS NOT NULL OR recipient_worker_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient_user_id
+  ON public.notifications(recipient_user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_recipient_worker_id
+  ON public.notifications(recipient_worker_id, created_at DESC);
+
+INSERT INTO resources (name, description)
+VALUES
+  ('suppliers', 'Supplier directory for parts purchasing'),
+  ('purchase_orders', 'Purchase orders grouping parts purchase requests')
+ON CONFLICT (name) DO NOTHING;
+
+WITH target_resources AS (
+  SELECT id, name
+  FROM resources
+  WHERE name IN ('suppliers', 'purchase_orders')
+), actions AS (
+  SELECT unnest(ARRAY['create','read','update','delete','assign']) AS action
+)
+INSERT INTO permissions (resource_id, action, code)
+SELECT tr.id, a.action, tr.name || ':' || a.action
+FROM target_resources tr
+CROSS JOIN actions a
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON TRUE
+WHERE r.name = 'owner'
+  AND (
+    p.code LIKE 'suppliers:%'
+    OR p.code LIKE 'purchase_orders:%'
+  )
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.code = 'suppliers:read'
+WHERE r.name = 'staff'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,79 @@
+ALTER TABLE public.parts_item_presets
+  ADD COLUMN IF NOT EXISTS sku TEXT,
+  ADD COLUMN IF NOT EXISTS track_inventory BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS on_hand_quantity INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS reorder_point INTEGER NOT NULL DEFAULT 0 CHECK (reorder_point >= 0),
+  ADD COLUMN IF NOT EXISTS reorder_quantity INTEGER NOT NULL DEFAULT 0 CHECK (reorder_quantity >= 0),
+  ADD COLUMN IF NOT EXISTS unit_cost NUMERIC(12,2) CHECK (unit_cost >= 0),
+  ADD COLUMN IF NOT EXISTS location_id BIGINT
+    REFERENCES public.locations(location_id)
+    ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS preferred_supplier_id BIGINT
+    REFERENCES public.suppliers(supplier_id)
+    ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_parts_item_presets_sku_lower
+  ON public.parts_item_presets (LOWER(BTRIM(sku)))
+  WHERE sku IS NOT NULL;
+
+ALTER TABLE public.parts_purchase_requests
+  ADD COLUMN IF NOT EXISTS parts_item_preset_id BIGINT
+    REFERENCES public.parts_item_presets(parts_item_preset_id)
+    ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_parts_purchase_requests_parts_item_preset_id
+  ON public.parts_purchase_requests(parts_item_preset_id);
+
+CREATE TABLE IF NOT EXISTS public.stock_movements (
+  stock_movement_id BIGSERIAL PRIMARY KEY,
+  parts_item_preset_id BIGINT NOT NULL
+    REFERENCES public.parts_item_presets(parts_item_preset_id)
+    ON DELETE CASCADE,
+  parts_purchase_request_id BIGINT
+    REFERENCES public.parts_purchase_requests(parts_purchase_request_id)
+    ON DELETE SET NULL,
+  movement_type TEXT NOT NULL CHECK (movement_type IN ('received', 'used', 'adjustment')),
+  quantity_delta INTEGER NOT NULL CHECK (quantity_delta <> 0),
+  unit_cost NUMERIC(12,2),
+  note TEXT,
+  created_by_user_id UUID
+    REFERENCES public.users(id)
+    ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT uq_stock_movements_request_type UNIQUE (parts_purchase_request_id, movement_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_preset_created
+  ON public.stock_movements(parts_item_preset_id, created_at DESC);
+
+INSERT INTO resources (name, description)
+VALUES ('inventory', 'Parts inventory stock levels and movements')
+ON CONFLICT (name) DO NOTHING;
+
+WITH target_resource AS (
+  SELECT id, name
+  FROM resources
+  WHERE name = 'inventory'
+), actions AS (
+  SELECT unnest(ARRAY['create','read','update','delete','assign']) AS action
+)
+INSERT INTO permissions (resource_id, action, code)
+SELECT tr.id, a.action, tr.name || ':' || a.action
+FROM target_resource tr
+CROSS JOIN actions a
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON TRUE
+WHERE r.name = 'owner'
+  AND p.code LIKE 'inventory:%'
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.code = 'inventory:read'
+WHERE r.name = 'staff'
+ON CONFLICT DO NOTHING;
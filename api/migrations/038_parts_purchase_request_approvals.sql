@@ -0,0 +1,49 @@
+ALTER TABLE public.parts_purchase_requests
+  ADD COLUMN IF NOT EXISTS approved_by_user_id UUID
+    REFERENCES public.users(id)
+    ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS approved_at TIMESTAMPTZ,
+  ADD COLUMN IF NOT EXISTS approved_total_price NUMERIC(12,2);
+
+CREATE TABLE IF NOT EXISTS public.parts_purchase_request_approvals (
+  approval_id BIGSERIAL PRIMARY KEY,
+  parts_purchase_request_id BIGINT NOT NULL
+    REFERENCES public.parts_purchase_requests(parts_purchase_request_id)
+    ON DELETE CASCADE,
+  approver_user_id UUID
+    REFERENCES public.users(id)
+    ON DELETE SET NULL,
+  decision TEXT NOT NULL CHECK (decision IN ('approved', 'rejected')),
+  total_price NUMERIC(12,2) NOT NULL,
+  comment TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_parts_purchase_request_approvals_request_created
+  ON public.parts_purchase_request_approvals(parts_purchase_request_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS public.parts_approval_limits (
+  user_id UUID PRIMARY KEY
+    REFERENCES public.users(id)
+    ON DELETE CASCADE,
+  max_total_price NUMERIC(12,2) NOT NULL CHECK (max_total_price >= 0),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+WITH target_resource AS (
+  SELECT id, name
+  FROM resources
+  WHERE name = 'parts_purchase_requests'
+)
+INSERT INTO permissions (resource_id, action, code)
+SELECT tr.id, 'approve', tr.name || ':approve'
+FROM target_resource tr
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.code = 'parts_purchase_requests:approve'
+WHERE r.name = 'owner'
+ON CONFLICT DO NOTHING;
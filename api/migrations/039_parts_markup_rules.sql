@@ -0,0 +1,57 @@
+CREATE TABLE IF NOT EXISTS public.parts_markup_rules (
+  markup_rule_id BIGSERIAL PRIMARY KEY,
+  rule_name TEXT NOT NULL CHECK (BTRIM(rule_name) <> ''),
+  supplier_id BIGINT
+    REFERENCES public.suppliers(supplier_id)
+    ON DELETE CASCADE,
+  min_unit_cost NUMERIC(12,2) CHECK (min_unit_cost >= 0),
+  max_unit_cost NUMERIC(12,2) CHECK (max_unit_cost >= 0),
+  markup_percent NUMERIC(7,2) NOT NULL CHECK (markup_percent >= 0),
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT chk_parts_markup_rules_band CHECK (
+    min_unit_cost IS NULL OR max_unit_cost IS NULL OR min_unit_cost < max_unit_cost
+  )
+);
+
+CREATE INDEX IF NOT EXISTS idx_parts_markup_rules_supplier_id
+  ON public.parts_markup_rules(supplier_id);
+
+ALTER TABLE public.work_order_line_items
+  ADD COLUMN IF NOT EXISTS parts_purchase_request_id BIGINT
+    REFERENCES public.parts_purchase_requests(parts_purchase_request_id)
+    ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS cost_total NUMERIC(12,2);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_work_order_line_items_parts_purchase_request_id
+  ON public.work_order_line_items(parts_purchase_request_id)
+  WHERE parts_purchase_request_id IS NOT NULL;
+
+ALTER TABLE public.work_orders
+  ADD COLUMN IF NOT EXISTS parts_cost_total NUMERIC(12,2);
+
+INSERT INTO resources (name, description)
+VALUES ('parts_markup_rules', 'Markup rules that price used parts onto customer line items')
+ON CONFLICT (name) DO NOTHING;
+
+WITH target_resource AS (
+  SELECT id, name
+  FROM resources
+  WHERE name = 'parts_markup_rules'
+), actions AS (
+  SELECT unnest(ARRAY['create','read','update','delete','assign']) AS action
+)
+INSERT INTO permissions (resource_id, action, code)
+SELECT tr.id, a.action, tr.name || ':' || a.action
+FROM target_resource tr
+CROSS JOIN actions a
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON TRUE
+WHERE r.name = 'owner'
+  AND p.code LIKE 'parts_markup_rules:%'
+ON CONFLICT DO NOTHING;
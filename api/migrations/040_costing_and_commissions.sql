@@ -0,0 +1,46 @@
+ALTER TABLE public.workers
+  ADD COLUMN IF NOT EXISTS hourly_rate NUMERIC(10,2) CHECK (hourly_rate >= 0);
+
+CREATE TABLE IF NOT EXISTS public.commission_rules (
+  commission_rule_id BIGSERIAL PRIMARY KEY,
+  rule_name TEXT NOT NULL CHECK (BTRIM(rule_name) <> ''),
+  worker_id BIGINT
+    REFERENCES public.workers(worker_id)
+    ON DELETE CASCADE,
+  job_type_id BIGINT
+    REFERENCES public.job_types(job_type_id)
+    ON DELETE CASCADE,
+  basis TEXT NOT NULL CHECK (basis IN ('revenue', 'labour', 'margin')),
+  rate_percent NUMERIC(6,2) NOT NULL CHECK (rate_percent >= 0 AND rate_percent <= 100),
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_commission_rules_worker_id
+  ON public.commission_rules(worker_id);
+
+INSERT INTO resources (name, description)
+VALUES ('costing', 'Job profitability, technician rates, and commission rules')
+ON CONFLICT (name) DO NOTHING;
+
+WITH target_resource AS (
+  SELECT id, name
+  FROM resources
+  WHERE name = 'costing'
+), actions AS (
+  SELECT unnest(ARRAY['create','read','update','delete','assign']) AS action
+)
+INSERT INTO permissions (resource_id, action, code)
+SELECT tr.id, a.action, tr.name || ':' || a.action
+FROM target_resource tr
+CROSS JOIN actions a
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON TRUE
+WHERE r.name = 'owner'
+  AND p.code LIKE 'costing:%'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,23 @@
+ALTER TABLE public.users
+  DROP CONSTRAINT IF EXISTS users_status_check;
+
+ALTER TABLE public.users
+  ADD CONSTRAINT users_status_check
+  CHECK (status IN ('invited', 'active', 'disabled', 'deleted'));
+
+CREATE TABLE IF NOT EXISTS public.account_tokens (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  purpose TEXT NOT NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ,
+  created_by_user_id UUID REFERENCES public.users(id) ON DELETE SET NULL,
+  created_by_ip TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT chk_account_tokens_purpose CHECK (purpose IN ('invite', 'password_reset'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_tokens_user_id
+  ON public.account_tokens(user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE public.users
+  ADD COLUMN IF NOT EXISTS permissions_version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS public.login_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID REFERENCES public.users(id) ON DELETE SET NULL,
+  email TEXT NOT NULL,
+  outcome TEXT NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  ip_address TEXT NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  actor_user_id UUID REFERENCES public.users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT chk_login_events_outcome CHECK (outcome IN ('success', 'failure', 'throttled', 'unlocked'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_email_created_at
+  ON public.login_events(email, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_ip_created_at
+  ON public.login_events(ip_address, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_login_events_user_id_created_at
+  ON public.login_events(user_id, created_at DESC);
@@ -0,0 +1,50 @@
+CREATE TABLE IF NOT EXISTS public.api_keys (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name TEXT NOT NULL CHECK (BTRIM(name) <> ''),
+  prefix TEXT NOT NULL UNIQUE,
+  key_hash TEXT NOT NULL UNIQUE,
+  created_by_user_id UUID NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  last_used_ip TEXT NOT NULL DEFAULT '',
+  revoked_at TIMESTAMPTZ,
+  revoked_by_user_id UUID REFERENCES public.users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_created_by_user_id
+  ON public.api_keys(created_by_user_id);
+
+CREATE TABLE IF NOT EXISTS public.api_key_permissions (
+  api_key_id UUID NOT NULL REFERENCES public.api_keys(id) ON DELETE CASCADE,
+  permission_id UUID NOT NULL REFERENCES public.permissions(id) ON DELETE CASCADE,
+  PRIMARY KEY (api_key_id, permission_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target_created_at
+  ON public.audit_logs(target_type, target_id, created_at DESC);
+
+INSERT INTO resources (name, description)
+VALUES ('api_keys', 'Scoped API keys for machine integrations')
+ON CONFLICT (name) DO NOTHING;
+
+WITH target_resource AS (
+  SELECT id, name
+  FROM resources
+  WHERE name = 'api_keys'
+), actions AS (
+  SELECT unnest(ARRAY['create','read','delete']) AS action
+)
+INSERT INTO permissions (resource_id, action, code)
+SELECT tr.id, a.action, tr.name || ':' || a.action
+FROM target_resource tr
+CROSS JOIN actions a
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON TRUE
+WHERE r.name = 'owner'
+  AND p.code LIKE 'api_keys:%'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS public.oidc_login_states (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  state_hash TEXT NOT NULL UNIQUE,
+  nonce TEXT NOT NULL,
+  code_verifier TEXT NOT NULL,
+  return_to TEXT NOT NULL DEFAULT '/',
+  expires_at TIMESTAMPTZ NOT NULL,
+  consumed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  created_by_ip TEXT,
+  user_agent TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires_at
+  ON public.oidc_login_states(expires_at);
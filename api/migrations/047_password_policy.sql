@@ -0,0 +1,9 @@
+ALTER TABLE public.users
+  ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE public.account_tokens
+  DROP CONSTRAINT IF EXISTS chk_account_tokens_purpose;
+
+ALTER TABLE public.account_tokens
+  ADD CONSTRAINT chk_account_tokens_purpose
+  CHECK (purpose IN ('invite', 'password_reset', 'password_change'));
@@ -0,0 +1,19 @@
+WITH target_resource AS (
+  SELECT id, name
+  FROM resources
+  WHERE name = 'users'
+)
+INSERT INTO permissions (resource_id, action, code)
+SELECT tr.id, 'impersonate', tr.name || ':impersonate'
+FROM target_resource tr
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.code = 'users:impersonate'
+WHERE r.name = 'owner'
+ON CONFLICT DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_created_at
+  ON public.audit_logs(actor_user_id, created_at DESC);
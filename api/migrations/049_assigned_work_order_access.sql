@@ -0,0 +1,17 @@
+ALTER TABLE public.users
+  ADD COLUMN IF NOT EXISTS worker_id BIGINT
+    REFERENCES public.workers(worker_id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_worker_id
+  ON public.users(worker_id)
+  WHERE worker_id IS NOT NULL;
+
+WITH target_resources AS (
+  SELECT id, name
+  FROM resources
+  WHERE name IN ('work_orders', 'repair_logs', 'parts_purchase_requests')
+)
+INSERT INTO permissions (resource_id, action, code)
+SELECT tr.id, 'read:assigned', tr.name || ':read:assigned'
+FROM target_resources tr
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,13 @@
+-- One row per work order a linked user is assigned to through their worker,
+-- so reports and queues can join user activity (repair logs, parts requests)
+-- to assignments on (user_id, reference_id).
+CREATE OR REPLACE VIEW public.user_worker_assignments AS
+SELECT
+  u.id AS user_id,
+  u.worker_id,
+  w.worker_name,
+  wo.reference_id
+FROM public.users u
+JOIN public.workers w ON w.worker_id = u.worker_id
+JOIN public.work_orders wo ON u.worker_id = ANY(wo.worker_ids)
+WHERE u.deleted_at IS NULL;
@@ -0,0 +1,9 @@
+-- Roles created from, or reset to, a built-in template remember its key so
+-- the template can be reapplied to all of them once later migrations add
+-- permissions it should include.
+ALTER TABLE public.roles
+  ADD COLUMN IF NOT EXISTS template_key TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_roles_template_key
+  ON public.roles(template_key)
+  WHERE template_key IS NOT NULL;
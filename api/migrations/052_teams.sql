@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS public.teams (
+  team_id BIGSERIAL PRIMARY KEY,
+  team_name TEXT NOT NULL CHECK (BTRIM(team_name) <> ''),
+  description TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CONSTRAINT uq_teams_name UNIQUE (team_name)
+);
+
+CREATE TABLE IF NOT EXISTS public.team_members (
+  team_id BIGINT NOT NULL
+    REFERENCES public.teams(team_id)
+    ON DELETE CASCADE,
+  user_id UUID NOT NULL
+    REFERENCES public.users(id)
+    ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_members_user_id
+  ON public.team_members(user_id);
+
+-- A work order is owned by at most one team; deleting the team returns its
+-- jobs to the unassigned pool.
+ALTER TABLE public.work_orders
+  ADD COLUMN IF NOT EXISTS team_id BIGINT
+    REFERENCES public.teams(team_id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_work_orders_team_id
+  ON public.work_orders(team_id)
+  WHERE team_id IS NOT NULL;
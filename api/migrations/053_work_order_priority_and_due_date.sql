@@ -0,0 +1,21 @@
+ALTER TABLE public.work_orders
+  ADD COLUMN IF NOT EXISTS priority TEXT NOT NULL DEFAULT 'normal',
+  ADD COLUMN IF NOT EXISTS due_at TIMESTAMPTZ;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (
+    SELECT 1
+    FROM pg_constraint
+    WHERE conname = 'chk_work_orders_priority'
+      AND conrelid = 'public.work_orders'::regclass
+  ) THEN
+    ALTER TABLE public.work_orders
+      ADD CONSTRAINT chk_work_orders_priority
+      CHECK (priority IN ('low', 'normal', 'high', 'urgent'));
+  END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_work_orders_due_at
+  ON public.work_orders(due_at)
+  WHERE due_at IS NOT NULL;
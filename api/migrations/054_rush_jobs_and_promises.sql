@@ -0,0 +1,36 @@
+ALTER TABLE public.work_orders
+  ADD COLUMN IF NOT EXISTS is_rush BOOLEAN NOT NULL DEFAULT FALSE,
+  ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+
+-- completed_at is when the bench finished the job (staged for pickup or
+-- completed), which is what a promised date is measured against.
+UPDATE public.work_orders wo
+SET completed_at = COALESCE(wo.status_updated_at, wo.updated_at, wo.created_at)
+FROM public.work_order_statuses st
+WHERE st.status_id = wo.status_id
+  AND st.status_group IN ('staged', 'completed')
+  AND wo.completed_at IS NULL;
+
+ALTER TABLE public.work_order_line_items
+  ADD COLUMN IF NOT EXISTS is_rush_surcharge BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_work_order_line_items_rush_surcharge
+  ON public.work_order_line_items(reference_id)
+  WHERE is_rush_surcharge;
+
+CREATE TABLE IF NOT EXISTS public.work_order_due_date_changes (
+  due_date_change_id BIGSERIAL PRIMARY KEY,
+  reference_id INTEGER NOT NULL
+    REFERENCES public.work_orders(reference_id)
+    ON DELETE CASCADE,
+  previous_due_at TIMESTAMPTZ,
+  new_due_at TIMESTAMPTZ,
+  reason TEXT,
+  changed_by_user_id UUID
+    REFERENCES public.users(id)
+    ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_work_order_due_date_changes_reference_id
+  ON public.work_order_due_date_changes(reference_id, created_at);
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS public.work_order_comments (
+  comment_id BIGSERIAL PRIMARY KEY,
+  reference_id INTEGER NOT NULL
+    REFERENCES public.work_orders(reference_id)
+    ON DELETE CASCADE,
+  body TEXT NOT NULL CHECK (BTRIM(body) <> ''),
+  created_by_user_id UUID
+    REFERENCES public.users(id)
+    ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  edited_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_work_order_comments_reference_id
+  ON public.work_order_comments(reference_id, created_at);
+
+-- Each edit keeps the body it replaced, so the thread shows what was said
+-- before a comment was reworded.
+CREATE TABLE IF NOT EXISTS public.work_order_comment_revisions (
+  revision_id BIGSERIAL PRIMARY KEY,
+  comment_id BIGINT NOT NULL
+    REFERENCES public.work_order_comments(comment_id)
+    ON DELETE CASCADE,
+  body TEXT NOT NULL,
+  edited_by_user_id UUID
+    REFERENCES public.users(id)
+    ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_work_order_comment_revisions_comment_id
+  ON public.work_order_comment_revisions(comment_id, created_at);
+
+CREATE TABLE IF NOT EXISTS public.work_order_comment_mentions (
+  comment_id BIGINT NOT NULL
+    REFERENCES public.work_order_comments(comment_id)
+    ON DELETE CASCADE,
+  user_id UUID NOT NULL
+    REFERENCES public.users(id)
+    ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (comment_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_work_order_comment_mentions_user_id
+  ON public.work_order_comment_mentions(user_id);
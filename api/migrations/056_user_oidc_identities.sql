@@ -0,0 +1,14 @@
+-- Once an SSO sign-in has been matched to an account by verified email, the
+-- account is bound to that issuer and subject; later sign-ins match on these
+-- and never on email again.
+CREATE TABLE IF NOT EXISTS public.user_oidc_identities (
+  issuer TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  user_id UUID NOT NULL
+    REFERENCES public.users(id)
+    ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_login_at TIMESTAMPTZ,
+  PRIMARY KEY (issuer, subject),
+  CONSTRAINT uq_user_oidc_identities_user_issuer UNIQUE (user_id, issuer)
+);
@@ -0,0 +1,9 @@
+-- Notifications addressed only to a worker were never listed, since users read
+-- notifications by recipient_user_id. Hand them to the worker's linked user.
+UPDATE public.notifications n
+SET recipient_user_id = u.id
+FROM public.users u
+WHERE n.recipient_user_id IS NULL
+  AND n.recipient_worker_id IS NOT NULL
+  AND u.worker_id = n.recipient_worker_id
+  AND u.deleted_at IS NULL;
@@ -0,0 +1,25 @@
+-- The rush surcharge is a charge for the turnaround, not a part, so it is
+-- carried in its own total instead of parts_total.
+ALTER TABLE public.work_orders
+  ADD COLUMN IF NOT EXISTS rush_surcharge_total NUMERIC(12,2) NOT NULL DEFAULT 0;
+
+UPDATE public.work_orders wo
+SET
+  rush_surcharge_total = sums.surcharge,
+  parts_total = wo.parts_total - sums.surcharge
+FROM (
+  SELECT
+    li.reference_id,
+    SUM(
+      CASE
+        WHEN regexp_replace(COALESCE(li.line_total_text, ''), '[^0-9.\-]', '', 'g') ~ '^-?[0-9]+(\.[0-9]+)?$'
+          THEN (regexp_replace(COALESCE(li.line_total_text, ''), '[^0-9.\-]', '', 'g'))::numeric
+        ELSE 0::numeric
+      END
+    ) AS surcharge
+  FROM public.work_order_line_items li
+  WHERE li.is_rush_surcharge
+  GROUP BY li.reference_id
+) sums
+WHERE wo.reference_id = sums.reference_id
+  AND wo.rush_surcharge_total = 0;
@@ -0,0 +1,4 @@
+-- Restock orders are parts requests for shelf stock, not for a customer's job,
+-- so they have no work order.
+ALTER TABLE public.parts_purchase_requests
+  ALTER COLUMN reference_id DROP NOT NULL;